@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// adoptBackupSuffix is appended to a container's name while it is being recreated
+const adoptBackupSuffix = "-dce-backup"
+
+// Recreate replaces a container with one created from the spec under the same name
+// The old container is renamed and kept until the new one has started, and restored if that fails
+func (m *Manager) Recreate(spec *containerconfig.ContainerSpec) error {
+	name := spec.Name
+	backup := name + adoptBackupSuffix
+	m.forget(name)
+
+	m.logger.Printf("Stopping '%s'...", name)
+	engine := m.engineClient()
+	if err := engine.Stop(name); err != nil {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+	if err := engine.Rename(name, backup); err != nil {
+		engine.Start(name)
+		return fmt.Errorf("failed to rename container: %w", err)
+	}
+
+	containerconfig.StampCreateValues(spec)
+	containerconfig.StampConfigHash(spec)
+	if err := m.createAndStart(spec, &containerconfig.RunOptions{Name: name}, nil); err != nil {
+		m.logger.Printf("Recreating failed, restoring the original container")
+		engine.Remove("container", name, true)
+		engine.Rename(backup, name)
+		engine.Start(name)
+		return err
+	}
+
+	if err := engine.Remove("container", backup, false); err != nil {
+		m.logger.Warnf("failed to remove old container: %v", err)
+	}
+	return nil
+}
+
+// Adopt snapshots a manually created container into the history store and recreates it with the
+// managed label; labels can't be added to an existing container, so recreation is the only way
+// Named and anonymous volumes are reattached by name, so their data is kept
+func (m *Manager) Adopt(history *historyStore) error {
+	opts := containerconfig.ParseOptions{KeepAnonymousVolumes: true}
+	if m.parseOptions != nil {
+		opts.LabelFilter = m.parseOptions.LabelFilter
+	}
+	m.parseOptions = &opts
+	spec, err := m.GetContainerConfig()
+	if err != nil {
+		return err
+	}
+	if spec.Labels[containerconfig.ManagedLabel] != "" {
+		return fmt.Errorf("container '%s' is already managed", spec.Name)
+	}
+
+	path, err := history.Save(spec)
+	if err != nil {
+		return err
+	}
+	m.logger.Printf("Saved spec snapshot to %s", path)
+
+	adopted := spec.Builder().WithLabel(containerconfig.ManagedLabel, "true").Build()
+	return m.Recreate(adopted)
+}
+
+// ListManaged returns "name<TAB>status<TAB>image" lines for the containers carrying the managed label
+func (m *Manager) ListManaged() ([]string, error) {
+	containers, err := m.engineClient().ListContainers(containerFilter{All: true, Labels: []string{containerconfig.ManagedLabel}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list managed containers: %w", err)
+	}
+	var lines []string
+	for _, container := range containers {
+		lines = append(lines, container.Name+"\t"+container.Status+"\t"+container.Image)
+	}
+	return lines, nil
+}
+
+// runAdopt implements the adopt subcommand
+func runAdopt(args []string) error {
+	fs := newFlagSet("adopt")
+	dockerContext := fs.String("context", "", "docker context of the container")
+	yes := fs.Bool("yes", false, "recreate without asking for confirmation")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: adopt <container> [--context name] [--yes]")
+	}
+
+	history, err := openHistory()
+	if err != nil {
+		return err
+	}
+	if !*yes && !confirm(tr("prompt.adopt", positional[0])) {
+		fmt.Println(tr("notice.aborted"))
+		return nil
+	}
+
+	manager := NewManager(positional[0], "")
+	manager.SetDockerContext(*dockerContext)
+	if err := manager.Adopt(history); err != nil {
+		return err
+	}
+	successf(os.Stdout, "%s", tr("done.adopt", positional[0]))
+	return nil
+}
+
+// runList implements the list subcommand
+func runList(args []string) error {
+	fs := newFlagSet("list")
+	dockerContext := fs.String("context", "", "docker context to list")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	manager := NewManager("", "")
+	manager.SetDockerContext(*dockerContext)
+	manager.logger.SetOutput(os.Stderr)
+	lines, err := manager.ListManaged()
+	if err != nil {
+		return err
+	}
+	history, err := openHistory()
+	if err != nil {
+		return err
+	}
+
+	if len(lines) == 0 {
+		fmt.Println(tr("notice.no-managed"))
+		return nil
+	}
+	fmt.Printf("%-30s %-25s %-30s %s\n", "NAME", "STATUS", "IMAGE", "SNAPSHOTS")
+	for _, line := range lines {
+		fields := strings.SplitN(line, "\t", 3)
+		for len(fields) < 3 {
+			fields = append(fields, "")
+		}
+		snapshots, err := history.Snapshots(fields[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-30s %-25s %-30s %d\n", fields[0], fields[1], fields[2], len(snapshots))
+	}
+	return nil
+}
+
+// runRecreate implements the recreate subcommand
+func runRecreate(args []string) error {
+	fs := newFlagSet("recreate")
+	dockerContext := fs.String("context", "", "docker context of the container")
+	yes := fs.Bool("yes", false, "recreate without asking for confirmation")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: recreate <container> [--context name] [--yes]")
+	}
+
+	history, err := openHistory()
+	if err != nil {
+		return err
+	}
+	spec, err := history.Latest(positional[0])
+	if err != nil {
+		return err
+	}
+	if !*yes && !confirm(tr("prompt.recreate.history", spec.Name)) {
+		fmt.Println(tr("notice.aborted"))
+		return nil
+	}
+
+	manager := NewManager(spec.Name, "")
+	manager.SetDockerContext(*dockerContext)
+	if err := manager.Recreate(spec); err != nil {
+		return err
+	}
+	successf(os.Stdout, "%s", tr("done.recreate", spec.Name))
+	return nil
+}
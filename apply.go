@@ -0,0 +1,459 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// builtinNetworks are created by the daemon itself and never need to be created by apply
+var builtinNetworks = map[string]bool{"bridge": true, "host": true, "none": true}
+
+// applyPlan lists every change apply will make on the target host, in execution order
+type applyPlan struct {
+	Networks   []string
+	Volumes    []string
+	Containers []*containerconfig.ContainerSpec
+	// Drifted marks the containers that exist with a different config and are recreated
+	Drifted  map[string]bool
+	Existing []string
+}
+
+// ListContainers returns the names of the containers on the host
+// Stopped containers are included only when all is true
+func (m *Manager) ListContainers(all bool) ([]string, error) {
+	containers, err := m.engineClient().ListContainers(containerFilter{All: all})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	names := make([]string, 0, len(containers))
+	for _, container := range containers {
+		names = append(names, container.Name)
+	}
+	return names, nil
+}
+
+// CountComposeReplicas returns how many containers belong to the same compose service as the spec,
+// or 0 when the spec isn't compose-managed
+func (m *Manager) CountComposeReplicas(spec *containerconfig.ContainerSpec) (int, error) {
+	project, service := spec.Labels[containerconfig.ComposeProjectLabel], spec.Labels[containerconfig.ComposeServiceLabel]
+	if project == "" || service == "" {
+		return 0, nil
+	}
+
+	replicas, err := m.engineClient().ListContainers(containerFilter{All: true, Labels: []string{
+		containerconfig.ComposeProjectLabel + "=" + project,
+		containerconfig.ComposeServiceLabel + "=" + service,
+	}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list replicas of '%s': %w", service, err)
+	}
+	return len(replicas), nil
+}
+
+// resourceExists reports whether a docker object of the given kind (network, volume, image) exists
+func (m *Manager) resourceExists(kind, name string) bool {
+	if _, ok := m.cachedImage(name); ok && kind == "image" {
+		return true
+	}
+	_, err := m.engineClient().Inspect(kind, name)
+	return err == nil
+}
+
+// createResource creates a docker object of the given kind (network, volume)
+func (m *Manager) createResource(kind, name string) error {
+	m.logger.Printf("Creating %s '%s'...", kind, name)
+
+	if err := m.engineClient().Create(kind, name, nil); err != nil {
+		return fmt.Errorf("failed to create %s '%s': %w", kind, name, err)
+	}
+	return nil
+}
+
+// ExportAll writes the spec of every container on the host into dir, one file per container, and
+// with compose options also all of them as the services of a docker-compose.yml
+func (m *Manager) ExportAll(dir string, all bool, compose *containerconfig.ComposeExportOptions) ([]string, error) {
+	names, err := m.ListContainers(all)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory '%s': %w", dir, err)
+	}
+
+	var specs []*containerconfig.ContainerSpec
+	for _, name := range names {
+		spec, err := m.InspectContainer(name)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+
+	// Scaled compose services are exported once with their replica count
+	var written []string
+	grouped := containerconfig.GroupReplicas(specs)
+	for _, spec := range grouped {
+		path := filepath.Join(dir, spec.Name+containerconfig.SpecFileExt)
+		if err := containerconfig.WriteSpecFile(path, spec); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+
+	if compose != nil {
+		files, err := m.WriteCompose(dir, grouped, *compose)
+		written = append(written, files...)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// composeEnvFiles adds the env files compose started the containers with to opts, where this
+// machine can read them; env files opts already has for a container are kept
+func (m *Manager) composeEnvFiles(specs []*containerconfig.ContainerSpec, opts *containerconfig.ComposeExportOptions) {
+	if opts.EnvFiles == nil {
+		opts.EnvFiles = make(map[string][]containerconfig.EnvFile)
+	}
+	for _, spec := range specs {
+		files, err := containerconfig.ComposeEnvFiles(spec)
+		if err != nil {
+			m.logger.Warnf("%v", err)
+		}
+		if _, ok := opts.EnvFiles[spec.Name]; !ok && len(files) > 0 {
+			opts.EnvFiles[spec.Name] = files
+		}
+	}
+}
+
+// WriteCompose exports the specs as the services of dir/docker-compose.yml, next to the env files
+// it references, and returns the paths written
+func (m *Manager) WriteCompose(dir string, specs []*containerconfig.ContainerSpec, opts containerconfig.ComposeExportOptions) ([]string, error) {
+	m.composeEnvFiles(specs, &opts)
+	data, envFiles, err := containerconfig.ExportCompose(specs, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export compose file: %w", err)
+	}
+	var written []string
+	for _, envFile := range envFiles {
+		path := filepath.Join(dir, envFile.Name)
+		if err := os.WriteFile(path, envFile.Bytes(), 0o600); err != nil {
+			return written, fmt.Errorf("failed to write env file '%s': %w", path, err)
+		}
+		written = append(written, path)
+	}
+	path := filepath.Join(dir, containerconfig.ComposeFileName)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return written, fmt.Errorf("failed to write compose file '%s': %w", path, err)
+	}
+	return append(written, path), nil
+}
+
+// parseDependsOn parses --depends-on values, service=dependency[:condition], keyed by service
+func parseDependsOn(values []string) (map[string][]string, error) {
+	dependsOn := make(map[string][]string)
+	for _, hint := range values {
+		service, dependency, ok := strings.Cut(hint, "=")
+		if !ok || service == "" || dependency == "" {
+			return nil, fmt.Errorf("invalid --depends-on '%s', expected service=dependency[:condition]", hint)
+		}
+		dependsOn[service] = append(dependsOn[service], dependency)
+	}
+	return dependsOn, nil
+}
+
+// PlanApply compares the specs against the target host and works out what has to be created
+func (m *Manager) PlanApply(specs []*containerconfig.ContainerSpec) (*applyPlan, error) {
+	plan := &applyPlan{Drifted: make(map[string]bool)}
+	seen := make(map[string]bool)
+
+	for _, spec := range specs {
+		for _, network := range spec.Networks {
+			if builtinNetworks[network] || seen["network/"+network] {
+				continue
+			}
+			seen["network/"+network] = true
+			if !m.resourceExists("network", network) {
+				plan.Networks = append(plan.Networks, network)
+			}
+		}
+		for _, volume := range spec.NamedVolumes() {
+			if seen["volume/"+volume] {
+				continue
+			}
+			seen["volume/"+volume] = true
+			if !m.resourceExists("volume", volume) {
+				plan.Volumes = append(plan.Volumes, volume)
+			}
+		}
+	}
+
+	ordered, err := containerconfig.OrderByDependencies(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, spec := range ordered {
+		for _, replica := range spec.ExpandReplicas() {
+			exists, err := m.CheckDevContainerExists(replica.Name)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				drifted, err := m.configDrifted(replica)
+				if err != nil {
+					return nil, err
+				}
+				if !drifted {
+					plan.Existing = append(plan.Existing, replica.Name)
+					continue
+				}
+				plan.Drifted[replica.Name] = true
+			}
+			plan.Containers = append(plan.Containers, replica)
+		}
+	}
+	return plan, nil
+}
+
+// configDrifted reports whether the existing container with the spec's name was created from a
+// different config, by its config hash label or, for containers created elsewhere, its inspected spec
+func (m *Manager) configDrifted(spec *containerconfig.ContainerSpec) (bool, error) {
+	existing, err := m.InspectContainer(spec.Name)
+	if err != nil {
+		return false, err
+	}
+	hash, ok := existing.Labels[containerconfig.ConfigHashLabel]
+	if !ok {
+		hash = existing.ConfigHash()
+	}
+	return hash != spec.ConfigHash(), nil
+}
+
+// ExecuteApply creates the planned networks, volumes and containers
+func (m *Manager) ExecuteApply(plan *applyPlan) error {
+	for _, network := range plan.Networks {
+		if err := m.createResource("network", network); err != nil {
+			return err
+		}
+	}
+	for _, volume := range plan.Volumes {
+		if err := m.createResource("volume", volume); err != nil {
+			return err
+		}
+	}
+	for _, spec := range plan.Containers {
+		containerconfig.StampConfigHash(spec)
+		if plan.Drifted[spec.Name] {
+			m.logger.Printf("Recreating drifted container '%s'...", spec.Name)
+			if err := m.Recreate(spec); err != nil {
+				return fmt.Errorf("failed to recreate container '%s': %w", spec.Name, err)
+			}
+			continue
+		}
+		m.logger.Printf("Starting container '%s'...", spec.Name)
+		containerconfig.StampCreateValues(spec)
+		if err := m.runContainer(spec, nil); err != nil {
+			return fmt.Errorf("failed to start container '%s': %w", spec.Name, err)
+		}
+	}
+	return nil
+}
+
+// printPlan prints a human-readable preview of an apply plan
+func printPlan(plan *applyPlan, target string) {
+	fmt.Println("\n" + tr("plan.apply", target))
+	for _, network := range plan.Networks {
+		fmt.Printf("  + network   %s\n", network)
+	}
+	for _, volume := range plan.Volumes {
+		fmt.Printf("  + volume    %s\n", volume)
+	}
+	for i, spec := range plan.Containers {
+		if plan.Drifted[spec.Name] {
+			fmt.Printf("  ~ container %s (%s) [step %d, config drifted, recreated]\n", spec.Name, spec.Image, i+1)
+			continue
+		}
+		fmt.Printf("  + container %s (%s) [step %d]\n", spec.Name, spec.Image, i+1)
+	}
+	for _, name := range plan.Existing {
+		fmt.Printf("  = container %s (up to date, skipped)\n", name)
+	}
+	if len(plan.Networks)+len(plan.Volumes)+len(plan.Containers) == 0 {
+		fmt.Println("  " + tr("notice.nothing-to-do"))
+	}
+}
+
+// runExportAll implements the export-all subcommand
+func runExportAll(args []string) error {
+	fs := newFlagSet("export-all")
+	dockerContext := fs.String("context", "", "docker context to export from")
+	all := fs.Bool("all", false, "include stopped containers")
+	compose := fs.Bool("compose", false, "also write all containers as the services of <dir>/docker-compose.yml")
+	var dependsOn stringList
+	fs.Var(&dependsOn, "depends-on", "service=dependency[:condition] added to the compose depends_on of a service (repeatable)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: export-all <dir> [--context name] [--all] [--compose [--depends-on service=dependency[:condition]]]")
+	}
+	if len(dependsOn) > 0 && !*compose {
+		return fmt.Errorf("--depends-on requires --compose")
+	}
+
+	var composeOpts *containerconfig.ComposeExportOptions
+	if *compose {
+		hints, err := parseDependsOn(dependsOn)
+		if err != nil {
+			return err
+		}
+		composeOpts = &containerconfig.ComposeExportOptions{DependsOn: hints}
+	}
+
+	manager := NewManager("", "")
+	manager.SetDockerContext(*dockerContext)
+
+	written, err := manager.ExportAll(positional[0], *all, composeOpts)
+	if err != nil {
+		return err
+	}
+	if *compose {
+		specs := 0
+		for _, path := range written {
+			if strings.HasSuffix(path, containerconfig.SpecFileExt) {
+				specs++
+			}
+		}
+		successf(os.Stdout, "\n✓ Exported %d container spec(s) and docker-compose.yml to %s", specs, positional[0])
+		return nil
+	}
+	successf(os.Stdout, "\n✓ Exported %d container spec(s) to %s", len(written), positional[0])
+	return nil
+}
+
+// runApply implements the apply subcommand
+func runApply(args []string) error {
+	fs := newFlagSet("apply")
+	targetContext := fs.String("target-context", "", "docker context to apply the specs to")
+	dryRun := fs.Bool("dry-run", false, "only print the plan")
+	yes := fs.Bool("yes", false, "apply without asking for confirmation")
+	registryAuth := fs.String("registry-auth", "", "docker config.json (or its directory) with the registry credentials of a plan's pulls")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: apply <dir>|<spec-file>|<plan.json> [--target-context name] [--dry-run] [--yes]")
+	}
+
+	var specs []*containerconfig.ContainerSpec
+	if info, err := os.Stat(positional[0]); err == nil && !info.IsDir() {
+		plan, err := readPlanFile(positional[0])
+		if err != nil {
+			return err
+		}
+		if plan != nil {
+			return applyDevPlan(plan, *targetContext, *registryAuth, *dryRun, *yes)
+		}
+		spec, err := containerconfig.ReadSpecFile(positional[0])
+		if err != nil {
+			return err
+		}
+		specs = append(specs, spec)
+	} else {
+		specs, err = containerconfig.ReadSpecDir(positional[0])
+		if err != nil {
+			return err
+		}
+	}
+
+	manager := NewManager("", "")
+	manager.SetDockerContext(*targetContext)
+
+	plan, err := manager.PlanApply(specs)
+	if err != nil {
+		return fmt.Errorf("failed to plan apply: %w", err)
+	}
+
+	target := "the current docker context"
+	if *targetContext != "" {
+		target = fmt.Sprintf("docker context '%s'", *targetContext)
+	}
+	printPlan(plan, target)
+	if len(plan.Containers) > 0 {
+		manager.preflight(plan.Containers...)
+	}
+
+	if *dryRun || len(plan.Networks)+len(plan.Volumes)+len(plan.Containers) == 0 {
+		return nil
+	}
+	if !*yes && !confirm("\n"+tr("prompt.apply")) {
+		fmt.Println(tr("notice.no-changes"))
+		return nil
+	}
+
+	if err := manager.ExecuteApply(plan); err != nil {
+		return err
+	}
+	successf(os.Stdout, "\n✓ Applied %d container(s) to %s", len(plan.Containers), target)
+	return nil
+}
+
+// applyDevPlan executes a dev container plan on its docker context, or the target context if given
+func applyDevPlan(plan *DevPlan, targetContext, registryAuth string, dryRun, yes bool) error {
+	if targetContext == "" {
+		targetContext = plan.Context
+	}
+	manager := NewManager(plan.Container, "")
+	manager.SetDockerContext(targetContext)
+	manager.SetDevOptions(DevOptions{RegistryAuth: registryAuth})
+
+	fmt.Println()
+	if err := plan.Write(os.Stdout, PlanFormatText); err != nil {
+		return err
+	}
+	if dryRun || len(plan.Actions) == 0 {
+		return nil
+	}
+	if !yes && !confirm("\n"+tr("prompt.apply")) {
+		fmt.Println(tr("notice.no-changes"))
+		return nil
+	}
+
+	if err := manager.ExecutePlan(plan); err != nil {
+		return err
+	}
+	successf(os.Stdout, "\n%s", tr("ready.title", plan.DevContainer))
+	return nil
+}
+
+// runGraph implements the graph subcommand
+func runGraph(args []string) error {
+	fs := newFlagSet("graph")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: graph <dir>")
+	}
+
+	specs, err := containerconfig.ReadSpecDir(positional[0])
+	if err != nil {
+		return err
+	}
+	if _, err := containerconfig.OrderByDependencies(specs); err != nil {
+		warnf(os.Stderr, "%v", err)
+	}
+
+	fmt.Print(containerconfig.DependencyGraphDOT(specs))
+	return nil
+}
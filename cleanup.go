@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// cleanupPlan lists the tool-created objects that are no longer needed
+type cleanupPlan struct {
+	// Tunnels are the dev containers whose recorded ssh tunnel is stopped
+	Tunnels    []string
+	Containers []string
+	Volumes    []string
+	Images     []string
+}
+
+// empty reports whether there is nothing to remove
+func (p *cleanupPlan) empty() bool {
+	return len(p.Tunnels)+len(p.Containers)+len(p.Volumes)+len(p.Images) == 0
+}
+
+// print lists the plan's objects under the given title
+func (p *cleanupPlan) print(title string) {
+	fmt.Println("\n" + title)
+	for _, name := range p.Tunnels {
+		fmt.Printf("  - tunnel    to %s\n", name)
+	}
+	for _, name := range p.Containers {
+		fmt.Printf("  - container %s\n", name)
+	}
+	for _, name := range p.Volumes {
+		fmt.Printf("  - volume    %s\n", name)
+	}
+	for _, name := range p.Images {
+		fmt.Printf("  - image     %s\n", name)
+	}
+}
+
+// PlanCleanup finds companions whose dev container is gone, stopped managed containers when
+// includeStopped is set, and tool-labelled volumes and images no container references any more
+func (m *Manager) PlanCleanup(includeStopped bool) (*cleanupPlan, error) {
+	plan := &cleanupPlan{}
+
+	existing := make(map[string]bool)
+	names, err := m.ListContainers(true)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		existing[name] = true
+	}
+
+	engine := m.engineClient()
+	companions, err := engine.ListContainers(containerFilter{All: true, Labels: []string{containerconfig.CompanionOfLabel}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list companions: %w", err)
+	}
+	for _, companion := range companions {
+		if !existing[companion.Labels[containerconfig.CompanionOfLabel]] {
+			plan.Containers = append(plan.Containers, companion.Name)
+		}
+	}
+
+	if includeStopped {
+		stopped, err := engine.ListContainers(containerFilter{All: true, Labels: []string{containerconfig.ManagedLabel}, Status: "exited"})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list stopped managed containers: %w", err)
+		}
+		for _, container := range stopped {
+			plan.Containers = append(plan.Containers, container.Name)
+		}
+	}
+
+	for _, label := range []string{containerconfig.CompanionOfLabel, containerconfig.ManagedLabel} {
+		volumes, err := engine.ListVolumes(label, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list volumes: %w", err)
+		}
+		plan.Volumes = append(plan.Volumes, volumes...)
+	}
+
+	used := make(map[string]bool)
+	for _, name := range names {
+		data, err := m.inspectContainerJSON(name)
+		if err != nil {
+			return nil, err
+		}
+		var inspected []struct{ Image string }
+		if err := json.Unmarshal([]byte(data), &inspected); err != nil {
+			return nil, fmt.Errorf("failed to parse inspect JSON for '%s': %w", name, err)
+		}
+		for _, container := range inspected {
+			used[strings.TrimPrefix(container.Image, "sha256:")] = true
+		}
+	}
+	images, err := engine.ListImages(containerconfig.ManagedLabel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	seen := make(map[string]bool)
+	for _, image := range images {
+		id, ref := strings.TrimPrefix(image.ID, "sha256:"), image.Ref
+		if used[id] || seen[id] {
+			continue
+		}
+		seen[id] = true
+		if ref == "" {
+			ref = id
+		}
+		plan.Images = append(plan.Images, ref)
+	}
+	return plan, nil
+}
+
+// ExecuteCleanup removes everything in the plan, containers first so volumes and images are released
+func (m *Manager) ExecuteCleanup(plan *cleanupPlan) error {
+	var failed []string
+	for _, name := range plan.Tunnels {
+		m.logger.Printf("Stopping tunnel to '%s'...", name)
+		if err := stopRecordedTunnel(name); err != nil {
+			m.logger.Warnf("%v", err)
+			failed = append(failed, "tunnel to "+name)
+		}
+	}
+	remove := func(kind string, names []string) {
+		for _, name := range names {
+			m.logger.Printf("Removing %s '%s'...", kind, name)
+			if err := m.removeResource(kind, name); err != nil {
+				m.logger.Warnf("%v", err)
+				failed = append(failed, name)
+			}
+		}
+	}
+	remove("container", plan.Containers)
+	remove("volume", plan.Volumes)
+	remove("image", plan.Images)
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to remove: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// runCleanup implements the cleanup subcommand
+func runCleanup(args []string) error {
+	fs := newFlagSet("cleanup")
+	dockerContext := fs.String("context", "", "docker context to clean up")
+	stopped := fs.Bool("stopped", false, "also remove stopped managed containers")
+	dryRun := fs.Bool("dry-run", false, "only print what would be removed")
+	yes := fs.Bool("yes", false, "remove without asking for confirmation")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	manager := NewManager("", "")
+	manager.SetDockerContext(*dockerContext)
+	plan, err := manager.PlanCleanup(*stopped)
+	if err != nil {
+		return err
+	}
+
+	if plan.empty() {
+		fmt.Println(tr("notice.nothing-to-clean"))
+		return nil
+	}
+	plan.print(tr("plan.cleanup"))
+
+	if *dryRun {
+		return nil
+	}
+	if !*yes && !confirm("\n"+tr("prompt.cleanup")) {
+		fmt.Println(tr("notice.aborted"))
+		return nil
+	}
+	return manager.ExecuteCleanup(plan)
+}
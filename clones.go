@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// defaultPortStep is how far apart the host ports of concurrent clones are
+const defaultPortStep = 100
+
+// nameData returns the naming template data of the manager's container, whose spec fields the
+// templates may reference
+func (m *Manager) nameData(devContainerName string) (containerconfig.NameData, error) {
+	spec, err := m.GetContainerConfig()
+	if err != nil {
+		return containerconfig.NameData{}, err
+	}
+	return containerconfig.NameData{ContainerSpec: spec, Source: m.containerName, User: containerconfig.CurrentUser(), DevName: devContainerName}, nil
+}
+
+// NextCloneName renders the name template with the lowest clone number whose name no container on
+// the host uses yet, so several developers get their own clone of the same source
+func (m *Manager) NextCloneName(text string) (string, error) {
+	tmpl, err := containerconfig.ParseNameTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	existing, err := m.ListContainers(true)
+	if err != nil {
+		return "", err
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		taken[name] = true
+	}
+
+	data, err := m.nameData("")
+	if err != nil {
+		return "", err
+	}
+	for data.N = 1; data.N <= 1000; data.N++ {
+		name, err := containerconfig.RenderName(tmpl, containerconfig.NameContainer, data)
+		if err != nil {
+			return "", err
+		}
+		if !taken[name] {
+			return name, nil
+		}
+		if !strings.Contains(text, ".N") {
+			return "", fmt.Errorf("container '%s' already exists; add {{.N}} to --name-template for numbered clones", name)
+		}
+	}
+	return "", fmt.Errorf("no free clone name found for --name-template '%s'", text)
+}
+
+// templateName renders a volume or image naming template for the dev container, or returns
+// fallback when the template is empty
+func (m *Manager) templateName(text, kind, devContainerName, volume, fallback string) (string, error) {
+	if text == "" {
+		return fallback, nil
+	}
+	tmpl, err := containerconfig.ParseNameTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	data, err := m.nameData(devContainerName)
+	if err != nil {
+		return "", err
+	}
+	data.Volume = volume
+	return containerconfig.RenderName(tmpl, kind, data)
+}
+
+// devContainerName returns the dev container's name: the dev-name argument when given, a clone
+// name from --name-template, or the container's name followed by -dev
+func (m *Manager) devContainerName(positional []string) (string, error) {
+	switch {
+	case len(positional) >= 2:
+		return positional[1], nil
+	case m.devOptions.NameTemplate != "":
+		return m.NextCloneName(m.devOptions.NameTemplate)
+	default:
+		return m.containerName + "-dev", nil
+	}
+}
+
+// publishedPortPattern matches the host side of a published port in docker ps output,
+// e.g. 0.0.0.0:8080->80/tcp or :::8000-8010->8000-8010/tcp
+var publishedPortPattern = regexp.MustCompile(`:(\d+)(?:-(\d+))?->`)
+
+// usedHostPorts returns the host ports published by the containers on the host
+func (m *Manager) usedHostPorts() (map[int]bool, error) {
+	containers, err := m.engineClient().ListContainers(containerFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list published ports: %w", err)
+	}
+	var ports []string
+	for _, container := range containers {
+		ports = append(ports, container.Ports)
+	}
+	used := make(map[int]bool)
+	for _, match := range publishedPortPattern.FindAllStringSubmatch(strings.Join(ports, "\n"), -1) {
+		first, _ := strconv.Atoi(match[1])
+		last := first
+		if match[2] != "" {
+			last, _ = strconv.Atoi(match[2])
+		}
+		for port := first; port <= last; port++ {
+			used[port] = true
+		}
+	}
+	return used, nil
+}
+
+// offsetHostPorts shifts the dev container's published host ports by the smallest multiple of the
+// port step that collides with no port published on the host, so clones don't fight over ports
+func (m *Manager) offsetHostPorts(builder *containerconfig.SpecBuilder) error {
+	ports := builder.Build().HostPorts()
+	if len(ports) == 0 {
+		return nil
+	}
+	used, err := m.usedHostPorts()
+	if err != nil {
+		return err
+	}
+
+	step := m.devOptions.PortStep
+	for offset := 0; ; offset += step {
+		free := true
+		for _, port := range ports {
+			if port+offset > 65535 {
+				return fmt.Errorf("no free host ports found for the clone in steps of %d", step)
+			}
+			if used[port+offset] {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+		if offset > 0 {
+			m.logger.Printf("Shifting published host ports by %d", offset)
+			builder.WithHostPortOffset(offset).Record(fmt.Sprintf("host port shifted by %d for a concurrent clone", offset))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// command describes a CLI subcommand
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+// commands lists the available subcommands; anything else falls back to dev container creation
+var commands = []command{
+	{name: "parse", usage: "parse [inspect.json|-] [--format json|yaml|compose]", run: runParse},
+	{name: "generate", usage: "generate [spec.yaml|-] [--format run|json|yaml|compose] [--name name] [--shell auto|sh|powershell|cmd] [--multiline] [--annotate] [--compose-service name] [--cgroup-version 1|2]", run: runGenerate},
+	{name: "extract", usage: "extract <container> [--context name] [--stats] [--ignore-label pattern] [--default-ignores]", run: runExtract},
+	{name: "export-all", usage: "export-all <dir> [--context name] [--all] [--compose [--depends-on service=dependency[:condition]]]", run: runExportAll},
+	{name: "compose", usage: "compose <container>... [--from spec.json]... [--depends-on service=dependency[:condition]]... [--output dir] [--context name]  (export containers as a docker-compose.yml)", run: runCompose},
+	{name: "apply", usage: "apply <dir>|<spec-file>|<plan.json> [--target-context name] [--dry-run] [--yes]", run: runApply},
+	{name: "plan", usage: "plan [dev flags] <container> [dev-name] [swap-dir] [--format text|json] [--output file]  (print the dev container's docker commands)", run: runPlan},
+	{name: "diff", usage: "diff <container> --compose docker-compose.yml --service web [--strict]", run: runDiff},
+	{name: "resources", usage: "resources <container>|--from spec.json [--headroom percent] [--annotate]  (infer Kubernetes requests/limits)", run: runResources},
+	{name: "kubernetes", usage: "kubernetes <container>|--from spec.json [--kind deployment|pod] [--headroom percent] [--pvc path[=size]]... [--overlay file] [--context name]  (export a Deployment or Pod)", run: runKubernetes},
+	{name: "suggest-override", usage: "suggest-override <container> [dev flags] [--format compose|flags] [--service name] [--swap-dir dir]  (print the dev modifications only)", run: runSuggestOverride},
+	{name: "fs", usage: "fs <container> ls [path] [-r] | cat <path> | cp <container-path> <host-path> [--context name]  (look at files in a container)", run: runFS},
+	{name: "collect-cores", usage: "collect-cores <dev-container> [--output dir] [--context name]  (copy out core dumps of the cores profile)", run: runCollectCores},
+	{name: "trace", usage: "trace <container> [--tool strace|tcpdump] [--duration 30s] [--pid n] [--image image] [--output file] [--context name]  (one-shot diagnostics from a sidecar)", run: runTrace},
+	{name: "health", usage: "health <container> [--context name] [--format text|json]  (print the health status and probe log)", run: runHealth},
+	{name: "report", usage: "report <container...|--all> [--format html|md|json] [--output file] [--stats] [--scan trivy]", run: runReport},
+	{name: "up", usage: "up [dev flags] <container> [dev-name] [swap-dir] | up [target...] [--project dce.yaml] [--restart on-failure|always|never] [--max-restarts n]", run: runUp},
+	{name: "debug-config", usage: "debug-config <dev-container> [--ide vscode|goland] [--output file]", run: runDebugConfig},
+	{name: "dap-proxy", usage: "dap-proxy <dev-container> [--listen addr] [--debug-port port] [--local-root dir] [--path-map local=remote]", run: runDAPProxy},
+	{name: "adopt", usage: "adopt <container> [--context name] [--yes]  (recreate a container as managed by this tool)", run: runAdopt},
+	{name: "recreate", usage: "recreate <container> [--context name] [--yes]  (recreate from the latest history snapshot)", run: runRecreate},
+	{name: "list", usage: "list [--context name]  (list managed containers)", run: runList},
+	{name: "cleanup", usage: "cleanup [--stopped] [--dry-run] [--yes]  (remove orphaned companions, volumes and images)", run: runCleanup},
+	{name: "down", usage: "down <source-container> [--context name] [--dry-run] [--yes]  (remove its dev containers and everything created for them)", run: runDown},
+	{name: "schema", usage: "schema spec|project|overlay [--output file] [--validate file]  (print or check against the JSON Schema of a file format)", run: runSchema},
+	{name: "coverage", usage: "coverage [--format text|json]  (list the docker settings a spec models and how faithfully)", run: runCoverage},
+	{name: "graph", usage: "graph <dir>  (print the container dependency graph in DOT format)", run: runGraph},
+}
+
+// findCommand returns the subcommand with the given name, or nil
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// printUsage prints the CLI usage including all subcommands
+func printUsage() {
+	fmt.Println(tr("usage.main"))
+	fmt.Println(tr("usage.command"))
+	fmt.Println("\n" + tr("usage.global"))
+	fmt.Println(tr("usage.no-color"))
+	fmt.Println(tr("usage.lang"))
+	fmt.Println("\n" + tr("usage.commands"))
+	for _, cmd := range commands {
+		fmt.Printf("  %s\n", cmd.usage)
+	}
+	fmt.Println("\n" + tr("usage.example"))
+	fmt.Println("  docker-config-extractor myapp myapp-dev /path/to/dev-swap")
+}
+
+// parseFlags parses flags that may appear before, between or after positional arguments
+// and returns the positional arguments in order
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// confirm asks a yes/no question on stdin and reports whether the answer was yes
+func confirm(question string) bool {
+	fmt.Print(question + " " + tr("prompt.yesno"))
+	var answer string
+	fmt.Scanln(&answer)
+	return isYes(answer)
+}
+
+// newFlagSet creates a flag set for a subcommand that reports errors instead of exiting
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// stringList is a repeatable string flag
+type stringList []string
+
+// String implements flag.Value
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set implements flag.Value
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// injectList is a repeatable flag collecting inject steps
+type injectList []InjectStep
+
+// String implements flag.Value
+func (l *injectList) String() string {
+	commands := make([]string, len(*l))
+	for i, step := range *l {
+		commands[i] = step.Command
+	}
+	return strings.Join(commands, ", ")
+}
+
+// Set implements flag.Value
+func (l *injectList) Set(value string) error {
+	step, err := ParseInjectStep(value)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, step)
+	return nil
+}
+
+// labelFlags holds the label ignore flags shared by several subcommands
+type labelFlags struct {
+	patterns stringList
+	defaults *bool
+}
+
+// addDevFlags registers the dev container flags shared by the default flow and up
+func addDevFlags(fs *flag.FlagSet, opts *DevOptions) {
+	fs.Var((*stringList)(&opts.Profiles), "profile", fmt.Sprintf("apply a dev profile (repeatable): %v", containerconfig.ProfileNames()))
+	fs.IntVar(&opts.ProfileOptions.PprofPort, "pprof-port", 6060, "port exposed by the pprof profile")
+	fs.StringVar(&opts.ProfileOptions.PprofEnv, "pprof-env", "", "env var set to true by the pprof profile so the app enables net/http/pprof")
+	fs.Var((*stringList)(&opts.ProfileOptions.PprofArgs), "pprof-arg", "argument appended to the command by the pprof profile (repeatable)")
+	fs.StringVar(&opts.ProfileOptions.GoMaxProcs, "gomaxprocs", "", "GOMAXPROCS set by the pprof profile")
+	fs.StringVar(&opts.ProfileOptions.GoDebug, "godebug", "", "GODEBUG set by the pprof profile and merged into the app's by the go-debug profile, e.g. schedtrace=1000,gctrace=1")
+	fs.StringVar(&opts.ProfileOptions.WatchPackage, "watch-package", ".", "Go package rebuilt by the watch profile, relative to the source directory")
+	fs.DurationVar(&opts.ProfileOptions.WatchInterval, "watch-interval", time.Second, "how often the watch profile checks the source for changes")
+	fs.StringVar(&opts.ProfileOptions.OtelEndpoint, "otel-endpoint", "", "OTLP endpoint used by the otel profile")
+	fs.StringVar(&opts.ProfileOptions.OtelServiceName, "otel-service-name", "", "service name used by the otel profile (default: dev container name)")
+	fs.Var((*stringList)(&opts.ProfileOptions.OtelResourceAttributes), "otel-attr", "key=value resource attribute added by the otel profile (repeatable)")
+	fs.StringVar(&opts.ProfileOptions.CoreDir, "core-dir", "", "host directory mounted at "+containerconfig.CoreDumpDir+" by the cores profile (default: a volume, read with collect-cores)")
+	fs.BoolVar(&opts.StartOtelCollector, "otel-collector", false, "start an OpenTelemetry collector next to the dev container (otel profile)")
+	fs.StringVar(&opts.OtelCollectorImage, "otel-collector-image", defaultOtelCollectorImage, "image of the collector started by --otel-collector")
+	fs.Var((*stringList)(&opts.Aliases), "alias", "network alias for the dev container (repeatable); the original's aliases are not inherited")
+	fs.BoolVar(&opts.Ephemeral, "ephemeral", false, "throwaway dev container: removed with everything created for it when the tool exits")
+	fs.Var((*stringList)(&opts.Copies), "copy", "host-path:container-path copied into the dev container before it starts (repeatable)")
+	fs.BoolVar(&opts.UseRun, "run", false, "start with a single docker run -d instead of create, network connect, copy and start")
+	fs.StringVar(&opts.Source, "source", "", "local checkout bind-mounted over the container's source directory: dir[:container-dir]")
+	fs.Var((*stringList)(&opts.Writable), "writable", "container path of a read-only mount to make writable in the dev container (repeatable, * for all)")
+	fs.BoolVar(&opts.HostAccess, "host-access", false, "map host.docker.internal to the docker host so the dev container can reach services on it")
+	fs.StringVar(&opts.DebuggerDir, "debugger-dir", "", "directory of prebuilt dlv_linux_<arch> binaries and a SHA256SUMS manifest, used instead of go install")
+	fs.StringVar(&opts.DebuggerKey, "debugger-key", "", "base64 ed25519 public key verifying SHA256SUMS.sig in --debugger-dir")
+	fs.StringVar(&opts.HTTPProxy, "http-proxy", "", "HTTP_PROXY for go install and inject steps (default: docker's config.json proxies, then the host's)")
+	fs.StringVar(&opts.HTTPSProxy, "https-proxy", "", "HTTPS_PROXY for go install and inject steps (default: docker's config.json proxies, then the host's)")
+	fs.StringVar(&opts.NoProxy, "no-proxy", "", "NO_PROXY for go install and inject steps (default: docker's config.json proxies, then the host's)")
+	fs.StringVar(&opts.Tools, "tools", ToolsAuto, "tools for images without a shell: sidecar (toolbox container sharing its namespaces), image (derived image with busybox) or none; asks when unset")
+	fs.StringVar(&opts.ToolboxImage, "toolbox-image", "", "toolbox image for --tools (default: DCE_TOOLBOX_IMAGE or "+defaultToolboxImage+"); --tools image needs a static /bin/busybox in it")
+	fs.StringVar(&opts.RegistryAuth, "registry-auth", "", "docker config.json (or its directory) with credentials for pulls (default: the docker CLI's own config and credential helpers)")
+	fs.BoolVar(&opts.Offline, "offline", false, "air-gapped mode: no image pulls or tool downloads; fails with the list of artifacts to pre-stage")
+	fs.Var((*stringList)(&opts.Packages), "package", "distro package installed in the dev container with its package manager (repeatable)")
+	fs.Var((*injectList)(&opts.Inject), "inject", "shell command run in the dev container once it is up (repeatable); prefix exit codes counted as success, e.g. 0,1:grep -q x /f")
+	fs.StringVar(&opts.NameTemplate, "name-template", "", "name the dev container from a template such as '{{.Name}}-dev-{{env \"USER\"}}-{{.N}}', {{.N}} being the first free clone number")
+	fs.StringVar(&opts.VolumeTemplate, "volume-template", "", "name the volume copies of --ephemeral from a template; {{.Volume}} is the copied volume, {{.DevName}} the dev container")
+	fs.StringVar(&opts.ImageTemplate, "image-template", "", "tag the derived image of --tools image from a template, e.g. 'dce-tools:{{.Name}}-{{.User}}'")
+	fs.IntVar(&opts.PortStep, "port-step", defaultPortStep, "with --name-template, shift the clone's published host ports by a multiple of this until they are free (0 keeps them)")
+	fs.StringVar(&opts.ScratchTmpfs, "scratch-tmpfs", "", "mount a tmpfs with these options, e.g. size=1g, at --scratch-path for build artifacts and core dumps")
+	fs.StringVar(&opts.ScratchPath, "scratch-path", defaultScratchPath, "container path of the --scratch-tmpfs mount")
+	fs.BoolVar(&opts.KeepRestart, "keep-restart", false, "keep the original's restart policy, which dev containers drop by default")
+	fs.BoolVar(&opts.KeepHealthcheck, "keep-healthcheck", false, "keep the original's healthcheck, which dev containers disable by default")
+	fs.BoolVar(&opts.DlvExec, "dlv-exec", false, "start the app under dlv exec instead of attaching later, to debug its startup")
+	fs.StringVar(&opts.DlvExecBinary, "dlv-exec-binary", "", "binary to run under dlv exec (default: from the entrypoint and command)")
+	fs.BoolVar(&opts.NoHealthcheck, "no-healthcheck", false, "disable the healthcheck even when the original shows none, e.g. one from the image of an image target")
+	fs.BoolVar(&opts.AllowEmulation, "allow-emulation", false, "run an image built for another architecture than the docker host's under emulation, where debuggers usually fail")
+	fs.StringVar(&opts.SELinuxRelabel, "selinux-relabel", SELinuxAuto, "SELinux relabel option added to the dev-swap, source and sync mounts: auto (z when the daemon uses SELinux), z, Z or off")
+	fs.BoolVar(&opts.AppArmorUnconfined, "apparmor-unconfined", false, "run the dev container without AppArmor confinement instead of the original's profile, e.g. when it forbids ptrace")
+	fs.BoolVar(&opts.Reuse, "reuse", false, "keep an existing dev container and only copy files and provision it again")
+	fs.BoolVar(&opts.Refresh, "refresh", false, "like --reuse, but recreate the dev container when the source container's config changed since it was created")
+	fs.StringVar(&opts.SummaryJSON, "summary-json", "", "write the end-of-run summary (steps, created objects, ports and next commands) to this file as JSON")
+	fs.StringVar(&opts.WorkingDir, "workdir", "", "working directory of the dev container, e.g. a directory under /dev-swap; created if missing")
+	fs.BoolVar(&opts.TerminalEnv, "terminal-env", false, "set LANG, LC_ALL and TERM in the dev container where the original doesn't, for interactive shells and TUIs")
+	fs.StringVar(&opts.Locale, "locale", containerconfig.DefaultLocale, "LANG and LC_ALL set by --terminal-env")
+	fs.StringVar(&opts.Term, "term", containerconfig.DefaultTerm, "TERM set by --terminal-env")
+	fs.StringVar(&opts.Timezone, "tz", "", "set TZ in the dev container, e.g. America/New_York, instead of keeping the original's timezone")
+	fs.StringVar(&opts.Dependencies, "deps", DepsAsk, "containers referenced from the env: ask, attach (share the originals) or clone")
+}
+
+// addLabelFlags registers --ignore-label and --default-ignores on a flag set
+// defaultsOn decides whether the built-in ignore list applies when --default-ignores isn't given
+func addLabelFlags(fs *flag.FlagSet, defaultsOn bool) *labelFlags {
+	lf := &labelFlags{}
+	fs.Var(&lf.patterns, "ignore-label", "ignore labels matching a glob pattern (repeatable)")
+	lf.defaults = fs.Bool("default-ignores", defaultsOn, "ignore compose/kubernetes/buildkit bookkeeping labels")
+	return lf
+}
+
+// filter builds the label filter from the flags and the DCE_IGNORE_LABELS environment variable
+// It returns nil when nothing is to be ignored
+func (lf *labelFlags) filter() (*containerconfig.LabelFilter, error) {
+	return newLabelFilter(*lf.defaults, lf.patterns)
+}
+
+// newLabelFilter combines the built-in ignore list, DCE_IGNORE_LABELS (comma-separated) and extra patterns
+func newLabelFilter(defaults bool, extra []string) (*containerconfig.LabelFilter, error) {
+	var patterns []string
+	if defaults {
+		patterns = append(patterns, containerconfig.DefaultIgnoredLabels...)
+	}
+	for _, pattern := range strings.Split(os.Getenv("DCE_IGNORE_LABELS"), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	patterns = append(patterns, extra...)
+
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	return containerconfig.NewLabelFilter(patterns)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// Dependency handling modes for containers referenced from the dev container's env
+const (
+	DepsAsk    = "ask"
+	DepsAttach = "attach"
+	DepsClone  = "clone"
+)
+
+// networkContainers lists the names of the containers attached to a network
+func (m *Manager) networkContainers(network string) ([]string, error) {
+	out, err := m.engineClient().Inspect("network", network)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect network '%s': %w", network, err)
+	}
+	var inspected []struct {
+		Containers map[string]struct{ Name string }
+	}
+	if err := json.Unmarshal([]byte(out), &inspected); err != nil {
+		return nil, fmt.Errorf("failed to parse network inspect JSON for '%s': %w", network, err)
+	}
+	if len(inspected) == 0 {
+		return nil, fmt.Errorf("empty network inspect data for '%s'", network)
+	}
+	var names []string
+	for _, container := range inspected[0].Containers {
+		names = append(names, container.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// FindEnvDependencies finds the containers sharing a user-defined network with the spec that its env
+// refers to by container or compose service name
+func (m *Manager) FindEnvDependencies(spec *containerconfig.ContainerSpec) ([]containerconfig.HostReference, map[string]*containerconfig.ContainerSpec, error) {
+	hosts := make(map[string]string)
+	specs := make(map[string]*containerconfig.ContainerSpec)
+	for _, network := range spec.Networks {
+		if builtinNetworks[network] {
+			continue
+		}
+		names, err := m.networkContainers(network)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, name := range names {
+			if name == spec.Name || specs[name] != nil {
+				continue
+			}
+			dep, err := m.InspectContainer(name)
+			if err != nil {
+				return nil, nil, err
+			}
+			specs[name] = dep
+			hosts[strings.ToLower(name)] = name
+			if service := dep.Labels[containerconfig.ComposeServiceLabel]; service != "" {
+				hosts[strings.ToLower(service)] = name
+			}
+		}
+	}
+	return containerconfig.EnvHostReferences(spec.Env, hosts), specs, nil
+}
+
+// cloneDependencies starts a fresh copy of every referenced container on a private network, each
+// answering to the host name the dev container's env uses, and moves the dev container onto it
+// Copies publish no ports and get empty anonymous volumes so the originals and their data are untouched
+func (m *Manager) cloneDependencies(devContainerName string, refs []containerconfig.HostReference, specs map[string]*containerconfig.ContainerSpec, builder *containerconfig.SpecBuilder) error {
+	network := devContainerName + "-deps"
+	if !m.resourceExists("network", network) {
+		if err := m.createResource("network", network); err != nil {
+			return err
+		}
+		m.track("network", network)
+	}
+
+	aliases := make(map[string][]string)
+	var order []string
+	for _, ref := range refs {
+		if _, ok := aliases[ref.Container]; !ok {
+			order = append(order, ref.Container)
+		}
+		aliases[ref.Container] = append(aliases[ref.Container], ref.Host)
+	}
+
+	for _, name := range order {
+		cloneName := devContainerName + "-" + name
+		exists, err := m.CheckDevContainerExists(cloneName)
+		if err != nil {
+			return err
+		}
+		if exists {
+			m.logger.Printf("Reusing existing dependency clone '%s'", cloneName)
+			continue
+		}
+
+		depBuilder := specs[name].Builder().
+			WithName(cloneName).
+			WithOnlyNetwork(network).
+			WithoutPorts().
+			WithAnonymousVolumes().
+			WithLabel(containerconfig.CompanionOfLabel, devContainerName)
+		for _, alias := range aliases[name] {
+			depBuilder.WithNetworkAlias(alias)
+		}
+		clone := depBuilder.Build()
+		clone.Links = nil
+		clone.VolumesFrom = nil
+		containerconfig.StampConfigHash(clone)
+
+		m.logger.Printf("Cloning dependency '%s' as '%s' (aliases: %s)...", name, cloneName, strings.Join(aliases[name], ", "))
+		if err := m.runContainer(clone, &containerconfig.RunOptions{Name: cloneName}); err != nil {
+			return fmt.Errorf("failed to clone dependency '%s': %w", name, err)
+		}
+		m.track("container", cloneName)
+	}
+
+	builder.WithOnlyNetwork(network)
+	return nil
+}
+
+// handleEnvDependencies decides, per DevOptions.Dependencies, whether the dev container keeps talking
+// to the original dependencies on their network or gets its own clones of them
+func (m *Manager) handleEnvDependencies(devContainerName string, spec *containerconfig.ContainerSpec, builder *containerconfig.SpecBuilder) error {
+	mode := m.devOptions.Dependencies
+	if mode == "" {
+		mode = DepsAsk
+	}
+	if mode == DepsAttach {
+		return nil
+	}
+
+	refs, specs, err := m.FindEnvDependencies(spec)
+	if err != nil {
+		return err
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	m.logger.Println("The container's environment references other containers:")
+	for _, ref := range refs {
+		m.logger.Printf("  %s -> %s (%s)", ref.EnvVar, ref.Host, ref.Container)
+	}
+
+	if mode == DepsAsk && !confirm(tr("prompt.deps.clone")) {
+		m.logger.Println("Attaching the dev container to the existing network(s)")
+		return nil
+	}
+	return m.cloneDependencies(devContainerName, refs, specs, builder)
+}
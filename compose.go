@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// runCompose implements the compose subcommand: the given containers, or extracted specs, as the
+// services of a compose file, printed on stdout or written with its env files into a directory
+func runCompose(args []string) error {
+	fs := newFlagSet("compose")
+	dockerContext := fs.String("context", "", "docker context of the containers")
+	output := fs.String("output", "", "write docker-compose.yml and its env files into this directory instead of printing it")
+	var from, dependsOn stringList
+	fs.Var(&from, "from", "read an extracted spec instead of inspecting a container (repeatable)")
+	fs.Var(&dependsOn, "depends-on", "service=dependency[:condition] added to the compose depends_on of a service (repeatable)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional)+len(from) == 0 {
+		return fmt.Errorf("usage: compose <container>... [--from spec.json]... [--depends-on service=dependency[:condition]]... [--output dir] [--context name]")
+	}
+	hints, err := parseDependsOn(dependsOn)
+	if err != nil {
+		return err
+	}
+
+	manager := NewManager("", "")
+	manager.SetDockerContext(*dockerContext)
+	manager.logger.SetOutput(os.Stderr)
+	var specs []*containerconfig.ContainerSpec
+	for _, path := range from {
+		data, err := readInput(path)
+		if err != nil {
+			return err
+		}
+		spec, err := containerconfig.UnmarshalSpec(data)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, spec)
+	}
+	for _, name := range positional {
+		spec, err := manager.InspectContainer(name)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, spec)
+	}
+	specs = containerconfig.GroupReplicas(specs)
+
+	opts := containerconfig.ComposeExportOptions{DependsOn: hints}
+	if *output != "" {
+		if err := os.MkdirAll(*output, 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory '%s': %w", *output, err)
+		}
+		written, err := manager.WriteCompose(*output, specs, opts)
+		if err != nil {
+			return err
+		}
+		for _, path := range written {
+			successf(os.Stdout, "✓ Wrote %s", path)
+		}
+		return nil
+	}
+
+	// Without a directory for env files, every env var stays in the compose file
+	opts.InlineEnv = true
+	data, _, err := containerconfig.ExportCompose(specs, opts)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
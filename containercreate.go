@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// containerCreate is a container to create, as the Engine API takes it
+type containerCreate struct {
+	Name       string                    `json:"name,omitempty"`
+	Config     *container.Config         `json:"config"`
+	HostConfig *container.HostConfig     `json:"hostConfig"`
+	Networking *network.NetworkingConfig `json:"networking,omitempty"`
+	// Args are the docker create arguments of the same container, which the CLI client runs; a plan
+	// holds them in the action's Args
+	Args []string `json:"-"`
+}
+
+// newContainerCreate converts a spec into the container the Engine API creates, with the same
+// settings GenerateRunCommand gives docker run
+func newContainerCreate(spec *containerconfig.ContainerSpec, opts *containerconfig.RunOptions) (containerCreate, error) {
+	if opts == nil {
+		opts = &containerconfig.RunOptions{}
+	}
+	create := containerCreate{Name: spec.Name, Args: containerconfig.GenerateRunCommand(spec, opts)}
+	if opts.Name != "" {
+		create.Name = opts.Name
+	}
+
+	env := []string(spec.Env)
+	if opts.EnvOverridesOnly {
+		env = spec.EnvOverrides()
+	}
+	config := &container.Config{
+		Image:      spec.Image,
+		Env:        resolveEnv(env),
+		WorkingDir: spec.WorkingDir,
+		User:       spec.User,
+		Labels:     opts.LabelFilter.Apply(spec.Labels),
+	}
+	if opts.WorkingDirOverride != "" {
+		config.WorkingDir = opts.WorkingDirOverride
+	}
+	entryPoint, command := spec.EntryPointArgs(), spec.CommandArgs()
+	if opts.OmitImageDefaults {
+		if spec.EntryPointInherited() {
+			entryPoint = nil
+		}
+		if spec.CommandInherited() {
+			command = nil
+		}
+	}
+	if len(entryPoint) > 0 {
+		config.Entrypoint = entryPoint
+	}
+	if len(command) > 0 {
+		config.Cmd = command
+	}
+	config.Healthcheck = healthConfig(spec.Healthcheck)
+
+	host := &container.HostConfig{
+		AutoRemove:  opts.Remove,
+		VolumesFrom: spec.VolumesFrom,
+		ExtraHosts:  spec.ExtraHosts,
+		Privileged:  spec.Privileged,
+		CapAdd:      spec.CapAdd,
+	}
+	if spec.AppArmorProfile != "" {
+		host.SecurityOpt = []string{"apparmor=" + spec.AppArmorProfile}
+	}
+	if err := addMounts(config, host, spec, opts); err != nil {
+		return containerCreate{}, err
+	}
+	for _, tmpfs := range spec.Tmpfs {
+		if host.Tmpfs == nil {
+			host.Tmpfs = make(map[string]string)
+		}
+		target, options, _ := strings.Cut(tmpfs, ":")
+		host.Tmpfs[target] = options
+	}
+	for _, port := range spec.Ports {
+		mappings, err := nat.ParsePortSpec(port.String())
+		if err != nil {
+			return containerCreate{}, err
+		}
+		for _, mapping := range mappings {
+			if config.ExposedPorts == nil {
+				config.ExposedPorts, host.PortBindings = make(nat.PortSet), make(nat.PortMap)
+			}
+			config.ExposedPorts[mapping.Port] = struct{}{}
+			host.PortBindings[mapping.Port] = append(host.PortBindings[mapping.Port], mapping.Binding)
+		}
+	}
+	for _, device := range spec.Devices {
+		if containerconfig.IsCDIDevice(device) {
+			host.DeviceRequests = append(host.DeviceRequests, container.DeviceRequest{Driver: "cdi", DeviceIDs: []string{device}})
+			continue
+		}
+		host.Devices = append(host.Devices, parseDevice(device))
+	}
+	if spec.Restart != "" {
+		name, count, _ := strings.Cut(spec.Restart, ":")
+		host.RestartPolicy.Name = container.RestartPolicyMode(name)
+		if count != "" {
+			var err error
+			if host.RestartPolicy.MaximumRetryCount, err = strconv.Atoi(count); err != nil {
+				return containerCreate{}, fmt.Errorf("invalid restart policy '%s'", spec.Restart)
+			}
+		}
+	}
+	if err := setResources(&host.Resources, spec); err != nil {
+		return containerCreate{}, err
+	}
+
+	create.Config, create.HostConfig = config, host
+	create.Networking = setNetworks(host, spec)
+	return create, nil
+}
+
+// resolveEnv completes KEY entries from the environment, as the CLI does for -e KEY; unset ones are
+// passed on bare
+func resolveEnv(env []string) []string {
+	resolved := make([]string, 0, len(env))
+	for _, entry := range env {
+		if !strings.Contains(entry, "=") {
+			if value, ok := os.LookupEnv(entry); ok {
+				entry += "=" + value
+			}
+		}
+		resolved = append(resolved, entry)
+	}
+	return resolved
+}
+
+// healthConfig converts the spec's healthcheck; nil keeps the image's
+func healthConfig(h *containerconfig.Healthcheck) *container.HealthConfig {
+	if h == nil {
+		return nil
+	}
+	return &container.HealthConfig{
+		Test:          h.Test,
+		Interval:      time.Duration(h.Interval),
+		Timeout:       time.Duration(h.Timeout),
+		StartPeriod:   time.Duration(h.StartPeriod),
+		StartInterval: time.Duration(h.StartInterval),
+		Retries:       h.Retries,
+	}
+}
+
+// addMounts adds the spec's volumes: binds and named volumes as binds, anonymous volumes as volumes
+// of the config, and what a bind string can't say (a volume driver, options of an anonymous
+// volume) as mounts, where RunArgs uses --mount
+func addMounts(config *container.Config, host *container.HostConfig, spec *containerconfig.ContainerSpec, opts *containerconfig.RunOptions) error {
+	loosened := make(map[string]bool)
+	for _, m := range containerconfig.LoosenedMounts(spec, opts) {
+		loosened[m.Target] = true
+	}
+	for _, m := range spec.Volumes {
+		if loosened[m.Target] {
+			m.ReadOnly = false
+		}
+		switch {
+		case m.Type == containerconfig.MountTmpfs:
+			if host.Tmpfs == nil {
+				host.Tmpfs = make(map[string]string)
+			}
+			host.Tmpfs[m.Target] = m.TmpfsOptions
+		case m.VolumeDriver != "" || m.Source == "" && (m.ReadOnly || len(m.Options) > 0):
+			volume := mount.Mount{Type: mount.TypeVolume, Source: m.Source, Target: m.Target, ReadOnly: m.ReadOnly}
+			for _, option := range m.Options {
+				if option == "nocopy" {
+					volume.VolumeOptions = &mount.VolumeOptions{NoCopy: true}
+				}
+			}
+			if m.VolumeDriver != "" {
+				if volume.VolumeOptions == nil {
+					volume.VolumeOptions = &mount.VolumeOptions{}
+				}
+				volume.VolumeOptions.DriverConfig = &mount.Driver{Name: m.VolumeDriver}
+			}
+			host.Mounts = append(host.Mounts, volume)
+		case m.Source == "":
+			if config.Volumes == nil {
+				config.Volumes = make(map[string]struct{})
+			}
+			config.Volumes[m.Target] = struct{}{}
+		default:
+			// The daemon only takes absolute bind sources; the CLI resolves relative ones
+			if strings.HasPrefix(m.Source, ".") {
+				source, err := filepath.Abs(m.Source)
+				if err != nil {
+					return err
+				}
+				m.Source = source
+			}
+			host.Binds = append(host.Binds, m.String())
+		}
+	}
+	return nil
+}
+
+// parseDevice converts a host device: host[:container][:permissions]
+func parseDevice(device string) container.DeviceMapping {
+	mapping := container.DeviceMapping{CgroupPermissions: "rwm"}
+	parts := strings.Split(device, ":")
+	mapping.PathOnHost = parts[0]
+	switch len(parts) {
+	case 2:
+		if strings.Trim(parts[1], "rwm") == "" {
+			mapping.CgroupPermissions = parts[1]
+		} else {
+			mapping.PathInContainer = parts[1]
+		}
+	case 3:
+		mapping.PathInContainer, mapping.CgroupPermissions = parts[1], parts[2]
+	}
+	if mapping.PathInContainer == "" {
+		mapping.PathInContainer = mapping.PathOnHost
+	}
+	return mapping
+}
+
+// setResources sets the spec's ulimits and memory and CPU limits, leaving out the combinations
+// docker rejects as GenerateRunCommand does
+func setResources(resources *container.Resources, spec *containerconfig.ContainerSpec) error {
+	for _, value := range spec.Ulimits {
+		ulimit, err := units.ParseUlimit(value)
+		if err != nil {
+			return err
+		}
+		resources.Ulimits = append(resources.Ulimits, ulimit)
+	}
+	resources.Memory = int64(spec.Memory)
+	resources.NanoCPUs = int64(spec.NanoCPUs)
+	resources.CPUShares = spec.CPUShares
+	resources.MemorySwappiness = spec.MemorySwappiness
+	resources.KernelMemory = int64(spec.KernelMemory)
+	resources.MemoryReservation = int64(spec.MemoryReservation)
+	if spec.Memory > 0 {
+		resources.MemorySwap = int64(spec.MemorySwap)
+	}
+	if spec.NanoCPUs == 0 {
+		resources.CPUQuota, resources.CPUPeriod = spec.CPUQuota, spec.CPUPeriod
+	}
+	return nil
+}
+
+// setNetworks sets the network mode and returns the endpoints to connect at creation: the first
+// network is the mode, and each user-defined network an endpoint, the first of which carries the
+// aliases and, as the CLI moves them there, the links
+func setNetworks(host *container.HostConfig, spec *containerconfig.ContainerSpec) *network.NetworkingConfig {
+	host.Links = spec.Links
+	if spec.NetworkMode != "" {
+		host.NetworkMode = container.NetworkMode(spec.NetworkMode)
+		return nil
+	}
+	if len(spec.Networks) == 0 {
+		return nil
+	}
+	host.NetworkMode = container.NetworkMode(spec.Networks[0])
+	endpoints := make(map[string]*network.EndpointSettings)
+	for _, name := range spec.Networks {
+		if !container.NetworkMode(name).IsUserDefined() {
+			continue
+		}
+		endpoint := &network.EndpointSettings{}
+		if len(endpoints) == 0 {
+			endpoint.Aliases = spec.NetworkAliases
+			endpoint.Links, host.Links = host.Links, nil
+		}
+		endpoints[name] = endpoint
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+	return &network.NetworkingConfig{EndpointsConfig: endpoints}
+}
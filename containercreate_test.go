@@ -0,0 +1,201 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+func TestNewContainerCreate(t *testing.T) {
+	spec := &containerconfig.ContainerSpec{
+		Name:       "app",
+		Image:      "golang:1.25",
+		Env:        containerconfig.Env{"A=1"},
+		WorkingDir: "/app",
+		Labels:     map[string]string{containerconfig.CompanionOfLabel: "app"},
+		Volumes: append(containerconfig.ParseMounts([]string{"/src:/app:ro", "/cache"}),
+			containerconfig.Mount{Type: containerconfig.MountVolume, Source: "data", Target: "/data", ReadOnly: true, VolumeDriver: "nfs"}),
+		Tmpfs:          []string{"/scratch:size=1g"},
+		Ports:          []containerconfig.PortMapping{{HostPort: 2345, ContainerPort: 2345}},
+		Networks:       []string{"backend"},
+		NetworkAliases: []string{"api"},
+		Links:          []string{"db"},
+		Devices:        []string{"/dev/fuse", "nvidia.com/gpu=0"},
+		Restart:        "on-failure:3",
+		CapAdd:         []string{"SYS_PTRACE"},
+		Ulimits:        []string{"nofile=1024:2048"},
+		Memory:         512 << 20,
+		MemorySwap:     -1,
+		NanoCPUs:       1.5e9,
+		Healthcheck:    &containerconfig.Healthcheck{Test: []string{"CMD", "curl", "-f", "localhost"}, Interval: containerconfig.Duration(30 * time.Second), Retries: 3},
+		EntryPoint:     []string{"/bin/sh"},
+		Command:        []string{"-c", "sleep infinity"},
+	}
+	create, err := newContainerCreate(spec, &containerconfig.RunOptions{Name: "app-dev", Remove: true})
+	if err != nil {
+		t.Fatalf("newContainerCreate: %v", err)
+	}
+	config, host := create.Config, create.HostConfig
+
+	if create.Name != "app-dev" || !host.AutoRemove {
+		t.Errorf("name, rm = %q, %v", create.Name, host.AutoRemove)
+	}
+	if config.Image != "golang:1.25" || config.WorkingDir != "/app" || !reflect.DeepEqual(config.Env, []string{"A=1"}) {
+		t.Errorf("image, workdir, env = %q, %q, %q", config.Image, config.WorkingDir, config.Env)
+	}
+	if !reflect.DeepEqual([]string(config.Entrypoint), []string{"/bin/sh"}) || !reflect.DeepEqual([]string(config.Cmd), []string{"-c", "sleep infinity"}) {
+		t.Errorf("entrypoint, cmd = %q, %q", config.Entrypoint, config.Cmd)
+	}
+	if !reflect.DeepEqual(host.Binds, []string{"/src:/app:ro"}) || !reflect.DeepEqual(config.Volumes, map[string]struct{}{"/cache": {}}) {
+		t.Errorf("binds, volumes = %v, %v", host.Binds, config.Volumes)
+	}
+	wantMount := mount.Mount{Type: mount.TypeVolume, Source: "data", Target: "/data", ReadOnly: true,
+		VolumeOptions: &mount.VolumeOptions{DriverConfig: &mount.Driver{Name: "nfs"}}}
+	if !reflect.DeepEqual(host.Mounts, []mount.Mount{wantMount}) {
+		t.Errorf("mounts = %+v", host.Mounts)
+	}
+	if host.Tmpfs["/scratch"] != "size=1g" {
+		t.Errorf("tmpfs = %v", host.Tmpfs)
+	}
+	if bindings := host.PortBindings[nat.Port("2345/tcp")]; len(bindings) != 1 || bindings[0].HostPort != "2345" {
+		t.Errorf("port bindings = %v", host.PortBindings)
+	}
+	if _, ok := config.ExposedPorts[nat.Port("2345/tcp")]; !ok {
+		t.Errorf("exposed ports = %v", config.ExposedPorts)
+	}
+	endpoint := create.Networking.EndpointsConfig["backend"]
+	if host.NetworkMode != "backend" || endpoint == nil || !reflect.DeepEqual(endpoint.Aliases, []string{"api"}) ||
+		!reflect.DeepEqual(endpoint.Links, []string{"db"}) || host.Links != nil {
+		t.Errorf("network mode, endpoint, links = %q, %+v, %v", host.NetworkMode, endpoint, host.Links)
+	}
+	if len(host.Devices) != 1 || host.Devices[0] != (container.DeviceMapping{PathOnHost: "/dev/fuse", PathInContainer: "/dev/fuse", CgroupPermissions: "rwm"}) {
+		t.Errorf("devices = %+v", host.Devices)
+	}
+	if len(host.DeviceRequests) != 1 || host.DeviceRequests[0].Driver != "cdi" {
+		t.Errorf("device requests = %+v", host.DeviceRequests)
+	}
+	if host.RestartPolicy != (container.RestartPolicy{Name: "on-failure", MaximumRetryCount: 3}) {
+		t.Errorf("restart policy = %+v", host.RestartPolicy)
+	}
+	if host.Memory != 512<<20 || host.MemorySwap != -1 || host.NanoCPUs != 1.5e9 || host.MemorySwappiness != nil {
+		t.Errorf("memory, swap, cpus, swappiness = %d, %d, %d, %v", host.Memory, host.MemorySwap, host.NanoCPUs, host.MemorySwappiness)
+	}
+	if len(host.Ulimits) != 1 || host.Ulimits[0].Name != "nofile" || host.Ulimits[0].Soft != 1024 || host.Ulimits[0].Hard != 2048 {
+		t.Errorf("ulimits = %+v", host.Ulimits)
+	}
+	// The exec form is passed through as is, where --health-cmd would turn it into CMD-SHELL
+	health := config.Healthcheck
+	if health == nil || !reflect.DeepEqual(health.Test, []string{"CMD", "curl", "-f", "localhost"}) || health.Interval != 30*time.Second || health.Retries != 3 {
+		t.Errorf("healthcheck = %+v", health)
+	}
+	if !reflect.DeepEqual(create.Args, containerconfig.GenerateRunCommand(spec, &containerconfig.RunOptions{Name: "app-dev", Remove: true})) {
+		t.Errorf("args = %q", create.Args)
+	}
+}
+
+func TestNewContainerCreateOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  *containerconfig.ContainerSpec
+		opts  *containerconfig.RunOptions
+		check func(*testing.T, containerCreate)
+	}{
+		{
+			name: "inherited entrypoint and command are left to the image",
+			spec: &containerconfig.ContainerSpec{Image: "app", EntryPoint: []string{"/entry"}, ImageEntryPoint: []string{"/entry"},
+				Command: []string{"serve"}, ImageCommand: []string{"serve"}},
+			opts: &containerconfig.RunOptions{OmitImageDefaults: true},
+			check: func(t *testing.T, create containerCreate) {
+				if create.Config.Entrypoint != nil || create.Config.Cmd != nil {
+					t.Errorf("entrypoint, cmd = %q, %q", create.Config.Entrypoint, create.Config.Cmd)
+				}
+			},
+		},
+		{
+			name: "working dir override",
+			spec: &containerconfig.ContainerSpec{Image: "app", WorkingDir: "/srv"},
+			opts: &containerconfig.RunOptions{WorkingDirOverride: "/workspace"},
+			check: func(t *testing.T, create containerCreate) {
+				if create.Config.WorkingDir != "/workspace" {
+					t.Errorf("working dir = %q", create.Config.WorkingDir)
+				}
+			},
+		},
+		{
+			name: "network mode keeps links on the host config",
+			spec: &containerconfig.ContainerSpec{Image: "app", NetworkMode: "bridge", Links: []string{"db"}},
+			check: func(t *testing.T, create containerCreate) {
+				if create.HostConfig.NetworkMode != "bridge" || create.Networking != nil || !reflect.DeepEqual(create.HostConfig.Links, []string{"db"}) {
+					t.Errorf("network mode, networking, links = %q, %+v, %v", create.HostConfig.NetworkMode, create.Networking, create.HostConfig.Links)
+				}
+			},
+		},
+		{
+			name: "swap without a memory limit is left out",
+			spec: &containerconfig.ContainerSpec{Image: "app", MemorySwap: -1, CPUQuota: 50000, CPUPeriod: 100000},
+			check: func(t *testing.T, create containerCreate) {
+				resources := create.HostConfig.Resources
+				if resources.MemorySwap != 0 || resources.CPUQuota != 50000 || resources.CPUPeriod != 100000 {
+					t.Errorf("swap, quota, period = %d, %d, %d", resources.MemorySwap, resources.CPUQuota, resources.CPUPeriod)
+				}
+			},
+		},
+		{
+			name: "nocopy anonymous volume is a mount",
+			spec: &containerconfig.ContainerSpec{Image: "app", Volumes: containerconfig.ParseMounts([]string{"/cache:nocopy"})},
+			check: func(t *testing.T, create containerCreate) {
+				want := []mount.Mount{{Type: mount.TypeVolume, Target: "/cache", VolumeOptions: &mount.VolumeOptions{NoCopy: true}}}
+				if !reflect.DeepEqual(create.HostConfig.Mounts, want) || create.Config.Volumes != nil {
+					t.Errorf("mounts, volumes = %+v, %v", create.HostConfig.Mounts, create.Config.Volumes)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			create, err := newContainerCreate(tt.spec, tt.opts)
+			if err != nil {
+				t.Fatalf("newContainerCreate: %v", err)
+			}
+			tt.check(t, create)
+		})
+	}
+}
+
+func TestNewContainerCreateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    *containerconfig.ContainerSpec
+		wantErr string
+	}{
+		{"restart count", &containerconfig.ContainerSpec{Image: "app", Restart: "on-failure:many"}, "invalid restart policy"},
+		{"ulimit", &containerconfig.ContainerSpec{Image: "app", Ulimits: []string{"nofile"}}, "nofile"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newContainerCreate(tt.spec, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("newContainerCreate error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDockerfileBaseImages(t *testing.T) {
+	dockerfile := "FROM --platform=linux/amd64 registry.example.com/tools:1 AS tools\n" +
+		"FROM app:latest\n" +
+		"COPY --from=tools /bin/busybox /bin/busybox\n" +
+		"FROM tools\n" +
+		"FROM scratch\n"
+	want := []string{"registry.example.com/tools:1", "app:latest"}
+	if got := dockerfileBaseImages([]byte(dockerfile)); !reflect.DeepEqual(got, want) {
+		t.Errorf("dockerfileBaseImages = %v, want %v", got, want)
+	}
+}
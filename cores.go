@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// checkCorePattern warns when the docker host's kernel.core_pattern doesn't write core dumps into
+// the cores profile's directory; the setting is host-wide, so the container can't change it
+func (m *Manager) checkCorePattern(containerName string) {
+	out, err := m.execOutput("read kernel.core_pattern", containerName, execRequest{Cmd: []string{"cat", "/proc/sys/kernel/core_pattern"}})
+	if err != nil {
+		m.logger.Warnf("couldn't read kernel.core_pattern; check on the docker host that cores are written to %s", containerconfig.CoreDumpDir)
+		return
+	}
+	pattern := strings.TrimSpace(out)
+	switch {
+	case strings.HasPrefix(pattern, "|"):
+		m.logger.Warnf("the docker host pipes core dumps to %s, so none reach %s", strings.TrimPrefix(pattern, "|"), containerconfig.CoreDumpDir)
+	case !strings.HasPrefix(pattern, containerconfig.CoreDumpDir+"/"):
+		m.logger.Warnf("kernel.core_pattern is '%s', so core dumps aren't written to %s", pattern, containerconfig.CoreDumpDir)
+	default:
+		m.logger.Printf("Core dumps go to %s", pattern)
+		return
+	}
+	m.logger.Warnf("set it on the docker host (the VM with Docker Desktop) with: sudo sysctl -w kernel.core_pattern=%s/core.%%e.%%p; this applies to every process on the host, not just this container", containerconfig.CoreDumpDir)
+}
+
+// CollectCores copies the core dumps found in the container's core directory to a host directory
+// and returns their host paths; docker cp also works once the crashed container has stopped
+func (m *Manager) CollectCores(containerName, dir string) ([]string, error) {
+	entries, err := m.ListFiles(containerName, containerconfig.CoreDumpDir, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create '%s': %w", dir, err)
+	}
+
+	var collected []string
+	for _, entry := range entries {
+		if !entry.Mode.IsRegular() {
+			continue
+		}
+		source := path.Join(containerconfig.CoreDumpDir, entry.Name)
+		target := filepath.Join(dir, entry.Name)
+		m.logger.Printf("Copying %s (%d bytes)...", source, entry.Size)
+		if err := m.engineClient().CopyFrom(containerName, source, target, false); err != nil {
+			return collected, fmt.Errorf("failed to copy '%s': %w", source, err)
+		}
+		collected = append(collected, target)
+	}
+	return collected, nil
+}
+
+// runCollectCores implements the collect-cores subcommand: pulls the core dumps of a dev container
+// created with the cores profile out to the host
+func runCollectCores(args []string) error {
+	fs := newFlagSet("collect-cores")
+	output := fs.String("output", "cores", "host directory the core dumps are copied to")
+	dockerContext := fs.String("context", "", "docker context of the dev container")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: collect-cores <dev-container> [--output dir] [--context name]")
+	}
+
+	manager := NewManager(positional[0], "")
+	manager.SetDockerContext(*dockerContext)
+	manager.logger.SetOutput(io.Discard)
+	collected, err := manager.CollectCores(positional[0], *output)
+	if err != nil {
+		return err
+	}
+	if len(collected) == 0 {
+		fmt.Fprintf(os.Stderr, "No core dumps in %s of '%s'\n", containerconfig.CoreDumpDir, positional[0])
+		return nil
+	}
+	for _, file := range collected {
+		fmt.Println(file)
+	}
+	successf(os.Stderr, "✓ Collected %d core dump(s) into %s; open one with: dlv core <binary> <core>", len(collected), *output)
+	return nil
+}
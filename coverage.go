@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// runCoverage implements the coverage subcommand: which docker inspect fields a spec models and how
+// faithfully they come back when the spec is generated
+func runCoverage(args []string) error {
+	fs := newFlagSet("coverage")
+	format := fs.String("format", "text", "output format: text or json")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("usage: coverage [--format text|json]")
+	}
+
+	entries := containerconfig.Coverage()
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	case "text":
+		fmt.Printf("%-40s %-28s %-14s %s\n", "FIELD", "FLAG", "FIDELITY", "NOTE")
+		for _, entry := range entries {
+			fmt.Printf("%-40s %-28s %-14s %s\n", entry.Field, entry.Flag, entry.Fidelity, entry.Note)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown format '%s' (expected text or json)", *format)
+	}
+}
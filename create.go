@@ -0,0 +1,131 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// execOutput runs a command in a container and returns its trimmed output; a non-zero exit is an
+// error carrying the command's stderr
+func (m *Manager) execOutput(description, containerName string, exec execRequest) (string, error) {
+	var stdout, stderr bytes.Buffer
+	code, err := m.engineClient().Exec(containerName, exec, commandIO{Stdout: &stdout, Stderr: &stderr})
+	if err == nil && code != 0 {
+		err = fmt.Errorf("exit status %d", code)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to %s: %w", description, withStderr(err, stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// withStderr adds a command's stderr to its error, unless it wrote none
+func withStderr(err error, stderr string) error {
+	if stderr = strings.TrimSpace(stderr); stderr == "" {
+		return err
+	}
+	return fmt.Errorf("%w, stderr: %s", err, stderr)
+}
+
+// ensureImage pulls the image unless it is already present, so a slow pull shows up as its own step
+func (m *Manager) ensureImage(image string) error {
+	if m.resourceExists("image", image) {
+		m.logger.Printf("Image '%s' is present", image)
+		return nil
+	}
+	if m.devOptions.Offline {
+		return fmt.Errorf("image '%s' is not present and offline mode forbids pulling it", image)
+	}
+	m.logger.Printf("Pulling image '%s'...", image)
+	return registryError(fmt.Sprintf("pull image '%s'", image), image, m.engineClient().Pull(image))
+}
+
+// createAndStart creates the container with docker create, connects its additional networks, copies
+// files into it and only then starts it, so everything is in place before the process runs
+// Copies are "host-path:container-path" pairs
+func (m *Manager) createAndStart(spec *containerconfig.ContainerSpec, opts *containerconfig.RunOptions, copies []string) error {
+	actions, err := createActions(spec, opts, copies)
+	if err != nil {
+		return err
+	}
+
+	for _, action := range actions {
+		m.logger.Printf("Running docker %s...", action.Args[0])
+		if err := m.runAction(action); err != nil {
+			return err
+		}
+		if action.Kind == ActionCreate {
+			m.track("container", action.Container)
+		}
+	}
+	m.logger.Printf("Container started: %s", actions[len(actions)-1].Container)
+	return nil
+}
+
+// mkdirArchive returns a tar archive holding only the given directory, which is extracted into a
+// created container; docker creates the missing parents, also inside mounts, which a
+// nonexistent -w under a mount otherwise fails the start on
+func mkdirArchive(dir string) (string, error) {
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	header := &tar.Header{Typeflag: tar.TypeDir, Name: strings.TrimPrefix(path.Clean(dir), "/") + "/", Mode: 0o755}
+	if err := tw.WriteHeader(header); err != nil {
+		return "", fmt.Errorf("failed to archive working directory: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return "", fmt.Errorf("failed to archive working directory: %w", err)
+	}
+	return b.String(), nil
+}
+
+// createActions returns the create, network connect, copy and start actions of createAndStart
+func createActions(spec *containerconfig.ContainerSpec, opts *containerconfig.RunOptions, copies []string) ([]PlanAction, error) {
+	primary, extraNetworks := containerconfig.SplitNetworks(spec)
+	create, err := newContainerCreate(primary, opts)
+	if err != nil {
+		return nil, err
+	}
+	name := create.Name
+
+	actions := []PlanAction{{
+		Kind:        ActionCreate,
+		Description: "create container",
+		Container:   name,
+		Args:        append([]string{"create"}, create.Args...),
+		Create:      &create,
+	}}
+	for _, network := range extraNetworks {
+		actions = append(actions, PlanAction{
+			Kind:        ActionConnect,
+			Description: fmt.Sprintf("connect network '%s'", network),
+			Container:   name,
+			Args:        []string{"network", "connect", network, name},
+			Network:     network,
+		})
+	}
+	if dir := opts.WorkingDirOverride; strings.HasPrefix(dir, "/") {
+		archive, err := mkdirArchive(dir)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, copyAction(fmt.Sprintf("create working directory '%s'", dir), name, copyRequest{Archive: archive, Target: "/"}))
+	}
+	for _, copy := range copies {
+		source, target, found := strings.Cut(copy, ":")
+		if !found || source == "" || target == "" {
+			return nil, fmt.Errorf("invalid copy '%s', expected host-path:container-path", copy)
+		}
+		actions = append(actions, copyAction(fmt.Sprintf("copy '%s' to '%s'", source, target), name, copyRequest{Source: source, Target: target}))
+	}
+	return append(actions, PlanAction{
+		Kind:        ActionStart,
+		Description: "start container",
+		Container:   name,
+		Args:        []string{"start", name},
+	}), nil
+}
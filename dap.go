@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/lhc03/docker-config-extractor/pkg/dap"
+	"github.com/lhc03/docker-config-extractor/pkg/debugconfig"
+)
+
+// runDAPProxy implements the dap-proxy subcommand
+func runDAPProxy(args []string) error {
+	fs := newFlagSet("dap-proxy")
+	listen := fs.String("listen", "127.0.0.1:4711", "address editors connect to")
+	debugPort := fs.Int("debug-port", debugconfig.DefaultDelvePort, "container port of the debug adapter (dlv, debugpy, ...)")
+	paths := addPathMappingFlags(fs, ".", "host directory mapped to the container's working directory")
+	dockerContext := fs.String("context", "", "docker context of the dev container")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: dap-proxy <dev-container> [--listen addr] [--debug-port port] [--local-root dir] [--path-map local=remote]")
+	}
+
+	manager := NewManager(positional[0], "")
+	manager.SetDockerContext(*dockerContext)
+	spec, err := manager.GetContainerConfig()
+	if err != nil {
+		return err
+	}
+
+	hostPort := publishedHostPort(spec, *debugPort)
+	if hostPort == 0 {
+		return fmt.Errorf("container '%s' does not publish port %d", spec.Name, *debugPort)
+	}
+	local, err := filepath.Abs(*paths.localRoot)
+	if err != nil {
+		return fmt.Errorf("failed to resolve local root: %w", err)
+	}
+	mappings, err := paths.mappings(spec, filepath.ToSlash(local))
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", *listen, err)
+	}
+	defer listener.Close()
+
+	proxy := &dap.Proxy{
+		Target:       fmt.Sprintf("127.0.0.1:%d", hostPort),
+		PathMappings: mappings,
+		Logger:       log.New(os.Stdout, "[DAP] ", log.LstdFlags),
+	}
+	fmt.Printf("DAP proxy for '%s' listening on %s\n", spec.Name, listener.Addr())
+	for _, mapping := range mappings {
+		fmt.Printf("  %s <-> %s\n", mapping.Local, mapping.Remote)
+	}
+	return proxy.Serve(listener)
+}
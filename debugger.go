@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Files of a prebuilt debugger directory
+const (
+	// debuggerManifest lists "<sha256>  <file>" lines, as written by sha256sum
+	debuggerManifest = "SHA256SUMS"
+	// debuggerSignature is the base64 ed25519 signature of the manifest
+	debuggerSignature = "SHA256SUMS.sig"
+)
+
+// containerArchs maps uname -m output to Go architecture names
+var containerArchs = map[string]string{
+	"x86_64":  "amd64",
+	"amd64":   "amd64",
+	"aarch64": "arm64",
+	"arm64":   "arm64",
+	"armv7l":  "arm",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+}
+
+// readPublicKey reads a base64-encoded ed25519 public key file
+func readPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key in '%s'", path)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// readDebuggerManifest reads the checksum manifest of a debugger directory and, with a public
+// key, verifies its signature; it returns the expected checksums by file name
+func readDebuggerManifest(dir, keyPath string) (map[string]string, error) {
+	manifest, err := os.ReadFile(filepath.Join(dir, debuggerManifest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksum manifest: %w", err)
+	}
+
+	if keyPath != "" {
+		key, err := readPublicKey(keyPath)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filepath.Join(dir, debuggerSignature))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest signature: %w", err)
+		}
+		signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || !ed25519.Verify(key, manifest, signature) {
+			return nil, fmt.Errorf("signature of %s does not match the public key", debuggerManifest)
+		}
+	}
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		// sha256sum marks binary mode with a leading "*"
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return sums, nil
+}
+
+// verifyChecksum checks a file's SHA256 against the manifest
+func verifyChecksum(path string, sums map[string]string) error {
+	expected, ok := sums[filepath.Base(path)]
+	if !ok {
+		return fmt.Errorf("'%s' is not listed in %s", filepath.Base(path), debuggerManifest)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read '%s': %w", path, err)
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("checksum mismatch for '%s': expected %s, got %s", filepath.Base(path), expected, actual)
+	}
+	return nil
+}
+
+// containerArch returns the Go architecture name of the container's machine
+func (m *Manager) containerArch(containerName string) (string, error) {
+	out, err := m.execOutput("detect container architecture", containerName, execRequest{Cmd: []string{"uname", "-m"}})
+	if err != nil {
+		return "", err
+	}
+	arch, ok := containerArchs[out]
+	if !ok {
+		return "", fmt.Errorf("unsupported container architecture '%s'", out)
+	}
+	return arch, nil
+}
+
+// copyDebugger copies a prebuilt dlv from the debugger directory into the container after
+// verifying it against the directory's checksum manifest; the binary is dlv_linux_<arch>
+func (m *Manager) copyDebugger(containerName string) error {
+	actions, err := m.copyDebuggerActions(containerName, containerName)
+	if err != nil {
+		return err
+	}
+	for _, action := range actions {
+		if err := m.runAction(action); err != nil {
+			return err
+		}
+	}
+	m.logger.Printf("Delve debugger copied into '%s'", containerName)
+	return nil
+}
+
+// copyDebuggerActions verifies the prebuilt dlv matching the architecture of the probe container
+// and returns the commands copying it into the container
+func (m *Manager) copyDebuggerActions(containerName, probe string) ([]PlanAction, error) {
+	dir := m.devOptions.DebuggerDir
+	if m.devOptions.DebuggerKey == "" {
+		m.logger.Warnf("no --debugger-key given; checksums are verified but the manifest's signature is not")
+	}
+	sums, err := readDebuggerManifest(dir, m.devOptions.DebuggerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	arch, err := m.containerArch(probe)
+	if err != nil {
+		return nil, err
+	}
+	binary := filepath.Join(dir, "dlv_linux_"+arch)
+	if err := verifyChecksum(binary, sums); err != nil {
+		return nil, err
+	}
+	m.logger.Printf("Checksum of %s verified", filepath.Base(binary))
+
+	return []PlanAction{
+		copyAction("copy debugger", containerName, copyRequest{Source: binary, Target: "/usr/local/bin/dlv"}),
+		execAction("make debugger executable", containerName, execRequest{User: "0", Cmd: []string{"chmod", "755", "/usr/local/bin/dlv"}}),
+	}, nil
+}
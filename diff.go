@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// runDiff implements the diff subcommand
+func runDiff(args []string) error {
+	fs := newFlagSet("diff")
+	composePath := fs.String("compose", "docker-compose.yml", "compose file to compare against")
+	service := fs.String("service", "", "compose service (defaults to the container's compose service label)")
+	project := fs.String("project", "", "compose project name (defaults to the container's compose project label)")
+	strict := fs.Bool("strict", false, "also report env vars and labels not defined in the compose file")
+	dockerContext := fs.String("context", "", "docker context of the container")
+	ignoreImageEnv := fs.Bool("ignore-image-env", true, "leave env vars inherited unchanged from the image out of the comparison")
+	labels := addLabelFlags(fs, true)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: diff <container> --compose docker-compose.yml --service web")
+	}
+
+	manager := NewManager(positional[0], "")
+	manager.SetDockerContext(*dockerContext)
+
+	actual, err := manager.GetContainerConfig()
+	if err != nil {
+		return err
+	}
+
+	if *service == "" {
+		*service = actual.Labels[containerconfig.ComposeServiceLabel]
+	}
+	if *service == "" {
+		return fmt.Errorf("container '%s' has no compose service label, pass --service", actual.Name)
+	}
+	if *project == "" {
+		*project = actual.Labels[containerconfig.ComposeProjectLabel]
+	}
+
+	data, err := os.ReadFile(*composePath)
+	if err != nil {
+		return fmt.Errorf("failed to read compose file: %w", err)
+	}
+	expected, err := containerconfig.ParseComposeService(data, *service, containerconfig.ComposeOptions{
+		Project: *project,
+		Dir:     filepath.Dir(*composePath),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, update := range containerconfig.RuntimeUpdates(actual) {
+		warnf(os.Stdout, "%s", update)
+	}
+
+	labelFilter, err := labels.filter()
+	if err != nil {
+		return err
+	}
+	diffs := containerconfig.DiffSpecs(expected, actual, containerconfig.DiffOptions{
+		OnlyExpectedKeys: !*strict,
+		LabelFilter:      labelFilter,
+		IgnoreImageEnv:   *ignoreImageEnv,
+	})
+	if len(diffs) == 0 {
+		successf(os.Stdout, "✓ Container '%s' matches service '%s' in %s", actual.Name, *service, *composePath)
+		return nil
+	}
+
+	fmt.Printf("Container '%s' drifted from service '%s' in %s:\n", actual.Name, *service, *composePath)
+	fmt.Println("  (- only in compose file, + only in container, ~ changed)")
+	for _, diff := range diffs {
+		fmt.Printf("  %s\n", diff)
+	}
+	// A non-zero exit lets scripts and CI jobs fail on drift
+	return fmt.Errorf("container '%s' drifted from service '%s' (%d difference(s))", actual.Name, *service, len(diffs))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// findBinary locates a binary of the original container, searching its PATH for a bare name, and
+// reads it; docker cp follows symlinks and needs no shell or tools in the container
+func (m *Manager) findBinary(spec *containerconfig.ContainerSpec, name string) (string, []byte, error) {
+	candidates := spec.BinaryCandidates(name)
+	for _, candidate := range candidates {
+		var data []byte
+		err := m.readArchive(m.containerName, candidate, true, func(header *tar.Header, r io.Reader) error {
+			if header.Typeflag != tar.TypeReg || data != nil {
+				return nil
+			}
+			var err error
+			data, err = io.ReadAll(r)
+			return err
+		})
+		if err == nil && data != nil {
+			return candidate, data, nil
+		}
+	}
+	return "", nil, fmt.Errorf("binary '%s' not found in '%s' (looked in %s)", name, m.containerName, strings.Join(candidates, ", "))
+}
+
+// dlvExecTransform finds the app's binary in the original container, warns when delve won't debug
+// it well, and returns the transform starting it under dlv exec
+func (m *Manager) dlvExecTransform(spec *containerconfig.ContainerSpec) (containerconfig.DevTransform, error) {
+	if m.sourceImage != "" {
+		return containerconfig.DevTransform{}, fmt.Errorf("--dlv-exec reads the binary from a container; it can't be used for a target started from an image")
+	}
+	name, args, err := spec.AppCommand()
+	if m.devOptions.DlvExecBinary != "" {
+		name, err = m.devOptions.DlvExecBinary, nil
+	}
+	if err != nil {
+		return containerconfig.DevTransform{}, err
+	}
+
+	binary, data, err := m.findBinary(spec, name)
+	if err != nil {
+		return containerconfig.DevTransform{}, err
+	}
+	m.logger.Printf("Found the app's binary: %s (%d bytes)", binary, len(data))
+	info, err := containerconfig.InspectBinary(bytes.NewReader(data))
+	if err != nil {
+		return containerconfig.DevTransform{}, fmt.Errorf("can't debug '%s': %w", binary, err)
+	}
+	for _, warning := range info.Warnings() {
+		m.logger.Warnf("%s: %s", binary, warning)
+	}
+	if info.GoVersion != "" {
+		m.logger.Printf("Starting %s (%s) under dlv exec", binary, info.GoVersion)
+	}
+	return containerconfig.DlvExec(binary, args, containerconfig.DefaultDebugPort), nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// defaultContextName is the docker context that talks to the local daemon, or to DOCKER_HOST
+const defaultContextName = "default"
+
+// dockerCLIConfig holds the settings of the docker CLI's config.json this tool follows, so it
+// targets the same daemon, uses the same proxies and pulls with the same credentials as docker
+// does on the same shell
+type dockerCLIConfig struct {
+	CurrentContext string `json:"currentContext,omitempty"`
+	// Proxies are the proxy settings docker passes to containers, keyed by daemon endpoint or "default"
+	Proxies map[string]dockerProxyConfig `json:"proxies,omitempty"`
+	// Auths are the credentials docker login stored in the file, keyed by registry
+	Auths map[string]dockerAuthConfig `json:"auths,omitempty"`
+	// CredsStore is the credential helper holding every registry's credentials instead
+	CredsStore string `json:"credsStore,omitempty"`
+	// CredHelpers are per-registry credential helpers, taking precedence over CredsStore
+	CredHelpers map[string]string `json:"credHelpers,omitempty"`
+}
+
+// dockerAuthConfig is one entry of the auths setting of config.json
+type dockerAuthConfig struct {
+	// Auth is base64 of "username:password"
+	Auth          string `json:"auth,omitempty"`
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// dockerProxyConfig is one entry of the proxies setting of config.json
+type dockerProxyConfig struct {
+	HTTPProxy  string `json:"httpProxy,omitempty"`
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	NoProxy    string `json:"noProxy,omitempty"`
+}
+
+// loadDockerCLIConfig reads config.json from the CLI's config directory; a missing file is an empty config
+func loadDockerCLIConfig() (*dockerCLIConfig, error) {
+	path := filepath.Join(defaultDockerConfigDir(), "config.json")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &dockerCLIConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read docker config: %w", err)
+	}
+	var config dockerCLIConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse docker config '%s': %w", path, err)
+	}
+	return &config, nil
+}
+
+// dockerConfig returns the CLI's config.json, read once; an unreadable one is warned about and ignored
+func (m *Manager) dockerConfig() *dockerCLIConfig {
+	if m.cliConfig == nil {
+		config, err := loadDockerCLIConfig()
+		if err != nil {
+			m.logger.Warnf("%v", err)
+			config = &dockerCLIConfig{}
+		}
+		m.cliConfig = config
+	}
+	return m.cliConfig
+}
+
+// currentContext returns the docker context the CLI uses without --context, in its own order:
+// DOCKER_HOST selects the default context, then DOCKER_CONTEXT, then config.json's currentContext
+func (m *Manager) currentContext() string {
+	if os.Getenv("DOCKER_HOST") != "" {
+		return defaultContextName
+	}
+	if name := os.Getenv("DOCKER_CONTEXT"); name != "" {
+		return name
+	}
+	if name := m.dockerConfig().CurrentContext; name != "" {
+		return name
+	}
+	return defaultContextName
+}
+
+// configProxies returns the proxies config.json sets for the manager's daemon: the entry of its
+// endpoint, otherwise the default entry
+func (m *Manager) configProxies() dockerProxyConfig {
+	proxies := m.dockerConfig().Proxies
+	if len(proxies) == 0 {
+		return dockerProxyConfig{}
+	}
+	if len(proxies) > 1 {
+		if endpoint, err := m.contextEndpoint(); err == nil {
+			if proxy, ok := proxies[endpoint.String()]; ok {
+				return proxy
+			}
+		}
+	}
+	return proxies["default"]
+}
+
+// mergeCLIConfig adds the user's currentContext and proxies to a config.json that doesn't set its
+// own, so pointing DOCKER_CONFIG at it keeps docker on the same daemon with the same proxies
+func (m *Manager) mergeCLIConfig(data []byte) ([]byte, error) {
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse registry auth: %w", err)
+	}
+	if config == nil {
+		config = make(map[string]json.RawMessage)
+	}
+	user := m.dockerConfig()
+	if _, ok := config["currentContext"]; !ok && user.CurrentContext != "" {
+		config["currentContext"], _ = json.Marshal(user.CurrentContext)
+	}
+	if _, ok := config["proxies"]; !ok && len(user.Proxies) > 0 {
+		config["proxies"], _ = json.Marshal(user.Proxies)
+	}
+	return json.MarshalIndent(config, "", "\t")
+}
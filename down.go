@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// devContainersOf returns the dev containers created from the source container: those labelled as
+// its clones, and <source>-dev when it is managed, as dev containers from before the label have no other mark
+func (m *Manager) devContainersOf(source string) ([]string, error) {
+	engine := m.engineClient()
+	clones, err := engine.ListContainers(containerFilter{All: true, Labels: []string{containerconfig.CloneOfLabel + "=" + source}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dev containers: %w", err)
+	}
+	legacy, err := engine.ListContainers(containerFilter{All: true, Labels: []string{containerconfig.ManagedLabel}, Name: source + "-dev"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dev containers: %w", err)
+	}
+	var names []string
+	for _, container := range append(clones, legacy...) {
+		if !containsString(names, container.Name) {
+			names = append(names, container.Name)
+		}
+	}
+	return names, nil
+}
+
+// PlanDown finds everything created for the dev containers of a source container: the dev containers,
+// their tunnels, companions (sidecars, collectors, cloned dependencies), volume copies and built images
+func (m *Manager) PlanDown(source string) (*cleanupPlan, error) {
+	plan := &cleanupPlan{}
+	engine := m.engineClient()
+	devs, err := m.devContainersOf(source)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dev := range devs {
+		if hasRecordedTunnel(dev) {
+			plan.Tunnels = append(plan.Tunnels, dev)
+		}
+		companion := containerconfig.CompanionOfLabel + "=" + dev
+		companions, err := engine.ListContainers(containerFilter{All: true, Labels: []string{companion}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list companions: %w", err)
+		}
+		for _, container := range companions {
+			plan.Containers = append(plan.Containers, container.Name)
+		}
+		plan.Containers = append(plan.Containers, dev)
+
+		volumes, err := engine.ListVolumes(companion, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list volumes: %w", err)
+		}
+		plan.Volumes = append(plan.Volumes, volumes...)
+
+		images, err := engine.ListImages(companion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list images: %w", err)
+		}
+		for _, image := range images {
+			ref := image.Ref
+			if ref == "" {
+				ref = strings.TrimPrefix(image.ID, "sha256:")
+			}
+			if !containsString(plan.Images, ref) {
+				plan.Images = append(plan.Images, ref)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// containsString reports whether the list holds the value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// runDown implements the down subcommand
+func runDown(args []string) error {
+	fs := newFlagSet("down")
+	dockerContext := fs.String("context", "", "docker context the dev containers run on")
+	dryRun := fs.Bool("dry-run", false, "only print what would be removed")
+	yes := fs.Bool("yes", false, "remove without asking for confirmation")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: down <source-container> [--context name] [--dry-run] [--yes]")
+	}
+
+	manager := NewManager("", "")
+	manager.SetDockerContext(*dockerContext)
+	plan, err := manager.PlanDown(positional[0])
+	if err != nil {
+		return err
+	}
+
+	if plan.empty() {
+		fmt.Println(tr("notice.nothing-to-down", positional[0]))
+		return nil
+	}
+	plan.print(tr("plan.down", positional[0]))
+
+	if *dryRun {
+		return nil
+	}
+	if !*yes && !confirm("\n"+tr("prompt.cleanup")) {
+		fmt.Println(tr("notice.aborted"))
+		return nil
+	}
+	return manager.ExecuteCleanup(plan)
+}
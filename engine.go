@@ -0,0 +1,831 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+)
+
+// engineClientEnv selects the daemon client: "cli" makes every daemon access a docker CLI command
+const engineClientEnv = "DCE_DOCKER_CLIENT"
+
+// engineClient is the part of the Docker Engine API the Manager queries the daemon and manages
+// container lifecycles with. It is served by the Go SDK, or by the docker CLI with
+// DCE_DOCKER_CLIENT=cli
+type engineClient interface {
+	// Inspect returns what docker <kind> inspect prints for one container, image, network or
+	// volume: a JSON array holding it
+	Inspect(kind, name string) (string, error)
+	// Info returns the daemon's docker info as JSON
+	Info() ([]byte, error)
+	// ServerPlatform returns the os/arch of the daemon's host
+	ServerPlatform() (string, error)
+	ListContainers(filter containerFilter) ([]containerSummary, error)
+	// ListVolumes returns the names of the volumes carrying the label, only those no container
+	// mounts when dangling is set
+	ListVolumes(label string, dangling bool) ([]string, error)
+	// ListImages returns the images carrying the label ("key" or "key=value"), once per tag
+	ListImages(label string) ([]imageSummary, error)
+	// Create creates a network or volume with default settings and the given labels
+	Create(kind, name string, labels map[string]string) error
+	Start(name string) error
+	Stop(name string) error
+	Rename(name, newName string) error
+	// Remove removes a container, volume, image or network
+	Remove(kind, name string, force bool) error
+	// CreateContainer creates a container, pulling its image first when it is missing as docker
+	// create does, and returns the container's ID
+	CreateContainer(create containerCreate) (string, error)
+	// RunContainer creates and starts a container and streams its output until it exits, as docker
+	// run does in the foreground, and returns its exit code
+	RunContainer(create containerCreate, stdio commandIO) (int, error)
+	// Connect connects a container to a network
+	Connect(network, container string) error
+	// Exec runs a command in a running container and returns its exit code; stdin is attached when
+	// stdio has one
+	Exec(container string, exec execRequest, stdio commandIO) (int, error)
+	// CopyTo copies a host file or directory, or the entries of a tar archive, into a container the
+	// way docker cp does
+	CopyTo(container string, copy copyRequest) error
+	// CopyFrom copies a container path to a host path the way docker cp does; with follow, a symlink
+	// is copied as the file it points to
+	CopyFrom(container, source, target string, follow bool) error
+	// ReadArchive calls read with a container path as the tar archive docker cp streams
+	ReadArchive(container, source string, follow bool, read func(io.Reader) error) error
+	// Pull pulls an image with the registry credentials docker would use
+	Pull(image string) error
+	// Build builds an image from a Dockerfile alone, with the credentials of the registries its FROM
+	// lines name
+	Build(build buildRequest) error
+	// Logs writes a container's logs; with Follow, until the container stops or ctx is cancelled
+	Logs(ctx context.Context, container string, logs logsRequest, stdio commandIO) error
+	// Wait blocks until a container stops and returns its exit code
+	Wait(container string) (int, error)
+	// Events streams the given events of a container from since on, until ctx is cancelled; the
+	// error channel reports why the stream ended, nil or io.EOF when the daemon closed it
+	Events(ctx context.Context, container string, since time.Time, actions []string) (<-chan containerEvent, <-chan error)
+	// Stats returns a container's resource usage once, as the line docker stats --no-stream
+	// --format '{{json .}}' prints
+	Stats(container string) ([]byte, error)
+	// Top returns the processes of a container with the ps options given, as docker top prints them
+	Top(container string, psArgs []string) (string, error)
+}
+
+// commandIO is the standard input and output of a command; nil streams are empty or discarded
+type commandIO struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// execRequest is a command run in a container
+type execRequest struct {
+	Cmd  []string `json:"cmd"`
+	User string   `json:"user,omitempty"`
+	// Env holds KEY=value entries added to the container's environment
+	Env []string `json:"env,omitempty"`
+}
+
+// args returns the docker exec arguments of the command
+func (r execRequest) args(container string) []string {
+	args := []string{"exec"}
+	if r.User != "" {
+		args = append(args, "-u", r.User)
+	}
+	for _, env := range r.Env {
+		args = append(args, "-e", env)
+	}
+	return append(append(args, container), r.Cmd...)
+}
+
+// copyRequest copies a host path, or the entries of a tar archive, into a container
+type copyRequest struct {
+	// Source is the host file or directory; empty when Archive is copied
+	Source string `json:"source,omitempty"`
+	// Archive is a tar archive whose entries are extracted into Target, a directory
+	Archive string `json:"archive,omitempty"`
+	Target  string `json:"target"`
+}
+
+// args returns the docker cp arguments of the copy
+func (r copyRequest) args(container string) []string {
+	if r.Source == "" {
+		return []string{"cp", "-", container + ":" + r.Target}
+	}
+	return []string{"cp", r.Source, container + ":" + r.Target}
+}
+
+// buildRequest is an image built from a Dockerfile without a build context
+type buildRequest struct {
+	Dockerfile string            `json:"dockerfile"`
+	Tags       []string          `json:"tags"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// args returns the docker build arguments of the build, which reads the Dockerfile from stdin
+func (r buildRequest) args() []string {
+	args := []string{"build"}
+	for _, tag := range r.Tags {
+		args = append(args, "-t", tag)
+	}
+	for _, key := range sortedKeys(r.Labels) {
+		args = append(args, "--label", key+"="+r.Labels[key])
+	}
+	return append(args, "-")
+}
+
+// logsRequest selects the logs Logs writes
+type logsRequest struct {
+	// Follow keeps streaming new output
+	Follow bool
+	// Since leaves out older logs; zero means from the start
+	Since time.Time
+	// Tail is how many of the last lines are written; 0 means all
+	Tail int
+}
+
+// containerEvent is an event of a container, such as start, die or health_status: healthy
+type containerEvent struct {
+	Action string
+	// Attributes are the event's details, e.g. the exitCode of a die
+	Attributes map[string]string
+}
+
+// containerFilter selects the containers ListContainers returns
+type containerFilter struct {
+	// All includes stopped containers
+	All bool
+	// Name matches the container name exactly; the daemon takes the filter as a regular expression,
+	// so it is quoted
+	Name string
+	// Labels are label filters, "key" or "key=value", all of which must match
+	Labels []string
+	// Status matches the container state, e.g. exited
+	Status string
+}
+
+// containerSummary is a line of docker ps
+type containerSummary struct {
+	Name   string
+	Status string
+	Image  string
+	// Ports are the published ports as docker ps prints them, e.g. 0.0.0.0:8080->80/tcp
+	Ports  string
+	Labels map[string]string
+}
+
+// imageSummary is a line of docker image ls
+type imageSummary struct {
+	ID string
+	// Ref is repository:tag, empty for an untagged image
+	Ref string
+}
+
+// engineClient returns the daemon client, connecting on first use
+// The SDK is used unless DCE_DOCKER_CLIENT=cli
+func (m *Manager) engineClient() engineClient {
+	if m.engine == nil {
+		m.engine = m.connectEngine()
+	}
+	return m.engine
+}
+
+// connectEngine creates the SDK client for the manager's endpoint
+// When the endpoint can't be resolved, every daemon access fails with the reason, as every docker
+// command would
+func (m *Manager) connectEngine() engineClient {
+	if os.Getenv(engineClientEnv) == "cli" {
+		return cliEngine{m}
+	}
+	sdk, err := m.sdkClient()
+	if err != nil {
+		sdk, _ = client.NewClientWithOpts(client.WithDialContext(func(context.Context, string, string) (net.Conn, error) {
+			return nil, err
+		}))
+	}
+	return sdkEngine{client: sdk, m: m}
+}
+
+// sdkClient creates the SDK client for the manager's endpoint; an ssh endpoint is dialed through
+// ssh, the way the docker CLI does
+func (m *Manager) sdkClient() (*client.Client, error) {
+	endpoint, err := m.engineEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	opts := []client.Opt{client.WithAPIVersionNegotiation()}
+	switch {
+	case endpoint == nil:
+		opts = append(opts, client.FromEnv)
+	case strings.HasPrefix(endpoint.Host, "ssh://"):
+		dial, err := sshDialer(endpoint.Host)
+		if err != nil {
+			return nil, err
+		}
+		// The host only names the daemon in request URLs; every connection is dialed through ssh
+		opts = append(opts, client.WithHost("http://docker.example.com"), client.WithDialContext(dial))
+	default:
+		if endpoint.TLS != nil {
+			opts = append(opts, client.WithHTTPClient(&http.Client{Transport: &http.Transport{TLSClientConfig: endpoint.TLS}}))
+		}
+		opts = append(opts, client.WithHost(endpoint.Host))
+	}
+	sdk, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	return sdk, nil
+}
+
+// dockerEndpoint is a daemon address with the TLS settings to reach it with
+type dockerEndpoint struct {
+	Host string
+	// TLS is nil for an endpoint without TLS client settings
+	TLS *tls.Config
+}
+
+// engineEndpoint returns the endpoint the SDK client connects to: the --context endpoint, or the
+// context's endpoint from the CLI's context store; nil means the SDK's own DOCKER_HOST handling
+func (m *Manager) engineEndpoint() (*dockerEndpoint, error) {
+	if isEndpoint(m.dockerContext) {
+		return &dockerEndpoint{Host: m.dockerContext}, nil
+	}
+	name := m.dockerContext
+	if name == "" {
+		name = m.currentContext()
+	}
+	if name == defaultContextName {
+		if host := os.Getenv("DOCKER_HOST"); strings.HasPrefix(host, "ssh://") {
+			return &dockerEndpoint{Host: host}, nil
+		}
+		return nil, nil
+	}
+	return readContextEndpoint(name)
+}
+
+// contextMeta is the meta.json of a context in the CLI's context store
+type contextMeta struct {
+	Endpoints struct {
+		Docker struct {
+			Host          string
+			SkipTLSVerify bool
+		} `json:"docker"`
+	}
+}
+
+// readContextEndpoint reads a context's docker endpoint from the CLI's context store, where each
+// context is a directory named after the SHA-256 of its name, with its TLS files kept apart
+func readContextEndpoint(name string) (*dockerEndpoint, error) {
+	sum := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(sum[:])
+	store := filepath.Join(defaultDockerConfigDir(), "contexts")
+
+	data, err := os.ReadFile(filepath.Join(store, "meta", id, "meta.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("docker context '%s' not found", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read docker context '%s': %w", name, err)
+	}
+	var meta contextMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse docker context '%s': %w", name, err)
+	}
+	endpoint := &dockerEndpoint{Host: meta.Endpoints.Docker.Host}
+	if endpoint.Host == "" {
+		return nil, fmt.Errorf("docker context '%s' has no docker endpoint", name)
+	}
+
+	tlsDir := filepath.Join(store, "tls", id, "docker")
+	if _, err := os.Stat(tlsDir); err == nil || meta.Endpoints.Docker.SkipTLSVerify {
+		endpoint.TLS, err = contextTLSConfig(tlsDir, meta.Endpoints.Docker.SkipTLSVerify)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS files of docker context '%s': %w", name, err)
+		}
+	}
+	return endpoint, nil
+}
+
+// contextTLSConfig builds the client TLS settings from a context's ca.pem, cert.pem and key.pem,
+// each of which is optional
+func contextTLSConfig(dir string, skipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: skipVerify}
+	ca, err := os.ReadFile(filepath.Join(dir, "ca.pem"))
+	switch {
+	case err == nil:
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates in %s", filepath.Join(dir, "ca.pem"))
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if _, err := os.Stat(certFile); err == nil {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// sdkEngine talks to the daemon through the Docker Engine Go SDK
+type sdkEngine struct {
+	client *client.Client
+	// m resolves registry credentials for pulls and builds
+	m *Manager
+}
+
+func (e sdkEngine) Inspect(kind, name string) (string, error) {
+	ctx := context.Background()
+	var raw []byte
+	var err error
+	switch kind {
+	case "container":
+		_, raw, err = e.client.ContainerInspectWithRaw(ctx, name, false)
+	case "image":
+		var buf bytes.Buffer
+		_, err = e.client.ImageInspect(ctx, name, client.ImageInspectWithRawResponse(&buf))
+		raw = buf.Bytes()
+	case "network":
+		_, raw, err = e.client.NetworkInspectWithRaw(ctx, name, network.InspectOptions{})
+	case "volume":
+		_, raw, err = e.client.VolumeInspectWithRaw(ctx, name)
+	default:
+		return "", fmt.Errorf("unknown object kind '%s'", kind)
+	}
+	if err != nil {
+		return "", err
+	}
+	return "[" + string(bytes.TrimSpace(raw)) + "]", nil
+}
+
+func (e sdkEngine) Info() ([]byte, error) {
+	info, err := e.client.Info(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(info)
+}
+
+func (e sdkEngine) ServerPlatform() (string, error) {
+	version, err := e.client.ServerVersion(context.Background())
+	if err != nil {
+		return "", err
+	}
+	return version.Os + "/" + version.Arch, nil
+}
+
+func (e sdkEngine) ListContainers(filter containerFilter) ([]containerSummary, error) {
+	args := filters.NewArgs()
+	if filter.Name != "" {
+		args.Add("name", "^"+regexp.QuoteMeta(filter.Name)+"$")
+	}
+	for _, label := range filter.Labels {
+		args.Add("label", label)
+	}
+	if filter.Status != "" {
+		args.Add("status", filter.Status)
+	}
+	containers, err := e.client.ContainerList(context.Background(), container.ListOptions{All: filter.All, Filters: args})
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]containerSummary, 0, len(containers))
+	for _, c := range containers {
+		summary := containerSummary{Status: c.Status, Image: c.Image, Labels: c.Labels}
+		if len(c.Names) > 0 {
+			summary.Name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		var ports []string
+		for _, port := range c.Ports {
+			if port.PublicPort != 0 {
+				ports = append(ports, port.IP+":"+strconv.Itoa(int(port.PublicPort))+"->"+strconv.Itoa(int(port.PrivatePort))+"/"+port.Type)
+			}
+		}
+		summary.Ports = strings.Join(ports, ", ")
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+func (e sdkEngine) ListVolumes(label string, dangling bool) ([]string, error) {
+	args := filters.NewArgs(filters.Arg("label", label))
+	if dangling {
+		args.Add("dangling", "true")
+	}
+	list, err := e.client.VolumeList(context.Background(), volume.ListOptions{Filters: args})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, v := range list.Volumes {
+		names = append(names, v.Name)
+	}
+	return names, nil
+}
+
+func (e sdkEngine) ListImages(label string) ([]imageSummary, error) {
+	images, err := e.client.ImageList(context.Background(), image.ListOptions{Filters: filters.NewArgs(filters.Arg("label", label))})
+	if err != nil {
+		return nil, err
+	}
+	var summaries []imageSummary
+	for _, img := range images {
+		if len(img.RepoTags) == 0 {
+			summaries = append(summaries, imageSummary{ID: img.ID})
+		}
+		for _, tag := range img.RepoTags {
+			summaries = append(summaries, imageSummary{ID: img.ID, Ref: tag})
+		}
+	}
+	return summaries, nil
+}
+
+func (e sdkEngine) Create(kind, name string, labels map[string]string) error {
+	var err error
+	switch kind {
+	case "network":
+		_, err = e.client.NetworkCreate(context.Background(), name, network.CreateOptions{Labels: labels})
+	case "volume":
+		_, err = e.client.VolumeCreate(context.Background(), volume.CreateOptions{Name: name, Labels: labels})
+	default:
+		err = fmt.Errorf("unknown object kind '%s'", kind)
+	}
+	return err
+}
+
+func (e sdkEngine) Start(name string) error {
+	return e.client.ContainerStart(context.Background(), name, container.StartOptions{})
+}
+
+func (e sdkEngine) Stop(name string) error {
+	return e.client.ContainerStop(context.Background(), name, container.StopOptions{})
+}
+
+func (e sdkEngine) Rename(name, newName string) error {
+	return e.client.ContainerRename(context.Background(), name, newName)
+}
+
+func (e sdkEngine) Remove(kind, name string, force bool) error {
+	ctx := context.Background()
+	switch kind {
+	case "container":
+		return e.client.ContainerRemove(ctx, name, container.RemoveOptions{Force: force})
+	case "volume":
+		return e.client.VolumeRemove(ctx, name, force)
+	case "image":
+		_, err := e.client.ImageRemove(ctx, name, image.RemoveOptions{Force: force})
+		return err
+	case "network":
+		return e.client.NetworkRemove(ctx, name)
+	}
+	return fmt.Errorf("unknown object kind '%s'", kind)
+}
+
+// cliEngine runs the equivalent docker CLI commands, for DCE_DOCKER_CLIENT=cli
+type cliEngine struct {
+	m *Manager
+}
+
+// command builds a docker command against the manager's context or endpoint
+func (e cliEngine) command(ctx context.Context, args ...string) *exec.Cmd {
+	if isEndpoint(e.m.dockerContext) {
+		args = append([]string{"--host", e.m.dockerContext}, args...)
+	} else if e.m.dockerContext != "" {
+		args = append([]string{"--context", e.m.dockerContext}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	if errors.Is(cmd.Err, exec.ErrNotFound) {
+		cmd.Err = fmt.Errorf("docker CLI not found in PATH (%s=cli makes every daemon access a CLI command): %w", engineClientEnv, cmd.Err)
+	}
+	return cmd
+}
+
+// run runs a docker command and returns its trimmed output, with its stderr in the error
+func (e cliEngine) run(args ...string) (string, error) {
+	cmd := e.command(context.Background(), args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w, stderr: %s", err, stderr.String())
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// stream runs a docker command with the given streams; its stderr, when not passed on, is added to
+// the error
+func (e cliEngine) stream(ctx context.Context, args []string, stdio commandIO) error {
+	cmd := e.command(ctx, args...)
+	var stderr bytes.Buffer
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdio.Stdin, stdio.Stdout, stdio.Stderr
+	if cmd.Stderr == nil {
+		cmd.Stderr = &stderr
+	}
+	if err := cmd.Run(); err != nil {
+		return withStderr(err, stderr.String())
+	}
+	return nil
+}
+
+// exitCode returns the exit status of a docker command that ran, or the error that kept it from running
+func exitCode(err error) (int, error) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
+
+// registryStream is stream for a command that talks to a registry, with the --registry-auth
+// credentials merged into a temporary config directory
+func (e cliEngine) registryStream(args []string, stdin io.Reader) error {
+	cmd := e.command(context.Background(), args...)
+	if e.m.devOptions.RegistryAuth != "" {
+		dir, cleanup, err := e.m.registryAuthConfigDir()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		cmd.Env = append(os.Environ(), "DOCKER_CONFIG="+dir)
+	}
+	var stderr bytes.Buffer
+	cmd.Stdin, cmd.Stderr = stdin, &stderr
+	if err := cmd.Run(); err != nil {
+		return withStderr(err, stderr.String())
+	}
+	return nil
+}
+
+func (e cliEngine) CreateContainer(create containerCreate) (string, error) {
+	return e.run(append([]string{"create"}, create.Args...)...)
+}
+
+func (e cliEngine) RunContainer(create containerCreate, stdio commandIO) (int, error) {
+	return exitCode(e.stream(context.Background(), append([]string{"run"}, create.Args...), stdio))
+}
+
+func (e cliEngine) Connect(network, container string) error {
+	_, err := e.run("network", "connect", network, container)
+	return err
+}
+
+func (e cliEngine) Exec(container string, exec execRequest, stdio commandIO) (int, error) {
+	args := exec.args(container)
+	if stdio.Stdin != nil {
+		args = append([]string{"exec", "-i"}, args[1:]...)
+	}
+	return exitCode(e.stream(context.Background(), args, stdio))
+}
+
+func (e cliEngine) CopyTo(container string, copy copyRequest) error {
+	var stdin io.Reader
+	if copy.Source == "" {
+		stdin = strings.NewReader(copy.Archive)
+	}
+	return e.stream(context.Background(), copy.args(container), commandIO{Stdin: stdin, Stdout: io.Discard})
+}
+
+func (e cliEngine) CopyFrom(container, source, target string, follow bool) error {
+	args := []string{"cp"}
+	if follow {
+		args = append(args, "-L")
+	}
+	_, err := e.run(append(args, container+":"+source, target)...)
+	return err
+}
+
+func (e cliEngine) ReadArchive(container, source string, follow bool, read func(io.Reader) error) error {
+	args := []string{"cp"}
+	if follow {
+		args = append(args, "-L")
+	}
+	archive, stdout := io.Pipe()
+	copied := make(chan error, 1)
+	go func() {
+		err := e.stream(context.Background(), append(args, container+":"+source, "-"), commandIO{Stdout: stdout})
+		stdout.Close()
+		copied <- err
+	}()
+	readErr := read(archive)
+	// Drain so docker cp isn't blocked writing when read stopped early
+	io.Copy(io.Discard, archive)
+	if err := <-copied; err != nil {
+		return err
+	}
+	return readErr
+}
+
+func (e cliEngine) Pull(image string) error {
+	return e.registryStream([]string{"pull", image}, nil)
+}
+
+func (e cliEngine) Build(build buildRequest) error {
+	return e.registryStream(build.args(), strings.NewReader(build.Dockerfile))
+}
+
+func (e cliEngine) Logs(ctx context.Context, container string, logs logsRequest, stdio commandIO) error {
+	args := []string{"logs"}
+	if logs.Follow {
+		args = append(args, "-f")
+	}
+	if !logs.Since.IsZero() {
+		args = append(args, "--since", logs.Since.Format(time.RFC3339Nano))
+	}
+	if logs.Tail > 0 {
+		args = append(args, "--tail", strconv.Itoa(logs.Tail))
+	}
+	return e.stream(ctx, append(args, container), stdio)
+}
+
+func (e cliEngine) Wait(container string) (int, error) {
+	out, err := e.run("wait", container)
+	if err != nil {
+		return 0, err
+	}
+	code, err := strconv.Atoi(out)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected exit code output '%s'", out)
+	}
+	return code, nil
+}
+
+func (e cliEngine) Events(ctx context.Context, container string, since time.Time, actions []string) (<-chan containerEvent, <-chan error) {
+	args := []string{"events", "--since", fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond()),
+		"--filter", "type=container", "--filter", "container=" + container, "--format", "{{json .}}"}
+	for _, action := range actions {
+		args = append(args, "--filter", "event="+action)
+	}
+	out, stdout := io.Pipe()
+	go func() {
+		stdout.CloseWithError(e.stream(ctx, args, commandIO{Stdout: stdout}))
+	}()
+
+	messages, errs := make(chan containerEvent), make(chan error, 1)
+	go func() {
+		defer out.Close()
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			var message events.Message
+			if json.Unmarshal(scanner.Bytes(), &message) != nil {
+				continue
+			}
+			select {
+			case messages <- containerEvent{Action: string(message.Action), Attributes: message.Actor.Attributes}:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+		errs <- scanner.Err()
+	}()
+	return messages, errs
+}
+
+func (e cliEngine) Stats(container string) ([]byte, error) {
+	out, err := e.run("stats", "--no-stream", "--format", "{{json .}}", container)
+	return []byte(out), err
+}
+
+func (e cliEngine) Top(container string, psArgs []string) (string, error) {
+	return e.run(append([]string{"top", container}, psArgs...)...)
+}
+
+func (e cliEngine) Inspect(kind, name string) (string, error) {
+	return e.run(kind, "inspect", name)
+}
+
+func (e cliEngine) Info() ([]byte, error) {
+	out, err := e.run("info", "--format", "{{json .}}")
+	return []byte(out), err
+}
+
+func (e cliEngine) ServerPlatform() (string, error) {
+	return e.run("version", "--format", "{{.Server.Os}}/{{.Server.Arch}}")
+}
+
+func (e cliEngine) ListContainers(filter containerFilter) ([]containerSummary, error) {
+	args := []string{"ps", "--format", "{{.Names}}\t{{.Status}}\t{{.Image}}\t{{.Ports}}\t{{.Labels}}"}
+	if filter.All {
+		args = append(args, "-a")
+	}
+	if filter.Name != "" {
+		args = append(args, "--filter", "name=^"+regexp.QuoteMeta(filter.Name)+"$")
+	}
+	for _, label := range filter.Labels {
+		args = append(args, "--filter", "label="+label)
+	}
+	if filter.Status != "" {
+		args = append(args, "--filter", "status="+filter.Status)
+	}
+	out, err := e.run(args...)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	var summaries []containerSummary
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 5)
+		for len(fields) < 5 {
+			fields = append(fields, "")
+		}
+		summary := containerSummary{Name: fields[0], Status: fields[1], Image: fields[2], Ports: fields[3], Labels: make(map[string]string)}
+		// docker ps joins the labels with commas, so a value holding one is cut short
+		for _, label := range strings.Split(fields[4], ",") {
+			if key, value, ok := strings.Cut(label, "="); ok {
+				summary.Labels[key] = value
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+func (e cliEngine) ListVolumes(label string, dangling bool) ([]string, error) {
+	args := []string{"volume", "ls", "-q", "--filter", "label=" + label}
+	if dangling {
+		args = append(args, "--filter", "dangling=true")
+	}
+	out, err := e.run(args...)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+func (e cliEngine) ListImages(label string) ([]imageSummary, error) {
+	out, err := e.run("image", "ls", "--no-trunc", "--filter", "label="+label, "--format", "{{.ID}}\t{{.Repository}}:{{.Tag}}")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	var summaries []imageSummary
+	for _, line := range strings.Split(out, "\n") {
+		id, ref, _ := strings.Cut(line, "\t")
+		if ref == "<none>:<none>" {
+			ref = ""
+		}
+		summaries = append(summaries, imageSummary{ID: id, Ref: ref})
+	}
+	return summaries, nil
+}
+
+func (e cliEngine) Create(kind, name string, labels map[string]string) error {
+	args := []string{kind, "create"}
+	for key, value := range labels {
+		args = append(args, "--label", key+"="+value)
+	}
+	_, err := e.run(append(args, name)...)
+	return err
+}
+
+func (e cliEngine) Start(name string) error {
+	_, err := e.run("start", name)
+	return err
+}
+
+func (e cliEngine) Stop(name string) error {
+	_, err := e.run("stop", name)
+	return err
+}
+
+func (e cliEngine) Rename(name, newName string) error {
+	_, err := e.run("rename", name, newName)
+	return err
+}
+
+func (e cliEngine) Remove(kind, name string, force bool) error {
+	args := []string{kind, "rm"}
+	if force {
+		args = append(args, "-f")
+	}
+	_, err := e.run(append(args, name)...)
+	return err
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// volumeCopyImage runs the copy when named volumes are cloned for an ephemeral dev container
+const volumeCopyImage = "alpine:latest"
+
+// cleanupStep is one teardown action registered while setting up a dev container
+type cleanupStep struct {
+	description string
+	run         func() error
+}
+
+// cleanupStack runs registered teardown steps in reverse order of registration, so resources are
+// removed before the networks and volumes they depend on
+type cleanupStack struct {
+	mu    sync.Mutex
+	steps []cleanupStep
+}
+
+// push registers a teardown step
+func (c *cleanupStack) push(description string, run func() error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.steps = append(c.steps, cleanupStep{description: description, run: run})
+}
+
+// drain removes and returns the registered steps, most recent first
+func (c *cleanupStack) drain() []cleanupStep {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	steps := make([]cleanupStep, 0, len(c.steps))
+	for i := len(c.steps) - 1; i >= 0; i-- {
+		steps = append(steps, c.steps[i])
+	}
+	c.steps = nil
+	return steps
+}
+
+// track registers the removal of a docker object created for the dev container
+// Kind is container, network or volume
+func (m *Manager) track(kind, name string) {
+	m.created.add(kind, name)
+	if kind == "container" {
+		m.forget(name)
+	}
+	m.cleanup.push(fmt.Sprintf("remove %s '%s'", kind, name), func() error {
+		return m.removeResource(kind, name)
+	})
+}
+
+// removeResource removes a docker object of the given kind; containers are force-removed
+func (m *Manager) removeResource(kind, name string) error {
+	if kind == "container" {
+		m.forget(name)
+	}
+	if err := m.engineClient().Remove(kind, name, kind == "container"); err != nil {
+		return fmt.Errorf("failed to remove %s '%s': %w", kind, name, err)
+	}
+	return nil
+}
+
+// Teardown runs every registered cleanup step, logging failures instead of stopping at them
+func (m *Manager) Teardown() {
+	for _, step := range m.cleanup.drain() {
+		m.logger.Printf("Teardown: %s", step.description)
+		if err := step.run(); err != nil {
+			m.logger.Warnf("%v", err)
+		}
+	}
+}
+
+// cloneVolumes copies every named volume of the spec into a throwaway volume and points the
+// builder at the copies, so an ephemeral dev container never writes to the original's data
+func (m *Manager) cloneVolumes(devContainerName string, builder *containerconfig.SpecBuilder) error {
+	for _, source := range builder.Build().NamedVolumes() {
+		clone, err := m.templateName(m.devOptions.VolumeTemplate, containerconfig.NameVolume, devContainerName, source, devContainerName+"-"+source)
+		if err != nil {
+			return err
+		}
+		m.logger.Printf("Cloning volume '%s' into '%s'...", source, clone)
+
+		if err := m.engineClient().Create("volume", clone, map[string]string{containerconfig.CompanionOfLabel: devContainerName}); err != nil {
+			return fmt.Errorf("failed to create volume '%s': %w", clone, err)
+		}
+		m.track("volume", clone)
+
+		copySpec := &containerconfig.ContainerSpec{
+			Image:   volumeCopyImage,
+			Volumes: containerconfig.ParseMounts([]string{source + ":/from:ro", clone + ":/to"}),
+			Command: []string{"cp", "-a", "/from/.", "/to/"},
+		}
+		create, err := newContainerCreate(copySpec, &containerconfig.RunOptions{Remove: true})
+		if err != nil {
+			return err
+		}
+		var stderr bytes.Buffer
+		code, err := m.engineClient().RunContainer(create, commandIO{Stderr: &stderr})
+		if err == nil && code != 0 {
+			err = fmt.Errorf("exit status %d", code)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to copy volume '%s': %w", source, withStderr(err, stderr.String()))
+		}
+
+		builder.WithVolumeSource(source, clone)
+	}
+	return nil
+}
+
+// waitForSignal blocks until the process is interrupted or terminated
+func waitForSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	signal.Stop(signals)
+}
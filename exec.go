@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExecResult is the outcome of a command run in a container with docker exec
+type ExecResult struct {
+	Command  string
+	ExitCode int
+	// Output is the command's combined stdout and stderr
+	Output   string
+	Duration time.Duration
+}
+
+// Expected reports whether the exit code is one of the given codes; no codes means only 0
+func (r *ExecResult) Expected(codes []int) bool {
+	if len(codes) == 0 {
+		return r.ExitCode == 0
+	}
+	for _, code := range codes {
+		if r.ExitCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// InjectStep is a shell command run in the dev container once it is up
+type InjectStep struct {
+	Command string
+	// ExpectedExitCodes are the exit codes counted as success; empty means only 0
+	ExpectedExitCodes []int
+}
+
+// injectCodesPattern matches the "codes:" prefix of an --inject value
+var injectCodesPattern = regexp.MustCompile(`^(\d+(?:,\d+)*):`)
+
+// ParseInjectStep parses an --inject value: a shell command, optionally prefixed with the
+// comma-separated exit codes counted as success, e.g. "0,1:grep -q debug /etc/app.conf"
+func ParseInjectStep(value string) (InjectStep, error) {
+	step := InjectStep{Command: value}
+	if match := injectCodesPattern.FindStringSubmatch(value); match != nil {
+		for _, field := range strings.Split(match[1], ",") {
+			code, err := strconv.Atoi(field)
+			if err != nil || code > 255 {
+				return InjectStep{}, fmt.Errorf("invalid exit code '%s' in --inject '%s'", field, value)
+			}
+			step.ExpectedExitCodes = append(step.ExpectedExitCodes, code)
+		}
+		step.Command = value[len(match[0]):]
+	}
+	if strings.TrimSpace(step.Command) == "" {
+		return InjectStep{}, fmt.Errorf("empty command in --inject '%s'", value)
+	}
+	return step, nil
+}
+
+// ExecInContainer runs a shell command in the container, streaming its output to stdout while
+// capturing it; a non-zero exit code is reported in the result, not as an error
+func (m *Manager) ExecInContainer(containerName, command string) (*ExecResult, error) {
+	m.logger.Printf("Executing command in container '%s': %s", containerName, command)
+
+	var output bytes.Buffer
+	stdio := commandIO{Stdout: io.MultiWriter(os.Stdout, &output), Stderr: io.MultiWriter(os.Stderr, &output)}
+
+	start := time.Now()
+	code, err := m.engineClient().Exec(containerName, m.shellExec(command), stdio)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute command in container '%s': %w", containerName, err)
+	}
+	return &ExecResult{Command: command, ExitCode: code, Output: output.String(), Duration: time.Since(start)}, nil
+}
+
+// shellExec returns the exec running a shell command in the container with the proxy settings
+func (m *Manager) shellExec(command string) execRequest {
+	return execRequest{Cmd: []string{"sh", "-c", command}, Env: m.proxyEnv()}
+}
+
+// runInjectStep runs an inject step and fails unless it exits with an expected code
+func (m *Manager) runInjectStep(containerName string, step InjectStep) (*ExecResult, error) {
+	result, err := m.ExecInContainer(containerName, step.Command)
+	if err != nil {
+		return nil, err
+	}
+	m.logger.Printf("Command exited with code %d after %s", result.ExitCode, formatElapsed(result.Duration))
+	if !result.Expected(step.ExpectedExitCodes) {
+		return result, fmt.Errorf("command '%s' exited with code %d", step.Command, result.ExitCode)
+	}
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// runExtract implements the extract subcommand
+func runExtract(args []string) error {
+	fs := newFlagSet("extract")
+	dockerContext := fs.String("context", "", "docker context of the container")
+	envOverridesOnly := fs.Bool("env-overrides-only", false, "drop env vars inherited unchanged from the image")
+	withStats := fs.Bool("stats", false, "include the container's state and a docker stats snapshot (CPU, memory, IO)")
+	labels := addLabelFlags(fs, false)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: extract <container> [--context name] [--stats] [--ignore-label pattern] [--default-ignores]")
+	}
+
+	manager := NewManager(positional[0], "")
+	manager.SetDockerContext(*dockerContext)
+	manager.logger.SetOutput(os.Stderr)
+	labelFilter, err := labels.filter()
+	if err != nil {
+		return err
+	}
+	manager.SetParseLabelFilter(labelFilter)
+
+	spec, err := manager.GetContainerConfig()
+	if err != nil {
+		return err
+	}
+
+	if *withStats {
+		if spec.Runtime, err = manager.CaptureRuntime(positional[0]); err != nil {
+			return err
+		}
+	}
+
+	if *envOverridesOnly {
+		spec.Env = spec.EnvOverrides()
+		spec.ImageEnv = nil
+	}
+
+	replicas, err := manager.CountComposeReplicas(spec)
+	if err != nil {
+		return err
+	}
+	if replicas > 1 {
+		spec.Replicas = replicas
+		fmt.Fprintf(os.Stderr, "Service '%s' runs %d replicas; the spec stands for all of them\n", spec.Labels[containerconfig.ComposeServiceLabel], replicas)
+	}
+
+	// Provenance and warnings go to stderr so the spec on stdout stays machine-readable
+	if provenance := spec.Provenance(); len(provenance) > 0 {
+		fmt.Fprintln(os.Stderr, "Provenance:")
+		for _, key := range sortedKeys(provenance) {
+			fmt.Fprintf(os.Stderr, "  %-14s %s\n", strings.TrimPrefix(key, containerconfig.OCIImageLabelPrefix)+":", provenance[key])
+		}
+	}
+	for _, update := range containerconfig.RuntimeUpdates(spec) {
+		warnf(os.Stderr, "%s", update)
+	}
+
+	data, err := containerconfig.MarshalSpec(spec)
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(data)
+	return nil
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// FileEntry is a file or directory found in a container
+type FileEntry struct {
+	// Name is the path relative to the listed directory
+	Name     string
+	Mode     os.FileMode
+	Size     int64
+	ModTime  time.Time
+	Linkname string
+}
+
+// readArchive streams a container path as the tar archive docker cp produces and calls fn for each
+// entry; docker cp works on stopped containers and images without a shell, unlike docker exec
+func (m *Manager) readArchive(containerName, containerPath string, follow bool, fn func(*tar.Header, io.Reader) error) error {
+	var readErr error
+	err := m.engineClient().ReadArchive(containerName, containerPath, follow, func(r io.Reader) error {
+		archive := tar.NewReader(r)
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				readErr = fmt.Errorf("failed to read archive of '%s': %w", containerPath, err)
+				return readErr
+			}
+			if err := fn(header, archive); err != nil {
+				readErr = err
+				return err
+			}
+		}
+	})
+	if readErr != nil {
+		return readErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read '%s' in '%s': %w", containerPath, containerName, err)
+	}
+	return nil
+}
+
+// ListFiles lists a directory in the container, or all of its tree when recursive
+// A file path lists just the file
+func (m *Manager) ListFiles(containerName, containerPath string, recursive bool) ([]FileEntry, error) {
+	var entries []FileEntry
+	root, first := "", true
+	err := m.readArchive(containerName, containerPath, false, func(header *tar.Header, _ io.Reader) error {
+		name := strings.TrimSuffix(header.Name, "/")
+		// The archive's first entry is the listed path itself, named after its base name
+		if first {
+			root, first = name, false
+			if header.Typeflag != tar.TypeDir {
+				entries = append(entries, fileEntry(header, name))
+			}
+			return nil
+		}
+		relative := strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+		if !recursive && strings.Contains(relative, "/") {
+			return nil
+		}
+		entries = append(entries, fileEntry(header, relative))
+		return nil
+	})
+	return entries, err
+}
+
+// fileEntry converts a tar header into a FileEntry named name
+func fileEntry(header *tar.Header, name string) FileEntry {
+	return FileEntry{
+		Name:     name,
+		Mode:     header.FileInfo().Mode(),
+		Size:     header.Size,
+		ModTime:  header.ModTime,
+		Linkname: header.Linkname,
+	}
+}
+
+// ReadContainerFile returns the content of a file in the container, following symlinks
+func (m *Manager) ReadContainerFile(containerName, containerPath string) ([]byte, error) {
+	var content []byte
+	found := false
+	err := m.readArchive(containerName, containerPath, true, func(header *tar.Header, r io.Reader) error {
+		if header.Typeflag == tar.TypeDir {
+			return fmt.Errorf("'%s' is a directory; list it with fs %s ls %s", containerPath, containerName, containerPath)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to read '%s': %w", containerPath, err)
+		}
+		content, found = data, true
+		return errStopArchive
+	})
+	if errors.Is(err, errStopArchive) {
+		err = nil
+	}
+	if err == nil && !found {
+		err = fmt.Errorf("'%s' is not a regular file", containerPath)
+	}
+	return content, err
+}
+
+// errStopArchive ends an archive walk early without an error
+var errStopArchive = errors.New("stop")
+
+// printFileEntries prints entries in the style of ls -l
+func printFileEntries(w io.Writer, entries []FileEntry) {
+	for _, entry := range entries {
+		name := entry.Name
+		if entry.Linkname != "" && entry.Mode&os.ModeSymlink != 0 {
+			name += " -> " + entry.Linkname
+		}
+		fmt.Fprintf(w, "%s %10d %s %s\n", entry.Mode, entry.Size, entry.ModTime.Format("2006-01-02 15:04"), name)
+	}
+}
+
+// runFS implements the fs subcommand: ls, cat and cp for looking at files inside a container while
+// deciding what to override in the dev container
+func runFS(args []string) error {
+	fs := newFlagSet("fs")
+	dockerContext := fs.String("context", "", "docker context of the container")
+	recursive := fs.Bool("r", false, "ls: list the whole tree")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	usage := fmt.Errorf("usage: fs <container> ls [path] [-r] | cat <path> | cp <container-path> <host-path> [--context name]")
+	if len(positional) < 2 {
+		return usage
+	}
+
+	manager := NewManager(positional[0], "")
+	manager.SetDockerContext(*dockerContext)
+	manager.logger.SetOutput(io.Discard)
+	container := positional[0]
+
+	switch action, rest := positional[1], positional[2:]; {
+	case action == "ls" && len(rest) <= 1:
+		dir := "/"
+		if len(rest) == 1 {
+			dir = rest[0]
+		}
+		entries, err := manager.ListFiles(container, path.Clean(dir), *recursive)
+		if err != nil {
+			return err
+		}
+		printFileEntries(os.Stdout, entries)
+		return nil
+	case action == "cat" && len(rest) == 1:
+		content, err := manager.ReadContainerFile(container, rest[0])
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(content)
+		return err
+	case action == "cp" && len(rest) == 2:
+		if err := manager.engineClient().CopyFrom(container, rest[0], rest[1], true); err != nil {
+			return fmt.Errorf("failed to copy '%s': %w", rest[0], err)
+		}
+		successf(os.Stderr, "✓ Copied %s:%s to %s", container, rest[0], rest[1])
+		return nil
+	default:
+		return usage
+	}
+}
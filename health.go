@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// containerHealth is .State.Health of docker inspect: the status and the last few probe results
+type containerHealth struct {
+	Status        string        `json:"Status"`
+	FailingStreak int           `json:"FailingStreak"`
+	Log           []healthProbe `json:"Log"`
+}
+
+// healthProbe is one healthcheck run
+type healthProbe struct {
+	Start    time.Time `json:"Start"`
+	End      time.Time `json:"End"`
+	ExitCode int       `json:"ExitCode"`
+	Output   string    `json:"Output"`
+}
+
+// maxProbeOutput is how much of a probe's output is printed per line; docker keeps up to 4KB
+const maxProbeOutput = 200
+
+// runHealth implements the health subcommand: a container's health status and probe log
+func runHealth(args []string) error {
+	fs := newFlagSet("health")
+	dockerContext := fs.String("context", "", "docker context of the container")
+	format := fs.String("format", "text", "output format: text or json")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: health <container> [--context name] [--format text|json]")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format '%s' (expected text or json)", *format)
+	}
+
+	manager := NewManager(positional[0], "")
+	manager.SetDockerContext(*dockerContext)
+	manager.logger.SetOutput(io.Discard)
+	state, err := manager.readContainerState(positional[0])
+	if err != nil {
+		return err
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(state.State.Health)
+	}
+
+	spec, err := manager.InspectContainer(positional[0])
+	if err != nil {
+		return err
+	}
+	printHealth(os.Stdout, positional[0], spec.Healthcheck, state.State.Health)
+	return nil
+}
+
+// printHealth writes the status line, the healthcheck and one line per probe, oldest first
+func printHealth(w io.Writer, name string, check *containerconfig.Healthcheck, health *containerHealth) {
+	if health == nil {
+		if check.Disabled() {
+			fmt.Fprintf(w, "%s: healthcheck disabled\n", name)
+		} else {
+			fmt.Fprintf(w, "%s: no healthcheck\n", name)
+		}
+		return
+	}
+
+	status := health.Status
+	if health.FailingStreak > 0 {
+		status += fmt.Sprintf(" (failing streak %d)", health.FailingStreak)
+	}
+	fmt.Fprintf(w, "%s: %s\n", name, status)
+	if check != nil {
+		fmt.Fprintf(w, "Healthcheck: %s\n", check)
+	}
+	for _, probe := range health.Log {
+		result := "ok"
+		if probe.ExitCode != 0 {
+			result = fmt.Sprintf("exit %d", probe.ExitCode)
+		}
+		fmt.Fprintf(w, "  %s  %6s  %-7s  %s\n", probe.Start.Local().Format("2006-01-02 15:04:05"),
+			probe.End.Sub(probe.Start).Round(time.Millisecond), result, probeOutput(probe.Output))
+	}
+}
+
+// probeOutput squeezes a probe's output onto one line
+func probeOutput(output string) string {
+	output = strings.Join(strings.Fields(output), " ")
+	if len(output) > maxProbeOutput {
+		output = output[:maxProbeOutput] + "..."
+	}
+	return output
+}
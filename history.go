@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// historyTimeFormat names snapshot files so they sort chronologically
+const historyTimeFormat = "20060102T150405.000000000Z"
+
+// historyStore keeps timestamped spec snapshots of managed containers, one directory per container
+type historyStore struct {
+	dir string
+}
+
+// openHistory returns the history store in DCE_HISTORY_DIR, or in the user config directory
+func openHistory() (*historyStore, error) {
+	dir := os.Getenv("DCE_HISTORY_DIR")
+	if dir == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate history directory: %w", err)
+		}
+		dir = filepath.Join(configDir, "docker-config-extractor", "history")
+	}
+	return &historyStore{dir: dir}, nil
+}
+
+// Save writes a snapshot of the spec and returns its path
+func (h *historyStore) Save(spec *containerconfig.ContainerSpec) (string, error) {
+	dir := filepath.Join(h.dir, spec.Name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create history directory '%s': %w", dir, err)
+	}
+	path := filepath.Join(dir, time.Now().UTC().Format(historyTimeFormat)+containerconfig.SpecFileExt)
+	if err := containerconfig.WriteSpecFile(path, spec); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Snapshots returns the snapshot paths of a container, oldest first
+func (h *historyStore) Snapshots(name string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(h.dir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history of '%s': %w", name, err)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), containerconfig.SpecFileExt) {
+			paths = append(paths, filepath.Join(h.dir, name, entry.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Latest returns the most recent snapshot of a container
+func (h *historyStore) Latest(name string) (*containerconfig.ContainerSpec, error) {
+	paths, err := h.Snapshots(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no history for container '%s'", name)
+	}
+	spec, err := containerconfig.ReadSpecFile(paths[len(paths)-1])
+	if err != nil {
+		return nil, err
+	}
+	spec.Name = name
+	return spec, nil
+}
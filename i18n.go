@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Supported locales of user-facing messages
+const (
+	localeEN = "en"
+	localeZH = "zh"
+)
+
+// currentLocale selects the message catalog; set by --lang or detected from the environment
+var currentLocale = detectLocale()
+
+// detectLocale picks the locale from DCE_LANG, then the usual POSIX locale variables
+func detectLocale() string {
+	for _, name := range []string{"DCE_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			if strings.HasPrefix(strings.ToLower(value), localeZH) {
+				return localeZH
+			}
+			return localeEN
+		}
+	}
+	return localeEN
+}
+
+// setLocale switches the message catalog
+func setLocale(name string) error {
+	name = strings.ToLower(name)
+	if strings.HasPrefix(name, localeZH) {
+		name = localeZH
+	}
+	if _, ok := messages[name]; !ok {
+		return fmt.Errorf("unsupported language '%s' (supported: en, zh)", name)
+	}
+	currentLocale = name
+	return nil
+}
+
+// tr formats a catalog message in the current locale, falling back to English
+func tr(id string, args ...interface{}) string {
+	format, ok := messages[currentLocale][id]
+	if !ok {
+		format, ok = messages[localeEN][id]
+	}
+	if !ok {
+		format = id
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// isYes reports whether an answer to a y/n prompt means yes in any supported locale
+func isYes(answer string) bool {
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes", "是", "好", "确定":
+		return true
+	}
+	return false
+}
+
+// messages holds the user-facing prompts, notices and error prefixes per locale
+var messages = map[string]map[string]string{
+	localeEN: {
+		"prompt.yesno":            "(y/n): ",
+		"prompt.recreate":         "Do you want to recreate it?",
+		"prompt.deps.clone":       "Clone these dependencies for the dev container instead of sharing the originals?",
+		"prompt.adopt":            "Adopting recreates '%s' with the same configuration (it will restart). Continue?",
+		"prompt.recreate.history": "Recreate '%s' from its latest snapshot?",
+		"prompt.apply":            "Apply these changes?",
+		"prompt.cleanup":          "Remove these objects?",
+		"prompt.resume":           "Resume provisioning instead of recreating it?",
+		"prompt.tools.sidecar":    "Start a toolbox sidecar from '%s' to provision it?",
+		"prompt.up.replace":       "Dev container '%s' already exists. Remove it, with any state in it, and create it anew?",
+		"notice.exists":           "Dev container '%s' already exists.",
+		"notice.refresh":          "'%s' changed since '%s' was created from it; recreating the dev container.",
+		"notice.no-changes":       "Exiting without changes.",
+		"notice.aborted":          "Aborted.",
+		"notice.nothing-to-do":    "Nothing to do.",
+		"notice.nothing-to-clean": "Nothing to clean up.",
+		"notice.nothing-to-down":  "Nothing was created for '%s'.",
+		"notice.no-managed":       "No managed containers.",
+		"notice.unprovisioned":    "Its provisioning did not finish: %s",
+		"notice.ephemeral":        "Ephemeral mode: press Ctrl+C to remove the dev container and everything created for it.",
+		"plan.apply":              "Apply plan for %s:",
+		"plan.cleanup":            "Cleanup plan:",
+		"plan.down":               "Teardown plan for '%s':",
+		"plan.dev":                "Plan for dev container '%s' from '%s':",
+		"ready.title":             "✓ Dev container '%s' is ready!",
+		"ready.next":              "You can now:",
+		"ready.attach":            "  - Attach to it: %s",
+		"ready.debug":             "  - Debug with delve on port 2345",
+		"ready.debug-attach":      "  - Attach delve to %s: %s",
+		"ready.pprof":             "  - Profile with pprof: %s",
+		"ready.otel":              "  - Watch traces: %s",
+		"done.adopt":              "✓ Container '%s' is now managed",
+		"done.recreate":           "✓ Container '%s' recreated",
+		"label.error":             "Error: ",
+		"label.warning":           "Warning: ",
+		"error.check":             "failed to check dev container: %v",
+		"error.remove":            "failed to remove container: %v",
+		"error.stop":              "failed to stop container: %v",
+		"error.create":            "failed to create dev container: %v",
+		"usage.main":              "Usage: docker-config-extractor [flags] <container-name> [dev-container-name] [dev-swap-dir]",
+		"usage.command":           "       docker-config-extractor <command> [args]",
+		"usage.global":            "Global flags:",
+		"usage.no-color":          "  --no-color  disable colored output (also set by NO_COLOR)",
+		"usage.lang":              "  --lang      message language: en or zh (also set by DCE_LANG or LANG)",
+		"usage.commands":          "Commands:",
+		"usage.example":           "Example:",
+	},
+	localeZH: {
+		"prompt.yesno":            "(y/n)：",
+		"prompt.recreate":         "是否重新创建？",
+		"prompt.deps.clone":       "是否为开发容器克隆这些依赖容器，而不是共用原有容器？",
+		"prompt.adopt":            "接管会以相同配置重新创建容器 '%s'（容器将重启）。是否继续？",
+		"prompt.recreate.history": "是否根据最新快照重新创建 '%s'？",
+		"prompt.apply":            "是否执行以上变更？",
+		"prompt.cleanup":          "是否删除以上对象？",
+		"prompt.resume":           "是否继续未完成的初始化步骤，而不是重新创建？",
+		"prompt.tools.sidecar":    "是否基于 '%s' 启动工具箱 sidecar 容器来完成初始化？",
+		"prompt.up.replace":       "开发容器 '%s' 已存在。是否删除它（其中的状态一并丢失）并重新创建？",
+		"notice.exists":           "开发容器 '%s' 已存在。",
+		"notice.refresh":          "'%s' 在创建 '%s' 之后已有变更，正在重新创建开发容器。",
+		"notice.no-changes":       "未做任何更改，已退出。",
+		"notice.aborted":          "已取消。",
+		"notice.nothing-to-do":    "无需任何操作。",
+		"notice.nothing-to-clean": "没有需要清理的对象。",
+		"notice.nothing-to-down":  "没有为 '%s' 创建的对象。",
+		"notice.no-managed":       "没有受管理的容器。",
+		"notice.unprovisioned":    "其初始化步骤尚未完成：%s",
+		"notice.ephemeral":        "临时模式：按 Ctrl+C 删除开发容器及为其创建的所有资源。",
+		"plan.apply":              "%s 的应用计划：",
+		"plan.cleanup":            "清理计划：",
+		"plan.down":               "'%s' 的拆除计划：",
+		"plan.dev":                "从 '%[2]s' 创建开发容器 '%[1]s' 的计划：",
+		"ready.title":             "✓ 开发容器 '%s' 已就绪！",
+		"ready.next":              "接下来可以：",
+		"ready.attach":            "  - 进入容器：%s",
+		"ready.debug":             "  - 使用 delve 在 2345 端口调试",
+		"ready.debug-attach":      "  - 将 delve 附加到 %s：%s",
+		"ready.pprof":             "  - 使用 pprof 分析性能：%s",
+		"ready.otel":              "  - 查看链路追踪：%s",
+		"done.adopt":              "✓ 容器 '%s' 已纳入管理",
+		"done.recreate":           "✓ 容器 '%s' 已重新创建",
+		"label.error":             "错误：",
+		"label.warning":           "警告：",
+		"error.check":             "检查开发容器失败：%v",
+		"error.remove":            "删除容器失败：%v",
+		"error.stop":              "停止容器失败：%v",
+		"error.create":            "创建开发容器失败：%v",
+		"usage.main":              "用法：docker-config-extractor [选项] <容器名> [开发容器名] [dev-swap 目录]",
+		"usage.command":           "      docker-config-extractor <命令> [参数]",
+		"usage.global":            "全局选项：",
+		"usage.no-color":          "  --no-color  关闭彩色输出（也可设置 NO_COLOR）",
+		"usage.lang":              "  --lang      消息语言：en 或 zh（也可设置 DCE_LANG 或 LANG）",
+		"usage.commands":          "命令：",
+		"usage.example":           "示例：",
+	},
+}
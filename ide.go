@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+	"github.com/lhc03/docker-config-extractor/pkg/debugconfig"
+)
+
+// publishedHostPort returns the host port published for a container port, or 0 if it isn't published
+func publishedHostPort(spec *containerconfig.ContainerSpec, containerPort int) int {
+	for _, port := range spec.Ports {
+		if hostPort := port.HostPortFor(containerPort); hostPort != 0 && port.TCP() {
+			return hostPort
+		}
+	}
+	return 0
+}
+
+// pathMappingFlags holds the source path mapping flags shared by debug-config and dap-proxy
+type pathMappingFlags struct {
+	localRoot *string
+	overrides stringList
+}
+
+// addPathMappingFlags registers --local-root and --path-map on a flag set
+func addPathMappingFlags(fs *flag.FlagSet, defaultLocalRoot, localRootUsage string) *pathMappingFlags {
+	pf := &pathMappingFlags{}
+	pf.localRoot = fs.String("local-root", defaultLocalRoot, localRootUsage)
+	fs.Var(&pf.overrides, "path-map", "local=remote source path mapping, overriding the derived one for the same container path (repeatable)")
+	return pf
+}
+
+// mappings derives the path mappings from the spec's bind mounts and working directory and applies
+// the --path-map overrides
+func (pf *pathMappingFlags) mappings(spec *containerconfig.ContainerSpec, localRoot string) (debugconfig.PathMappings, error) {
+	var overrides []debugconfig.PathMapping
+	for _, value := range pf.overrides {
+		mapping, err := debugconfig.ParsePathMapping(value)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, mapping)
+	}
+	return debugconfig.DerivePathMappings(spec, localRoot).With(overrides...), nil
+}
+
+// runDebugConfig implements the debug-config subcommand
+func runDebugConfig(args []string) error {
+	fs := newFlagSet("debug-config")
+	ide := fs.String("ide", "vscode", "IDE to generate for: vscode or goland")
+	output := fs.String("output", "-", "file to write, - for stdout")
+	paths := addPathMappingFlags(fs, "", "host directory mapped to the container's working directory (default: the IDE workspace)")
+	dockerContext := fs.String("context", "", "docker context of the dev container")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: debug-config <dev-container> [--ide vscode|goland] [--output file]")
+	}
+
+	manager := NewManager(positional[0], "")
+	manager.SetDockerContext(*dockerContext)
+	manager.logger.SetOutput(os.Stderr)
+	spec, err := manager.GetContainerConfig()
+	if err != nil {
+		return err
+	}
+
+	target := debugconfig.Target{
+		Name: spec.Name,
+		Port: publishedHostPort(spec, debugconfig.DefaultDelvePort),
+	}
+	if target.Port == 0 {
+		warnf(os.Stderr, "port %d is not published by '%s', assuming it is reachable on localhost", debugconfig.DefaultDelvePort, spec.Name)
+	}
+
+	localRoot := *paths.localRoot
+	render := debugconfig.VSCodeLaunch
+	switch *ide {
+	case "vscode":
+		if localRoot == "" {
+			localRoot = "${workspaceFolder}"
+		}
+	case "goland":
+		if localRoot == "" {
+			localRoot = "$PROJECT_DIR$"
+		}
+		render = debugconfig.GoLandRunConfig
+	default:
+		return fmt.Errorf("unsupported IDE '%s' (supported: vscode, goland)", *ide)
+	}
+	if target.PathMappings, err = paths.mappings(spec, localRoot); err != nil {
+		return err
+	}
+
+	data, err := render(target)
+	if err != nil {
+		return err
+	}
+
+	if *output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", *output, err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", *output)
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// inspectCache remembers docker inspect results for the lifetime of a Manager, so a container or
+// image looked at by several steps (spec, image env, report, runtime state) costs one daemon round-trip
+type inspectCache struct {
+	// containers holds raw docker inspect output by the name it was requested with
+	containers map[string]string
+	images     map[string]*containerconfig.ImageInfo
+}
+
+// inspectContainerJSON returns the docker inspect output of a container, inspecting it only once
+func (m *Manager) inspectContainerJSON(name string) (string, error) {
+	if data, ok := m.inspected.containers[name]; ok {
+		return data, nil
+	}
+	data, err := m.engineClient().Inspect("container", name)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container '%s': %w", name, err)
+	}
+	if m.inspected.containers == nil {
+		m.inspected.containers = make(map[string]string)
+	}
+	m.inspected.containers[name] = data
+	return data, nil
+}
+
+// cachedImage returns image metadata inspected earlier, if any
+func (m *Manager) cachedImage(ref string) (*containerconfig.ImageInfo, bool) {
+	info, ok := m.inspected.images[ref]
+	return info, ok
+}
+
+// cacheImage remembers image metadata under the reference it was inspected with
+func (m *Manager) cacheImage(ref string, info *containerconfig.ImageInfo) {
+	if m.inspected.images == nil {
+		m.inspected.images = make(map[string]*containerconfig.ImageInfo)
+	}
+	m.inspected.images[ref] = info
+}
+
+// forget drops the cached inspect output of a container that is being created, changed or removed
+func (m *Manager) forget(name string) {
+	delete(m.inspected.containers, name)
+}
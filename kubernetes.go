@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// runKubernetes implements the kubernetes subcommand: a Deployment or Pod manifest for a container,
+// printed on stdout with what doesn't carry over on stderr
+func runKubernetes(args []string) error {
+	fs := newFlagSet("kubernetes")
+	dockerContext := fs.String("context", "", "docker context of the container")
+	kind := fs.String("kind", "deployment", "workload to export: deployment or pod")
+	headroom := fs.Int("headroom", containerconfig.DefaultHeadroomPercent, "percentage added on top of observed usage for requests")
+	from := fs.String("from", "", "read an extracted spec instead of inspecting a container")
+	overlayFile := fs.String("overlay", "", "YAML file choosing how mounts are exported")
+	var claims stringList
+	fs.Var(&claims, "pvc", "export the mount at this container path, or * for all, as a PersistentVolumeClaim: path[=size] (repeatable)")
+	labels := addLabelFlags(fs, true)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if (len(positional) != 1) == (*from == "") || *headroom < 0 {
+		return fmt.Errorf("usage: kubernetes <container>|--from spec.json [--kind deployment|pod] [--headroom percent] [--pvc path[=size]]... [--overlay file] [--context name]")
+	}
+	labelFilter, err := labels.filter()
+	if err != nil {
+		return err
+	}
+
+	opts := containerconfig.KubeExportOptions{HeadroomPercent: *headroom, LabelFilter: labelFilter, Mounts: make(map[string]containerconfig.KubeMount)}
+	switch *kind {
+	case "deployment":
+		opts.Kind = containerconfig.KubeKindDeployment
+	case "pod":
+		opts.Kind = containerconfig.KubeKindPod
+	default:
+		return fmt.Errorf("invalid --kind '%s': expected deployment or pod", *kind)
+	}
+	if *overlayFile != "" {
+		data, err := os.ReadFile(*overlayFile)
+		if err != nil {
+			return fmt.Errorf("failed to read overlay file: %w", err)
+		}
+		overlay, err := containerconfig.ParseKubeOverlay(data)
+		if err != nil {
+			return fmt.Errorf("invalid overlay file '%s': %w", *overlayFile, err)
+		}
+		for target, mount := range overlay.Mounts {
+			opts.Mounts[target] = mount
+		}
+	}
+	for _, value := range claims {
+		target, mount, err := containerconfig.ParseClaimFlag(value)
+		if err != nil {
+			return err
+		}
+		// A flag overrides the overlay's choice, but keeps a claim's storage class and size
+		if existing, ok := opts.Mounts[target]; ok && existing.As == containerconfig.KubeClaim {
+			mount.StorageClass = existing.StorageClass
+			if mount.Size == "" {
+				mount.Size = existing.Size
+			}
+		}
+		opts.Mounts[target] = mount
+	}
+
+	var spec *containerconfig.ContainerSpec
+	if *from != "" {
+		data, err := readInput(*from)
+		if err != nil {
+			return err
+		}
+		if spec, err = containerconfig.UnmarshalSpec(data); err != nil {
+			return err
+		}
+	} else {
+		manager := NewManager(positional[0], "")
+		manager.SetDockerContext(*dockerContext)
+		manager.logger.SetOutput(os.Stderr)
+		if spec, err = manager.GetContainerConfig(); err != nil {
+			return err
+		}
+		if spec.Runtime, err = manager.CaptureRuntime(positional[0]); err != nil {
+			return err
+		}
+	}
+
+	data, warnings, err := containerconfig.ExportKubernetes(spec, opts)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		warnf(os.Stderr, "%s", warning)
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
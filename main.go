@@ -1,11 +1,9 @@
 package main
 
 import (
-	"bytes"
 	"fmt"
-	"log"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -16,108 +14,431 @@ import (
 type Manager struct {
 	containerName string
 	devSwapDir    string
-	logger        *log.Logger
+	dockerContext string
+	// sourceImage makes the dev container start from this image instead of cloning containerName
+	sourceImage  string
+	parseOptions *containerconfig.ParseOptions
+	devOptions   DevOptions
+	cleanup      cleanupStack
+	// created lists the docker objects created during the run, for the run summary
+	created   resourceLog
+	inspected inspectCache
+	// daemon caches the docker info of the daemon once it has been queried
+	daemon *containerconfig.DaemonInfo
+	// engine is the daemon client, connected on first use
+	engine engineClient
+	// cliConfig caches the docker CLI's config.json once it has been read
+	cliConfig *dockerCLIConfig
+	progress  *progress
+	logger    *cliLogger
+}
+
+// DevOptions holds the optional modifications applied when creating a dev container
+type DevOptions struct {
+	Profiles       []string
+	ProfileOptions containerconfig.ProfileOptions
+	// StartOtelCollector starts a collector next to the dev container for the otel profile
+	StartOtelCollector bool
+	OtelCollectorImage string
+	// Aliases are the network aliases of the dev container; inherited aliases are always dropped
+	Aliases []string
+	// Ephemeral creates a throwaway dev container: --rm, no restart policy and cloned named volumes
+	Ephemeral bool
+	// Supervised drops docker's restart policy because the up supervisor restarts the container itself
+	Supervised bool
+	// Copies are "host-path:container-path" files copied in between docker create and docker start
+	Copies []string
+	// UseRun starts the dev container with a single docker run -d instead of create, connect and start
+	UseRun bool
+	// Source is a local checkout "dir[:container-dir]" mounted over the app's source directory
+	Source string
+	// Writable lists container paths of read-only mounts made writable; "*" selects all
+	Writable []string
+	// HostAccess maps host.docker.internal to the docker host's gateway
+	HostAccess bool
+	// DebuggerDir holds prebuilt dlv binaries and their checksum manifest, copied in instead of go install
+	DebuggerDir string
+	// DebuggerKey is a base64 ed25519 public key file the manifest's signature is verified with
+	DebuggerKey string
+	// HTTPProxy, HTTPSProxy and NoProxy are passed to tool installs; the host's proxy env is used when empty
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+	// Tools selects how tools get into a dev container without a shell: "" (ask), sidecar, image or none
+	Tools string
+	// ToolboxImage replaces the default toolbox image; for --tools image it must have a static /bin/busybox
+	ToolboxImage string
+	// RegistryAuth is a docker config.json (or its directory) with the credentials used for image pulls
+	RegistryAuth string
+	// Offline forbids network access: no image pulls, no go install and no package installs
+	Offline bool
+	// Packages are distro packages installed in the started dev container, on top of the profiles' own
+	Packages []string
+	// Inject are the shell commands run in the dev container once it is up
+	Inject []InjectStep
+	// Dependencies selects how containers referenced from the env are handled: ask, attach or clone
+	Dependencies string
+	// Mounts are extra "host-path:container-path" bind mounts, from the sync rules of a project file
+	Mounts []string
+	// ExtraHosts are "host:address" entries added to /etc/hosts, reaching targets on other contexts
+	ExtraHosts []string
+	// NameTemplate names the dev container from the original's spec fields, {{.User}}, {{env "VAR"}}
+	// and the clone number {{.N}}; VolumeTemplate and ImageTemplate name volume copies and derived images
+	NameTemplate   string
+	VolumeTemplate string
+	ImageTemplate  string
+	// PortStep spaces the published host ports of clones named by NameTemplate; 0 keeps them
+	PortStep int
+	// ScratchTmpfs are the options of a tmpfs mounted at ScratchPath, e.g. "size=1g"; empty mounts none
+	ScratchTmpfs string
+	ScratchPath  string
+	// Timezone overrides TZ, e.g. to reproduce a bug in production's timezone; empty keeps the original's
+	Timezone string
+	// KeepRestart and KeepHealthcheck keep the original's restart policy and healthcheck, which
+	// dev containers drop by default; NoHealthcheck also disables a healthcheck the spec doesn't show
+	KeepRestart     bool
+	KeepHealthcheck bool
+	NoHealthcheck   bool
+	// DlvExec starts the app under dlv exec; DlvExecBinary overrides the binary found from the
+	// entrypoint and command
+	DlvExec       bool
+	DlvExecBinary string
+	// AppArmorUnconfined runs the dev container without AppArmor confinement instead of the original's profile
+	AppArmorUnconfined bool
+	// SELinuxRelabel is the relabel option added to the bind mounts of the dev modifications: z, Z,
+	// off, or auto for z on daemons with SELinux enabled
+	SELinuxRelabel string
+	// Reuse keeps an existing dev container and only copies and provisions again; Refresh does so
+	// unless the source container's config changed since the dev container was created
+	Reuse   bool
+	Refresh bool
+	// SummaryJSON is a file the end-of-run summary is written to as JSON, for wrapper scripts
+	SummaryJSON string
+	// AllowEmulation runs an image built for another architecture than the docker host's under emulation
+	AllowEmulation bool
+	// WorkingDir overrides the working directory and is created before the container starts
+	WorkingDir string
+	// TerminalEnv sets LANG and LC_ALL to Locale and TERM to Term where the original leaves them unset
+	TerminalEnv bool
+	Locale      string
+	Term        string
 }
 
 // NewManager creates a new Manager instance with a logger
 func NewManager(containerName, devSwapDir string) *Manager {
+	logger := newCLILogger(os.Stdout, "[Manager] ")
 	return &Manager{
 		containerName: containerName,
 		devSwapDir:    devSwapDir,
-		logger:        log.New(os.Stdout, "[Manager] ", log.LstdFlags),
+		progress:      newProgress(os.Stdout, logger),
+		logger:        logger,
+	}
+}
+
+// SetDockerContext makes every docker command target the given docker context
+// A daemon endpoint such as tcp://host:2375 or npipe:////./pipe/docker_engine may be given instead
+// of a context name; an empty name uses the CLI's current context
+func (m *Manager) SetDockerContext(name string) {
+	m.dockerContext = normalizeEndpoint(name)
+}
+
+// isEndpoint reports whether a --context value is a daemon endpoint rather than a context name
+func isEndpoint(name string) bool {
+	return strings.Contains(name, "://")
+}
+
+// normalizeEndpoint accepts the npipe://./pipe/name shorthand for npipe:////./pipe/name,
+// the form the docker CLI expects for Windows named pipes
+func normalizeEndpoint(name string) string {
+	if rest, ok := strings.CutPrefix(name, "npipe://"); ok && !strings.HasPrefix(rest, "//") {
+		return "npipe:////" + strings.TrimPrefix(rest, "/")
+	}
+	return name
+}
+
+// hasProfile reports whether the named profile is selected
+func (o DevOptions) hasProfile(name string) bool {
+	for _, profile := range o.Profiles {
+		if profile == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks option values that the flag package cannot
+func (o DevOptions) validate() error {
+	if o.UseRun && len(o.Copies) > 0 {
+		return fmt.Errorf("--copy needs docker create and can't be combined with --run")
+	}
+	if o.Dependencies != DepsAsk && o.Dependencies != DepsAttach && o.Dependencies != DepsClone {
+		return fmt.Errorf("invalid --deps value '%s' (expected ask, attach or clone)", o.Dependencies)
+	}
+	switch o.Tools {
+	case ToolsAuto, ToolsSidecar, ToolsImage, ToolsNone:
+	default:
+		return fmt.Errorf("invalid --tools value '%s' (expected sidecar, image or none)", o.Tools)
+	}
+	if o.RegistryAuth != "" {
+		if _, err := os.Stat(o.RegistryAuth); err != nil {
+			return fmt.Errorf("invalid --registry-auth: %w", err)
+		}
+	}
+	if o.Offline && len(o.Packages) > 0 {
+		return fmt.Errorf("--package needs the network and can't be combined with --offline")
+	}
+	if o.DlvExec && o.hasProfile("watch") {
+		return fmt.Errorf("--dlv-exec can't be combined with the watch profile, which runs the rebuilt binary under dlv itself")
+	}
+	if o.DlvExecBinary != "" && !o.DlvExec {
+		return fmt.Errorf("--dlv-exec-binary needs --dlv-exec")
+	}
+	switch o.SELinuxRelabel {
+	case SELinuxAuto, SELinuxOff, containerconfig.SELinuxShared, containerconfig.SELinuxPrivate:
+	default:
+		return fmt.Errorf("invalid --selinux-relabel value '%s' (expected auto, z, Z or off)", o.SELinuxRelabel)
+	}
+	if o.Reuse && o.Refresh {
+		return fmt.Errorf("--reuse and --refresh contradict each other")
+	}
+	if o.KeepHealthcheck && o.NoHealthcheck {
+		return fmt.Errorf("--keep-healthcheck and --no-healthcheck contradict each other")
+	}
+	if o.KeepRestart && o.Ephemeral {
+		return fmt.Errorf("--keep-restart can't be combined with --ephemeral, which removes the container when it exits")
 	}
+	if o.DebuggerKey != "" && o.DebuggerDir == "" {
+		return fmt.Errorf("--debugger-key needs --debugger-dir")
+	}
+	if o.Source != "" {
+		if err := validateSource(o.Source); err != nil {
+			return err
+		}
+	}
+	// Windows containers take drive paths such as C:\app
+	if o.WorkingDir != "" && !strings.HasPrefix(o.WorkingDir, "/") && !strings.Contains(o.WorkingDir, ":") {
+		return fmt.Errorf("invalid --workdir '%s' (expected an absolute container path)", o.WorkingDir)
+	}
+	for _, text := range []string{o.NameTemplate, o.VolumeTemplate, o.ImageTemplate} {
+		if text == "" {
+			continue
+		}
+		if _, err := containerconfig.ParseNameTemplate(text); err != nil {
+			return err
+		}
+	}
+	if o.PortStep < 0 {
+		return fmt.Errorf("--port-step can't be negative")
+	}
+	if o.ScratchTmpfs != "" {
+		if err := validateTmpfsOptions(o.ScratchTmpfs); err != nil {
+			return fmt.Errorf("invalid --scratch-tmpfs: %w", err)
+		}
+		if !strings.HasPrefix(o.ScratchPath, "/") {
+			return fmt.Errorf("--scratch-path must be an absolute container path")
+		}
+	}
+	return nil
+}
+
+// SetDevOptions sets the optional modifications applied by CreateDevContainer
+func (m *Manager) SetDevOptions(opts DevOptions) {
+	m.devOptions = opts
+}
+
+// SetParseLabelFilter drops labels matching the filter from every inspected spec
+func (m *Manager) SetParseLabelFilter(filter *containerconfig.LabelFilter) {
+	m.parseOptions = &containerconfig.ParseOptions{LabelFilter: filter}
 }
 
 // CheckDevContainerExists checks if the dev container exists
 func (m *Manager) CheckDevContainerExists(devContainerName string) (bool, error) {
 	m.logger.Printf("Checking if dev container '%s' exists...", devContainerName)
-	
-	cmd := exec.Command("docker", "ps", "-a", "--filter", fmt.Sprintf("name=^%s$", devContainerName), "--format", "{{.Names}}")
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	
-	if err := cmd.Run(); err != nil {
+
+	containers, err := m.engineClient().ListContainers(containerFilter{All: true, Name: devContainerName})
+	if err != nil {
 		return false, fmt.Errorf("failed to check container '%s': %w", devContainerName, err)
 	}
 
-	exists := strings.TrimSpace(out.String()) == devContainerName
+	exists := len(containers) == 1 && containers[0].Name == devContainerName
 	m.logger.Printf("Container '%s' exists: %v", devContainerName, exists)
 	return exists, nil
 }
 
 // GetContainerConfig retrieves the container configuration using docker inspect
+// With a source image there is no container: the spec is the bare image, run with its defaults
 func (m *Manager) GetContainerConfig() (*containerconfig.ContainerSpec, error) {
-	m.logger.Printf("Inspecting container '%s'...", m.containerName)
-	
-	cmd := exec.Command("docker", "inspect", m.containerName)
-	var out bytes.Buffer
-	var errOut bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &errOut
-	
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("failed to inspect container '%s': %w, stderr: %s", m.containerName, err, errOut.String())
-	}
-
-	spec, err := containerconfig.ParseInspectJSON(out.String())
+	if m.sourceImage != "" {
+		return &containerconfig.ContainerSpec{Name: m.containerName, Image: m.sourceImage}, nil
+	}
+	return m.InspectContainer(m.containerName)
+}
+
+// SetSourceImage makes dev containers start from the image instead of a copy of a container
+func (m *Manager) SetSourceImage(image string) {
+	m.sourceImage = image
+}
+
+// InspectContainer retrieves the configuration of any container using docker inspect
+// The inspect output is cached, so asking again for the same container doesn't reach the daemon
+func (m *Manager) InspectContainer(containerName string) (*containerconfig.ContainerSpec, error) {
+	m.logger.Printf("Inspecting container '%s'...", containerName)
+
+	data, err := m.inspectContainerJSON(containerName)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, warnings, err := containerconfig.ParseInspectJSONWithWarnings(data, m.parseOptions)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse inspect JSON for container '%s': %w", m.containerName, err)
+		return nil, fmt.Errorf("failed to parse inspect JSON for container '%s': %w", containerName, err)
+	}
+	for _, warning := range warnings {
+		m.logger.Warnf("%s", warning)
+	}
+
+	// The image env, entrypoint and command tell image defaults apart from container overrides;
+	// the image may be gone
+	imageRef := spec.ImageID
+	if imageRef == "" {
+		imageRef = spec.Image
+	}
+	if image, err := m.InspectImage(imageRef); err == nil {
+		spec.ImageEnv = image.Env
+		containerconfig.ResolveImageDefaults(spec, image)
+	} else {
+		m.logger.Warnf("image env unavailable: %v", err)
 	}
 
-	m.logger.Printf("Successfully parsed container config for '%s'", m.containerName)
+	m.logger.Printf("Successfully parsed container config for '%s'", containerName)
 	return spec, nil
 }
 
 // CreateDevContainer creates a development container with additional dev tools
 // This method separates docker run from docker exec operations
-func (m *Manager) CreateDevContainer(devContainerName string, enableDebugger bool, injectScript string) error {
+// Each step is rendered by the progress renderer and a timing summary is printed at the end
+func (m *Manager) CreateDevContainer(devContainerName string, enableDebugger bool, inject []InjectStep) error {
 	m.logger.Printf("Starting creation of dev container '%s'...", devContainerName)
-	
+	defer m.progress.Summary()
+
 	// Step 1: Get original container config
-	spec, err := m.GetContainerConfig()
-	if err != nil {
+	var spec *containerconfig.ContainerSpec
+	if err := m.progress.Run("Inspect container", func() error {
+		var err error
+		spec, err = m.GetContainerConfig()
+		return err
+	}); err != nil {
 		return fmt.Errorf("failed to get container config: %w", err)
 	}
+	if m.devOptions.Offline {
+		if err := m.checkOffline(spec, enableDebugger); err != nil {
+			return err
+		}
+	}
 
-	// Step 2: Modify spec for dev container
-	if m.devSwapDir != "" {
-		m.logger.Printf("Adding dev-swap volume: %s:/dev-swap", m.devSwapDir)
-		spec.Volumes = append(spec.Volumes, fmt.Sprintf("%s:/dev-swap", m.devSwapDir))
+	// Step 2: Modify a copy of the spec for dev container
+	builder := spec.Builder().WithName(devContainerName).WithLabel(containerconfig.ManagedLabel, "true").
+		WithLabel(containerconfig.CloneOfLabel, m.containerName).WithLabel(containerconfig.SourceHashLabel, spec.ConfigHash()).
+		Record("dev container")
+	if err := m.applyDevModifications(spec, builder, enableDebugger); err != nil {
+		return err
+	}
+	if m.devOptions.Ephemeral && len(spec.NamedVolumes()) > 0 {
+		if err := m.progress.Run("Clone volumes", func() error {
+			return m.cloneVolumes(devContainerName, builder)
+		}); err != nil {
+			return fmt.Errorf("failed to clone volumes: %w", err)
+		}
+		builder.Record("cloned volume")
 	}
 
-	if enableDebugger {
-		m.logger.Println("Adding debugger port: 2345:2345")
-		spec.Ports = append(spec.Ports, "2345:2345")
+	// Not a progress step: it may ask the user a question
+	if err := m.handleEnvDependencies(devContainerName, spec, builder); err != nil {
+		return fmt.Errorf("failed to handle dependencies: %w", err)
 	}
+	builder.Record("dependency")
 
-	// Step 3: Generate and execute docker run command
-	opts := &containerconfig.RunOptions{
-		Name: devContainerName,
+	profileOpts := m.profileOptions()
+	if m.devOptions.StartOtelCollector && m.devOptions.hasProfile("otel") && profileOpts.OtelEndpoint == "" {
+		if err := m.progress.Run("Start otel collector", func() error {
+			endpoint, err := m.startOtelCollector(devContainerName, builder)
+			profileOpts.OtelEndpoint = endpoint
+			return err
+		}); err != nil {
+			return fmt.Errorf("failed to start otel collector: %w", err)
+		}
+		builder.Record("otel collector")
 	}
-	runArgs := containerconfig.GenerateRunCommand(spec, opts)
-	
-	m.logger.Printf("Executing docker run command...")
-	if err := m.executeDockerRun(runArgs); err != nil {
-		return fmt.Errorf("failed to run dev container: %w", err)
+	if err := m.applyProfiles(builder, profileOpts); err != nil {
+		return err
+	}
+	if m.devOptions.NameTemplate != "" && m.devOptions.PortStep > 0 {
+		if err := m.offsetHostPorts(builder); err != nil {
+			return err
+		}
+	}
+	devSpec := builder.Build()
+
+	// Step 3: Create and start the container (docker create, network connect, cp, start)
+	if err := m.progress.Run("Pull image", func() error {
+		return m.ensureImage(devSpec.Image)
+	}); err != nil {
+		return fmt.Errorf("failed to pull image: %w", err)
+	}
+	if err := m.checkPlatform(devSpec.Image); err != nil {
+		return err
+	}
+	m.preflight(devSpec)
+	if m.devOptions.Tools == ToolsImage {
+		if err := m.progress.Run("Build tools image", func() error {
+			image, err := m.buildToolsImage(devContainerName, devSpec.Image)
+			devSpec.Image = image
+			return err
+		}); err != nil {
+			return fmt.Errorf("failed to build tools image: %w", err)
+		}
+	}
+
+	containerconfig.StampCreateValues(devSpec)
+	containerconfig.StampConfigHash(devSpec)
+	opts, err := m.devRunOptions(devContainerName, devSpec)
+	if err != nil {
+		return err
+	}
+	createdAt := time.Now()
+	if err := m.progress.Run("Create container", func() error {
+		if !m.devOptions.UseRun {
+			return m.createAndStart(devSpec, opts, m.devOptions.Copies)
+		}
+		if err := m.runContainer(devSpec, opts); err != nil {
+			return err
+		}
+		m.track("container", devContainerName)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to create dev container: %w", err)
 	}
 
 	// Step 4: Wait for container to be ready
-	if err := m.waitForContainer(devContainerName, 10*time.Second); err != nil {
+	if err := m.progress.Run("Wait for container", func() error {
+		return m.waitForContainer(devContainerName, createdAt, 10*time.Second)
+	}); err != nil {
 		return fmt.Errorf("container failed to start: %w", err)
 	}
+	if m.devOptions.hasProfile("cores") {
+		m.checkCorePattern(devContainerName)
+	}
 
-	// Step 5: Install debugger if requested
-	if enableDebugger {
-		if err := m.installDebugger(devContainerName); err != nil {
-			m.logger.Printf("Warning: failed to install debugger: %v", err)
-			// Don't fail the entire operation if debugger installation fails
-		}
+	// Step 5: Provision the container: the debugger install runs alongside the inject steps, which
+	// run in order. Failed steps are reported but don't fail the entire operation
+	if err := m.provisionDevContainer(devContainerName, enableDebugger, inject, false); err != nil {
+		return fmt.Errorf("failed to provision dev container: %w", err)
 	}
 
-	// Step 6: Inject custom script if provided
-	if injectScript != "" {
-		if err := m.executeInContainer(devContainerName, injectScript); err != nil {
-			m.logger.Printf("Warning: failed to execute inject script: %v", err)
+	// Step 6: Record the spec so the container can be listed and recreated later
+	if history, err := openHistory(); err == nil {
+		if _, err := history.Save(devSpec); err != nil {
+			m.logger.Warnf("failed to save spec snapshot: %v", err)
 		}
 	}
 
@@ -125,114 +446,268 @@ func (m *Manager) CreateDevContainer(devContainerName string, enableDebugger boo
 	return nil
 }
 
-// executeDockerRun executes a docker run command (separated from docker exec)
-func (m *Manager) executeDockerRun(args []string) error {
-	m.logger.Println("Running docker run command...")
-	
-	cmd := exec.Command("docker", append([]string{"run", "-d"}, args...)...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("docker run failed: %w, stderr: %s", err, stderr.String())
-	}
-	
-	m.logger.Printf("Container started: %s", strings.TrimSpace(stdout.String()))
+// applyDevModifications applies the dev transforms selected by the dev options to the builder
+func (m *Manager) applyDevModifications(spec *containerconfig.ContainerSpec, builder *containerconfig.SpecBuilder, enableDebugger bool) error {
+	transforms, err := m.devTransforms(spec, enableDebugger)
+	if err != nil {
+		return err
+	}
+	for _, transform := range transforms {
+		transform.Apply(builder)
+	}
 	return nil
 }
 
-// waitForContainer waits for the container to be in running state
-func (m *Manager) waitForContainer(containerName string, timeout time.Duration) error {
-	m.logger.Printf("Waiting for container '%s' to be ready...", containerName)
-	
-	deadline := time.Now().Add(timeout)
-	for time.Now().Before(deadline) {
-		cmd := exec.Command("docker", "inspect", "-f", "{{.State.Running}}", containerName)
-		var out bytes.Buffer
-		cmd.Stdout = &out
-		
-		if err := cmd.Run(); err == nil {
-			if strings.TrimSpace(out.String()) == "true" {
-				m.logger.Printf("Container '%s' is running", containerName)
-				return nil
+// devTransforms returns the modifications the dev options make to the original's spec, in the
+// order they apply: the swap and source mounts, network aliases, restart policy and healthcheck,
+// host access, scratch space, timezone, terminal env, SELinux relabeling, AppArmor and debugger
+func (m *Manager) devTransforms(spec *containerconfig.ContainerSpec, enableDebugger bool) ([]containerconfig.DevTransform, error) {
+	var transforms []containerconfig.DevTransform
+	add := func(transform containerconfig.DevTransform) {
+		transforms = append(transforms, transform)
+	}
+	windows := strings.EqualFold(spec.Platform, "windows")
+
+	if m.devSwapDir != "" {
+		m.logger.Printf("Adding dev-swap volume: %s:%s", m.devSwapDir, containerconfig.DevSwapDir)
+		add(containerconfig.AddSwapMount(m.devSwapDir))
+	}
+
+	if m.devOptions.Source != "" {
+		transform, err := m.mountSource(spec)
+		if err != nil {
+			return nil, err
+		}
+		add(transform)
+	} else if m.devOptions.hasProfile("watch") {
+		m.logger.Warnf("the watch profile rebuilds from the image's copy of the source; mount your checkout with --source")
+	}
+
+	for _, mount := range m.devOptions.Mounts {
+		m.logger.Printf("Adding mount: %s", mount)
+		add(containerconfig.AddMount(mount))
+	}
+	for _, host := range m.devOptions.ExtraHosts {
+		m.logger.Printf("Adding extra host: %s", host)
+		add(containerconfig.AddExtraHost(host))
+	}
+	for _, alias := range m.devOptions.Aliases {
+		m.logger.Printf("Adding network alias: %s", alias)
+	}
+	add(containerconfig.ReplaceNetworkAliases(m.devOptions.Aliases...))
+
+	// --rm and up's supervisor take no restart policy at all
+	if !m.devOptions.KeepRestart || m.devOptions.Ephemeral || m.devOptions.Supervised {
+		if spec.Restart != "" {
+			m.logger.Printf("Dropping restart policy '%s'", spec.Restart)
+		}
+		add(containerconfig.StripRestart())
+	}
+	switch {
+	case spec.Healthcheck.Disabled():
+	case m.devOptions.NoHealthcheck && spec.Healthcheck == nil:
+		m.logger.Printf("Disabling healthcheck")
+		add(containerconfig.DisableHealthcheck())
+	case !m.devOptions.KeepHealthcheck && spec.Healthcheck != nil:
+		m.logger.Printf("Disabling healthcheck: %s", spec.Healthcheck)
+		add(containerconfig.DisableHealthcheck())
+	}
+
+	if m.devOptions.HostAccess {
+		if windows {
+			m.logger.Warnf("host-gateway is not supported for Windows containers; reach the host by its IP instead")
+		} else {
+			m.logger.Printf("Adding host access: %s:%s", containerconfig.HostGatewayName, containerconfig.HostGatewayAddress)
+			add(containerconfig.AddHostAccess())
+		}
+	}
+
+	if m.devOptions.ScratchTmpfs != "" {
+		if windows {
+			m.logger.Warnf("tmpfs is not supported for Windows containers; --scratch-tmpfs is ignored")
+		} else {
+			m.logger.Printf("Adding scratch tmpfs: %s (%s)", m.devOptions.ScratchPath, m.devOptions.ScratchTmpfs)
+			add(containerconfig.AddScratchTmpfs(m.devOptions.ScratchPath, m.devOptions.ScratchTmpfs))
+		}
+	}
+
+	// Dev mounts over /etc or /usr/share must not change the container's local time
+	add(containerconfig.KeepTimezone(spec))
+	if tz := spec.Timezone(); !tz.Empty() {
+		m.logger.Printf("Keeping timezone: %s", tz)
+	}
+	if m.devOptions.Timezone != "" {
+		if windows {
+			m.logger.Warnf("Windows containers don't read TZ; --tz is ignored")
+		} else {
+			if _, err := time.LoadLocation(m.devOptions.Timezone); err != nil {
+				m.logger.Warnf("timezone '%s' is unknown on this host; it only works if the image's zoneinfo has it", m.devOptions.Timezone)
 			}
+			m.logger.Printf("Setting timezone: %s=%s", containerconfig.TimezoneEnv, m.devOptions.Timezone)
+			add(containerconfig.SetTimezone(m.devOptions.Timezone))
+		}
+	}
+
+	if m.devOptions.TerminalEnv {
+		m.logger.Printf("Adding terminal env: LANG=LC_ALL=%s TERM=%s (where unset)", m.devOptions.Locale, m.devOptions.Term)
+		add(containerconfig.AddTerminalEnv(m.devOptions.Locale, m.devOptions.Term))
+	}
+
+	if !windows {
+		if mode := m.selinuxRelabel(); mode != "" {
+			add(containerconfig.RelabelBindMounts(spec, mode))
+		}
+	}
+	if m.devOptions.AppArmorUnconfined {
+		m.logger.Printf("Running without AppArmor confinement instead of profile '%s'", spec.AppArmorProfile)
+		add(containerconfig.SetAppArmorProfile(containerconfig.AppArmorUnconfined))
+	}
+
+	if enableDebugger {
+		m.logger.Printf("Adding debugger port: %d:%d", containerconfig.DefaultDebugPort, containerconfig.DefaultDebugPort)
+		add(containerconfig.AddDebugPort(containerconfig.DefaultDebugPort))
+		add(containerconfig.LoosenSecurity())
+	}
+	if enableDebugger && m.devOptions.DlvExec {
+		transform, err := m.dlvExecTransform(spec)
+		if err != nil {
+			return nil, err
+		}
+		add(transform)
+	}
+	return transforms, nil
+}
+
+// devRunOptions returns the run options of the dev container and warns about loosened mounts
+func (m *Manager) devRunOptions(devContainerName string, devSpec *containerconfig.ContainerSpec) (*containerconfig.RunOptions, error) {
+	labelFilter, err := newLabelFilter(true, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label ignore pattern: %w", err)
+	}
+	opts := &containerconfig.RunOptions{
+		Name:               devContainerName,
+		LabelFilter:        labelFilter,
+		Remove:             m.devOptions.Ephemeral,
+		MakeMountsWritable: m.devOptions.Writable,
+		WorkingDirOverride: m.devOptions.WorkingDir,
+	}
+	if m.devOptions.WorkingDir != "" && m.devOptions.UseRun {
+		m.logger.Warnf("--run can't create the working directory before the start; %s must exist in the image or a mount", m.devOptions.WorkingDir)
+	}
+	if len(m.devOptions.Writable) > 0 {
+		loosened := containerconfig.LoosenedMounts(devSpec, opts)
+		if len(loosened) == 0 {
+			m.logger.Warnf("--writable matched no read-only mount")
+		}
+		for _, volume := range loosened {
+			m.logger.Warnf("read-only mount %s is writable in the dev container", volume)
+		}
+	}
+	return opts, nil
+}
+
+// profileOptions returns the profile options with defaults derived from the other dev options
+func (m *Manager) profileOptions() containerconfig.ProfileOptions {
+	opts := m.devOptions.ProfileOptions
+	if _, dir := parseSourceFlag(m.devOptions.Source); dir != "" && opts.WatchDir == "" {
+		opts.WatchDir = dir
+	}
+	if opts.CoreDir != "" {
+		if abs, err := filepath.Abs(opts.CoreDir); err == nil {
+			opts.CoreDir = abs
+		}
+	}
+	return opts
+}
+
+// applyProfiles applies the selected profiles in order
+func (m *Manager) applyProfiles(builder *containerconfig.SpecBuilder, opts containerconfig.ProfileOptions) error {
+	for _, profile := range m.devOptions.Profiles {
+		m.logger.Printf("Applying profile '%s'", profile)
+		if err := containerconfig.ApplyProfile(builder, profile, opts); err != nil {
+			return fmt.Errorf("failed to apply profile: %w", err)
 		}
-		
-		time.Sleep(500 * time.Millisecond)
 	}
-	
-	return fmt.Errorf("timeout waiting for container '%s' to start", containerName)
+	return nil
+}
+
+// runContainer creates and starts the spec's container, as docker run -d does
+func (m *Manager) runContainer(spec *containerconfig.ContainerSpec, opts *containerconfig.RunOptions) error {
+	m.logger.Println("Running container...")
+
+	create, err := newContainerCreate(spec, opts)
+	if err != nil {
+		return err
+	}
+	engine := m.engineClient()
+	id, err := engine.CreateContainer(create)
+	if err != nil {
+		return fmt.Errorf("docker run failed: %w", err)
+	}
+	if err := engine.Start(id); err != nil {
+		return fmt.Errorf("docker run failed: %w", err)
+	}
+
+	m.logger.Printf("Container started: %s", id)
+	return nil
 }
 
 // installDebugger installs delve debugger in the container
 func (m *Manager) installDebugger(containerName string) error {
 	m.logger.Printf("Installing debugger in container '%s'...", containerName)
-	
+	if m.devOptions.DebuggerDir != "" {
+		return m.copyDebugger(containerName)
+	}
+	if m.devOptions.Offline {
+		return fmt.Errorf("offline mode forbids go install; pass prebuilt binaries with --debugger-dir")
+	}
+
 	// Step 1: Check if Go is installed
-	checkGoCmd := exec.Command("docker", "exec", containerName, "which", "go")
-	var checkOut bytes.Buffer
-	checkGoCmd.Stdout = &checkOut
-	
-	if err := checkGoCmd.Run(); err != nil {
+	engine := m.engineClient()
+	if code, err := engine.Exec(containerName, execRequest{Cmd: []string{"which", "go"}}, commandIO{}); err != nil || code != 0 {
 		return fmt.Errorf("Go is not installed in container '%s', cannot install debugger", containerName)
 	}
-	
+
 	m.logger.Printf("Go found in container, proceeding with delve installation...")
-	
+
 	// Step 2: Install delve
-	installCmd := exec.Command("docker", "exec", containerName, "go", "install", "github.com/go-delve/delve/cmd/dlv@latest")
-	installCmd.Stdout = os.Stdout
-	installCmd.Stderr = os.Stderr
-	
-	if err := installCmd.Run(); err != nil {
+	code, err := engine.Exec(containerName, m.goInstallDebugger(), commandIO{Stdout: os.Stdout, Stderr: os.Stderr})
+	if err == nil && code != 0 {
+		err = fmt.Errorf("exit status %d", code)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to install delve: %w", err)
 	}
-	
+
 	// Step 3: Verify delve installation
-	verifyCmd := exec.Command("docker", "exec", containerName, "sh", "-c", "command -v dlv || echo 'dlv not found'")
-	var verifyOut bytes.Buffer
-	verifyCmd.Stdout = &verifyOut
-	
-	if err := verifyCmd.Run(); err != nil {
-		return fmt.Errorf("failed to verify delve installation: %w", err)
-	}
-	
-	if strings.Contains(verifyOut.String(), "not found") {
+	verify := execRequest{Cmd: []string{"sh", "-c", "command -v dlv || echo 'dlv not found'"}}
+	out, err := m.execOutput("verify delve installation", containerName, verify)
+	if err != nil {
+		return err
+	}
+
+	if strings.Contains(out, "not found") {
 		return fmt.Errorf("delve installed but not found in PATH")
 	}
-	
+
 	m.logger.Printf("Delve debugger installed successfully in '%s'", containerName)
 	return nil
 }
 
-// executeInContainer executes a command inside the container using docker exec
-func (m *Manager) executeInContainer(containerName, command string) error {
-	m.logger.Printf("Executing command in container '%s': %s", containerName, command)
-	
-	cmd := exec.Command("docker", "exec", containerName, "sh", "-c", command)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to execute command in container '%s': %w", containerName, err)
-	}
-	
-	return nil
+// goInstallDebugger returns the exec installing delve with go install
+func (m *Manager) goInstallDebugger() execRequest {
+	return execRequest{Cmd: []string{"go", "install", "github.com/go-delve/delve/cmd/dlv@latest"}, Env: m.proxyEnv()}
 }
 
 // StopDevContainer stops the dev container
 func (m *Manager) StopDevContainer(devContainerName string) error {
 	m.logger.Printf("Stopping container '%s'...", devContainerName)
-	
-	cmd := exec.Command("docker", "stop", devContainerName)
-	var errOut bytes.Buffer
-	cmd.Stderr = &errOut
-	
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to stop container '%s': %w, stderr: %s", devContainerName, err, errOut.String())
-	}
-	
+	m.forget(devContainerName)
+
+	if err := m.engineClient().Stop(devContainerName); err != nil {
+		return fmt.Errorf("failed to stop container '%s': %w", devContainerName, err)
+	}
+
 	m.logger.Printf("Container '%s' stopped successfully", devContainerName)
 	return nil
 }
@@ -240,75 +715,173 @@ func (m *Manager) StopDevContainer(devContainerName string) error {
 // RemoveDevContainer removes the dev container
 func (m *Manager) RemoveDevContainer(devContainerName string) error {
 	m.logger.Printf("Removing container '%s'...", devContainerName)
-	
-	cmd := exec.Command("docker", "rm", devContainerName)
-	var errOut bytes.Buffer
-	cmd.Stderr = &errOut
-	
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to remove container '%s': %w, stderr: %s", devContainerName, err, errOut.String())
-	}
-	
+	m.forget(devContainerName)
+
+	if err := m.engineClient().Remove("container", devContainerName, false); err != nil {
+		return fmt.Errorf("failed to remove container '%s': %w", devContainerName, err)
+	}
+
 	m.logger.Printf("Container '%s' removed successfully", devContainerName)
 	return nil
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: docker-config-extractor <container-name> [dev-container-name] [dev-swap-dir]")
-		fmt.Println("\nExample:")
-		fmt.Println("  docker-config-extractor myapp myapp-dev /path/to/dev-swap")
+	args := stripGlobalFlags(os.Args[1:])
+	if len(args) < 1 {
+		printUsage()
 		os.Exit(1)
 	}
 
-	containerName := os.Args[1]
-	devContainerName := containerName + "-dev"
-	devSwapDir := ""
+	if cmd := findCommand(args[0]); cmd != nil {
+		if err := cmd.run(args[1:]); err != nil {
+			fatalf("%v", err)
+		}
+		return
+	}
 
-	if len(os.Args) >= 3 {
-		devContainerName = os.Args[2]
+	fs := newFlagSet("docker-config-extractor")
+	var devOpts DevOptions
+	addDevFlags(fs, &devOpts)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		os.Exit(2)
+	}
+	if err := devOpts.validate(); err != nil {
+		fatalf("%v", err)
+	}
+	if len(positional) == 0 {
+		printUsage()
+		os.Exit(1)
 	}
-	if len(os.Args) >= 4 {
-		devSwapDir = os.Args[3]
+
+	containerName := positional[0]
+	devSwapDir := ""
+
+	if len(positional) >= 3 {
+		devSwapDir = positional[2]
 	}
 
 	manager := NewManager(containerName, devSwapDir)
+	manager.SetDevOptions(devOpts)
+
+	devContainerName, err := manager.devContainerName(positional)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	// The dev container is provisioned with the debugger and the inject steps
+	enableDebugger := true
+	inject := devOpts.Inject
+	if len(inject) == 0 {
+		inject = []InjectStep{{Command: "echo 'Dev container is ready for development!'"}}
+	}
+
+	started := time.Now()
 
 	// Check if dev container already exists
 	exists, err := manager.CheckDevContainerExists(devContainerName)
 	if err != nil {
-		log.Fatalf("Error checking dev container: %v", err)
+		fatalf("%s", tr("error.check", err))
+	}
+
+	if exists && devOpts.Refresh {
+		changed, err := manager.SourceChanged(devContainerName)
+		if err != nil {
+			fatalf("%s", tr("error.check", err))
+		}
+		if changed {
+			fmt.Println("\n" + tr("notice.refresh", containerName, devContainerName))
+			if err := manager.StopDevContainer(devContainerName); err != nil {
+				warnf(os.Stderr, "%s", tr("error.stop", err))
+			}
+			if err := manager.RemoveDevContainer(devContainerName); err != nil {
+				fatalf("%s", tr("error.remove", err))
+			}
+			exists = false
+		}
+	}
+	if exists && (devOpts.Reuse || devOpts.Refresh) {
+		err := manager.ReuseDevContainer(devContainerName, enableDebugger, inject)
+		summary := manager.Summary(devContainerName, started, err, nil)
+		writeSummaryJSON(devOpts.SummaryJSON, summary)
+		if err != nil {
+			fatalf("%s", tr("error.create", err))
+		}
+		successf(os.Stdout, "\n%s", tr("ready.title", devContainerName))
+		summary.Print(os.Stdout)
+		return
 	}
 
 	if exists {
-		fmt.Printf("\nDev container '%s' already exists.\n", devContainerName)
-		fmt.Print("Do you want to recreate it? (y/n): ")
-		var answer string
-		fmt.Scanln(&answer)
-		
-		if strings.ToLower(strings.TrimSpace(answer)) == "y" {
+		fmt.Println("\n" + tr("notice.exists", devContainerName))
+
+		pending, err := manager.PendingProvisioning(devContainerName, enableDebugger, inject)
+		if err != nil {
+			warnf(os.Stderr, "%v", err)
+		}
+		if len(pending) > 0 {
+			fmt.Println(tr("notice.unprovisioned", strings.Join(pending, ", ")))
+		}
+		if len(pending) > 0 && confirm(tr("prompt.resume")) {
+			if err := manager.ResumeProvisioning(devContainerName, enableDebugger, inject); err != nil {
+				fatalf("%s", tr("error.create", err))
+			}
+			successf(os.Stdout, "\n%s", tr("ready.title", devContainerName))
+			return
+		}
+		if confirm(tr("prompt.recreate")) {
 			if err := manager.StopDevContainer(devContainerName); err != nil {
-				log.Printf("Warning: error stopping container: %v", err)
+				warnf(os.Stderr, "%s", tr("error.stop", err))
 			}
 			if err := manager.RemoveDevContainer(devContainerName); err != nil {
-				log.Fatalf("Error removing container: %v", err)
+				fatalf("%s", tr("error.remove", err))
 			}
 		} else {
-			fmt.Println("Exiting without changes.")
+			fmt.Println(tr("notice.no-changes"))
 			return
 		}
 	}
 
-	// Create dev container with debugger support
-	enableDebugger := true
-	injectScript := "echo 'Dev container is ready for development!'"
-	
-	if err := manager.CreateDevContainer(devContainerName, enableDebugger, injectScript); err != nil {
-		log.Fatalf("Error creating dev container: %v", err)
+	if err := manager.CreateDevContainer(devContainerName, enableDebugger, inject); err != nil {
+		writeSummaryJSON(devOpts.SummaryJSON, manager.Summary(devContainerName, started, err, nil))
+		if devOpts.Ephemeral {
+			manager.Teardown()
+		}
+		fatalf("%s", tr("error.create", err))
 	}
 
-	fmt.Printf("\n✓ Dev container '%s' is ready!\n", devContainerName)
-	fmt.Println("\nYou can now:")
-	fmt.Printf("  - Attach to it: docker exec -it %s /bin/sh\n", devContainerName)
-	fmt.Printf("  - Debug with delve on port 2345\n")
+	successf(os.Stdout, "\n%s", tr("ready.title", devContainerName))
+	next := []string{"docker exec -it " + devContainerName + " /bin/sh"}
+	fmt.Println("\n" + tr("ready.next"))
+	fmt.Println(tr("ready.attach", highlight(os.Stdout, next[0])))
+	fmt.Println(tr("ready.debug"))
+	if enableDebugger && !devOpts.DlvExec {
+		if attach, err := manager.SuggestAttach(devContainerName); err != nil {
+			warnf(os.Stderr, "%v", err)
+		} else {
+			next = append(next, attach.AttachCommand(devContainerName))
+			fmt.Println(tr("ready.debug-attach", attach.Process.Binary(), highlight(os.Stdout, attach.AttachCommand(devContainerName))))
+		}
+	}
+	for _, profile := range devOpts.Profiles {
+		if profile == "pprof" {
+			command := fmt.Sprintf("go tool pprof http://localhost:%d/debug/pprof/profile", devOpts.ProfileOptions.PprofPort)
+			next = append(next, command)
+			fmt.Println(tr("ready.pprof", command))
+		}
+		if profile == "otel" && devOpts.StartOtelCollector {
+			command := "docker logs -f " + devContainerName + "-otel-collector"
+			next = append(next, command)
+			fmt.Println(tr("ready.otel", command))
+		}
+	}
+	summary := manager.Summary(devContainerName, started, nil, next)
+	summary.Print(os.Stdout)
+	writeSummaryJSON(devOpts.SummaryJSON, summary)
+
+	if devOpts.Ephemeral {
+		fmt.Println("\n" + tr("notice.ephemeral"))
+		waitForSignal()
+		manager.Teardown()
+	}
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// offlineArtifacts lists what must be pre-staged for the dev container to be created without
+// network access: images missing from the daemon and tools that would be downloaded
+func (m *Manager) offlineArtifacts(spec *containerconfig.ContainerSpec, enableDebugger bool) []string {
+	images := []string{spec.Image}
+	if m.devOptions.Tools == ToolsSidecar || m.devOptions.Tools == ToolsImage {
+		images = append(images, m.toolboxImage())
+	}
+	if m.devOptions.StartOtelCollector && m.devOptions.hasProfile("otel") {
+		image := m.devOptions.OtelCollectorImage
+		if image == "" {
+			image = defaultOtelCollectorImage
+		}
+		images = append(images, image)
+	}
+	if m.devOptions.Ephemeral && len(spec.NamedVolumes()) > 0 {
+		images = append(images, volumeCopyImage)
+	}
+
+	var missing []string
+	for _, image := range images {
+		if !m.resourceExists("image", image) {
+			missing = append(missing, fmt.Sprintf("image %s (docker load it)", image))
+		}
+	}
+	if enableDebugger && m.devOptions.DebuggerDir == "" {
+		missing = append(missing, "prebuilt dlv binaries with a SHA256SUMS manifest (--debugger-dir), since go install needs the network")
+	}
+	if packages := m.devOptions.devPackages(); len(packages) > 0 {
+		missing = append(missing, fmt.Sprintf("packages %s, which can't be installed offline; bake them into the image or drop them", strings.Join(packages, ", ")))
+	}
+	return missing
+}
+
+// checkOffline fails with the list of artifacts to pre-stage when offline mode can't be honored
+func (m *Manager) checkOffline(spec *containerconfig.ContainerSpec, enableDebugger bool) error {
+	missing := m.offlineArtifacts(spec, enableDebugger)
+	if len(missing) == 0 {
+		if len(m.devOptions.Inject) > 0 {
+			m.logger.Warnf("offline mode can't check --inject commands; make sure they don't need the network")
+		}
+		return nil
+	}
+	return fmt.Errorf("offline mode needs these artifacts pre-staged:\n  - %s", strings.Join(missing, "\n  - "))
+}
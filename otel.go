@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// defaultOtelCollectorImage is the collector started next to the dev container; its default
+// configuration receives OTLP on 4317/4318 and prints received spans
+const defaultOtelCollectorImage = "otel/opentelemetry-collector:latest"
+
+// otelCollectorNetwork is created when the dev container has no user-defined network to share
+const otelCollectorNetwork = "dce-otel"
+
+// startOtelCollector starts an OpenTelemetry collector on a network shared with the dev container
+// and returns the OTLP endpoint the dev container should export to
+func (m *Manager) startOtelCollector(devContainerName string, builder *containerconfig.SpecBuilder) (string, error) {
+	collectorName := devContainerName + "-otel-collector"
+	image := m.devOptions.OtelCollectorImage
+	if image == "" {
+		image = defaultOtelCollectorImage
+	}
+
+	// Name resolution only works on user-defined networks
+	network := ""
+	for _, candidate := range builder.Build().Networks {
+		if !builtinNetworks[candidate] {
+			network = candidate
+			break
+		}
+	}
+	if network == "" {
+		network = otelCollectorNetwork
+		if !m.resourceExists("network", network) {
+			if err := m.createResource("network", network); err != nil {
+				return "", err
+			}
+			m.track("network", network)
+		}
+		builder.WithNetwork(network)
+	}
+
+	exists, err := m.CheckDevContainerExists(collectorName)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		m.logger.Printf("Reusing existing collector '%s'", collectorName)
+	} else {
+		m.logger.Printf("Starting OpenTelemetry collector '%s' on network '%s'...", collectorName, network)
+		collector := &containerconfig.ContainerSpec{
+			Image:    image,
+			Networks: []string{network},
+			Labels:   map[string]string{containerconfig.CompanionOfLabel: devContainerName},
+		}
+		containerconfig.StampConfigHash(collector)
+		if err := m.runContainer(collector, &containerconfig.RunOptions{Name: collectorName}); err != nil {
+			return "", fmt.Errorf("failed to start collector: %w", err)
+		}
+		m.track("container", collectorName)
+	}
+
+	return fmt.Sprintf("http://%s:4317", collectorName), nil
+}
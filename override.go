@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// SuggestOverride builds the dev spec for the container without creating anything and returns
+// the modifications it makes, along with the compose service name the container belongs to
+func (m *Manager) SuggestOverride() (*containerconfig.Override, string, error) {
+	spec, err := m.GetContainerConfig()
+	if err != nil {
+		return nil, "", err
+	}
+
+	builder := spec.Builder()
+	if err := m.applyDevModifications(spec, builder, true); err != nil {
+		return nil, "", err
+	}
+	if err := m.applyProfiles(builder, m.profileOptions()); err != nil {
+		return nil, "", err
+	}
+	opts := &containerconfig.RunOptions{MakeMountsWritable: m.devOptions.Writable, WorkingDirOverride: m.devOptions.WorkingDir}
+
+	service := spec.Labels[containerconfig.ComposeServiceLabel]
+	if service == "" {
+		service = spec.Name
+	}
+	return containerconfig.NewOverride(spec, builder.Build(), opts), service, nil
+}
+
+// runSuggestOverride implements the suggest-override subcommand: the dev modifications as a
+// docker-compose.override.yml or docker run flags, for containers managed by compose or k8s
+func runSuggestOverride(args []string) error {
+	fs := newFlagSet("suggest-override")
+	var devOpts DevOptions
+	addDevFlags(fs, &devOpts)
+	format := fs.String("format", containerconfig.OverrideFormatCompose, "output format: compose or flags")
+	service := fs.String("service", "", "compose service name (default: the container's compose service or name)")
+	swapDir := fs.String("swap-dir", "", "host directory mounted at /dev-swap")
+	dockerContext := fs.String("context", "", "docker context of the container")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: suggest-override <container> [dev flags] [--format compose|flags] [--service name] [--swap-dir dir]")
+	}
+	if *format != containerconfig.OverrideFormatCompose && *format != containerconfig.OverrideFormatFlags {
+		return fmt.Errorf("unsupported format '%s' (expected compose or flags)", *format)
+	}
+	if err := devOpts.validate(); err != nil {
+		return err
+	}
+
+	manager := NewManager(positional[0], *swapDir)
+	manager.SetDockerContext(*dockerContext)
+	manager.SetDevOptions(devOpts)
+	manager.logger.SetOutput(os.Stderr)
+	if devOpts.StartOtelCollector || devOpts.Ephemeral || len(devOpts.Copies) > 0 {
+		warnf(os.Stderr, "--otel-collector, --ephemeral and --copy create resources and aren't part of the override")
+	}
+
+	override, name, err := manager.SuggestOverride()
+	if err != nil {
+		return err
+	}
+	if *service != "" {
+		name = *service
+	}
+	if override.Empty() {
+		fmt.Fprintln(os.Stderr, "The dev options change nothing")
+		return nil
+	}
+
+	if *format == containerconfig.OverrideFormatFlags {
+		line := containerconfig.FormatShellCommand(override.RunFlags())
+		if command := override.RunCommandArgs(); command != nil {
+			line += "  # image followed by: " + containerconfig.FormatShellCommand(command)
+		}
+		fmt.Println(strings.TrimSpace(line))
+		return nil
+	}
+	data, err := override.ComposeYAML(name)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
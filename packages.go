@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// packageManager installs distro packages in a container
+type packageManager struct {
+	name string
+	// install is the shell command installing the packages given as "%s"
+	install string
+	// aliases maps generic package names to this distro's names
+	aliases map[string]string
+}
+
+// packageManagers are detected in this order; dnf and microdnf come before yum, which is often
+// a compatibility shim for them
+var packageManagers = []packageManager{
+	{name: "apk", install: "apk add --no-cache %s"},
+	{name: "apt-get", install: "apt-get update -qq && DEBIAN_FRONTEND=noninteractive apt-get install -y -qq --no-install-recommends %s"},
+	{name: "dnf", install: "dnf install -y -q %s", aliases: map[string]string{"procps": "procps-ng"}},
+	{name: "microdnf", install: "microdnf install -y %s", aliases: map[string]string{"procps": "procps-ng"}},
+	{name: "yum", install: "yum install -y -q %s", aliases: map[string]string{"procps": "procps-ng"}},
+}
+
+// command returns the shell command installing the packages, with names translated for the distro
+func (pm *packageManager) command(packages []string) string {
+	names := make([]string, len(packages))
+	for i, name := range packages {
+		if alias, ok := pm.aliases[name]; ok {
+			name = alias
+		}
+		names[i] = containerconfig.QuoteShellArg(name)
+	}
+	return fmt.Sprintf(pm.install, strings.Join(names, " "))
+}
+
+// detectPackageManager finds the first known package manager on the container's PATH
+func (m *Manager) detectPackageManager(containerName string) (*packageManager, error) {
+	var checks []string
+	for _, pm := range packageManagers {
+		checks = append(checks, fmt.Sprintf("command -v %s >/dev/null 2>&1 && echo %s && exit 0", pm.name, pm.name))
+	}
+	out, err := m.execOutput("detect package manager", containerName, execRequest{Cmd: []string{"sh", "-c", strings.Join(checks, "; ") + "; exit 1"}})
+	if err != nil {
+		return nil, fmt.Errorf("no supported package manager (apk, apt-get, dnf, microdnf, yum) found in '%s'", containerName)
+	}
+	for i := range packageManagers {
+		if packageManagers[i].name == out {
+			return &packageManagers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected package manager '%s'", out)
+}
+
+// InstallPackages installs distro packages in the container as root with its package manager;
+// generic names such as procps are translated for the distro
+func (m *Manager) InstallPackages(containerName string, packages ...string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+	if m.devOptions.Offline {
+		return fmt.Errorf("offline mode forbids installing packages")
+	}
+	action, err := m.installPackagesAction(containerName, containerName, packages)
+	if err != nil {
+		return err
+	}
+	m.logger.Printf("Installing %s...", strings.Join(packages, ", "))
+	if _, err := m.execOutput(action.Description, containerName, *action.Exec); err != nil {
+		return err
+	}
+	m.logger.Printf("Packages installed in '%s'", containerName)
+	return nil
+}
+
+// installPackagesAction returns the command installing the packages in the container with the
+// package manager found in the probe container
+func (m *Manager) installPackagesAction(containerName, probe string, packages []string) (PlanAction, error) {
+	pm, err := m.detectPackageManager(probe)
+	if err != nil {
+		return PlanAction{}, err
+	}
+	install := execRequest{User: "0", Env: m.proxyEnv(), Cmd: []string{"sh", "-c", pm.command(packages)}}
+	return execAction(fmt.Sprintf("install %s with %s", strings.Join(packages, ", "), pm.name), containerName, install), nil
+}
+
+// devPackages returns the packages to install in the dev container: those of the selected
+// profiles followed by --package, without duplicates
+func (o DevOptions) devPackages() []string {
+	seen := make(map[string]bool)
+	var packages []string
+	add := func(names []string) {
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				packages = append(packages, name)
+			}
+		}
+	}
+	for _, profile := range o.Profiles {
+		add(containerconfig.ProfilePackages(profile))
+	}
+	add(o.Packages)
+	return packages
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// readInput reads a file, or stdin when the path is "-" or empty
+func readInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read '%s': %w", path, err)
+	}
+	return data, nil
+}
+
+// writeSpec prints a spec to stdout in the given format; compose prints it as the single service
+// of a compose file, with its env vars inline
+func writeSpec(spec *containerconfig.ContainerSpec, format string) error {
+	var data []byte
+	var err error
+	switch format {
+	case "json":
+		data, err = containerconfig.MarshalSpec(spec)
+	case "yaml":
+		data, err = containerconfig.MarshalSpecYAML(spec)
+	case "compose":
+		data, _, err = containerconfig.ExportCompose([]*containerconfig.ContainerSpec{spec}, containerconfig.ComposeExportOptions{InlineEnv: true})
+	default:
+		return fmt.Errorf("unsupported spec format '%s' (supported: json, yaml, compose)", format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// runParse implements the parse subcommand: docker inspect JSON in, spec out, no daemon needed
+func runParse(args []string) error {
+	fs := newFlagSet("parse")
+	format := fs.String("format", "json", "output format: json, yaml or compose")
+	labels := addLabelFlags(fs, false)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return fmt.Errorf("usage: parse [inspect.json|-] [--format json|yaml|compose]")
+	}
+
+	input := ""
+	if len(positional) == 1 {
+		input = positional[0]
+	}
+	data, err := readInput(input)
+	if err != nil {
+		return err
+	}
+
+	labelFilter, err := labels.filter()
+	if err != nil {
+		return err
+	}
+	spec, warnings, err := containerconfig.ParseInspectJSONWithWarnings(string(data), &containerconfig.ParseOptions{LabelFilter: labelFilter})
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		warnf(os.Stderr, "%s", warning)
+	}
+	return writeSpec(spec, *format)
+}
+
+// runGenerate implements the generate subcommand: spec in, command or spec out, no daemon needed
+func runGenerate(args []string) error {
+	fs := newFlagSet("generate")
+	format := fs.String("format", "run", "output format: run, json, yaml or compose")
+	name := fs.String("name", "", "container name to use instead of the spec's")
+	envOverridesOnly := fs.Bool("env-overrides-only", false, "emit only env vars that differ from the spec's image env")
+	omitImageDefaults := fs.Bool("omit-image-defaults", false, "leave out the entrypoint and command inherited from the spec's image")
+	shell := fs.String("shell", "auto", "quote the command for sh, powershell or cmd; auto picks powershell for Windows containers")
+	multiline := fs.Bool("multiline", false, "put each flag on its own line, using the shell's line continuation")
+	annotate := fs.Bool("annotate", false, "multiline, preceded by comments saying which inspect field each line came from and why dce added it")
+	var writable stringList
+	fs.Var(&writable, "writable", "container path of a read-only mount to emit writable (repeatable, * for all)")
+	composeService := fs.String("compose-service", "", "read the input as a compose file and generate the command for this service")
+	cgroupVersion := fs.String("cgroup-version", "", "cgroup version of the target daemon, 1 or 2; leaves out the limits it can't apply")
+	labels := addLabelFlags(fs, false)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return fmt.Errorf("usage: generate [spec.yaml|-] [--format run|json|yaml|compose] [--name name] [--shell auto|sh|powershell|cmd] [--multiline] [--annotate] [--compose-service name] [--cgroup-version 1|2]")
+	}
+	if *cgroupVersion != "" && *cgroupVersion != "1" && *cgroupVersion != "2" {
+		return fmt.Errorf("invalid --cgroup-version '%s': expected 1 or 2", *cgroupVersion)
+	}
+
+	input := ""
+	if len(positional) == 1 {
+		input = positional[0]
+	}
+	data, err := readInput(input)
+	if err != nil {
+		return err
+	}
+	var spec *containerconfig.ContainerSpec
+	if *composeService != "" {
+		var warnings []containerconfig.Warning
+		spec, warnings, err = containerconfig.ImportComposeService(data, *composeService, containerconfig.ComposeOptions{Dir: filepath.Dir(input)})
+		for _, warning := range warnings {
+			warnf(os.Stderr, "%s", warning)
+		}
+	} else {
+		spec, err = containerconfig.UnmarshalSpec(data)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *cgroupVersion != "" {
+		// A v1 daemon is assumed to support kernel memory limits, which only a real one can tell
+		daemon := &containerconfig.DaemonInfo{CgroupVersion: *cgroupVersion, KernelMemory: true}
+		for _, warning := range daemon.AdaptResources(spec) {
+			warnf(os.Stderr, "%s", warning)
+		}
+	}
+
+	if *format != "run" {
+		if *format == "compose" && *name != "" {
+			spec.Name = *name
+		}
+		return writeSpec(spec, *format)
+	}
+
+	if *shell == "auto" {
+		*shell = spec.DefaultShell()
+	}
+	if err := containerconfig.ValidateShell(*shell); err != nil {
+		return err
+	}
+
+	labelFilter, err := labels.filter()
+	if err != nil {
+		return err
+	}
+	opts := &containerconfig.RunOptions{
+		Name:               *name,
+		LabelFilter:        labelFilter,
+		EnvOverridesOnly:   *envOverridesOnly,
+		OmitImageDefaults:  *omitImageDefaults,
+		MakeMountsWritable: writable,
+	}
+	for _, warning := range containerconfig.GenerationWarnings(spec, opts) {
+		warnf(os.Stderr, "%s", warning)
+	}
+	prefix := []string{"docker", "run", "-d"}
+	runArgs := append(prefix, containerconfig.GenerateRunCommand(spec, opts)...)
+	if *annotate {
+		fmt.Println(highlight(os.Stdout, containerconfig.FormatAnnotatedCommand(prefix, spec, opts, *shell)))
+	} else if *multiline {
+		fmt.Println(highlight(os.Stdout, containerconfig.FormatCommandMultiline(runArgs, *shell)))
+	} else {
+		fmt.Println(highlight(os.Stdout, containerconfig.FormatCommand(runArgs, *shell)))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package containerconfig
+
+import "strings"
+
+// AppArmor profiles docker provides itself
+const (
+	// AppArmorDefault is the profile docker confines containers by when none is given
+	AppArmorDefault = "docker-default"
+	// AppArmorUnconfined runs the container without AppArmor confinement
+	AppArmorUnconfined = "unconfined"
+)
+
+// AppArmorProfilesPath lists the AppArmor profiles loaded into the kernel, one "name (mode)" per line
+const AppArmorProfilesPath = "/sys/kernel/security/apparmor/profiles"
+
+// ParseAppArmorProfiles parses the content of AppArmorProfilesPath into the set of loaded profile names
+func ParseAppArmorProfiles(data string) map[string]bool {
+	profiles := make(map[string]bool)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.LastIndex(line, " ("); i > 0 {
+			line = line[:i]
+		}
+		if line != "" {
+			profiles[line] = true
+		}
+	}
+	return profiles
+}
+
+// appArmorProfile returns the profile a container was created with as the spec records it: empty for
+// docker's default profile, and for the unconfined profile --privileged implies
+func appArmorProfile(profile string, privileged bool) string {
+	if profile == AppArmorDefault || (privileged && profile == AppArmorUnconfined) {
+		return ""
+	}
+	return profile
+}
+
+// WithAppArmorProfile confines the container by the named AppArmor profile; empty restores docker's default
+func (b *SpecBuilder) WithAppArmorProfile(profile string) *SpecBuilder {
+	b.spec.AppArmorProfile = profile
+	return b
+}
+
+// SetAppArmorProfile replaces the AppArmor profile, e.g. with AppArmorUnconfined when a custom
+// profile keeps the debugger from tracing the app
+func SetAppArmorProfile(profile string) DevTransform {
+	return NewDevTransform("AppArmor profile", func(b *SpecBuilder) {
+		b.WithAppArmorProfile(profile)
+	})
+}
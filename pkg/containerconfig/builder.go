@@ -0,0 +1,385 @@
+package containerconfig
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Clone returns a deep copy of the spec; modifying the copy never affects the original
+func (s *ContainerSpec) Clone() *ContainerSpec {
+	if s == nil {
+		return nil
+	}
+	clone := *s
+	clone.Env = cloneStrings(s.Env)
+	clone.ImageEnv = cloneStrings(s.ImageEnv)
+	clone.ImageEntryPoint = cloneStrings(s.ImageEntryPoint)
+	clone.ImageCommand = cloneStrings(s.ImageCommand)
+	clone.Volumes = cloneMounts(s.Volumes)
+	clone.Ports = append([]PortMapping(nil), s.Ports...)
+	clone.Networks = cloneStrings(s.Networks)
+	clone.Command = cloneStrings(s.Command)
+	clone.EntryPoint = cloneStrings(s.EntryPoint)
+	clone.Devices = cloneStrings(s.Devices)
+	clone.ExtraHosts = cloneStrings(s.ExtraHosts)
+	clone.Links = cloneStrings(s.Links)
+	clone.NetworkAliases = cloneStrings(s.NetworkAliases)
+	clone.VolumesFrom = cloneStrings(s.VolumesFrom)
+	clone.Tmpfs = cloneStrings(s.Tmpfs)
+	clone.Ulimits = cloneStrings(s.Ulimits)
+	clone.CapAdd = cloneStrings(s.CapAdd)
+	clone.Healthcheck = s.Healthcheck.clone()
+	if s.Runtime != nil {
+		runtime := *s.Runtime
+		clone.Runtime = &runtime
+	}
+	if s.MemorySwappiness != nil {
+		swappiness := *s.MemorySwappiness
+		clone.MemorySwappiness = &swappiness
+	}
+	clone.Labels = cloneMap(s.Labels)
+	clone.DevNotes = cloneMap(s.DevNotes)
+	return &clone
+}
+
+// cloneStrings copies a string slice, keeping nil as nil
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string(nil), values...)
+}
+
+// cloneMap copies a string map, keeping nil as nil
+func cloneMap(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	clone := make(map[string]string, len(values))
+	for key, value := range values {
+		clone[key] = value
+	}
+	return clone
+}
+
+// SpecBuilder applies modifications to a private copy of a spec
+// Every method returns the builder so calls can be chained; Build returns the result
+type SpecBuilder struct {
+	spec *ContainerSpec
+	// recorded is the spec as of the last Record
+	recorded *ContainerSpec
+}
+
+// Builder starts a modification of a copy of the spec; the spec itself is never mutated
+func (s *ContainerSpec) Builder() *SpecBuilder {
+	return &SpecBuilder{spec: s.Clone(), recorded: s.Clone()}
+}
+
+// Record notes the run flags the modifications since the previous Record added, with the reason
+// they were made, in the spec's DevNotes; a flag keeps the reason it was first added for
+func (b *SpecBuilder) Record(reason string) *SpecBuilder {
+	before := runArgKeys(b.recorded)
+	for args, key := range runArgKeys(b.spec) {
+		if _, ok := before[args]; ok || b.spec.DevNotes[key] != "" {
+			continue
+		}
+		if b.spec.DevNotes == nil {
+			b.spec.DevNotes = make(map[string]string)
+		}
+		b.spec.DevNotes[key] = reason
+	}
+	b.recorded = b.spec.Clone()
+	return b
+}
+
+// Build returns a copy of the modified spec; the builder can keep being used afterwards
+func (b *SpecBuilder) Build() *ContainerSpec {
+	return b.spec.Clone()
+}
+
+// WithName sets the container name
+func (b *SpecBuilder) WithName(name string) *SpecBuilder {
+	b.spec.Name = name
+	return b
+}
+
+// WithImage sets the image
+func (b *SpecBuilder) WithImage(image string) *SpecBuilder {
+	b.spec.Image = image
+	return b
+}
+
+// WithEnv sets an environment variable, replacing any existing value
+func (b *SpecBuilder) WithEnv(key, value string) *SpecBuilder {
+	b.spec.Env.Set(key, value)
+	return b
+}
+
+// WithoutEnv removes an environment variable
+func (b *SpecBuilder) WithoutEnv(key string) *SpecBuilder {
+	b.spec.Env.Unset(key)
+	return b
+}
+
+// WithVolume adds a "source:target[:mode]" volume, replacing any volume mounted at the same target
+func (b *SpecBuilder) WithVolume(volume string) *SpecBuilder {
+	return b.WithMount(ParseMount(volume))
+}
+
+// WithMount adds a mount, replacing any mount at the same target
+func (b *SpecBuilder) WithMount(m Mount) *SpecBuilder {
+	b.WithoutVolume(m.Target)
+	b.spec.Volumes = append(b.spec.Volumes, m)
+	return b
+}
+
+// WithoutVolume removes the volume mounted at the given container path
+func (b *SpecBuilder) WithoutVolume(target string) *SpecBuilder {
+	var volumes []Mount
+	for _, m := range b.spec.Volumes {
+		if m.Target != target {
+			volumes = append(volumes, m)
+		}
+	}
+	b.spec.Volumes = volumes
+	return b
+}
+
+// WithPort publishes a port mapping unless it is already present
+func (b *SpecBuilder) WithPort(port PortMapping) *SpecBuilder {
+	for _, existing := range b.spec.Ports {
+		if existing == port {
+			return b
+		}
+	}
+	b.spec.Ports = append(b.spec.Ports, port)
+	return b
+}
+
+// WithHostPortOffset shifts the host side of every published port with a fixed host port by offset,
+// so several copies of a container can publish the same container ports side by side
+func (b *SpecBuilder) WithHostPortOffset(offset int) *SpecBuilder {
+	for i, port := range b.spec.Ports {
+		if port.HostPort == 0 {
+			continue
+		}
+		b.spec.Ports[i].HostPort += offset
+		if port.HostPortEnd != 0 {
+			b.spec.Ports[i].HostPortEnd += offset
+		}
+	}
+	return b
+}
+
+// HostPorts returns the fixed host ports the spec's published ports bind, ranges expanded
+func (s *ContainerSpec) HostPorts() []int {
+	var ports []int
+	for _, port := range s.Ports {
+		ports = append(ports, port.HostPorts()...)
+	}
+	return ports
+}
+
+// WithoutPort removes a port mapping
+func (b *SpecBuilder) WithoutPort(port PortMapping) *SpecBuilder {
+	var ports []PortMapping
+	for _, existing := range b.spec.Ports {
+		if existing != port {
+			ports = append(ports, existing)
+		}
+	}
+	b.spec.Ports = ports
+	return b
+}
+
+// WithCommandArgs appends arguments to the command
+// For a shell-form command the arguments are quoted into the command string, since arguments
+// after "sh -c <string>" would become the shell's positional parameters instead
+func (b *SpecBuilder) WithCommandArgs(args ...string) *SpecBuilder {
+	command := b.spec.CommandArgs()
+	if line, ok := ShellCommand(command); ok && len(args) > 0 {
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = QuoteShellArg(arg)
+		}
+		command[len(command)-1] = line + " " + strings.Join(quoted, " ")
+		b.spec.Command = command
+		b.spec.CommandForm = FormShell
+		return b
+	}
+	b.spec.Command = append(b.spec.Command, args...)
+	return b
+}
+
+// WithEntryPoint replaces the entrypoint with an exec-form argv
+func (b *SpecBuilder) WithEntryPoint(args ...string) *SpecBuilder {
+	b.spec.EntryPoint = args
+	b.spec.EntryPointForm = ""
+	return b
+}
+
+// WithCommand replaces the command with an exec-form argv
+func (b *SpecBuilder) WithCommand(args ...string) *SpecBuilder {
+	b.spec.Command = args
+	b.spec.CommandForm = ""
+	return b
+}
+
+// WithNetwork attaches the container to a network unless it already is
+func (b *SpecBuilder) WithNetwork(network string) *SpecBuilder {
+	for _, existing := range b.spec.Networks {
+		if existing == network {
+			return b
+		}
+	}
+	b.spec.Networks = append(b.spec.Networks, network)
+	return b
+}
+
+// WithOnlyNetwork replaces all networks with a single network
+func (b *SpecBuilder) WithOnlyNetwork(network string) *SpecBuilder {
+	b.spec.Networks = []string{network}
+	b.spec.NetworkMode = ""
+	return b
+}
+
+// WithNetworkAlias adds a DNS alias on the container's first network
+func (b *SpecBuilder) WithNetworkAlias(alias string) *SpecBuilder {
+	b.spec.NetworkAliases = append(b.spec.NetworkAliases, alias)
+	return b
+}
+
+// WithoutNetworkAliases drops every network alias
+func (b *SpecBuilder) WithoutNetworkAliases() *SpecBuilder {
+	b.spec.NetworkAliases = nil
+	return b
+}
+
+// WithVolumeSource points mounts of one named volume at another volume, keeping target and mode
+func (b *SpecBuilder) WithVolumeSource(from, to string) *SpecBuilder {
+	for i, m := range b.spec.Volumes {
+		if m.Named() && m.Source == from {
+			b.spec.Volumes[i].Source = to
+		}
+	}
+	return b
+}
+
+// WithRestart sets the restart policy; an empty policy disables restarts
+func (b *SpecBuilder) WithRestart(policy string) *SpecBuilder {
+	b.spec.Restart = policy
+	return b
+}
+
+// WithoutHealthcheck disables the healthcheck, including one inherited from the image
+func (b *SpecBuilder) WithoutHealthcheck() *SpecBuilder {
+	b.spec.Healthcheck = &Healthcheck{Test: []string{HealthNone}}
+	return b
+}
+
+// WithoutPorts stops publishing any port
+func (b *SpecBuilder) WithoutPorts() *SpecBuilder {
+	b.spec.Ports = nil
+	return b
+}
+
+// WithAnonymousVolumes replaces named volumes with anonymous ones at the same path, so a copy
+// of a container starts with fresh data instead of sharing it with the original
+func (b *SpecBuilder) WithAnonymousVolumes() *SpecBuilder {
+	named := make(map[string]bool)
+	for _, name := range b.spec.NamedVolumes() {
+		named[name] = true
+	}
+	for i, m := range b.spec.Volumes {
+		if m.Named() && named[m.Source] {
+			b.spec.Volumes[i] = Mount{Type: MountVolume, Target: m.Target}
+		}
+	}
+	return b
+}
+
+// WithTmpfs adds a tmpfs mount at a container path, replacing any tmpfs already there
+// options are the comma-separated tmpfs options such as "size=1g", or empty
+func (b *SpecBuilder) WithTmpfs(path, options string) *SpecBuilder {
+	var kept []string
+	for _, tmpfs := range b.spec.Tmpfs {
+		if existing, _, _ := strings.Cut(tmpfs, ":"); existing != path {
+			kept = append(kept, tmpfs)
+		}
+	}
+	if options != "" {
+		path += ":" + options
+	}
+	b.spec.Tmpfs = append(kept, path)
+	return b
+}
+
+// WithUlimit sets a resource limit, replacing any limit of the same name; -1 is unlimited
+func (b *SpecBuilder) WithUlimit(name string, soft, hard int64) *SpecBuilder {
+	var kept []string
+	for _, ulimit := range b.spec.Ulimits {
+		if existing, _, _ := strings.Cut(ulimit, "="); existing != name {
+			kept = append(kept, ulimit)
+		}
+	}
+	b.spec.Ulimits = append(kept, FormatUlimit(name, soft, hard))
+	return b
+}
+
+// FormatUlimit formats a resource limit as docker run --ulimit takes it
+func FormatUlimit(name string, soft, hard int64) string {
+	if soft == hard {
+		return name + "=" + strconv.FormatInt(soft, 10)
+	}
+	return name + "=" + strconv.FormatInt(soft, 10) + ":" + strconv.FormatInt(hard, 10)
+}
+
+// WithLabel sets a label
+func (b *SpecBuilder) WithLabel(key, value string) *SpecBuilder {
+	if b.spec.Labels == nil {
+		b.spec.Labels = make(map[string]string)
+	}
+	b.spec.Labels[key] = value
+	return b
+}
+
+// WithoutLabel removes a label
+func (b *SpecBuilder) WithoutLabel(key string) *SpecBuilder {
+	delete(b.spec.Labels, key)
+	return b
+}
+
+// WithCapAdd adds a Linux capability unless the spec already has it
+func (b *SpecBuilder) WithCapAdd(capability string) *SpecBuilder {
+	capability = strings.ToUpper(strings.TrimPrefix(strings.ToUpper(capability), "CAP_"))
+	for _, existing := range b.spec.CapAdd {
+		if strings.TrimPrefix(strings.ToUpper(existing), "CAP_") == capability {
+			return b
+		}
+	}
+	b.spec.CapAdd = append(b.spec.CapAdd, capability)
+	return b
+}
+
+// WithExtraHost adds a "host:ip" entry to /etc/hosts
+func (b *SpecBuilder) WithExtraHost(host string) *SpecBuilder {
+	b.spec.ExtraHosts = append(b.spec.ExtraHosts, host)
+	return b
+}
+
+// HostGatewayName is the hostname containers conventionally use to reach the docker host
+const HostGatewayName = "host.docker.internal"
+
+// HostGatewayAddress is the special --add-host address docker (20.10+) replaces with the host's gateway IP
+const HostGatewayAddress = "host-gateway"
+
+// WithHostAccess maps host.docker.internal to the docker host, so the container can reach services
+// running on the host; an existing entry for the name is kept
+func (b *SpecBuilder) WithHostAccess() *SpecBuilder {
+	for _, host := range b.spec.ExtraHosts {
+		if name, _, _ := strings.Cut(host, ":"); name == HostGatewayName {
+			return b
+		}
+	}
+	return b.WithExtraHost(HostGatewayName + ":" + HostGatewayAddress)
+}
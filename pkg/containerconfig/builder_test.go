@@ -0,0 +1,57 @@
+package containerconfig_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// cloneTestSpec is a spec with every shared-memory field (slices, maps and pointers) set
+func cloneTestSpec() *containerconfig.ContainerSpec {
+	swappiness := int64(60)
+	return &containerconfig.ContainerSpec{
+		Name:             "api",
+		Image:            "api:1.4",
+		Env:              containerconfig.Env{"A=1"},
+		Volumes:          containerconfig.ParseMounts([]string{"/srv/config:/config:ro,z"}),
+		Ports:            []containerconfig.PortMapping{{HostPort: 8080, ContainerPort: 80}},
+		Networks:         []string{"backend"},
+		Command:          []string{"/app/server"},
+		Labels:           map[string]string{"tier": "api"},
+		MemorySwappiness: &swappiness,
+		Healthcheck:      &containerconfig.Healthcheck{Test: []string{"CMD", "true"}},
+		Runtime:          &containerconfig.RuntimeSnapshot{Status: "running"},
+		DevNotes:         map[string]string{"-p 2345:2345": "debug port"},
+	}
+}
+
+func TestClone(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*containerconfig.ContainerSpec)
+	}{
+		{"env", func(s *containerconfig.ContainerSpec) { s.Env[0] = "A=2" }},
+		{"mount options", func(s *containerconfig.ContainerSpec) { s.Volumes[0].Options[0] = "Z" }},
+		{"ports", func(s *containerconfig.ContainerSpec) { s.Ports[0].HostPort = 9090 }},
+		{"command", func(s *containerconfig.ContainerSpec) { s.Command[0] = "/bin/sh" }},
+		{"labels", func(s *containerconfig.ContainerSpec) { s.Labels["tier"] = "dev" }},
+		{"memory swappiness", func(s *containerconfig.ContainerSpec) { *s.MemorySwappiness = 0 }},
+		{"healthcheck test", func(s *containerconfig.ContainerSpec) { s.Healthcheck.Test[1] = "false" }},
+		{"runtime", func(s *containerconfig.ContainerSpec) { s.Runtime.Status = "exited" }},
+		{"dev notes", func(s *containerconfig.ContainerSpec) { s.DevNotes["-p 2345:2345"] = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := cloneTestSpec()
+			clone := original.Clone()
+			if !reflect.DeepEqual(clone, original) {
+				t.Fatalf("Clone() = %+v, want %+v", clone, original)
+			}
+			tt.modify(clone)
+			if !reflect.DeepEqual(original, cloneTestSpec()) {
+				t.Errorf("modifying the clone changed the original: %+v", original)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package containerconfig
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CDIAnnotationPrefix starts the annotations that request Container Device Interface devices,
+// e.g. cdi.k8s.io/nvidia-device-plugin_0=nvidia.com/gpu=0
+const CDIAnnotationPrefix = "cdi.k8s.io/"
+
+// NvidiaGPUKind is the CDI kind nvidia-ctk cdi generate names GPUs with
+const NvidiaGPUKind = "nvidia.com/gpu"
+
+// cdiDeviceName matches a fully qualified CDI device name, vendor.com/class=name
+var cdiDeviceName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.-]*/[A-Za-z0-9][A-Za-z0-9_.-]*=[A-Za-z0-9_.:-]+$`)
+
+// IsCDIDevice reports whether a --device value is a CDI device name rather than a host path
+func IsCDIDevice(device string) bool {
+	return cdiDeviceName.MatchString(device)
+}
+
+// deviceRequest is a HostConfig.DeviceRequests entry, written by --gpus or by --device with a CDI name
+type deviceRequest struct {
+	Driver       string            `json:"Driver"`
+	Count        int               `json:"Count"`
+	DeviceIDs    []string          `json:"DeviceIDs"`
+	Capabilities [][]string        `json:"Capabilities"`
+	Options      map[string]string `json:"Options"`
+}
+
+// isGPU reports whether the request asks for GPUs
+func (r deviceRequest) isGPU() bool {
+	if r.Driver == "nvidia" {
+		return true
+	}
+	for _, set := range r.Capabilities {
+		for _, capability := range set {
+			if capability == "gpu" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cdiDevices returns the request as CDI device names: CDI requests as they are, GPU requests
+// (--gpus all, --gpus device=0,1) as nvidia.com/gpu devices, which the NVIDIA toolkit's CDI spec
+// names by index and UUID alike; add receives what can't be carried over
+func (r deviceRequest) cdiDevices(add func(field, format string, args ...interface{})) []string {
+	if len(r.Options) > 0 {
+		add("devices", "options %v of the %s device request are dropped", r.Options, r.Driver)
+	}
+	switch {
+	case r.Driver == "cdi":
+		return r.DeviceIDs
+	case !r.isGPU():
+		add("devices", "device request of driver '%s' is dropped; only GPU and CDI requests are converted", r.Driver)
+		return nil
+	}
+
+	var devices []string
+	switch {
+	case len(r.DeviceIDs) > 0:
+		for _, id := range r.DeviceIDs {
+			devices = append(devices, NvidiaGPUKind+"="+id)
+		}
+	case r.Count < 0:
+		devices = []string{NvidiaGPUKind + "=all"}
+	default:
+		for i := 0; i < r.Count; i++ {
+			devices = append(devices, NvidiaGPUKind+"="+strconv.Itoa(i))
+		}
+		add("devices", "a request for any %d GPU(s) becomes GPUs 0 to %d", r.Count, r.Count-1)
+	}
+	add("devices", "GPUs are requested by CDI name (%s); the docker host needs CDI enabled and a spec from nvidia-ctk cdi generate", strings.Join(devices, ", "))
+	return devices
+}
+
+// cdiAnnotationDevices returns the CDI devices requested by the cdi.k8s.io annotations, whose
+// values are comma-separated device names
+func cdiAnnotationDevices(annotations map[string]string) []string {
+	var devices []string
+	for _, key := range sortedKeys(annotations) {
+		if !strings.HasPrefix(key, CDIAnnotationPrefix) {
+			continue
+		}
+		for _, device := range strings.Split(annotations[key], ",") {
+			if device = strings.TrimSpace(device); IsCDIDevice(device) {
+				devices = appendUnique(devices, device)
+			}
+		}
+	}
+	return devices
+}
+
+// composeDeviceRequest is a deploy.resources.reservations.devices entry of a compose service
+type composeDeviceRequest struct {
+	Driver       string            `yaml:"driver"`
+	Count        string            `yaml:"count"`
+	DeviceIDs    []string          `yaml:"device_ids"`
+	Capabilities []string          `yaml:"capabilities"`
+	Options      map[string]string `yaml:"options"`
+}
+
+// deviceRequest converts the compose form, where count may be "all", to the inspect form
+func (c composeDeviceRequest) deviceRequest() (deviceRequest, error) {
+	r := deviceRequest{Driver: c.Driver, DeviceIDs: c.DeviceIDs, Options: c.Options}
+	if len(c.Capabilities) > 0 {
+		r.Capabilities = [][]string{c.Capabilities}
+	}
+	switch c.Count {
+	case "":
+		if len(c.DeviceIDs) == 0 {
+			r.Count = -1
+		}
+	case "all":
+		r.Count = -1
+	default:
+		count, err := strconv.Atoi(c.Count)
+		if err != nil {
+			return r, fmt.Errorf("invalid device count '%s'", c.Count)
+		}
+		r.Count = count
+	}
+	return r, nil
+}
+
+// composeCDIDevices converts the device reservations of a compose service to CDI device names
+func composeCDIDevices(nodes []yaml.Node, add func(field, format string, args ...interface{})) ([]string, error) {
+	var devices []string
+	for _, node := range nodes {
+		var c composeDeviceRequest
+		if err := node.Decode(&c); err != nil {
+			return nil, fmt.Errorf("invalid device reservation at line %d: %w", node.Line, err)
+		}
+		request, err := c.deviceRequest()
+		if err != nil {
+			return nil, err
+		}
+		for _, device := range request.cdiDevices(add) {
+			devices = appendUnique(devices, device)
+		}
+	}
+	return devices, nil
+}
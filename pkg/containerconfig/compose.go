@@ -0,0 +1,691 @@
+package containerconfig
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ComposeOptions controls how a compose file is resolved into container specs
+type ComposeOptions struct {
+	// Project is the compose project name; defaults to the file's top-level name or its directory name
+	Project string
+	// Dir is the directory relative paths and the .env file are resolved against
+	Dir string
+}
+
+// composeFile is the subset of the compose file format that maps onto a ContainerSpec
+type composeFile struct {
+	Name     string                      `yaml:"name"`
+	Services map[string]composeService   `yaml:"services"`
+	Networks map[string]*composeResource `yaml:"networks"`
+	Volumes  map[string]*composeResource `yaml:"volumes"`
+}
+
+// composeResource is a top-level network or volume declaration
+type composeResource struct {
+	Name     string      `yaml:"name"`
+	External interface{} `yaml:"external"`
+}
+
+// composeService is a single service entry of a compose file
+type composeService struct {
+	Image          string                   `yaml:"image"`
+	ContainerName  string                   `yaml:"container_name"`
+	Environment    listOrMap                `yaml:"environment"`
+	Volumes        []yaml.Node              `yaml:"volumes"`
+	Ports          []yaml.Node              `yaml:"ports"`
+	Networks       listOrMap                `yaml:"networks"`
+	Command        stringOrList             `yaml:"command"`
+	Entrypoint     stringOrList             `yaml:"entrypoint"`
+	WorkingDir     string                   `yaml:"working_dir"`
+	Labels         listOrMap                `yaml:"labels"`
+	Devices        []string                 `yaml:"devices"`
+	ExtraHosts     listOrMap                `yaml:"extra_hosts"`
+	Restart        string                   `yaml:"restart"`
+	Links          []string                 `yaml:"links"`
+	NetworkMode    string                   `yaml:"network_mode"`
+	VolumesFrom    []string                 `yaml:"volumes_from"`
+	Tmpfs          stringOrList             `yaml:"tmpfs"`
+	Ulimits        map[string]composeUlimit `yaml:"ulimits"`
+	MemLimit       string                   `yaml:"mem_limit"`
+	CPUs           string                   `yaml:"cpus"`
+	CPUShares      int64                    `yaml:"cpu_shares"`
+	MemSwappiness  *int64                   `yaml:"mem_swappiness"`
+	MemswapLimit   string                   `yaml:"memswap_limit"`
+	MemReservation string                   `yaml:"mem_reservation"`
+	CPUQuota       int64                    `yaml:"cpu_quota"`
+	CPUPeriod      int64                    `yaml:"cpu_period"`
+	User           string                   `yaml:"user"`
+	Privileged     bool                     `yaml:"privileged"`
+	CapAdd         []string                 `yaml:"cap_add"`
+	// Healthcheck and Deploy are translated into docker run flags where docker run has an equivalent
+	Healthcheck *composeHealthcheck `yaml:"healthcheck"`
+	Deploy      *composeDeploy      `yaml:"deploy"`
+}
+
+// composeUlimit is a ulimit of a service, given as a single number or as soft and hard limits
+type composeUlimit struct {
+	Soft int64 `yaml:"soft"`
+	Hard int64 `yaml:"hard"`
+}
+
+// UnmarshalYAML accepts both the number and the soft/hard mapping form
+func (u *composeUlimit) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var limit int64
+		if err := node.Decode(&limit); err != nil {
+			return fmt.Errorf("invalid ulimit '%s': %w", node.Value, err)
+		}
+		u.Soft, u.Hard = limit, limit
+		return nil
+	}
+	type plain composeUlimit
+	return node.Decode((*plain)(u))
+}
+
+// composeDeploy is the deploy section of a service; much of it only applies to swarm services
+type composeDeploy struct {
+	Mode      string `yaml:"mode"`
+	Replicas  *int   `yaml:"replicas"`
+	Resources struct {
+		Limits struct {
+			CPUs   string `yaml:"cpus"`
+			Memory string `yaml:"memory"`
+			Pids   int64  `yaml:"pids"`
+		} `yaml:"limits"`
+		Reservations struct {
+			CPUs    string      `yaml:"cpus"`
+			Memory  string      `yaml:"memory"`
+			Devices []yaml.Node `yaml:"devices"`
+		} `yaml:"reservations"`
+	} `yaml:"resources"`
+	RestartPolicy *struct {
+		Condition   string `yaml:"condition"`
+		MaxAttempts int    `yaml:"max_attempts"`
+		Delay       string `yaml:"delay"`
+		Window      string `yaml:"window"`
+	} `yaml:"restart_policy"`
+	Labels         listOrMap `yaml:"labels"`
+	EndpointMode   string    `yaml:"endpoint_mode"`
+	Placement      yaml.Node `yaml:"placement"`
+	UpdateConfig   yaml.Node `yaml:"update_config"`
+	RollbackConfig yaml.Node `yaml:"rollback_config"`
+}
+
+// stringOrList accepts both the string and the list form of command/entrypoint
+type stringOrList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (s *stringOrList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = splitCommandLine(node.Value)
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// listOrMap accepts both the "KEY=value" list form and the mapping form, keeping the list form
+type listOrMap []string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (l *listOrMap) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		var list []string
+		if err := node.Decode(&list); err != nil {
+			return err
+		}
+		*l = list
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a list or a mapping", node.Line)
+	}
+
+	// Mapping nodes alternate key and value; keep the file order
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		if value.Tag == "!!null" {
+			*l = append(*l, key)
+		} else {
+			*l = append(*l, key+"="+value.Value)
+		}
+	}
+	return nil
+}
+
+// ParseComposeService resolves one service of a compose file into the ContainerSpec
+// docker compose would create for it
+func ParseComposeService(data []byte, service string, opts ComposeOptions) (*ContainerSpec, error) {
+	spec, _, err := ImportComposeService(data, service, opts)
+	return spec, err
+}
+
+// ImportComposeService is ParseComposeService that also reports the settings of the service
+// that a container spec can't represent, such as swarm-only deploy keys
+func ImportComposeService(data []byte, service string, opts ComposeOptions) (*ContainerSpec, []Warning, error) {
+	lookup := composeEnvLookup(opts.Dir)
+
+	// Variables are expanded in the parsed values, as compose does, so a value holding ": ", "#" or a
+	// newline can't change the document's structure and comments are left alone
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	interpolateNode(&root, lookup)
+	var file composeFile
+	if err := root.Decode(&file); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+
+	svc, ok := file.Services[service]
+	if !ok {
+		return nil, nil, fmt.Errorf("service '%s' not found in compose file", service)
+	}
+
+	project := opts.Project
+	if project == "" {
+		project = file.Name
+	}
+	if project == "" {
+		dir, _ := filepath.Abs(opts.Dir)
+		project = strings.ToLower(filepath.Base(dir))
+	}
+
+	spec := &ContainerSpec{
+		Name:        svc.ContainerName,
+		Image:       svc.Image,
+		Command:     svc.Command,
+		EntryPoint:  svc.Entrypoint,
+		WorkingDir:  svc.WorkingDir,
+		Devices:     svc.Devices,
+		Restart:     normalizeRestart(svc.Restart),
+		VolumesFrom: svc.VolumesFrom,
+		Tmpfs:       svc.Tmpfs,
+		User:        svc.User,
+		Privileged:  svc.Privileged,
+		CapAdd:      svc.CapAdd,
+	}
+	if spec.Name == "" {
+		spec.Name = fmt.Sprintf("%s-%s-1", project, service)
+	}
+
+	// Parse resource limits
+	if svc.MemLimit != "" {
+		memory, err := ParseBytes(svc.MemLimit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("service '%s': %w", service, err)
+		}
+		spec.Memory = memory
+	}
+	if svc.CPUs != "" {
+		nanoCPUs, err := ParseCPUs(svc.CPUs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("service '%s': %w", service, err)
+		}
+		spec.NanoCPUs = nanoCPUs
+	}
+	switch svc.MemswapLimit {
+	case "":
+	case "-1":
+		spec.MemorySwap = -1
+	default:
+		swap, err := ParseBytes(svc.MemswapLimit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("service '%s': %w", service, err)
+		}
+		spec.MemorySwap = swap
+	}
+	if svc.MemReservation != "" {
+		reservation, err := ParseBytes(svc.MemReservation)
+		if err != nil {
+			return nil, nil, fmt.Errorf("service '%s': %w", service, err)
+		}
+		spec.MemoryReservation = reservation
+	}
+	spec.CPUShares = svc.CPUShares
+	spec.CPUQuota = svc.CPUQuota
+	spec.CPUPeriod = svc.CPUPeriod
+	spec.MemorySwappiness = svc.MemSwappiness
+
+	healthcheck, err := svc.Healthcheck.healthcheck()
+	if err != nil {
+		return nil, nil, fmt.Errorf("service '%s': %w", service, err)
+	}
+	spec.Healthcheck = healthcheck
+
+	warnings, err := applyComposeDeploy(spec, svc.Deploy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("service '%s': %w", service, err)
+	}
+	warnings = append(warnings, dropConflictingLimits(spec)...)
+
+	// Compose resolves environment entries without a value from the shell
+	for _, env := range svc.Environment {
+		if !strings.Contains(env, "=") {
+			value, ok := lookup(env)
+			if !ok {
+				continue
+			}
+			env += "=" + value
+		}
+		spec.Env = append(spec.Env, env)
+	}
+
+	if len(svc.Labels) > 0 {
+		spec.Labels = make(map[string]string)
+		for _, label := range svc.Labels {
+			key, value, _ := strings.Cut(label, "=")
+			spec.Labels[key] = value
+		}
+	}
+
+	for name, ulimit := range svc.Ulimits {
+		spec.Ulimits = append(spec.Ulimits, FormatUlimit(name, ulimit.Soft, ulimit.Hard))
+	}
+	sort.Strings(spec.Ulimits)
+
+	// Extra hosts use "host:ip" in the list form and "host=ip" in the mapping form
+	for _, host := range svc.ExtraHosts {
+		spec.ExtraHosts = append(spec.ExtraHosts, strings.Replace(host, "=", ":", 1))
+	}
+
+	for _, link := range svc.Links {
+		if !strings.Contains(link, ":") {
+			link += ":" + link
+		}
+		spec.Links = append(spec.Links, link)
+	}
+
+	// Parse volumes
+	for _, node := range svc.Volumes {
+		volume, err := composeVolume(&node, project, opts.Dir, file.Volumes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("service '%s': %w", service, err)
+		}
+		if volume != "" {
+			spec.Volumes = append(spec.Volumes, ParseMount(volume))
+		}
+	}
+
+	// Parse ports
+	for _, node := range svc.Ports {
+		port, err := composePort(&node)
+		if err != nil {
+			return nil, nil, fmt.Errorf("service '%s': %w", service, err)
+		}
+		if port != nil {
+			spec.Ports = append(spec.Ports, *port)
+		}
+	}
+
+	// Parse network mode and networks
+	switch {
+	case strings.HasPrefix(svc.NetworkMode, "service:"):
+		spec.NetworkMode = "container:" + fmt.Sprintf("%s-%s-1", project, strings.TrimPrefix(svc.NetworkMode, "service:"))
+	case strings.HasPrefix(svc.NetworkMode, "container:"):
+		spec.NetworkMode = svc.NetworkMode
+	case svc.NetworkMode != "":
+		spec.Networks = []string{svc.NetworkMode}
+	case len(svc.Networks) == 0:
+		spec.Networks = []string{composeResourceName(project, "default", file.Networks)}
+	default:
+		for _, network := range svc.Networks {
+			name, _, _ := strings.Cut(network, "=")
+			spec.Networks = append(spec.Networks, composeResourceName(project, name, file.Networks))
+		}
+	}
+
+	return spec, warnings, nil
+}
+
+// applyComposeDeploy applies the deploy keys docker compose honors for plain containers (resource
+// limits, replicas, restart policy) and warns about the ones only a swarm service understands
+func applyComposeDeploy(spec *ContainerSpec, deploy *composeDeploy) ([]Warning, error) {
+	if deploy == nil {
+		return nil, nil
+	}
+	var warnings []Warning
+	warn := func(field, format string, args ...interface{}) {
+		warnings = append(warnings, Warning{Field: "deploy." + field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	limits := deploy.Resources.Limits
+	if limits.Memory != "" {
+		memory, err := ParseBytes(limits.Memory)
+		if err != nil {
+			return nil, err
+		}
+		if spec.Memory > 0 && spec.Memory != memory {
+			warn("resources.limits.memory", "overrides mem_limit %s with %s", spec.Memory, memory)
+		}
+		spec.Memory = memory
+	}
+	if limits.CPUs != "" {
+		nanoCPUs, err := ParseCPUs(limits.CPUs)
+		if err != nil {
+			return nil, err
+		}
+		if spec.NanoCPUs > 0 && spec.NanoCPUs != nanoCPUs {
+			warn("resources.limits.cpus", "overrides cpus %s with %s", spec.NanoCPUs, nanoCPUs)
+		}
+		spec.NanoCPUs = nanoCPUs
+	}
+	if limits.Pids > 0 {
+		warn("resources.limits.pids", "pids limit %d is not carried over; add --pids-limit %d by hand", limits.Pids, limits.Pids)
+	}
+
+	reservations := deploy.Resources.Reservations
+	if reservations.Memory != "" {
+		reservation, err := ParseBytes(reservations.Memory)
+		if err != nil {
+			return nil, err
+		}
+		if spec.MemoryReservation > 0 && spec.MemoryReservation != reservation {
+			warn("resources.reservations.memory", "overrides mem_reservation %s with %s", spec.MemoryReservation, reservation)
+		}
+		spec.MemoryReservation = reservation
+	}
+	if reservations.CPUs != "" {
+		warn("resources.reservations.cpus", "CPU reservations only apply to swarm services")
+	}
+	devices, err := composeCDIDevices(reservations.Devices, func(field, format string, args ...interface{}) {
+		warn("resources.reservations."+field, format, args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		spec.Devices = appendUnique(spec.Devices, device)
+	}
+
+	if deploy.Replicas != nil {
+		spec.Replicas = *deploy.Replicas
+	}
+	if deploy.Mode == "global" {
+		warn("mode", "global mode only applies to swarm services")
+	}
+
+	if policy := deploy.RestartPolicy; policy != nil {
+		// restart takes precedence over deploy.restart_policy, as in docker compose
+		if spec.Restart == "" {
+			switch policy.Condition {
+			case "", "any":
+				spec.Restart = "always"
+			case "on-failure":
+				spec.Restart = "on-failure"
+			case "none":
+			default:
+				return nil, fmt.Errorf("invalid restart_policy condition '%s'", policy.Condition)
+			}
+		}
+		if policy.MaxAttempts > 0 {
+			warn("restart_policy.max_attempts", "max attempts %d are not carried over; use --restart on-failure:%d", policy.MaxAttempts, policy.MaxAttempts)
+		}
+		if policy.Delay != "" || policy.Window != "" {
+			warn("restart_policy", "delay and window only apply to swarm services")
+		}
+	}
+
+	swarmOnly := []struct {
+		field string
+		set   bool
+	}{
+		{"labels", len(deploy.Labels) > 0},
+		{"endpoint_mode", deploy.EndpointMode != ""},
+		{"placement", deploy.Placement.Kind != 0},
+		{"update_config", deploy.UpdateConfig.Kind != 0},
+		{"rollback_config", deploy.RollbackConfig.Kind != 0},
+	}
+	for _, key := range swarmOnly {
+		if key.set {
+			warn(key.field, "only applies to swarm services and is ignored")
+		}
+	}
+	return warnings, nil
+}
+
+// composeResourceName returns the docker name of a project network or volume
+func composeResourceName(project, name string, declared map[string]*composeResource) string {
+	if res := declared[name]; res != nil {
+		if res.Name != "" {
+			return res.Name
+		}
+		if external, ok := res.External.(bool); ok && external {
+			return name
+		}
+	}
+	return project + "_" + name
+}
+
+// composeVolume converts a short or long syntax volume entry into the "source:target[:ro]" form
+func composeVolume(node *yaml.Node, project, dir string, declared map[string]*composeResource) (string, error) {
+	var source, target string
+	readOnly := false
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		// Split like docker run -v, so a Windows drive letter stays part of its path
+		parts := splitVolume(node.Value)
+		if len(parts) == 1 {
+			// Anonymous volume, nothing to compare against
+			return "", nil
+		}
+		source, target = parts[0], parts[1]
+		if len(parts) > 2 {
+			for _, opt := range strings.Split(strings.Join(parts[2:], ":"), ",") {
+				if opt == "ro" {
+					readOnly = true
+				}
+			}
+		}
+	case yaml.MappingNode:
+		var long struct {
+			Type     string `yaml:"type"`
+			Source   string `yaml:"source"`
+			Target   string `yaml:"target"`
+			ReadOnly bool   `yaml:"read_only"`
+		}
+		if err := node.Decode(&long); err != nil {
+			return "", fmt.Errorf("line %d: invalid volume: %w", node.Line, err)
+		}
+		if long.Type != "" && long.Type != "bind" && long.Type != "volume" {
+			return "", nil
+		}
+		source, target, readOnly = long.Source, long.Target, long.ReadOnly
+		if source == "" {
+			return "", nil
+		}
+	default:
+		return "", fmt.Errorf("line %d: invalid volume entry", node.Line)
+	}
+
+	switch {
+	case strings.HasPrefix(source, "."):
+		source = filepath.Join(dir, source)
+		if abs, err := filepath.Abs(source); err == nil {
+			source = abs
+		}
+	case strings.HasPrefix(source, "~"):
+		if home, err := os.UserHomeDir(); err == nil {
+			source = filepath.Join(home, strings.TrimPrefix(source, "~"))
+		}
+	case !isHostPath(source):
+		source = composeResourceName(project, source, declared)
+	}
+
+	volume := source + ":" + target
+	if readOnly {
+		volume += ":ro"
+	}
+	return volume, nil
+}
+
+// composePort converts a short or long syntax port entry into a port mapping
+// Ports without a published host port get a random one and are skipped
+func composePort(node *yaml.Node) (*PortMapping, error) {
+	var value string
+	switch node.Kind {
+	case yaml.ScalarNode:
+		value = node.Value
+	case yaml.MappingNode:
+		var long struct {
+			Target    string `yaml:"target"`
+			Published string `yaml:"published"`
+			HostIP    string `yaml:"host_ip"`
+			Protocol  string `yaml:"protocol"`
+		}
+		if err := node.Decode(&long); err != nil {
+			return nil, fmt.Errorf("line %d: invalid port: %w", node.Line, err)
+		}
+		value = hostIPPrefix(long.HostIP) + long.Published + ":" + long.Target
+		if long.Protocol != "" {
+			value += "/" + long.Protocol
+		}
+	default:
+		return nil, fmt.Errorf("line %d: invalid port entry", node.Line)
+	}
+	port, err := ParsePortMapping(value)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	if port.HostPort == 0 {
+		return nil, nil
+	}
+	return &port, nil
+}
+
+// normalizeRestart maps a restart policy to the form produced by the inspect parser
+func normalizeRestart(policy string) string {
+	name, _, _ := strings.Cut(policy, ":")
+	if name == "no" {
+		return ""
+	}
+	return name
+}
+
+// composeEnvLookup resolves variables from the shell first and the .env file in dir second
+func composeEnvLookup(dir string) func(string) (string, bool) {
+	dotEnv := make(map[string]string)
+	if data, err := os.ReadFile(filepath.Join(dir, ".env")); err == nil {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, value, found := strings.Cut(line, "=")
+			if found {
+				dotEnv[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+			}
+		}
+	}
+
+	return func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		value, ok := dotEnv[name]
+		return value, ok
+	}
+}
+
+// interpolateNode expands the variables in the scalar values of a parsed YAML document in place;
+// mapping keys are left as they are, and an alias shares the expanded value of its anchor
+func interpolateNode(node *yaml.Node, lookup func(string) (string, bool)) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		value := interpolate(node.Value, lookup)
+		// A plain scalar is typed by its value, so cpu_shares: ${SHARES} decodes as the number it expands to
+		if value != node.Value && node.Style == 0 {
+			node.Tag = ""
+		}
+		node.Value = value
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			interpolateNode(node.Content[i], lookup)
+		}
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			interpolateNode(child, lookup)
+		}
+	}
+}
+
+// interpolate expands $VAR, ${VAR}, ${VAR:-default} and ${VAR-default} like compose does; $$ is a literal $
+func interpolate(s string, lookup func(string) (string, bool)) string {
+	return os.Expand(s, func(expr string) string {
+		if expr == "$" {
+			return "$"
+		}
+		if name, def, found := strings.Cut(expr, ":-"); found {
+			if value, ok := lookup(name); ok && value != "" {
+				return value
+			}
+			return def
+		}
+		if name, def, found := strings.Cut(expr, "-"); found {
+			if value, ok := lookup(name); ok {
+				return value
+			}
+			return def
+		}
+		value, _ := lookup(expr)
+		return value
+	})
+}
+
+// splitCommandLine splits a shell-style command string into arguments, honoring quotes and escapes
+func splitCommandLine(s string) []string {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' && i+1 < len(runes) {
+				i++
+				current.WriteRune(runes[i])
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == '\\' && i+1 < len(runes):
+			i++
+			current.WriteRune(runes[i])
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
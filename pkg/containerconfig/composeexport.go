@@ -0,0 +1,553 @@
+package containerconfig
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Conditions of a compose depends_on entry
+const (
+	ComposeServiceStarted   = "service_started"
+	ComposeServiceHealthy   = "service_healthy"
+	ComposeServiceCompleted = "service_completed_successfully"
+)
+
+// ComposeExportOptions controls how specs are exported to a compose file
+type ComposeExportOptions struct {
+	// DependsOn adds dependencies the containers don't record, keyed by service name; each entry
+	// is a service name, optionally followed by ":" and a condition, e.g. "db:service_healthy"
+	DependsOn map[string][]string
+	// EnvFiles are the env files each container was started with, keyed by container name, e.g.
+	// from ComposeEnvFiles; the env vars they hold are exported into env files again
+	EnvFiles map[string][]EnvFile
+	// InlineEnv keeps every env var in the services' environment, for a compose file written
+	// without a directory to hold env files
+	InlineEnv bool
+}
+
+// composeExportFile is the compose file written by ExportCompose
+type composeExportFile struct {
+	Services map[string]composeExportService `yaml:"services"`
+	Networks map[string]composeExternal      `yaml:"networks,omitempty"`
+	Volumes  map[string]composeExternal      `yaml:"volumes,omitempty"`
+}
+
+// composeExternal declares a network or volume that exists already, so compose uses it as it is
+// instead of creating one prefixed with the project name
+type composeExternal struct {
+	External bool `yaml:"external"`
+}
+
+// composeServiceNetwork is the long form of a service's network, for its aliases
+type composeServiceNetwork struct {
+	Aliases []string `yaml:"aliases,omitempty"`
+}
+
+// composeServiceVolume is the long form of a service's volume, for an anonymous volume with options,
+// which the short form would read as a bind mount
+type composeServiceVolume struct {
+	Type     string               `yaml:"type"`
+	Target   string               `yaml:"target"`
+	ReadOnly bool                 `yaml:"read_only,omitempty"`
+	Volume   *composeVolumeNoCopy `yaml:"volume,omitempty"`
+}
+
+// composeVolumeNoCopy is the volume section of a long-form volume
+type composeVolumeNoCopy struct {
+	NoCopy bool `yaml:"nocopy"`
+}
+
+// composeExportDeploy is the deploy section of a scaled service
+type composeExportDeploy struct {
+	Replicas int `yaml:"replicas"`
+}
+
+// composeExportService is a service written by ExportCompose
+type composeExportService struct {
+	Image         string            `yaml:"image"`
+	ContainerName string            `yaml:"container_name,omitempty"`
+	Entrypoint    []string          `yaml:"entrypoint,omitempty"`
+	Command       []string          `yaml:"command,omitempty"`
+	WorkingDir    string            `yaml:"working_dir,omitempty"`
+	User          string            `yaml:"user,omitempty"`
+	EnvFile       []string          `yaml:"env_file,omitempty"`
+	Environment   map[string]string `yaml:"environment,omitempty"`
+	Labels        map[string]string `yaml:"labels,omitempty"`
+	Ports         []string          `yaml:"ports,omitempty"`
+	// Volumes are short-form strings or composeServiceVolume
+	Volumes     []any    `yaml:"volumes,omitempty"`
+	VolumesFrom []string `yaml:"volumes_from,omitempty"`
+	Tmpfs       []string `yaml:"tmpfs,omitempty"`
+	NetworkMode string   `yaml:"network_mode,omitempty"`
+	// Networks is a list of names, or a map to their aliases when there are aliases
+	Networks       any                          `yaml:"networks,omitempty"`
+	Links          []string                     `yaml:"links,omitempty"`
+	ExternalLinks  []string                     `yaml:"external_links,omitempty"`
+	ExtraHosts     []string                     `yaml:"extra_hosts,omitempty"`
+	Devices        []string                     `yaml:"devices,omitempty"`
+	Restart        string                       `yaml:"restart,omitempty"`
+	Privileged     bool                         `yaml:"privileged,omitempty"`
+	CapAdd         []string                     `yaml:"cap_add,omitempty"`
+	SecurityOpt    []string                     `yaml:"security_opt,omitempty"`
+	Ulimits        map[string]any               `yaml:"ulimits,omitempty"`
+	MemLimit       string                       `yaml:"mem_limit,omitempty"`
+	CPUs           string                       `yaml:"cpus,omitempty"`
+	CPUShares      int64                        `yaml:"cpu_shares,omitempty"`
+	MemSwappiness  *int64                       `yaml:"mem_swappiness,omitempty"`
+	MemswapLimit   string                       `yaml:"memswap_limit,omitempty"`
+	MemReservation string                       `yaml:"mem_reservation,omitempty"`
+	CPUQuota       int64                        `yaml:"cpu_quota,omitempty"`
+	CPUPeriod      int64                        `yaml:"cpu_period,omitempty"`
+	Healthcheck    *composeExportHealthcheck    `yaml:"healthcheck,omitempty"`
+	Deploy         *composeExportDeploy         `yaml:"deploy,omitempty"`
+	DependsOn      map[string]composeDependency `yaml:"depends_on,omitempty"`
+}
+
+// composeExportHealthcheck is a healthcheck block; durations are compose duration strings
+type composeExportHealthcheck struct {
+	Test          []string `yaml:"test,omitempty"`
+	Interval      string   `yaml:"interval,omitempty"`
+	Timeout       string   `yaml:"timeout,omitempty"`
+	StartPeriod   string   `yaml:"start_period,omitempty"`
+	StartInterval string   `yaml:"start_interval,omitempty"`
+	Retries       int      `yaml:"retries,omitempty"`
+	Disable       bool     `yaml:"disable,omitempty"`
+}
+
+// composeDependency is the long form of a depends_on entry
+type composeDependency struct {
+	Condition string `yaml:"condition"`
+	Restart   bool   `yaml:"restart,omitempty"`
+}
+
+// composeLiteral escapes a value so compose reads it back as written: compose expands $VAR when it
+// loads the file, and $$ is its literal $
+func composeLiteral(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
+
+// composeLiterals escapes every value of a list with composeLiteral, keeping nil as nil
+func composeLiterals(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = composeLiteral(value)
+	}
+	return escaped
+}
+
+// composeHealthcheckBlock converts a healthcheck into its compose form; the test is escaped so its
+// variables expand in the container's shell, not from the environment compose runs in
+func composeHealthcheckBlock(h *Healthcheck) *composeExportHealthcheck {
+	if h == nil {
+		return nil
+	}
+	if h.Disabled() {
+		return &composeExportHealthcheck{Disable: true}
+	}
+	block := &composeExportHealthcheck{Test: composeLiterals(h.Test), Retries: h.Retries}
+	for _, field := range []struct {
+		value Duration
+		dest  *string
+	}{
+		{h.Interval, &block.Interval},
+		{h.Timeout, &block.Timeout},
+		{h.StartPeriod, &block.StartPeriod},
+		{h.StartInterval, &block.StartInterval},
+	} {
+		if field.value != 0 {
+			*field.dest = field.value.String()
+		}
+	}
+	return block
+}
+
+// ComposeServiceName returns the service name a spec is exported under: its compose service when
+// it was started by compose, otherwise the container name
+func ComposeServiceName(spec *ContainerSpec) string {
+	if service := spec.Labels[ComposeServiceLabel]; service != "" {
+		return service
+	}
+	return spec.Name
+}
+
+// composeServiceNames assigns every spec a unique service name, falling back to the container name
+// when two compose projects use the same service name; specs without a name, or with a name
+// another spec has as well, can't be told apart and are an error
+func composeServiceNames(specs []*ContainerSpec) (map[string]string, error) {
+	count := make(map[string]int)
+	for _, spec := range specs {
+		count[ComposeServiceName(spec)]++
+	}
+	names := make(map[string]string, len(specs))
+	services := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		if spec.Name == "" {
+			return nil, fmt.Errorf("a spec of image '%s' has no name to export it as a service under", spec.Image)
+		}
+		if _, ok := names[spec.Name]; ok {
+			return nil, fmt.Errorf("more than one spec is named '%s'", spec.Name)
+		}
+		service := ComposeServiceName(spec)
+		if count[service] > 1 {
+			service = spec.Name
+		}
+		if other, ok := services[service]; ok {
+			return nil, fmt.Errorf("containers '%s' and '%s' would both be exported as service '%s'", other, spec.Name, service)
+		}
+		names[spec.Name] = service
+		services[service] = spec.Name
+	}
+	return names, nil
+}
+
+// composeDependsOnConditions returns the conditions recorded in the compose depends_on label, keyed by service
+func composeDependsOnConditions(spec *ContainerSpec) map[string]composeDependency {
+	conditions := make(map[string]composeDependency)
+	for _, entry := range strings.Split(spec.Labels[ComposeDependsOnLabel], ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) < 2 || parts[0] == "" {
+			continue
+		}
+		conditions[parts[0]] = composeDependency{Condition: parts[1], Restart: len(parts) > 2 && parts[2] == "true"}
+	}
+	return conditions
+}
+
+// dependencyCondition is the condition to wait for a dependency with when none is recorded:
+// service_healthy when its healthcheck is known, otherwise service_started
+func dependencyCondition(dep *ContainerSpec) string {
+	if dep.Healthcheck != nil && !dep.Healthcheck.Disabled() {
+		return ComposeServiceHealthy
+	}
+	return ComposeServiceStarted
+}
+
+// validComposeCondition reports whether compose knows the depends_on condition
+func validComposeCondition(condition string) bool {
+	return condition == ComposeServiceStarted || condition == ComposeServiceHealthy || condition == ComposeServiceCompleted
+}
+
+// composeUlimits converts "name=soft[:hard]" ulimits into the ulimits of a service: a number when
+// both limits are the same, otherwise soft and hard
+func composeUlimits(ulimits []string) map[string]any {
+	if len(ulimits) == 0 {
+		return nil
+	}
+	result := make(map[string]any, len(ulimits))
+	for _, ulimit := range ulimits {
+		name, limits, _ := strings.Cut(ulimit, "=")
+		softText, hardText, found := strings.Cut(limits, ":")
+		soft, err := strconv.ParseInt(softText, 10, 64)
+		if err != nil {
+			continue
+		}
+		hard := soft
+		if found {
+			if hard, err = strconv.ParseInt(hardText, 10, 64); err != nil {
+				continue
+			}
+		}
+		if soft == hard {
+			result[name] = soft
+		} else {
+			result[name] = composeUlimit{Soft: soft, Hard: hard}
+		}
+	}
+	return result
+}
+
+// composeServiceNetworking sets the network mode or networks of a service, referring to exported
+// containers by service name, and declares the user networks it joins as external in the file
+// The default bridge network is left out, so the service joins the project's default network
+func composeServiceNetworking(svc *composeExportService, spec *ContainerSpec, names map[string]string, file *composeExportFile) {
+	if spec.NetworkMode != "" {
+		svc.NetworkMode = spec.NetworkMode
+		if target, ok := strings.CutPrefix(spec.NetworkMode, "container:"); ok && names[target] != "" {
+			svc.NetworkMode = "service:" + names[target]
+		}
+		return
+	}
+	if len(spec.Networks) == 1 && (spec.Networks[0] == "host" || spec.Networks[0] == "none") {
+		svc.NetworkMode = spec.Networks[0]
+		return
+	}
+
+	var networks []string
+	for _, network := range spec.Networks {
+		if hasUserNetwork([]string{network}) {
+			networks = append(networks, network)
+			file.Networks[network] = composeExternal{External: true}
+		}
+	}
+	if len(networks) == 0 {
+		return
+	}
+	if len(spec.NetworkAliases) == 0 {
+		svc.Networks = networks
+		return
+	}
+	// docker run gives the aliases on the first network
+	attached := make(map[string]composeServiceNetwork, len(networks))
+	for i, network := range networks {
+		if i == 0 {
+			attached[network] = composeServiceNetwork{Aliases: spec.NetworkAliases}
+		} else {
+			attached[network] = composeServiceNetwork{}
+		}
+	}
+	svc.Networks = attached
+}
+
+// composeServiceVolumes sets the volumes and volumes_from of a service, referring to exported
+// containers by service name, and declares the named volumes it mounts as external in the file
+func composeServiceVolumes(svc *composeExportService, spec *ContainerSpec, names map[string]string, file *composeExportFile) {
+	for _, m := range spec.Volumes {
+		if !m.anonymousWithOptions() {
+			svc.Volumes = append(svc.Volumes, m.String())
+			continue
+		}
+		volume := composeServiceVolume{Type: MountVolume, Target: m.Target, ReadOnly: m.ReadOnly}
+		for _, option := range m.Options {
+			if option == "nocopy" {
+				volume.Volume = &composeVolumeNoCopy{NoCopy: true}
+			}
+		}
+		svc.Volumes = append(svc.Volumes, volume)
+	}
+	for _, volume := range spec.NamedVolumes() {
+		file.Volumes[volume] = composeExternal{External: true}
+	}
+	for _, from := range spec.VolumesFrom {
+		container, mode, found := strings.Cut(from, ":")
+		entry := "container:" + container
+		if service := names[container]; service != "" {
+			entry = service
+		}
+		if found {
+			entry += ":" + mode
+		}
+		svc.VolumesFrom = append(svc.VolumesFrom, entry)
+	}
+}
+
+// ExportCompose renders the specs as the services of one compose file, with healthchecks as
+// healthcheck blocks and the order the containers depend on each other as depends_on entries, and
+// returns the env files the services reference
+// Services keep the containers' ports, mounts, networks, restart policy, devices, privileges and
+// limits; the named volumes and user networks they use are declared external, so compose uses the
+// existing ones with their data instead of creating new ones
+// Dependencies come from links, volumes-from, container network modes and compose depends_on
+// labels, whose conditions are kept, plus opts.DependsOn; other dependencies wait for
+// service_healthy when the dependency has a healthcheck
+// Only env vars set over the image's are exported, grouped into env files by GroupEnv
+// Env vars, labels, entrypoint, command and healthcheck test have their $ escaped, so compose
+// doesn't expand them from the environment it runs in
+func ExportCompose(specs []*ContainerSpec, opts ComposeExportOptions) ([]byte, []EnvFile, error) {
+	names, err := composeServiceNames(specs)
+	if err != nil {
+		return nil, nil, err
+	}
+	byService := make(map[string]*ContainerSpec, len(specs))
+	for _, spec := range specs {
+		byService[names[spec.Name]] = spec
+	}
+	for service := range opts.DependsOn {
+		if byService[service] == nil {
+			return nil, nil, fmt.Errorf("depends-on: no exported service '%s'", service)
+		}
+	}
+
+	file := composeExportFile{
+		Services: make(map[string]composeExportService, len(specs)),
+		Networks: make(map[string]composeExternal),
+		Volumes:  make(map[string]composeExternal),
+	}
+	graph := DependencyGraph(specs)
+	envFiles := make(map[string][]EnvFile)
+	inlineEnv := make(map[string][]string)
+	for _, spec := range specs {
+		service := names[spec.Name]
+		if opts.InlineEnv {
+			inlineEnv[service] = spec.EnvOverrides()
+			continue
+		}
+		envFiles[service], inlineEnv[service] = GroupEnv(spec, service, opts.EnvFiles[spec.Name])
+	}
+	envFiles = nameEnvFiles(envFiles)
+	var written []EnvFile
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		service := names[spec.Name]
+		svc := composeExportService{
+			Image:       spec.Image,
+			WorkingDir:  spec.WorkingDir,
+			User:        spec.User,
+			Ports:       spec.PortStrings(),
+			Tmpfs:       spec.Tmpfs,
+			ExtraHosts:  spec.ExtraHosts,
+			Devices:     spec.Devices,
+			Restart:     spec.Restart,
+			Privileged:  spec.Privileged,
+			CapAdd:      spec.CapAdd,
+			Ulimits:     composeUlimits(spec.Ulimits),
+			CPUShares:   spec.CPUShares,
+			Healthcheck: composeHealthcheckBlock(spec.Healthcheck),
+		}
+		// The image applies its own entrypoint and command
+		if !spec.EntryPointInherited() {
+			svc.Entrypoint = composeLiterals(spec.EntryPoint)
+		}
+		if !spec.CommandInherited() {
+			svc.Command = composeLiterals(spec.Command)
+		}
+		// Memory and CPUs are rendered the way docker run takes them, which compose accepts as well
+		svc.MemSwappiness = spec.MemorySwappiness
+		if spec.Memory > 0 {
+			svc.MemLimit = spec.Memory.String()
+		}
+		if spec.NanoCPUs > 0 {
+			svc.CPUs = spec.NanoCPUs.String()
+		}
+		if spec.MemorySwap != 0 && spec.Memory > 0 {
+			svc.MemswapLimit = spec.MemorySwap.String()
+		}
+		if spec.NanoCPUs == 0 {
+			svc.CPUQuota, svc.CPUPeriod = spec.CPUQuota, spec.CPUPeriod
+		}
+		if spec.MemoryReservation > 0 {
+			svc.MemReservation = spec.MemoryReservation.String()
+		}
+		if spec.AppArmorProfile != "" {
+			svc.SecurityOpt = []string{"apparmor=" + spec.AppArmorProfile}
+		}
+		if spec.Replicas > 1 {
+			svc.Deploy = &composeExportDeploy{Replicas: spec.Replicas}
+		} else if spec.Labels[ComposeServiceLabel] == "" {
+			svc.ContainerName = spec.Name
+		}
+		composeServiceNetworking(&svc, spec, names, &file)
+		composeServiceVolumes(&svc, spec, names, &file)
+		for _, link := range spec.Links {
+			container, alias, _ := strings.Cut(link, ":")
+			if service := names[container]; service != "" {
+				svc.Links = append(svc.Links, service+":"+alias)
+			} else {
+				svc.ExternalLinks = append(svc.ExternalLinks, link)
+			}
+		}
+		for _, envFile := range envFiles[service] {
+			svc.EnvFile = append(svc.EnvFile, envFile.Name)
+			if !seen[envFile.Name] {
+				seen[envFile.Name] = true
+				written = append(written, envFile)
+			}
+		}
+		if env := inlineEnv[service]; len(env) > 0 {
+			svc.Environment = Env(env).Map()
+			for key, value := range svc.Environment {
+				svc.Environment[key] = composeLiteral(value)
+			}
+		}
+		for key, value := range spec.Labels {
+			if strings.HasPrefix(key, "com.docker.compose.") {
+				continue
+			}
+			if svc.Labels == nil {
+				svc.Labels = make(map[string]string)
+			}
+			svc.Labels[key] = composeLiteral(value)
+		}
+
+		recorded := composeDependsOnConditions(spec)
+		depends := make(map[string]composeDependency)
+		for _, name := range graph[spec.Name] {
+			dep := byService[names[name]]
+			entry, ok := recorded[ComposeServiceName(dep)]
+			if !ok || !validComposeCondition(entry.Condition) {
+				entry = composeDependency{Condition: dependencyCondition(dep)}
+			}
+			depends[names[name]] = entry
+		}
+		for _, hint := range opts.DependsOn[service] {
+			target, condition, found := strings.Cut(hint, ":")
+			dep := byService[target]
+			if dep == nil || target == service {
+				return nil, nil, fmt.Errorf("depends-on: %s can't depend on '%s', which is not another exported service", service, target)
+			}
+			if !found {
+				condition = dependencyCondition(dep)
+			} else if !validComposeCondition(condition) {
+				return nil, nil, fmt.Errorf("depends-on: invalid condition '%s' for %s, expected %s, %s or %s",
+					condition, service, ComposeServiceStarted, ComposeServiceHealthy, ComposeServiceCompleted)
+			}
+			depends[target] = composeDependency{Condition: condition}
+		}
+		// A recorded service_healthy may rely on the image's healthcheck, but not on a disabled one
+		for target, entry := range depends {
+			if entry.Condition == ComposeServiceHealthy && byService[target].Healthcheck.Disabled() {
+				entry.Condition = ComposeServiceStarted
+				depends[target] = entry
+			}
+		}
+		if len(depends) > 0 {
+			svc.DependsOn = depends
+		}
+		file.Services[service] = svc
+	}
+
+	if err := checkComposeCycles(file.Services); err != nil {
+		return nil, nil, err
+	}
+	data, err := encodeYAML(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal compose file: %w", err)
+	}
+	return data, written, nil
+}
+
+// checkComposeCycles rejects depends_on entries that wait on each other, which compose refuses to start
+func checkComposeCycles(services map[string]composeExportService) error {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	state := make(map[string]int)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			for len(path) > 0 && path[0] != name {
+				path = path[1:]
+			}
+			return fmt.Errorf("dependency cycle between services: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		deps := make([]string, 0, len(services[name].DependsOn))
+		for dep := range services[name].DependsOn {
+			deps = append(deps, dep)
+		}
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package containerconfig_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+func TestExportCompose(t *testing.T) {
+	data, warnings, err := containerconfig.ExportCompose([]*containerconfig.ContainerSpec{exportTestSpec()}, containerconfig.ComposeExportOptions{InlineEnv: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	want := `services:
+  web:
+    image: nginx:1.27
+    container_name: web
+    command:
+      - nginx
+      - -g
+      - daemon off;
+    environment:
+      MODE: prod
+      PASSWORD_FILE: /run/secrets/pw
+    labels:
+      team: web
+    ports:
+      - 8080:80
+      - 53:53/udp
+    volumes:
+      - html:/usr/share/nginx/html:ro
+      - /srv/conf:/etc/nginx/conf.d
+    networks:
+      - frontend
+    restart: unless-stopped
+    mem_limit: 512m
+    cpus: "1.5"
+networks:
+  frontend:
+    external: true
+volumes:
+  html:
+    external: true
+`
+	if string(data) != want {
+		t.Errorf("got\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestExportComposeServiceNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []*containerconfig.ContainerSpec
+		wantErr string
+	}{
+		{
+			name:    "empty name",
+			specs:   []*containerconfig.ContainerSpec{{Image: "nginx"}},
+			wantErr: "no name",
+		},
+		{
+			name:    "same name",
+			specs:   []*containerconfig.ContainerSpec{{Name: "web", Image: "nginx"}, {Name: "web", Image: "httpd"}},
+			wantErr: "more than one spec is named 'web'",
+		},
+		{
+			// Two projects' api services fall back to their container names, one of which is
+			// the service name of a third container
+			name: "fallback collides with a service",
+			specs: []*containerconfig.ContainerSpec{
+				{Name: "api", Image: "a", Labels: map[string]string{containerconfig.ComposeServiceLabel: "svc"}},
+				{Name: "b", Image: "b", Labels: map[string]string{containerconfig.ComposeServiceLabel: "api"}},
+				{Name: "c", Image: "c", Labels: map[string]string{containerconfig.ComposeServiceLabel: "svc"}},
+			},
+			wantErr: "containers 'api' and 'b' would both be exported as service 'api'",
+		},
+		{
+			name: "service name of one is the container name of another",
+			specs: []*containerconfig.ContainerSpec{
+				{Name: "app-web-1", Image: "a", Labels: map[string]string{containerconfig.ComposeServiceLabel: "web"}},
+				{Name: "web", Image: "b"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := containerconfig.ExportCompose(tt.specs, containerconfig.ComposeExportOptions{InlineEnv: true})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExportComposeAnonymousVolumes(t *testing.T) {
+	spec := &containerconfig.ContainerSpec{Name: "db", Image: "postgres", Volumes: containerconfig.ParseMounts([]string{"/cache", "/data:ro", "pgdata:/backup"})}
+	data, _, err := containerconfig.ExportCompose([]*containerconfig.ContainerSpec{spec}, containerconfig.ComposeExportOptions{InlineEnv: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `    volumes:
+      - /cache
+      - type: volume
+        target: /data
+        read_only: true
+      - pgdata:/backup
+`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("got\n%s\nwant volumes\n%s", data, want)
+	}
+}
+
+func TestExportComposeHealthcheck(t *testing.T) {
+	spec := &containerconfig.ContainerSpec{
+		Name:  "db",
+		Image: "postgres:16",
+		Healthcheck: &containerconfig.Healthcheck{
+			Test:     []string{containerconfig.HealthCmdShell, "pg_isready -U $POSTGRES_USER -d ${POSTGRES_DB}"},
+			Interval: containerconfig.Duration(10 * time.Second),
+			Retries:  5,
+		},
+	}
+	data, _, err := containerconfig.ExportCompose([]*containerconfig.ContainerSpec{spec}, containerconfig.ComposeExportOptions{InlineEnv: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// $$ is compose's literal $, so the variables are left for the container's shell
+	want := `    healthcheck:
+      test:
+        - CMD-SHELL
+        - pg_isready -U $$POSTGRES_USER -d $${POSTGRES_DB}
+      interval: 10s
+      retries: 5
+`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("got\n%s\nwant healthcheck\n%s", data, want)
+	}
+}
+
+// dollarTestSpec is a spec whose values hold $ that has to reach the container unexpanded
+func dollarTestSpec() *containerconfig.ContainerSpec {
+	return &containerconfig.ContainerSpec{
+		Name:       "app",
+		Image:      "alpine",
+		Env:        containerconfig.Env{"PASSWORD=a$b", "PRICE=${COST:-5}$"},
+		Labels:     map[string]string{"template": "$HOME/app"},
+		EntryPoint: []string{"/bin/sh", "-c"},
+		Command:    []string{"echo $HOME $$"},
+	}
+}
+
+func TestExportComposeKeepsDollars(t *testing.T) {
+	t.Setenv("HOME", "/host-home")
+	t.Setenv("b", "host-b")
+	spec := dollarTestSpec()
+	data, _, err := containerconfig.ExportCompose([]*containerconfig.ContainerSpec{spec}, containerconfig.ComposeExportOptions{InlineEnv: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reading the file back expands variables the way compose does
+	got, err := containerconfig.ParseComposeService(data, "app", containerconfig.ComposeOptions{Project: "app", Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Env, spec.Env) || !reflect.DeepEqual(got.Labels, spec.Labels) ||
+		!reflect.DeepEqual(got.EntryPoint, spec.EntryPoint) || !reflect.DeepEqual(got.Command, spec.Command) {
+		t.Errorf("read back env %q, labels %v, entrypoint %q, command %q from\n%s", got.Env, got.Labels, got.EntryPoint, got.Command, data)
+	}
+}
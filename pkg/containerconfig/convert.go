@@ -0,0 +1,70 @@
+package containerconfig
+
+import "fmt"
+
+// Warning describes a lossy or surprising decision made while converting a container configuration
+type Warning struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// String formats the warning as a single line
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Field, w.Message)
+}
+
+// GenerationWarnings reports where the command produced by GenerateRunCommand
+// won't behave exactly like the original container
+func GenerationWarnings(spec *ContainerSpec, opts *RunOptions) []Warning {
+	var warnings []Warning
+	add := func(field, format string, args ...interface{}) {
+		warnings = append(warnings, Warning{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if spec.NetworkMode == "" && len(spec.Networks) > 1 {
+		add("networks", "docker run attaches only the first of %d networks on Docker Engine before 25.0; connect the others with docker network connect", len(spec.Networks))
+	}
+	if spec.NetworkMode != "" && len(spec.Ports) > 0 {
+		add("ports", "ports can't be published with network mode '%s' and will be rejected", spec.NetworkMode)
+	}
+	if entryPoint := spec.EntryPointArgs(); len(entryPoint) > 1 {
+		add("entryPoint", "entrypoint arguments %v are passed ahead of the command, since --entrypoint only takes the executable", entryPoint[1:])
+	}
+	if DetectForm(spec.EntryPointArgs()) == FormShell && len(spec.Command) > 0 {
+		add("command", "the shell-form entrypoint ignores the command %v", spec.Command)
+	}
+	if h := spec.Healthcheck; h != nil && len(h.Test) > 1 && h.Test[0] == HealthCmd {
+		add("healthcheck", "--health-cmd always runs through the shell, so the exec-form test %v becomes a shell command", h.Test[1:])
+	}
+	for _, volume := range LoosenedMounts(spec, opts) {
+		add("volumes", "read-only mount %s is made writable; changes reach the source", volume)
+	}
+	if len(spec.Links) > 0 {
+		add("links", "legacy links only work on the default bridge network and require the linked containers to be running")
+	}
+	if opts != nil && opts.LabelFilter != nil {
+		if dropped := len(spec.Labels) - len(opts.LabelFilter.Apply(spec.Labels)); dropped > 0 {
+			add("labels", "%d label(s) dropped by the label filter", dropped)
+		}
+	}
+	if spec.Replicas > 1 {
+		add("replicas", "the command creates one of the %d replicas; run it once per replica with a distinct --name", spec.Replicas)
+	}
+	if spec.Image == "" {
+		add("image", "spec has no image; the generated command is incomplete")
+	}
+
+	return warnings
+}
+
+// FromInspectJSONToRunArgs parses docker inspect JSON and generates docker run arguments in one call
+// It returns the arguments (without "docker" and "run"), warnings about lossy conversions and the
+// intermediate spec, so callers can adjust and regenerate without parsing again
+func FromInspectJSONToRunArgs(jsonData []byte, opts *RunOptions) ([]string, []Warning, *ContainerSpec, error) {
+	spec, warnings, err := ParseInspectJSONWithWarnings(string(jsonData), nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return GenerateRunCommand(spec, opts), append(warnings, GenerationWarnings(spec, opts)...), spec, nil
+}
@@ -0,0 +1,102 @@
+package containerconfig
+
+import "sort"
+
+// Fidelity levels of a coverage entry
+const (
+	// FidelityExact settings are carried from inspect to the spec and back into docker run as they are
+	FidelityExact = "exact"
+	// FidelityPartial settings are carried over with the simplifications in the note; the parser
+	// warns whenever a container uses the part that is lost
+	FidelityPartial = "partial"
+	// FidelityInformational settings are recorded in the spec but never generated
+	FidelityInformational = "informational"
+	// FidelityDropped settings are not modeled; a recreated container gets docker's default
+	FidelityDropped = "dropped"
+)
+
+// CoverageEntry says how faithfully one docker inspect field survives extract and generate
+type CoverageEntry struct {
+	// Field is the docker inspect field, e.g. ".HostConfig.Memory"
+	Field string `json:"field"`
+	// Flag is the docker run flag that sets it; empty for fields no flag sets
+	Flag     string `json:"flag,omitempty"`
+	Fidelity string `json:"fidelity"`
+	Note     string `json:"note,omitempty"`
+}
+
+// partialCoverage notes what the generated flags lose, keyed by flag
+var partialCoverage = map[string]string{
+	"--rm":           "set by RunOptions.Remove; AutoRemove is not read from the container",
+	"--mount":        "only for volumes with a driver other than local; driver options are dropped",
+	"-v":             "bind mounts and volumes only; propagation options are dropped",
+	"-p":             "ports that are only exposed are left to the image",
+	"--network":      "service: network modes are dropped; they only exist under compose",
+	"--device":       "cgroup permissions are dropped; GPU requests become CDI device names",
+	"--restart":      "the maximum retry count is dropped",
+	"--security-opt": "only the AppArmor profile; seccomp, SELinux label and no-new-privileges options are dropped",
+	"--health-cmd":   "exec-form tests are quoted into a shell command, since --health-cmd always runs through the shell",
+}
+
+// informationalCoverage lists the fields the spec records without generating them
+var informationalCoverage = []CoverageEntry{
+	{Field: ".Image", Note: "recorded as imageId"},
+	{Field: ".Platform", Note: "recorded as platform"},
+	{Field: ".State", Note: "recorded in the runtime snapshot of extract --stats"},
+}
+
+// droppedCoverage lists common settings that are not modeled
+var droppedCoverage = []CoverageEntry{
+	{Field: ".Config.Hostname", Flag: "--hostname"},
+	{Field: ".Config.Domainname", Flag: "--domainname"},
+	{Field: ".Config.Tty", Flag: "-t"},
+	{Field: ".Config.OpenStdin", Flag: "-i"},
+	{Field: ".Config.ExposedPorts", Flag: "--expose", Note: "ports the image exposes are exposed again by the image"},
+	{Field: ".Config.StopSignal", Flag: "--stop-signal"},
+	{Field: ".Config.StopTimeout", Flag: "--stop-timeout"},
+	{Field: ".HostConfig.CapDrop", Flag: "--cap-drop"},
+	{Field: ".HostConfig.SecurityOpt", Flag: "--security-opt", Note: "apart from the AppArmor profile, read from .AppArmorProfile"},
+	{Field: ".HostConfig.ReadonlyRootfs", Flag: "--read-only"},
+	{Field: ".HostConfig.Init", Flag: "--init"},
+	{Field: ".HostConfig.PidMode", Flag: "--pid"},
+	{Field: ".HostConfig.IpcMode", Flag: "--ipc"},
+	{Field: ".HostConfig.UsernsMode", Flag: "--userns"},
+	{Field: ".HostConfig.GroupAdd", Flag: "--group-add"},
+	{Field: ".HostConfig.Dns", Flag: "--dns"},
+	{Field: ".HostConfig.ShmSize", Flag: "--shm-size"},
+	{Field: ".HostConfig.Sysctls", Flag: "--sysctl"},
+	{Field: ".HostConfig.LogConfig", Flag: "--log-driver, --log-opt"},
+	{Field: ".HostConfig.Runtime", Flag: "--runtime"},
+	{Field: ".HostConfig.PidsLimit", Flag: "--pids-limit"},
+	{Field: ".HostConfig.CpusetCpus", Flag: "--cpuset-cpus"},
+	{Field: ".HostConfig.OomKillDisable", Flag: "--oom-kill-disable"},
+	{Field: ".NetworkSettings.MacAddress", Flag: "--mac-address"},
+}
+
+// Coverage returns how faithfully each docker inspect field survives a round trip through a spec,
+// sorted by field; generated flags come from the generator itself, so the report can't fall behind it
+func Coverage() []CoverageEntry {
+	var entries []CoverageEntry
+	for flag, source := range runFlagSources {
+		entry := CoverageEntry{Field: source, Flag: flag, Fidelity: FidelityExact}
+		if note, ok := partialCoverage[flag]; ok {
+			entry.Fidelity, entry.Note = FidelityPartial, note
+		}
+		entries = append(entries, entry)
+	}
+	for _, entry := range informationalCoverage {
+		entry.Fidelity = FidelityInformational
+		entries = append(entries, entry)
+	}
+	for _, entry := range droppedCoverage {
+		entry.Fidelity = FidelityDropped
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Field != entries[j].Field {
+			return entries[i].Field < entries[j].Field
+		}
+		return entries[i].Flag < entries[j].Flag
+	})
+	return entries
+}
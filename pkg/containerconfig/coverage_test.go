@@ -0,0 +1,31 @@
+package containerconfig_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+func TestCoverageMatchesGenerator(t *testing.T) {
+	flags := make(map[string]bool)
+	for _, entry := range containerconfig.Coverage() {
+		if entry.Fidelity == containerconfig.FidelityExact || entry.Fidelity == containerconfig.FidelityPartial {
+			flags[entry.Flag] = true
+		}
+	}
+	spec := &containerconfig.ContainerSpec{
+		Image:       "busybox",
+		Env:         []string{"A=1"},
+		Volumes:     containerconfig.ParseMounts([]string{"data:/data"}),
+		Ports:       []containerconfig.PortMapping{{HostPort: 8080, ContainerPort: 80}},
+		Memory:      64 << 20,
+		NanoCPUs:    5e8,
+		Healthcheck: &containerconfig.Healthcheck{Test: []string{containerconfig.HealthCmdShell, "true"}},
+	}
+	for _, arg := range containerconfig.GenerateRunCommand(spec, &containerconfig.RunOptions{Name: "x"}) {
+		if strings.HasPrefix(arg, "-") && !flags[arg] {
+			t.Errorf("generated flag %s is missing from Coverage", arg)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package containerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// defaultCPUShares is the CPU weight of a container without --cpu-shares
+const defaultCPUShares = 1024
+
+// DaemonInfo is the part of docker info that decides which container settings a daemon can apply
+type DaemonInfo struct {
+	// Name is the hostname of the docker host
+	Name          string `json:"Name"`
+	OSType        string `json:"OSType"`
+	Architecture  string `json:"Architecture"`
+	Driver        string `json:"Driver"`
+	CgroupVersion string `json:"CgroupVersion"`
+	CgroupDriver  string `json:"CgroupDriver"`
+	MemoryLimit   bool   `json:"MemoryLimit"`
+	SwapLimit     bool   `json:"SwapLimit"`
+	CPUCfsQuota   bool   `json:"CpuCfsQuota"`
+	CPUShares     bool   `json:"CPUShares"`
+	KernelMemory  bool   `json:"KernelMemory"`
+	// SecurityOptions are "name=<feature>[,key=value...]" entries, e.g. "name=seccomp,profile=builtin"
+	SecurityOptions []string `json:"SecurityOptions"`
+}
+
+// ParseDaemonInfo parses the output of docker info --format '{{json .}}'
+func ParseDaemonInfo(data []byte) (*DaemonInfo, error) {
+	var info DaemonInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse docker info: %w", err)
+	}
+	return &info, nil
+}
+
+// hasSecurityOption reports whether the daemon lists the named security feature
+func (d *DaemonInfo) hasSecurityOption(name string) bool {
+	for _, option := range d.SecurityOptions {
+		if first, _, _ := strings.Cut(option, ","); first == "name="+name {
+			return true
+		}
+	}
+	return false
+}
+
+// Rootless reports whether the daemon runs as an unprivileged user
+func (d *DaemonInfo) Rootless() bool {
+	return d.hasSecurityOption("rootless")
+}
+
+// AppArmor reports whether the daemon confines containers with AppArmor
+func (d *DaemonInfo) AppArmor() bool {
+	return d.hasSecurityOption("apparmor")
+}
+
+// SELinux reports whether the daemon labels containers for SELinux
+func (d *DaemonInfo) SELinux() bool {
+	return d.hasSecurityOption("selinux")
+}
+
+// CgroupV2 reports whether the daemon puts containers in cgroup v2, the unified hierarchy
+func (d *DaemonInfo) CgroupV2() bool {
+	return d.CgroupVersion == "2"
+}
+
+// CPUWeight returns the cgroup v2 cpu.weight the runtime sets for CPU shares, 0 for the default
+func CPUWeight(shares int64) int64 {
+	if shares <= 0 {
+		return 0
+	}
+	if shares < 2 {
+		shares = 2
+	}
+	if shares > 262144 {
+		shares = 262144
+	}
+	return 1 + (shares-2)*9999/262142
+}
+
+// AdaptResources drops the limits of the spec the daemon's cgroup version can't apply, so the
+// generated command doesn't fail or get warned about by the daemon, and reports each one; on
+// cgroup v2 it also reports CPU shares whose cpu.weight loses the difference from the default
+func (d *DaemonInfo) AdaptResources(spec *ContainerSpec) []Warning {
+	var warnings []Warning
+	add := func(field, format string, args ...interface{}) {
+		warnings = append(warnings, Warning{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if d.CgroupV2() {
+		if spec.MemorySwappiness != nil {
+			add("memorySwappiness", "cgroup v2 has no per-container swappiness; --memory-swappiness %d is left out", *spec.MemorySwappiness)
+			spec.MemorySwappiness = nil
+		}
+		if spec.KernelMemory > 0 {
+			add("kernelMemory", "cgroup v2 accounts kernel memory in the memory limit; --kernel-memory %s is left out", spec.KernelMemory)
+			spec.KernelMemory = 0
+		}
+		if weight := CPUWeight(spec.CPUShares); spec.CPUShares > 0 && spec.CPUShares != defaultCPUShares && weight == CPUWeight(defaultCPUShares) {
+			add("cpuShares", "on cgroup v2 --cpu-shares %d becomes cpu.weight %d, the same as the default", spec.CPUShares, weight)
+		}
+		return warnings
+	}
+
+	if spec.KernelMemory > 0 && !d.KernelMemory {
+		add("kernelMemory", "the daemon doesn't support kernel memory limits; --kernel-memory %s is left out", spec.KernelMemory)
+		spec.KernelMemory = 0
+	}
+	return warnings
+}
+
+// dropConflictingLimits clears the limits docker refuses in combination with the others, a swap
+// limit without a memory limit and a CFS quota or period next to NanoCPUs, and reports each one
+func dropConflictingLimits(spec *ContainerSpec) []Warning {
+	var warnings []Warning
+	if spec.MemorySwap != 0 && spec.Memory == 0 {
+		warnings = append(warnings, Warning{Field: "memorySwap", Message: fmt.Sprintf("a swap limit needs a memory limit; swap limit %s is left out", spec.MemorySwap)})
+		spec.MemorySwap = 0
+	}
+	if spec.NanoCPUs > 0 && (spec.CPUQuota > 0 || spec.CPUPeriod > 0) {
+		warnings = append(warnings, Warning{Field: "cpuQuota", Message: fmt.Sprintf("a CFS quota and period conflict with cpus %s, which is kept", spec.NanoCPUs)})
+		spec.CPUQuota, spec.CPUPeriod = 0, 0
+	}
+	return warnings
+}
+
+// String summarizes the capabilities that Check looks at
+func (d *DaemonInfo) String() string {
+	features := []string{"storage driver " + d.Driver}
+	if d.CgroupVersion != "" {
+		features = append(features, "cgroup v"+d.CgroupVersion)
+	}
+	if d.Rootless() {
+		features = append(features, "rootless")
+	}
+	if d.SELinux() {
+		features = append(features, "SELinux")
+	}
+	return strings.Join(features, ", ")
+}
+
+// Check reports the settings of the spec that won't work, or won't work as written, on the daemon
+func (d *DaemonInfo) Check(spec *ContainerSpec) []Warning {
+	var warnings []Warning
+	add := func(field, format string, args ...interface{}) {
+		warnings = append(warnings, Warning{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	limited := spec.Memory > 0 || spec.NanoCPUs > 0 || spec.CPUShares > 0 || spec.CPUQuota > 0 ||
+		spec.MemorySwap != 0 || spec.MemoryReservation > 0
+	switch {
+	case limited && d.Rootless() && d.CgroupVersion == "1":
+		add("resources", "rootless daemons can't apply resource limits on cgroup v1; the limits are ignored")
+	case spec.Memory > 0 && !d.MemoryLimit:
+		add("memory", "the daemon's kernel doesn't support memory limits; --memory %s is ignored", spec.Memory)
+	case spec.MemorySwap != 0 && !d.SwapLimit:
+		add("memorySwap", "the host has no swap accounting; --memory-swap %s is ignored", spec.MemorySwap)
+	case spec.Memory > 0 && !d.SwapLimit:
+		add("memory", "the host has no swap accounting, so --memory %s limits memory only and the container may use as much swap again", spec.Memory)
+	}
+	if spec.NanoCPUs > 0 && !d.CPUCfsQuota && !(d.Rootless() && d.CgroupVersion == "1") {
+		add("nanoCpus", "the daemon's kernel doesn't support CFS quotas, which --cpus needs; the container will fail to start")
+	}
+	if spec.CPUQuota > 0 && !d.CPUCfsQuota && !(d.Rootless() && d.CgroupVersion == "1") {
+		add("cpuQuota", "the daemon's kernel doesn't support CFS quotas; --cpu-quota %d is ignored", spec.CPUQuota)
+	}
+	if spec.CPUShares > 0 && !d.CPUShares && !(d.Rootless() && d.CgroupVersion == "1") {
+		add("cpuShares", "the daemon's kernel doesn't support CPU shares; --cpu-shares %d is ignored", spec.CPUShares)
+	}
+
+	if d.Rootless() {
+		if spec.Privileged {
+			add("privileged", "a rootless daemon only grants the capabilities of its user namespace; host devices and kernel settings stay out of reach")
+		}
+		for _, port := range spec.HostPorts() {
+			if port < 1024 {
+				add("ports", "a rootless daemon can't publish host port %d below 1024 unless net.ipv4.ip_unprivileged_port_start allows it", port)
+			}
+		}
+		if len(spec.Devices) > 0 {
+			add("devices", "a rootless daemon can only pass through devices its user can open")
+		}
+	}
+
+	if d.SELinux() {
+		for _, bind := range spec.BindMounts() {
+			if !hasSELinuxLabel(bind) {
+				add("volumes", "bind mount of %s isn't relabeled; on this SELinux host the container can't read it without :z or :Z", bind.Source)
+			}
+		}
+	}
+
+	if d.Driver == "vfs" {
+		add("image", "the vfs storage driver copies the whole image for each container, so creating it is slow and uses a lot of disk")
+	}
+	return warnings
+}
@@ -0,0 +1,93 @@
+package containerconfig_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+func TestCPUWeight(t *testing.T) {
+	tests := []struct {
+		shares int64
+		want   int64
+	}{
+		{0, 0},
+		{-1, 0},
+		{1, 1},
+		{2, 1},
+		{512, 20},
+		{1000, 39},
+		{1024, 39},
+		{2048, 79},
+		{262144, 10000},
+		{1 << 20, 10000},
+	}
+	for _, tt := range tests {
+		if got := containerconfig.CPUWeight(tt.shares); got != tt.want {
+			t.Errorf("CPUWeight(%d) = %d, want %d", tt.shares, got, tt.want)
+		}
+	}
+}
+
+func TestAdaptResources(t *testing.T) {
+	swappiness := int64(60)
+	tests := []struct {
+		name   string
+		daemon containerconfig.DaemonInfo
+		spec   containerconfig.ContainerSpec
+		want   containerconfig.ContainerSpec
+		// fields are the fields of the warnings, in order
+		fields []string
+	}{
+		{
+			name:   "v2 drops swappiness and kernel memory",
+			daemon: containerconfig.DaemonInfo{CgroupVersion: "2", KernelMemory: true},
+			spec:   containerconfig.ContainerSpec{Memory: 512 << 20, MemorySwappiness: &swappiness, KernelMemory: 64 << 20, CPUShares: 512},
+			want:   containerconfig.ContainerSpec{Memory: 512 << 20, CPUShares: 512},
+			fields: []string{"memorySwappiness", "kernelMemory"},
+		},
+		{
+			name:   "v2 keeps shares that land on the default weight, with a warning",
+			daemon: containerconfig.DaemonInfo{CgroupVersion: "2"},
+			spec:   containerconfig.ContainerSpec{CPUShares: 1000},
+			want:   containerconfig.ContainerSpec{CPUShares: 1000},
+			fields: []string{"cpuShares"},
+		},
+		{
+			name:   "v2 doesn't warn about the default shares",
+			daemon: containerconfig.DaemonInfo{CgroupVersion: "2"},
+			spec:   containerconfig.ContainerSpec{CPUShares: 1024},
+			want:   containerconfig.ContainerSpec{CPUShares: 1024},
+		},
+		{
+			name:   "v1 changes nothing",
+			daemon: containerconfig.DaemonInfo{CgroupVersion: "1", KernelMemory: true},
+			spec:   containerconfig.ContainerSpec{MemorySwappiness: &swappiness, KernelMemory: 64 << 20, CPUShares: 1000},
+			want:   containerconfig.ContainerSpec{MemorySwappiness: &swappiness, KernelMemory: 64 << 20, CPUShares: 1000},
+		},
+		{
+			name:   "v1 without kernel memory support drops kernel memory",
+			daemon: containerconfig.DaemonInfo{CgroupVersion: "1"},
+			spec:   containerconfig.ContainerSpec{MemorySwappiness: &swappiness, KernelMemory: 64 << 20},
+			want:   containerconfig.ContainerSpec{MemorySwappiness: &swappiness},
+			fields: []string{"kernelMemory"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := tt.spec
+			warnings := tt.daemon.AdaptResources(&spec)
+			if !reflect.DeepEqual(spec, tt.want) {
+				t.Errorf("spec = %+v, want %+v", spec, tt.want)
+			}
+			var fields []string
+			for _, warning := range warnings {
+				fields = append(fields, warning.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("warnings = %v, want fields %v", warnings, tt.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package containerconfig
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Dependencies returns the containers the spec needs to be started after,
+// inferred from links, "container:" network mode, volumes-from and compose depends_on labels
+// Compose dependencies are returned as service names and resolved by OrderByDependencies
+func (s *ContainerSpec) Dependencies() []string {
+	var deps []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" && name != s.Name && !seen[name] {
+			seen[name] = true
+			deps = append(deps, name)
+		}
+	}
+
+	for _, link := range s.Links {
+		name, _, _ := strings.Cut(link, ":")
+		add(name)
+	}
+	if strings.HasPrefix(s.NetworkMode, "container:") {
+		add(strings.TrimPrefix(s.NetworkMode, "container:"))
+	}
+	for _, from := range s.VolumesFrom {
+		name, _, _ := strings.Cut(from, ":")
+		add(name)
+	}
+
+	// depends_on is stored as "service:condition:restart" entries separated by commas
+	for _, entry := range strings.Split(s.Labels[ComposeDependsOnLabel], ",") {
+		service, _, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		add(service)
+	}
+
+	return deps
+}
+
+// DependencyGraph maps every spec name to the names of the specs it depends on, once each,
+// keeping only dependencies that resolve to a spec in the given set
+func DependencyGraph(specs []*ContainerSpec) map[string][]string {
+	byName := make(map[string]*ContainerSpec)
+	for _, spec := range specs {
+		byName[spec.Name] = spec
+	}
+
+	// Compose services are referenced by service name within their project
+	byService := make(map[string]string)
+	for _, spec := range specs {
+		if service := spec.Labels[ComposeServiceLabel]; service != "" {
+			byService[spec.Labels[ComposeProjectLabel]+"/"+service] = spec.Name
+		}
+	}
+
+	graph := make(map[string][]string)
+	for _, spec := range specs {
+		graph[spec.Name] = nil
+		// A link and a depends_on entry may name the same container by container and service name
+		seen := make(map[string]bool)
+		for _, dep := range spec.Dependencies() {
+			name, ok := dep, byName[dep] != nil
+			if !ok {
+				name, ok = byService[spec.Labels[ComposeProjectLabel]+"/"+dep]
+			}
+			if ok && name != spec.Name && !seen[name] {
+				seen[name] = true
+				graph[spec.Name] = append(graph[spec.Name], name)
+			}
+		}
+	}
+	return graph
+}
+
+// OrderByDependencies sorts specs so that every container comes after the containers it depends on
+// The original order is kept wherever dependencies allow it; a cycle is reported as an error
+func OrderByDependencies(specs []*ContainerSpec) ([]*ContainerSpec, error) {
+	graph := DependencyGraph(specs)
+	placed := make(map[string]bool)
+	ordered := make([]*ContainerSpec, 0, len(specs))
+
+	for len(ordered) < len(specs) {
+		progress := false
+		for _, spec := range specs {
+			if placed[spec.Name] {
+				continue
+			}
+			ready := true
+			for _, dep := range graph[spec.Name] {
+				if !placed[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				placed[spec.Name] = true
+				ordered = append(ordered, spec)
+				progress = true
+			}
+		}
+		if !progress {
+			return nil, fmt.Errorf("dependency cycle detected: %s", strings.Join(findCycle(graph, placed), " -> "))
+		}
+	}
+	return ordered, nil
+}
+
+// findCycle returns one dependency cycle among the nodes that haven't been placed yet
+func findCycle(graph map[string][]string, placed map[string]bool) []string {
+	var names []string
+	for name := range graph {
+		if !placed[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// Every unplaced node has an unplaced dependency, so walking them must revisit a node
+	var path []string
+	index := make(map[string]int)
+	current := names[0]
+	for {
+		if i, ok := index[current]; ok {
+			return append(path[i:], current)
+		}
+		index[current] = len(path)
+		path = append(path, current)
+		for _, dep := range graph[current] {
+			if !placed[dep] {
+				current = dep
+				break
+			}
+		}
+	}
+}
+
+// DependencyGraphDOT renders the dependency graph of the specs in Graphviz DOT format
+func DependencyGraphDOT(specs []*ContainerSpec) string {
+	graph := DependencyGraph(specs)
+
+	var b strings.Builder
+	b.WriteString("digraph containers {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for _, spec := range specs {
+		fmt.Fprintf(&b, "  %q [label=\"%s\\n%s\"];\n", spec.Name, spec.Name, spec.Image)
+	}
+	for _, spec := range specs {
+		for _, dep := range graph[spec.Name] {
+			fmt.Fprintf(&b, "  %q -> %q;\n", spec.Name, dep)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// HostReference is an env var whose value points at another container by host name
+type HostReference struct {
+	EnvVar    string `json:"envVar"`
+	Host      string `json:"host"`
+	Container string `json:"container"`
+}
+
+// EnvHostReferences finds env vars whose values reference one of the known hosts, e.g. DB_HOST=postgres
+// or DATABASE_URL=postgres://user@postgres:5432/app; hosts maps host names (container names,
+// compose service names, aliases) to the container that answers to them
+func EnvHostReferences(env []string, hosts map[string]string) []HostReference {
+	var refs []HostReference
+	for _, entry := range env {
+		key, value, found := SplitEnv(entry)
+		if !found {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, token := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+			host := tokenHost(token)
+			container, ok := hosts[host]
+			if !ok || seen[host] {
+				continue
+			}
+			seen[host] = true
+			refs = append(refs, HostReference{EnvVar: key, Host: host, Container: container})
+		}
+	}
+	return refs
+}
+
+// tokenHost extracts the host part of a URL, "host:port" or bare host token, lowercased
+func tokenHost(token string) string {
+	if strings.Contains(token, "://") {
+		if u, err := url.Parse(token); err == nil {
+			return strings.ToLower(u.Hostname())
+		}
+		return ""
+	}
+	if at := strings.LastIndex(token, "@"); at >= 0 {
+		token = token[at+1:]
+	}
+	token, _, _ = strings.Cut(token, "/")
+	token, _, _ = strings.Cut(token, ":")
+	return strings.ToLower(token)
+}
@@ -0,0 +1,55 @@
+package containerconfig_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// composeSpec returns the spec of a container compose started for a service of project app
+func composeSpec(service string, labels map[string]string) *containerconfig.ContainerSpec {
+	all := map[string]string{containerconfig.ComposeProjectLabel: "app", containerconfig.ComposeServiceLabel: service}
+	for key, value := range labels {
+		all[key] = value
+	}
+	return &containerconfig.ContainerSpec{Name: "app-" + service + "-1", Image: service, Labels: all}
+}
+
+func TestDependencyGraph(t *testing.T) {
+	db := composeSpec("db", nil)
+	cache := composeSpec("cache", nil)
+	// The link names db by container name and depends_on by service name
+	api := composeSpec("api", map[string]string{containerconfig.ComposeDependsOnLabel: "db:service_healthy:false,cache:service_started:false"})
+	api.Links = []string{"app-db-1:database"}
+	api.VolumesFrom = []string{"app-db-1:ro"}
+	external := &containerconfig.ContainerSpec{Name: "worker", Image: "worker", Links: []string{"missing"}, NetworkMode: "container:app-api-1"}
+
+	got := containerconfig.DependencyGraph([]*containerconfig.ContainerSpec{db, cache, api, external})
+	want := map[string][]string{
+		"app-db-1":    nil,
+		"app-cache-1": nil,
+		"app-api-1":   {"app-db-1", "app-cache-1"},
+		"worker":      {"app-api-1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DependencyGraph = %v, want %v", got, want)
+	}
+}
+
+func TestOrderByDependencies(t *testing.T) {
+	api := &containerconfig.ContainerSpec{Name: "api", Image: "api", Links: []string{"db"}}
+	db := &containerconfig.ContainerSpec{Name: "db", Image: "db"}
+	ordered, err := containerconfig.OrderByDependencies([]*containerconfig.ContainerSpec{api, db})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ordered[0] != db || ordered[1] != api {
+		t.Errorf("got order %s, %s, want db, api", ordered[0].Name, ordered[1].Name)
+	}
+
+	db.Links = []string{"api"}
+	if _, err := containerconfig.OrderByDependencies([]*containerconfig.ContainerSpec{api, db}); err == nil {
+		t.Error("expected an error for the cycle between api and db")
+	}
+}
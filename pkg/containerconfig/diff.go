@@ -0,0 +1,192 @@
+package containerconfig
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Difference kinds reported by DiffSpecs
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// Difference describes one field that differs between an expected and an actual spec
+type Difference struct {
+	Field    string `json:"field"`
+	Kind     string `json:"kind"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	// RuntimeUpdatable is set for settings that docker update can change on a running container
+	RuntimeUpdatable bool `json:"runtimeUpdatable,omitempty"`
+}
+
+// String formats the difference as a single line
+func (d Difference) String() string {
+	var line string
+	switch d.Kind {
+	case DiffAdded:
+		line = fmt.Sprintf("+ %s: %s", d.Field, d.Actual)
+	case DiffRemoved:
+		line = fmt.Sprintf("- %s: %s", d.Field, d.Expected)
+	default:
+		line = fmt.Sprintf("~ %s: %s -> %s", d.Field, d.Expected, d.Actual)
+	}
+	if d.RuntimeUpdatable {
+		line += " (likely changed at runtime with docker update)"
+	}
+	return line
+}
+
+// DiffOptions controls how specs are compared
+type DiffOptions struct {
+	// OnlyExpectedKeys restricts env and label comparison to the keys present in the expected spec,
+	// ignoring values the image or the runtime adds on its own
+	OnlyExpectedKeys bool
+	// LabelFilter excludes matching labels from the comparison; nil compares all labels
+	LabelFilter *LabelFilter
+	// IgnoreImageEnv leaves env entries equal to an image default (from either spec's ImageEnv) out
+	// of the comparison, so changes on the image side don't show up as drift
+	IgnoreImageEnv bool
+}
+
+// DiffSpecs compares an expected spec (e.g. from a compose file) against an actual one (e.g. a live container)
+// Working directory, user, command, entrypoint, healthcheck and swappiness are only compared when
+// the expected spec sets them, since the actual side holds the image's defaults for them
+func DiffSpecs(expected, actual *ContainerSpec, opts DiffOptions) []Difference {
+	var diffs []Difference
+
+	scalar := func(field, want, got string) {
+		if want != got {
+			diffs = append(diffs, Difference{
+				Field:            field,
+				Kind:             DiffChanged,
+				Expected:         displayValue(want),
+				Actual:           displayValue(got),
+				RuntimeUpdatable: IsRuntimeUpdatable(field),
+			})
+		}
+	}
+	scalar("image", expected.Image, actual.Image)
+	scalar("restart", expected.Restart, actual.Restart)
+	scalar("networkMode", expected.NetworkMode, actual.NetworkMode)
+	scalar("privileged", strconv.FormatBool(expected.Privileged), strconv.FormatBool(actual.Privileged))
+	scalar("appArmorProfile", expected.AppArmorProfile, actual.AppArmorProfile)
+	scalar("memory", strconv.FormatInt(int64(expected.Memory), 10), strconv.FormatInt(int64(actual.Memory), 10))
+	scalar("cpus", expected.NanoCPUs.String(), actual.NanoCPUs.String())
+	scalar("cpuShares", strconv.FormatInt(expected.CPUShares, 10), strconv.FormatInt(actual.CPUShares, 10))
+	scalar("memorySwap", strconv.FormatInt(int64(expected.MemorySwap), 10), strconv.FormatInt(int64(actual.MemorySwap), 10))
+	scalar("memoryReservation", strconv.FormatInt(int64(expected.MemoryReservation), 10), strconv.FormatInt(int64(actual.MemoryReservation), 10))
+	scalar("cpuQuota", strconv.FormatInt(expected.CPUQuota, 10), strconv.FormatInt(actual.CPUQuota, 10))
+	scalar("cpuPeriod", strconv.FormatInt(expected.CPUPeriod, 10), strconv.FormatInt(actual.CPUPeriod, 10))
+	if expected.MemorySwappiness != nil {
+		scalar("memorySwappiness", strconv.FormatInt(*expected.MemorySwappiness, 10), formatSwappiness(actual.MemorySwappiness))
+	}
+
+	if expected.WorkingDir != "" {
+		scalar("workingDir", expected.WorkingDir, actual.WorkingDir)
+	}
+	if expected.User != "" {
+		scalar("user", expected.User, actual.User)
+	}
+	if len(expected.Command) > 0 {
+		scalar("command", strings.Join(expected.CommandArgs(), " "), strings.Join(actual.CommandArgs(), " "))
+	}
+	if len(expected.EntryPoint) > 0 {
+		scalar("entryPoint", strings.Join(expected.EntryPointArgs(), " "), strings.Join(actual.EntryPointArgs(), " "))
+	}
+	if expected.Healthcheck != nil {
+		scalar("healthcheck", expected.Healthcheck.String(), actual.Healthcheck.String())
+	}
+
+	expectedEnv, actualEnv := expected.Env, actual.Env
+	if opts.IgnoreImageEnv {
+		imageEnv := append(cloneStrings(expected.ImageEnv), actual.ImageEnv...)
+		expectedEnv = envOverrides(expectedEnv, imageEnv)
+		actualEnv = envOverrides(actualEnv, imageEnv)
+	}
+	diffs = append(diffs, diffKeyValues("env", Env(expectedEnv).Map(), Env(actualEnv).Map(), opts.OnlyExpectedKeys)...)
+	diffs = append(diffs, diffKeyValues("labels", opts.LabelFilter.Apply(expected.Labels), opts.LabelFilter.Apply(actual.Labels), opts.OnlyExpectedKeys)...)
+
+	diffs = append(diffs, diffSets("volumes", expected.VolumeStrings(), actual.VolumeStrings())...)
+	diffs = append(diffs, diffSets("ports", expected.PortStrings(), actual.PortStrings())...)
+	diffs = append(diffs, diffSets("networks", expected.Networks, actual.Networks)...)
+	diffs = append(diffs, diffSets("devices", expected.Devices, actual.Devices)...)
+	diffs = append(diffs, diffSets("extraHosts", expected.ExtraHosts, actual.ExtraHosts)...)
+	diffs = append(diffs, diffSets("links", expected.Links, actual.Links)...)
+	diffs = append(diffs, diffSets("networkAliases", expected.NetworkAliases, actual.NetworkAliases)...)
+	diffs = append(diffs, diffSets("volumesFrom", expected.VolumesFrom, actual.VolumesFrom)...)
+	diffs = append(diffs, diffSets("tmpfs", expected.Tmpfs, actual.Tmpfs)...)
+	diffs = append(diffs, diffSets("ulimits", expected.Ulimits, actual.Ulimits)...)
+	diffs = append(diffs, diffSets("capAdd", expected.CapAdd, actual.CapAdd)...)
+
+	return diffs
+}
+
+// diffKeyValues compares two maps key by key, in sorted key order
+func diffKeyValues(field string, expected, actual map[string]string, onlyExpected bool) []Difference {
+	var diffs []Difference
+	keys := make(map[string]bool)
+	for key := range expected {
+		keys[key] = true
+	}
+	if !onlyExpected {
+		for key := range actual {
+			keys[key] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		want, inExpected := expected[key]
+		got, inActual := actual[key]
+		name := field + "." + key
+		switch {
+		case inExpected && !inActual:
+			diffs = append(diffs, Difference{Field: name, Kind: DiffRemoved, Expected: want})
+		case !inExpected && inActual:
+			diffs = append(diffs, Difference{Field: name, Kind: DiffAdded, Actual: got})
+		case want != got:
+			diffs = append(diffs, Difference{Field: name, Kind: DiffChanged, Expected: want, Actual: got})
+		}
+	}
+	return diffs
+}
+
+// diffSets compares two string lists ignoring order
+func diffSets(field string, expected, actual []string) []Difference {
+	var diffs []Difference
+	inActual := make(map[string]bool, len(actual))
+	for _, value := range actual {
+		inActual[value] = true
+	}
+	inExpected := make(map[string]bool, len(expected))
+	for _, value := range expected {
+		inExpected[value] = true
+		if !inActual[value] {
+			diffs = append(diffs, Difference{Field: field, Kind: DiffRemoved, Expected: value})
+		}
+	}
+	for _, value := range actual {
+		if !inExpected[value] {
+			diffs = append(diffs, Difference{Field: field, Kind: DiffAdded, Actual: value})
+		}
+	}
+	return diffs
+}
+
+// formatSwappiness formats a swappiness setting, "" for the host's
+func formatSwappiness(swappiness *int64) string {
+	if swappiness == nil {
+		return ""
+	}
+	return strconv.FormatInt(*swappiness, 10)
+}
@@ -0,0 +1,131 @@
+package containerconfig
+
+import (
+	"debug/buildinfo"
+	"debug/elf"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// DefaultPath is the PATH docker gives containers whose image sets none
+const DefaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// dlvExecScript waits for the debugger, which is installed after the container starts, and
+// replaces itself with dlv exec running the app; $1 is the binary, the rest its arguments
+const dlvExecScript = `bin="$1"; shift
+until command -v dlv >/dev/null 2>&1; do sleep 1; done
+exec dlv exec --headless --listen=:"$DCE_DEBUG_PORT" --api-version=2 --accept-multiclient --continue "$bin" -- "$@"`
+
+// initWrappers are init processes an entrypoint may start the app under, with the argument that
+// ends their own options
+var initWrappers = map[string]string{"tini": "--", "docker-init": "--", "dumb-init": "", "catatonit": "--"}
+
+// AppCommand returns the app's binary and arguments from the spec's entrypoint and command,
+// skipping an init such as tini; a shell-form command is only understood when it is a plain
+// command line, as the binary can't be told apart from shell syntax otherwise
+func (s *ContainerSpec) AppCommand() (string, []string, error) {
+	argv := append(cloneStrings(s.EntryPointArgs()), s.CommandArgs()...)
+	if command, ok := ShellCommand(argv); ok {
+		if strings.ContainsAny(command, "|&;<>()$`\\\"'*?") {
+			return "", nil, fmt.Errorf("the app is started by the shell script '%s'; give its binary with --dlv-exec-binary", command)
+		}
+		argv = strings.Fields(strings.TrimPrefix(strings.TrimSpace(command), "exec "))
+	}
+	for len(argv) > 0 {
+		end, ok := initWrappers[path.Base(argv[0])]
+		if !ok {
+			break
+		}
+		argv = argv[1:]
+		if end != "" && len(argv) > 0 && argv[0] == end {
+			argv = argv[1:]
+		}
+	}
+	if len(argv) == 0 {
+		return "", nil, fmt.Errorf("the container has no entrypoint or command to debug")
+	}
+	return argv[0], argv[1:], nil
+}
+
+// BinaryCandidates returns the container paths a binary name may refer to: the name itself when it
+// is a path (relative ones from the working directory), otherwise each directory of the spec's PATH
+func (s *ContainerSpec) BinaryCandidates(name string) []string {
+	if strings.Contains(name, "/") {
+		if !path.IsAbs(name) {
+			name = path.Join("/", s.WorkingDir, name)
+		}
+		return []string{name}
+	}
+	searchPath := DefaultPath
+	if value, ok := s.Env.Get("PATH"); ok {
+		searchPath = value
+	}
+	var candidates []string
+	for _, dir := range strings.Split(searchPath, ":") {
+		if path.IsAbs(dir) {
+			candidates = append(candidates, path.Join(dir, name))
+		}
+	}
+	return candidates
+}
+
+// BinaryInfo describes how a Go binary was built, as far as debugging it is concerned
+type BinaryInfo struct {
+	// GoVersion is empty for binaries that aren't Go programs
+	GoVersion string
+	// Stripped is set when the DWARF sections are missing (-ldflags=-s -w), so delve has no symbols
+	Stripped bool
+	// Optimized is set unless the binary was built with -gcflags=all=-N -l, so variables may be
+	// optimized away and breakpoints land on unexpected lines
+	Optimized bool
+}
+
+// InspectBinary reads the build info and debug sections of an ELF binary
+func InspectBinary(r io.ReaderAt) (*BinaryInfo, error) {
+	file, err := elf.NewFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("not an ELF binary: %w", err)
+	}
+	defer file.Close()
+
+	info := &BinaryInfo{Stripped: file.Section(".debug_info") == nil && file.Section(".zdebug_info") == nil}
+	build, err := buildinfo.Read(r)
+	if err != nil {
+		return info, nil
+	}
+	info.GoVersion = build.GoVersion
+	info.Optimized = true
+	for _, setting := range build.Settings {
+		if setting.Key == "-gcflags" && strings.Contains(setting.Value, "-N") && strings.Contains(setting.Value, "-l") {
+			info.Optimized = false
+		}
+	}
+	return info, nil
+}
+
+// Warnings returns what keeps delve from debugging the binary well
+func (i *BinaryInfo) Warnings() []string {
+	var warnings []string
+	switch {
+	case i.GoVersion == "":
+		warnings = append(warnings, "it is not a Go program, so delve can't debug it")
+	case i.Stripped:
+		warnings = append(warnings, "it was built without debug info (-ldflags '-s -w'); delve can't set breakpoints, rebuild without those flags")
+	case i.Optimized:
+		warnings = append(warnings, "it was built with optimizations; variables may show as optimized away, build with -gcflags 'all=-N -l' for the best experience")
+	}
+	return warnings
+}
+
+// DlvExec starts the app under dlv exec instead of attaching to it later, so breakpoints in
+// startup code are hit; the script waits until the debugger is installed
+func DlvExec(binary string, args []string, port int) DevTransform {
+	return NewDevTransform("dlv exec", func(b *SpecBuilder) {
+		b.WithEntryPoint("/bin/sh", "-c", dlvExecScript, "dce-dlv-exec")
+		b.WithCommand(append([]string{binary}, args...)...)
+		b.WithEnv("DCE_DEBUG_PORT", strconv.Itoa(port))
+	})
+}
@@ -0,0 +1,175 @@
+//go:build e2e
+
+package containerconfig_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// The round-trip tests run a container with a set of flags, extract its spec, generate a docker run
+// command from it, run that, extract again and compare: every setting Coverage reports as exact must
+// come back unchanged. They need a docker daemon; run them with make e2e
+
+// e2eImage is the image the test containers run, overridable with DCE_E2E_IMAGE
+func e2eImage() string {
+	if image := os.Getenv("DCE_E2E_IMAGE"); image != "" {
+		return image
+	}
+	return "busybox:1.36"
+}
+
+// roundTripCases are the settings each round trip covers, as docker run flags
+var roundTripCases = []struct {
+	name  string
+	flags []string
+}{
+	{"env and labels", []string{"-e", "APP_MODE=prod", "-e", "EMPTY=", "-l", "team=core", "-l", "tier=backend"}},
+	{"mounts", []string{"-v", "dce-e2e-data:/data", "-v", "/tmp:/host-tmp:ro", "--tmpfs", "/scratch:size=16m"}},
+	{"ports", []string{"-p", "18080:80", "-p", "18443:443"}},
+	{"network", []string{"--network", "dce-e2e-net", "--network-alias", "api", "--add-host", "db.local:10.0.0.5"}},
+	{"resources", []string{"--memory", "64m", "--cpus", "0.5", "--cpu-shares", "512", "--ulimit", "nofile=1024:2048"}},
+	{"process", []string{"--user", "1000:1000", "-w", "/tmp", "--restart", "unless-stopped", "--cap-add", "NET_ADMIN"}},
+	{"healthcheck", []string{"--health-cmd", "true", "--health-interval", "30s", "--health-timeout", "5s", "--health-retries", "2"}},
+	{"entrypoint", []string{"--entrypoint", "/bin/sh"}},
+}
+
+// docker runs a docker command and returns its trimmed output, failing the test on error
+func docker(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("docker", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("docker %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// requireDocker skips the test when no daemon is reachable
+func requireDocker(t *testing.T) {
+	t.Helper()
+	if err := exec.Command("docker", "info").Run(); err != nil {
+		t.Skipf("no docker daemon: %v", err)
+	}
+}
+
+// extract inspects a container and parses its spec
+func extract(t *testing.T, name string) *containerconfig.ContainerSpec {
+	t.Helper()
+	spec, err := containerconfig.ParseInspectJSON(docker(t, "inspect", name))
+	if err != nil {
+		t.Fatalf("failed to parse inspect output of %s: %v", name, err)
+	}
+	return spec
+}
+
+// startContainer runs a container from docker run arguments and removes it when the test ends
+func startContainer(t *testing.T, name string, args ...string) {
+	t.Helper()
+	t.Cleanup(func() { _ = exec.Command("docker", "rm", "-f", name).Run() })
+	docker(t, append([]string{"run", "-d"}, args...)...)
+}
+
+func TestRoundTrip(t *testing.T) {
+	requireDocker(t)
+	docker(t, "pull", "-q", e2eImage())
+	if err := exec.Command("docker", "network", "inspect", "dce-e2e-net").Run(); err != nil {
+		docker(t, "network", "create", "dce-e2e-net")
+	}
+	t.Cleanup(func() {
+		_ = exec.Command("docker", "network", "rm", "dce-e2e-net").Run()
+		_ = exec.Command("docker", "volume", "rm", "dce-e2e-data").Run()
+	})
+
+	for i, tc := range roundTripCases {
+		t.Run(tc.name, func(t *testing.T) {
+			original := fmt.Sprintf("dce-e2e-%d-%d", time.Now().Unix(), i)
+			args := append([]string{"--name", original}, tc.flags...)
+			args = append(args, e2eImage())
+			if tc.flags[0] == "--entrypoint" {
+				args = append(args, "-c", "sleep 300")
+			} else {
+				args = append(args, "sleep", "300")
+			}
+			startContainer(t, original, args...)
+			expected := extract(t, original)
+			// The copy publishes the same ports
+			docker(t, "rm", "-f", original)
+
+			recreated := original + "-copy"
+			startContainer(t, recreated, containerconfig.GenerateRunCommand(expected, &containerconfig.RunOptions{Name: recreated})...)
+			actual := extract(t, recreated)
+
+			for _, diff := range containerconfig.DiffSpecs(expected, actual, containerconfig.DiffOptions{}) {
+				t.Errorf("%s did not survive the round trip: %s", diff.Field, diff)
+			}
+		})
+	}
+}
+
+func TestComposeConfigKeepsDollars(t *testing.T) {
+	if err := exec.Command("docker", "compose", "version").Run(); err != nil {
+		t.Skipf("no docker compose: %v", err)
+	}
+	t.Setenv("HOME", "/host-home")
+	t.Setenv("b", "host-b")
+	spec := dollarTestSpec()
+	data, _, err := containerconfig.ExportCompose([]*containerconfig.ContainerSpec{spec}, containerconfig.ComposeExportOptions{InlineEnv: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "compose.yaml")
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var config struct {
+		Services map[string]struct {
+			Environment map[string]string `json:"environment"`
+			Labels      map[string]string `json:"labels"`
+			Entrypoint  []string          `json:"entrypoint"`
+			Command     []string          `json:"command"`
+		} `json:"services"`
+	}
+	if err := json.Unmarshal([]byte(docker(t, "compose", "-f", file, "config", "--format", "json")), &config); err != nil {
+		t.Fatal(err)
+	}
+	// Newer compose versions escape $ again in their output, which stands for the same value
+	same := func(got, want []string) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] && strings.ReplaceAll(got[i], "$$", "$") != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+	svc := config.Services["app"]
+	var env []string
+	for key, value := range svc.Environment {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	if !same(env, spec.Env) {
+		t.Errorf("environment = %q, want %q", env, spec.Env)
+	}
+	if !same([]string{svc.Labels["template"]}, []string{spec.Labels["template"]}) {
+		t.Errorf("labels = %v, want %v", svc.Labels, spec.Labels)
+	}
+	if !same(svc.Entrypoint, spec.EntryPoint) {
+		t.Errorf("entrypoint = %q, want %q", svc.Entrypoint, spec.EntryPoint)
+	}
+	if !same(svc.Command, spec.Command) {
+		t.Errorf("command = %q, want %q", svc.Command, spec.Command)
+	}
+}
@@ -0,0 +1,94 @@
+package containerconfig
+
+import (
+	"sort"
+	"strings"
+)
+
+// Env is a container environment as docker keeps it: "KEY=value" entries, where a later entry for
+// a key wins. The value is everything after the first "=", so it may hold "=" itself, and "KEY="
+// sets an empty value; an entry without "=" names a variable docker run -e takes from the client's
+// environment, which has no value here
+type Env []string
+
+// SplitEnv splits an entry into its key and value; hasValue is false for an entry without "="
+func SplitEnv(entry string) (key, value string, hasValue bool) {
+	return strings.Cut(entry, "=")
+}
+
+// Get returns the value of a variable and whether the environment sets it
+func (e Env) Get(key string) (string, bool) {
+	value, found := "", false
+	for _, entry := range e {
+		if name, v, hasValue := SplitEnv(entry); name == key && hasValue {
+			value, found = v, true
+		}
+	}
+	return value, found
+}
+
+// Has reports whether the environment names a variable, with or without a value
+func (e Env) Has(key string) bool {
+	for _, entry := range e {
+		if name, _, _ := SplitEnv(entry); name == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Map returns the variables with a value as a map, the last entry of a key winning
+func (e Env) Map() map[string]string {
+	m := make(map[string]string, len(e))
+	for _, entry := range e {
+		if key, value, hasValue := SplitEnv(entry); hasValue {
+			m[key] = value
+		}
+	}
+	return m
+}
+
+// Set sets a variable, replacing every entry of the key with one at the end
+func (e *Env) Set(key, value string) {
+	e.Unset(key)
+	*e = append(*e, key+"="+value)
+}
+
+// Unset removes every entry of a variable
+func (e *Env) Unset(key string) {
+	var env Env
+	for _, entry := range *e {
+		if name, _, _ := SplitEnv(entry); name != key {
+			env = append(env, entry)
+		}
+	}
+	*e = env
+}
+
+// Merge sets the variables of other over the environment's; entries of other without a value
+// are added unless the environment already names the variable
+func (e *Env) Merge(other Env) {
+	for _, entry := range other {
+		key, value, hasValue := SplitEnv(entry)
+		switch {
+		case hasValue:
+			e.Set(key, value)
+		case !e.Has(key):
+			*e = append(*e, entry)
+		}
+	}
+}
+
+// EnvFromMap returns the variables of a map as an environment, sorted by key
+func EnvFromMap(m map[string]string) Env {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	env := make(Env, len(keys))
+	for i, key := range keys {
+		env[i] = key + "=" + m[key]
+	}
+	return env
+}
@@ -0,0 +1,91 @@
+package containerconfig_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+func TestEnvGet(t *testing.T) {
+	env := containerconfig.Env{"A=1", "URL=postgres://db/app?sslmode=disable", "EMPTY=", "FROM_CLIENT", "A=2"}
+	tests := []struct {
+		key       string
+		want      string
+		wantFound bool
+		wantHas   bool
+	}{
+		{"A", "2", true, true},
+		{"URL", "postgres://db/app?sslmode=disable", true, true},
+		{"EMPTY", "", true, true},
+		{"FROM_CLIENT", "", false, true},
+		{"MISSING", "", false, false},
+	}
+	for _, tt := range tests {
+		got, found := env.Get(tt.key)
+		if got != tt.want || found != tt.wantFound {
+			t.Errorf("Get(%q) = %q, %v, want %q, %v", tt.key, got, found, tt.want, tt.wantFound)
+		}
+		if has := env.Has(tt.key); has != tt.wantHas {
+			t.Errorf("Has(%q) = %v, want %v", tt.key, has, tt.wantHas)
+		}
+	}
+
+	want := map[string]string{"A": "2", "URL": "postgres://db/app?sslmode=disable", "EMPTY": ""}
+	if got := env.Map(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Map() = %v, want %v", got, want)
+	}
+}
+
+func TestEnvChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    containerconfig.Env
+		change func(*containerconfig.Env)
+		want   containerconfig.Env
+	}{
+		{
+			name:   "set replaces every entry at the end",
+			env:    containerconfig.Env{"A=1", "B=2", "A=3"},
+			change: func(e *containerconfig.Env) { e.Set("A", "4") },
+			want:   containerconfig.Env{"B=2", "A=4"},
+		},
+		{
+			name:   "set on an empty env",
+			change: func(e *containerconfig.Env) { e.Set("A", "x=y") },
+			want:   containerconfig.Env{"A=x=y"},
+		},
+		{
+			name:   "unset removes entries with and without a value",
+			env:    containerconfig.Env{"A=1", "A", "B=2"},
+			change: func(e *containerconfig.Env) { e.Unset("A") },
+			want:   containerconfig.Env{"B=2"},
+		},
+		{
+			name:   "merge overrides values and keeps what other names without one",
+			env:    containerconfig.Env{"A=1", "B=2"},
+			change: func(e *containerconfig.Env) { e.Merge(containerconfig.Env{"B=3", "A", "C", "D=4"}) },
+			want:   containerconfig.Env{"A=1", "B=3", "C", "D=4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := append(containerconfig.Env(nil), tt.env...)
+			tt.change(&env)
+			if !reflect.DeepEqual(env, tt.want) {
+				t.Errorf("got %q, want %q", env, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvFromMap(t *testing.T) {
+	got := containerconfig.EnvFromMap(map[string]string{"B": "2", "A": "1", "C": ""})
+	want := containerconfig.Env{"A=1", "B=2", "C="}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EnvFromMap = %q, want %q", got, want)
+	}
+	if back := got.Map(); !reflect.DeepEqual(containerconfig.EnvFromMap(back), want) {
+		t.Errorf("Map does not round-trip: %v", back)
+	}
+}
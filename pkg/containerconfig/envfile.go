@@ -0,0 +1,240 @@
+package containerconfig
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvFileThreshold is the number of container env vars from which an export without known env
+// files moves them into an env file of their own instead of listing them inline
+const EnvFileThreshold = 8
+
+// EnvFile is a group of env entries kept in a file of their own
+type EnvFile struct {
+	// Name is the file name, relative to the compose file that references it
+	Name    string
+	Entries []string
+}
+
+// Bytes renders the file in the KEY=value format of compose env_file; a value compose would read
+// differently is quoted, see quoteEnvValue
+func (f EnvFile) Bytes() []byte {
+	var b bytes.Buffer
+	for _, entry := range f.Entries {
+		if key, value, found := strings.Cut(entry, "="); found {
+			entry = key + "=" + quoteEnvValue(value)
+		}
+		b.WriteString(entry)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// quoteEnvValue quotes an env file value compose would change: one holding $ or #, starting with a
+// quote or padded with spaces. Single quotes keep it literally; a value holding a single quote, or
+// ending in a backslash that would escape the closing one, is double-quoted with \, " and $ escaped
+func quoteEnvValue(value string) string {
+	if !strings.ContainsAny(value, "$#") && !strings.HasPrefix(value, "'") && !strings.HasPrefix(value, `"`) &&
+		strings.TrimSpace(value) == value {
+		return value
+	}
+	if !strings.Contains(value, "'") && !strings.HasSuffix(value, `\`) {
+		return "'" + value + "'"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", "$$").Replace(value) + `"`
+}
+
+// ParseEnvFile parses an env file as compose reads it: KEY=value lines, blank lines and comments
+// skipped, single-quoted values taken literally, escapes in double-quoted ones resolved and comments
+// after unquoted ones dropped; variables are left unexpanded, and entries without a value left out
+func ParseEnvFile(data []byte) []string {
+	var entries []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !found {
+			continue
+		}
+		entries = append(entries, strings.TrimSpace(key)+"="+parseEnvValue(strings.TrimSpace(value)))
+	}
+	return entries
+}
+
+// parseEnvValue reads a value of an env file line, see ParseEnvFile
+func parseEnvValue(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		var b strings.Builder
+		quoted := value[1 : len(value)-1]
+		for i := 0; i < len(quoted); i++ {
+			switch {
+			case quoted[i] == '\\' && i+1 < len(quoted) && quoted[i+1] != '$':
+				i++
+				switch quoted[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 'r':
+					b.WriteByte('\r')
+				default:
+					b.WriteByte(quoted[i])
+				}
+			case quoted[i] == '$' && i+1 < len(quoted) && quoted[i+1] == '$':
+				i++
+				b.WriteByte('$')
+			default:
+				b.WriteByte(quoted[i])
+			}
+		}
+		return b.String()
+	}
+	value, _, _ = strings.Cut(value, " #")
+	return strings.ReplaceAll(strings.TrimSpace(value), "$$", "$")
+}
+
+// composeEnvFileNode accepts the string, list and long form of a service's env_file
+type composeEnvFileNode []string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (e *composeEnvFileNode) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*e = []string{node.Value}
+		return nil
+	}
+	if node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: expected a path or a list of paths", node.Line)
+	}
+	for _, item := range node.Content {
+		if item.Kind == yaml.MappingNode {
+			var long struct {
+				Path string `yaml:"path"`
+			}
+			if err := item.Decode(&long); err != nil {
+				return err
+			}
+			*e = append(*e, long.Path)
+			continue
+		}
+		*e = append(*e, item.Value)
+	}
+	return nil
+}
+
+// ComposeEnvFiles reads the env files a compose-started container's service declares, found through
+// the compose labels naming the project's files; the files must be readable from this machine
+// Files that are gone are skipped, later files taking precedence as they do in compose
+func ComposeEnvFiles(spec *ContainerSpec) ([]EnvFile, error) {
+	service := spec.Labels[ComposeServiceLabel]
+	configFiles := spec.Labels[ComposeConfigFilesLabel]
+	if service == "" || configFiles == "" {
+		return nil, nil
+	}
+	dir := spec.Labels[ComposeWorkingDirLabel]
+
+	var files []EnvFile
+	for _, configFile := range strings.Split(configFiles, ",") {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			continue
+		}
+		var file struct {
+			Services map[string]struct {
+				EnvFile composeEnvFileNode `yaml:"env_file"`
+			} `yaml:"services"`
+		}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse compose file '%s': %w", configFile, err)
+		}
+		for _, path := range file.Services[service].EnvFile {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			files = append(files, EnvFile{Name: filepath.Base(path), Entries: ParseEnvFile(data)})
+		}
+	}
+	return files, nil
+}
+
+// GroupEnv splits the env vars a container sets over the image's into env files and inline entries
+// With known env files, each entry whose value one of them holds goes into the last such file and
+// the rest stays inline; without any, EnvFileThreshold or more entries go into one file named after
+// the service, fewer stay inline. Files are left out when none of their entries is set, and
+// multi-line values, which env files can't hold, always stay inline
+func GroupEnv(spec *ContainerSpec, service string, known []EnvFile) ([]EnvFile, []string) {
+	var overrides, inline []string
+	for _, entry := range spec.EnvOverrides() {
+		if strings.Contains(entry, "\n") {
+			inline = append(inline, entry)
+		} else {
+			overrides = append(overrides, entry)
+		}
+	}
+	if len(known) == 0 {
+		if len(overrides) < EnvFileThreshold {
+			return nil, append(inline, overrides...)
+		}
+		return []EnvFile{{Name: service + ".env", Entries: overrides}}, inline
+	}
+
+	owner := make(map[string]int)
+	for i, file := range known {
+		for _, entry := range file.Entries {
+			owner[entry] = i
+		}
+	}
+	grouped := make([][]string, len(known))
+	for _, entry := range overrides {
+		if i, ok := owner[entry]; ok {
+			grouped[i] = append(grouped[i], entry)
+		} else {
+			inline = append(inline, entry)
+		}
+	}
+	var files []EnvFile
+	for i, entries := range grouped {
+		if len(entries) > 0 {
+			files = append(files, EnvFile{Name: known[i].Name, Entries: entries})
+		}
+	}
+	return files, inline
+}
+
+// nameEnvFiles gives the env files of all services unique names: services sharing a file with the
+// same entries share it, otherwise the file is prefixed with the service name
+func nameEnvFiles(byService map[string][]EnvFile) map[string][]EnvFile {
+	services := make([]string, 0, len(byService))
+	for service := range byService {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	content := make(map[string]string)
+	named := make(map[string][]EnvFile, len(byService))
+	for _, service := range services {
+		for _, file := range byService[service] {
+			data := string(file.Bytes())
+			if existing, ok := content[file.Name]; ok && existing != data {
+				file.Name = service + "-" + file.Name
+			}
+			content[file.Name] = data
+			named[service] = append(named[service], file)
+		}
+	}
+	return named
+}
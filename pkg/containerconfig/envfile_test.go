@@ -0,0 +1,46 @@
+package containerconfig_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+func TestEnvFileBytes(t *testing.T) {
+	tests := []struct {
+		entry string
+		// line is how the entry is written to the file
+		line string
+	}{
+		{"MODE=prod", "MODE=prod"},
+		{"EMPTY=", "EMPTY="},
+		{`PATTERN=a\d+`, `PATTERN=a\d+`},
+		{`GREETING=say "hi"`, `GREETING=say "hi"`},
+		{"PASSWORD=a$b", "PASSWORD='a$b'"},
+		{"COLOR=#fff", "COLOR='#fff'"},
+		{"NOTE=a #b", "NOTE='a #b'"},
+		{"PADDED= x ", "PADDED=' x '"},
+		{`QUOTED="x"`, `QUOTED='"x"'`},
+		{"NAME=it's $5", `NAME="it's $$5"`},
+		{`MIXED='a' "\n"`, `MIXED="'a' \"\\n\""`},
+		{`WINDOWS=C:\$tmp\`, `WINDOWS="C:\\$$tmp\\"`},
+	}
+	for _, tt := range tests {
+		data := containerconfig.EnvFile{Entries: []string{tt.entry}}.Bytes()
+		if got := string(data); got != tt.line+"\n" {
+			t.Errorf("%s: written as %q, want %q", tt.entry, got, tt.line+"\n")
+		}
+		if got := containerconfig.ParseEnvFile(data); !reflect.DeepEqual(got, []string{tt.entry}) {
+			t.Errorf("%s: read back as %q", tt.entry, got)
+		}
+	}
+}
+
+func TestParseEnvFile(t *testing.T) {
+	data := "# settings\n\nexport A=1\nB = two # comment\nC='$LITERAL'\nD=\"line\\nbreak \\\"q\\\" $$5\"\nE=$$HOME\nNO_VALUE\n"
+	want := []string{"A=1", "B=two", "C=$LITERAL", "D=line\nbreak \"q\" $5", "E=$HOME"}
+	if got := containerconfig.ParseEnvFile([]byte(data)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseEnvFile = %q, want %q", got, want)
+	}
+}
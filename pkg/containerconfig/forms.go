@@ -0,0 +1,81 @@
+package containerconfig
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Forms of a Cmd or Entrypoint, as written in a Dockerfile
+const (
+	// FormExec runs the argv directly
+	FormExec = "exec"
+	// FormShell runs a command string through "/bin/sh -c", with shell expansion and the shell as PID 1
+	FormShell = "shell"
+)
+
+// shellPrefixes are the argv prefixes docker uses for shell-form instructions
+var shellPrefixes = [][]string{
+	{"/bin/sh", "-c"},
+	{"/bin/bash", "-c"},
+	{"sh", "-c"},
+	{"cmd", "/S", "/C"},
+}
+
+// DetectForm reports whether an argv is the expansion of a shell-form instruction
+// Docker stores both forms as argv, so shell form is recognized by its "/bin/sh -c <string>" shape
+func DetectForm(args []string) string {
+	if _, ok := ShellCommand(args); ok {
+		return FormShell
+	}
+	return FormExec
+}
+
+// ShellCommand returns the command string of a shell-form argv
+func ShellCommand(args []string) (string, bool) {
+	for _, prefix := range shellPrefixes {
+		if len(args) != len(prefix)+1 {
+			continue
+		}
+		matches := true
+		for i, part := range prefix {
+			if !strings.EqualFold(args[i], part) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return args[len(prefix)], true
+		}
+	}
+	return "", false
+}
+
+// expandForm returns the argv docker runs; a shell-form instruction written as a single command
+// string, as in a hand-written spec, is wrapped in "/bin/sh -c"
+func expandForm(args []string, form string) []string {
+	if form == FormShell && len(args) == 1 {
+		return []string{"/bin/sh", "-c", args[0]}
+	}
+	return args
+}
+
+// CommandArgs returns the command as the argv docker runs
+func (s *ContainerSpec) CommandArgs() []string {
+	return expandForm(s.Command, s.CommandForm)
+}
+
+// EntryPointArgs returns the entrypoint as the argv docker runs
+func (s *ContainerSpec) EntryPointArgs() []string {
+	return expandForm(s.EntryPoint, s.EntryPointForm)
+}
+
+// DockerfileArgs formats an argv for a CMD or ENTRYPOINT instruction in its original form: a plain
+// command string for shell form, a JSON array for exec form
+func DockerfileArgs(args []string, form string) string {
+	args = expandForm(args, form)
+	if command, ok := ShellCommand(args); ok && form == FormShell {
+		return command
+	}
+	data, _ := json.Marshal(args)
+	return string(data)
+}
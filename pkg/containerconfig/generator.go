@@ -2,6 +2,7 @@ package containerconfig
 
 import (
 	"fmt"
+	"strconv"
 )
 
 // GenerateRunCommand generates docker run arguments from ContainerSpec
@@ -15,35 +16,80 @@ func GenerateRunCommand(spec *ContainerSpec, opts *RunOptions) []string {
 	} else if spec.Name != "" {
 		args = append(args, "--name", spec.Name)
 	}
+	if opts != nil && opts.Remove {
+		args = append(args, "--rm")
+	}
 
 	// Add environment variables
-	for _, env := range spec.Env {
+	env := spec.Env
+	if opts != nil && opts.EnvOverridesOnly {
+		env = spec.EnvOverrides()
+	}
+	for _, env := range env {
 		args = append(args, "-e", env)
 	}
 
 	// Add volumes
-	for _, vol := range spec.Volumes {
-		args = append(args, "-v", vol)
+	for _, m := range spec.Volumes {
+		if opts != nil && opts.writable(m.Target) {
+			m.ReadOnly = false
+		}
+		args = append(args, m.RunArgs()...)
 	}
 
 	// Add ports
 	for _, port := range spec.Ports {
-		args = append(args, "-p", port)
+		args = append(args, "-p", port.String())
 	}
 
 	// Add networks
-	for _, network := range spec.Networks {
-		args = append(args, "--network", network)
+	if spec.NetworkMode != "" {
+		args = append(args, "--network", spec.NetworkMode)
+	} else {
+		for _, network := range spec.Networks {
+			args = append(args, "--network", network)
+		}
+	}
+
+	// Add network aliases; docker only accepts them on user-defined networks
+	if spec.NetworkMode == "" && hasUserNetwork(spec.Networks) {
+		for _, alias := range spec.NetworkAliases {
+			args = append(args, "--network-alias", alias)
+		}
+	}
+
+	// Add links
+	for _, link := range spec.Links {
+		args = append(args, "--link", link)
+	}
+
+	// Add volumes-from
+	for _, from := range spec.VolumesFrom {
+		args = append(args, "--volumes-from", from)
+	}
+
+	// Add tmpfs mounts
+	for _, tmpfs := range spec.Tmpfs {
+		args = append(args, "--tmpfs", tmpfs)
 	}
 
 	// Add working directory
-	if spec.WorkingDir != "" {
-		args = append(args, "-w", spec.WorkingDir)
+	workingDir := spec.WorkingDir
+	if opts != nil && opts.WorkingDirOverride != "" {
+		workingDir = opts.WorkingDirOverride
+	}
+	if workingDir != "" {
+		args = append(args, "-w", workingDir)
 	}
 
 	// Add labels
-	for key, value := range spec.Labels {
-		args = append(args, "-l", fmt.Sprintf("%s=%s", key, value))
+	labels := spec.Labels
+	if opts != nil {
+		labels = opts.LabelFilter.Apply(labels)
+	}
+	// Sorted, so the same spec always generates the same command and plans can be diffed
+	for _, key := range sortedKeys(labels) {
+		args = append(args, "-l", fmt.Sprintf("%s=%s", key, labels[key]))
 	}
 
 	// Add devices
@@ -61,18 +107,125 @@ func GenerateRunCommand(spec *ContainerSpec, opts *RunOptions) []string {
 		args = append(args, "--restart", spec.Restart)
 	}
 
+	// Add user and privileges
+	if spec.User != "" {
+		args = append(args, "--user", spec.User)
+	}
+	if spec.Privileged {
+		args = append(args, "--privileged")
+	}
+	for _, capability := range spec.CapAdd {
+		args = append(args, "--cap-add", capability)
+	}
+	if spec.AppArmorProfile != "" {
+		args = append(args, "--security-opt", "apparmor="+spec.AppArmorProfile)
+	}
+
+	// Add resource limits
+	for _, ulimit := range spec.Ulimits {
+		args = append(args, "--ulimit", ulimit)
+	}
+	if spec.Memory > 0 {
+		args = append(args, "--memory", spec.Memory.String())
+	}
+	if spec.NanoCPUs > 0 {
+		args = append(args, "--cpus", spec.NanoCPUs.String())
+	}
+	if spec.CPUShares > 0 {
+		args = append(args, "--cpu-shares", strconv.FormatInt(spec.CPUShares, 10))
+	}
+	if spec.MemorySwappiness != nil {
+		args = append(args, "--memory-swappiness", strconv.FormatInt(*spec.MemorySwappiness, 10))
+	}
+	if spec.KernelMemory > 0 {
+		args = append(args, "--kernel-memory", spec.KernelMemory.String())
+	}
+	// docker rejects a swap limit without a memory limit, and a CFS quota or period next to --cpus
+	if spec.MemorySwap != 0 && spec.Memory > 0 {
+		args = append(args, "--memory-swap", spec.MemorySwap.String())
+	}
+	if spec.MemoryReservation > 0 {
+		args = append(args, "--memory-reservation", spec.MemoryReservation.String())
+	}
+	if spec.CPUQuota > 0 && spec.NanoCPUs == 0 {
+		args = append(args, "--cpu-quota", strconv.FormatInt(spec.CPUQuota, 10))
+	}
+	if spec.CPUPeriod > 0 && spec.NanoCPUs == 0 {
+		args = append(args, "--cpu-period", strconv.FormatInt(spec.CPUPeriod, 10))
+	}
+
+	// Add healthcheck
+	args = append(args, spec.Healthcheck.runArgs()...)
+
 	// Add entrypoint
-	if len(spec.EntryPoint) > 0 {
-		args = append(args, "--entrypoint", spec.EntryPoint[0])
+	entryPoint, command := spec.EntryPointArgs(), spec.CommandArgs()
+	if opts != nil && opts.OmitImageDefaults {
+		if spec.EntryPointInherited() {
+			entryPoint = nil
+		}
+		if spec.CommandInherited() {
+			command = nil
+		}
+	}
+	if len(entryPoint) > 0 {
+		args = append(args, "--entrypoint", entryPoint[0])
 	}
 
 	// Add image
 	args = append(args, spec.Image)
 
-	// Add command arguments
-	if len(spec.Command) > 0 {
-		args = append(args, spec.Command...)
+	// Add command arguments; --entrypoint only takes the executable, so the
+	// remaining entrypoint arguments have to go ahead of the command
+	if len(entryPoint) > 1 {
+		args = append(args, entryPoint[1:]...)
 	}
+	args = append(args, command...)
 
 	return args
 }
+
+// writable reports whether the mount at a container path is selected by MakeMountsWritable
+func (o *RunOptions) writable(target string) bool {
+	for _, selected := range o.MakeMountsWritable {
+		if selected == AllMounts || selected == target {
+			return true
+		}
+	}
+	return false
+}
+
+// LoosenedMounts returns the read-only volumes of the spec that the options make writable
+func LoosenedMounts(spec *ContainerSpec, opts *RunOptions) []Mount {
+	if opts == nil {
+		return nil
+	}
+	var loosened []Mount
+	for _, m := range spec.Volumes {
+		if m.ReadOnly && opts.writable(m.Target) {
+			loosened = append(loosened, m)
+		}
+	}
+	return loosened
+}
+
+// hasUserNetwork reports whether any of the networks is user-defined rather than bridge, host or none
+func hasUserNetwork(networks []string) bool {
+	for _, network := range networks {
+		if network != "bridge" && network != "host" && network != "none" && network != "default" {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitNetworks returns a copy of the spec attached only to its first network, together with the
+// remaining networks, which are connected with docker network connect after docker create
+// Older engines silently ignore all but one --network flag, so this is the portable way to attach several
+func SplitNetworks(spec *ContainerSpec) (*ContainerSpec, []string) {
+	primary := spec.Clone()
+	if spec.NetworkMode != "" || len(spec.Networks) <= 1 {
+		return primary, nil
+	}
+	primary.Networks = spec.Networks[:1:1]
+	return primary, cloneStrings(spec.Networks[1:])
+}
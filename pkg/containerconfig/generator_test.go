@@ -0,0 +1,152 @@
+package containerconfig_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+func TestGenerateRunCommandResources(t *testing.T) {
+	tests := []struct {
+		name string
+		spec containerconfig.ContainerSpec
+		want string
+	}{
+		{
+			name: "memory and swap",
+			spec: containerconfig.ContainerSpec{Memory: 512 << 20, MemorySwap: 1 << 30, MemoryReservation: 256 << 20},
+			want: "--memory 512m --memory-swap 1g --memory-reservation 256m",
+		},
+		{
+			name: "unlimited swap",
+			spec: containerconfig.ContainerSpec{Memory: 512 << 20, MemorySwap: -1},
+			want: "--memory 512m --memory-swap -1",
+		},
+		{
+			name: "swap without memory",
+			spec: containerconfig.ContainerSpec{MemorySwap: 1 << 30},
+			want: "",
+		},
+		{
+			name: "quota and period",
+			spec: containerconfig.ContainerSpec{CPUQuota: 50000, CPUPeriod: 100000, CPUShares: 512},
+			want: "--cpu-shares 512 --cpu-quota 50000 --cpu-period 100000",
+		},
+		{
+			name: "cpus win over quota",
+			spec: containerconfig.ContainerSpec{NanoCPUs: 1500000000, CPUQuota: 50000, CPUPeriod: 100000},
+			want: "--cpus 1.5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.spec.Image = "nginx"
+			args := containerconfig.GenerateRunCommand(&tt.spec, nil)
+			got := strings.TrimSpace(strings.TrimSuffix(strings.Join(args, " "), "nginx"))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseResourceLimits(t *testing.T) {
+	tests := []struct {
+		hostConfig string
+		want       string
+	}{
+		{`{"Memory": 536870912, "MemorySwap": 1073741824}`, "--memory 512m"},
+		{`{"Memory": 536870912, "MemorySwap": 2147483648, "MemoryReservation": 268435456}`, "--memory 512m --memory-swap 2g --memory-reservation 256m"},
+		{`{"Memory": 536870912, "MemorySwap": -1}`, "--memory 512m --memory-swap -1"},
+		{`{"MemorySwap": -1}`, ""},
+		{`{"CpuQuota": 50000, "CpuPeriod": 100000}`, "--cpu-quota 50000 --cpu-period 100000"},
+		{`{"NanoCpus": 500000000, "CpuQuota": 50000, "CpuPeriod": 100000}`, "--cpus 0.5"},
+	}
+	for _, tt := range tests {
+		spec, err := containerconfig.ParseInspectJSON(`[{"Name": "/web", "Config": {"Image": "nginx"}, "HostConfig": ` + tt.hostConfig + `}]`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args := strings.Join(containerconfig.GenerateRunCommand(spec, &containerconfig.RunOptions{Name: "web"}), " ")
+		got := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(args, "--name web"), "nginx"))
+		if got != tt.want {
+			t.Errorf("HostConfig %s: got %q, want %q", tt.hostConfig, got, tt.want)
+		}
+	}
+}
+
+func TestParseNetworkAliases(t *testing.T) {
+	inspect := `[{"Id": "0123456789abcdef", "Name": "/api", "Config": {"Image": "api"}, "NetworkSettings": {"Networks": {
+		"frontend": {"Aliases": ["web", "api", "0123456789ab"]},
+		"backend": {"Aliases": ["service", "api"]}
+	}}}]`
+	spec, warnings, err := containerconfig.ParseInspectJSONWithWarnings(inspect, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"backend", "frontend"}; !reflect.DeepEqual(spec.Networks, want) {
+		t.Errorf("networks = %v, want %v", spec.Networks, want)
+	}
+	if want := []string{"service"}; !reflect.DeepEqual(spec.NetworkAliases, want) {
+		t.Errorf("aliases = %v, want the first network's %v", spec.NetworkAliases, want)
+	}
+	if len(warnings) != 1 || warnings[0].Field != "networkAliases" || !strings.Contains(warnings[0].Message, "web") {
+		t.Errorf("warnings = %v, want one about the frontend aliases", warnings)
+	}
+}
+
+func TestImportComposeConflictingLimits(t *testing.T) {
+	compose := `services:
+  web:
+    image: nginx
+    cpus: "0.5"
+    cpu_quota: 20000
+    memswap_limit: 1g
+`
+	spec, warnings, err := containerconfig.ImportComposeService([]byte(compose), "web", containerconfig.ComposeOptions{Project: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.MemorySwap != 0 || spec.CPUQuota != 0 || spec.NanoCPUs != 500000000 {
+		t.Errorf("got swap %d, quota %d, cpus %d", spec.MemorySwap, spec.CPUQuota, spec.NanoCPUs)
+	}
+	if len(warnings) != 2 {
+		t.Errorf("got warnings %v, want two", warnings)
+	}
+}
+
+func TestImportComposeShortVolumes(t *testing.T) {
+	tests := []struct {
+		volume string
+		want   containerconfig.Mount
+	}{
+		{`/srv/data:/data`, containerconfig.Mount{Type: containerconfig.MountBind, Source: "/srv/data", Target: "/data"}},
+		{`data:/data:ro`, containerconfig.Mount{Type: containerconfig.MountVolume, Source: "app_data", Target: "/data", ReadOnly: true}},
+		{`C:\data:/data`, containerconfig.Mount{Type: containerconfig.MountBind, Source: `C:\data`, Target: "/data"}},
+		{`C:\data:/data:ro`, containerconfig.Mount{Type: containerconfig.MountBind, Source: `C:\data`, Target: "/data", ReadOnly: true}},
+		{`d:/data:C:\data`, containerconfig.Mount{Type: containerconfig.MountBind, Source: "d:/data", Target: `C:\data`}},
+	}
+	for _, tt := range tests {
+		compose := "services:\n  web:\n    image: nginx\n    volumes:\n      - '" + tt.volume + "'\n"
+		spec, err := containerconfig.ParseComposeService([]byte(compose), "web", containerconfig.ComposeOptions{Project: "app"})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.volume, err)
+		}
+		if len(spec.Volumes) != 1 || !reflect.DeepEqual(spec.Volumes[0], tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.volume, spec.Volumes, tt.want)
+		}
+	}
+}
+
+func TestGenerateRunCommandLabelOrder(t *testing.T) {
+	spec := &containerconfig.ContainerSpec{Image: "nginx", Labels: map[string]string{"zone": "b", "app": "web", "tier": "front", "env": "dev"}}
+	want := "-l app=web -l env=dev -l tier=front -l zone=b nginx"
+	// Map order changes between runs, so a few runs would catch an unsorted command
+	for i := 0; i < 10; i++ {
+		if got := strings.Join(containerconfig.GenerateRunCommand(spec, nil), " "); got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
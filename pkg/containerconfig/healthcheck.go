@@ -0,0 +1,193 @@
+package containerconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Healthcheck test kinds, the first element of Healthcheck.Test
+const (
+	HealthNone     = "NONE"
+	HealthCmd      = "CMD"
+	HealthCmdShell = "CMD-SHELL"
+)
+
+// Healthcheck describes how docker probes a container's health
+// Durations are written as Go duration strings such as "30s"; zero means the daemon default
+type Healthcheck struct {
+	// Test is ["NONE"], ["CMD", args...] or ["CMD-SHELL", command], as in the image config
+	Test          []string `json:"test,omitempty" yaml:"test,omitempty"`
+	Interval      Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Timeout       Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	StartPeriod   Duration `json:"startPeriod,omitempty" yaml:"startPeriod,omitempty"`
+	StartInterval Duration `json:"startInterval,omitempty" yaml:"startInterval,omitempty"`
+	Retries       int      `json:"retries,omitempty" yaml:"retries,omitempty"`
+}
+
+// Disabled reports whether the healthcheck turns off a healthcheck inherited from the image
+func (h *Healthcheck) Disabled() bool {
+	return h != nil && len(h.Test) > 0 && h.Test[0] == HealthNone
+}
+
+// Command returns the command string --health-cmd takes; docker always runs it through the shell,
+// so an exec-form test is quoted into a shell command
+func (h *Healthcheck) Command() string {
+	if h == nil || len(h.Test) < 2 {
+		return ""
+	}
+	if h.Test[0] == HealthCmdShell {
+		return h.Test[1]
+	}
+	return FormatShellCommand(h.Test[1:])
+}
+
+// String formats the healthcheck as a single line
+func (h *Healthcheck) String() string {
+	if h == nil {
+		return ""
+	}
+	if h.Disabled() {
+		return "disabled"
+	}
+	parts := []string{strings.Join(h.Test, " ")}
+	for _, opt := range []struct {
+		name  string
+		value Duration
+	}{
+		{"interval", h.Interval},
+		{"timeout", h.Timeout},
+		{"start-period", h.StartPeriod},
+		{"start-interval", h.StartInterval},
+	} {
+		if opt.value > 0 {
+			parts = append(parts, opt.name+"="+opt.value.String())
+		}
+	}
+	if h.Retries > 0 {
+		parts = append(parts, "retries="+strconv.Itoa(h.Retries))
+	}
+	return strings.Join(parts, " ")
+}
+
+// runArgs returns the docker run flags reproducing the healthcheck
+func (h *Healthcheck) runArgs() []string {
+	if h == nil {
+		return nil
+	}
+	if h.Disabled() {
+		return []string{"--no-healthcheck"}
+	}
+	var args []string
+	if command := h.Command(); command != "" {
+		args = append(args, "--health-cmd", command)
+	}
+	for _, opt := range []struct {
+		flag  string
+		value Duration
+	}{
+		{"--health-interval", h.Interval},
+		{"--health-timeout", h.Timeout},
+		{"--health-start-period", h.StartPeriod},
+		{"--health-start-interval", h.StartInterval},
+	} {
+		if opt.value > 0 {
+			args = append(args, opt.flag, opt.value.String())
+		}
+	}
+	if h.Retries > 0 {
+		args = append(args, "--health-retries", strconv.Itoa(h.Retries))
+	}
+	return args
+}
+
+// clone returns a deep copy of the healthcheck
+func (h *Healthcheck) clone() *Healthcheck {
+	if h == nil {
+		return nil
+	}
+	clone := *h
+	clone.Test = cloneStrings(h.Test)
+	return &clone
+}
+
+// inspectHealthcheck is the healthcheck as found in docker inspect output, with durations in nanoseconds
+type inspectHealthcheck struct {
+	Test          []string `json:"Test"`
+	Interval      int64    `json:"Interval"`
+	Timeout       int64    `json:"Timeout"`
+	StartPeriod   int64    `json:"StartPeriod"`
+	StartInterval int64    `json:"StartInterval"`
+	Retries       int      `json:"Retries"`
+}
+
+// healthcheck converts the inspect form into a Healthcheck, or nil when no test is set
+func (i *inspectHealthcheck) healthcheck() *Healthcheck {
+	if i == nil || len(i.Test) == 0 {
+		return nil
+	}
+	return &Healthcheck{
+		Test:          i.Test,
+		Interval:      Duration(i.Interval),
+		Timeout:       Duration(i.Timeout),
+		StartPeriod:   Duration(i.StartPeriod),
+		StartInterval: Duration(i.StartInterval),
+		Retries:       i.Retries,
+	}
+}
+
+// composeHealthcheck is the healthcheck stanza of a compose service
+type composeHealthcheck struct {
+	Test          yaml.Node `yaml:"test"`
+	Interval      string    `yaml:"interval"`
+	Timeout       string    `yaml:"timeout"`
+	StartPeriod   string    `yaml:"start_period"`
+	StartInterval string    `yaml:"start_interval"`
+	Retries       int       `yaml:"retries"`
+	Disable       bool      `yaml:"disable"`
+}
+
+// healthcheck converts the compose stanza into a Healthcheck
+// A string test runs through the shell; a list test starts with NONE, CMD or CMD-SHELL
+func (c *composeHealthcheck) healthcheck() (*Healthcheck, error) {
+	if c == nil {
+		return nil, nil
+	}
+	if c.Disable {
+		return &Healthcheck{Test: []string{HealthNone}}, nil
+	}
+
+	check := &Healthcheck{Retries: c.Retries}
+	switch c.Test.Kind {
+	case 0:
+	case yaml.ScalarNode:
+		check.Test = []string{HealthCmdShell, c.Test.Value}
+	case yaml.SequenceNode:
+		if err := c.Test.Decode(&check.Test); err != nil {
+			return nil, fmt.Errorf("line %d: invalid healthcheck test: %w", c.Test.Line, err)
+		}
+		if len(check.Test) > 0 && check.Test[0] != HealthNone && check.Test[0] != HealthCmd && check.Test[0] != HealthCmdShell {
+			return nil, fmt.Errorf("line %d: healthcheck test must start with %s, %s or %s", c.Test.Line, HealthNone, HealthCmd, HealthCmdShell)
+		}
+	default:
+		return nil, fmt.Errorf("line %d: invalid healthcheck test", c.Test.Line)
+	}
+
+	var err error
+	for _, field := range []struct {
+		value string
+		dest  *Duration
+	}{
+		{c.Interval, &check.Interval},
+		{c.Timeout, &check.Timeout},
+		{c.StartPeriod, &check.StartPeriod},
+		{c.StartInterval, &check.StartInterval},
+	} {
+		if *field.dest, err = ParseDuration(field.value); err != nil {
+			return nil, fmt.Errorf("healthcheck: %w", err)
+		}
+	}
+	return check, nil
+}
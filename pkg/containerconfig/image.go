@@ -0,0 +1,133 @@
+package containerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ImageInspectData represents the structure of docker image inspect JSON output
+type ImageInspectData struct {
+	ID          string   `json:"Id"`
+	RepoTags    []string `json:"RepoTags"`
+	RepoDigests []string `json:"RepoDigests"`
+	Created     string   `json:"Created"`
+	Config      struct {
+		Env        []string          `json:"Env"`
+		Labels     map[string]string `json:"Labels"`
+		User       string            `json:"User"`
+		Entrypoint []string          `json:"Entrypoint"`
+		Cmd        []string          `json:"Cmd"`
+	} `json:"Config"`
+}
+
+// ImageInfo describes the image a container was created from
+type ImageInfo struct {
+	ID          string            `json:"id"`
+	RepoTags    []string          `json:"repoTags,omitempty"`
+	RepoDigests []string          `json:"repoDigests,omitempty"`
+	Created     string            `json:"created,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Env         []string          `json:"env,omitempty"`
+	// User is the image's default user; empty means root
+	User string `json:"user,omitempty"`
+	// EntryPoint and Command are the image's ENTRYPOINT and CMD
+	EntryPoint []string `json:"entryPoint,omitempty"`
+	Command    []string `json:"command,omitempty"`
+}
+
+// ParseImageInspectJSON parses docker image inspect JSON output and returns ImageInfo
+func ParseImageInspectJSON(jsonData string) (*ImageInfo, error) {
+	var inspectArray []ImageInspectData
+	if err := json.Unmarshal([]byte(jsonData), &inspectArray); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	if len(inspectArray) == 0 {
+		return nil, fmt.Errorf("empty image inspect data")
+	}
+
+	data := inspectArray[0]
+	return &ImageInfo{
+		ID:          data.ID,
+		RepoTags:    data.RepoTags,
+		RepoDigests: data.RepoDigests,
+		Created:     data.Created,
+		Labels:      data.Config.Labels,
+		Env:         data.Config.Env,
+		User:        data.Config.User,
+		EntryPoint:  data.Config.Entrypoint,
+		Command:     data.Config.Cmd,
+	}, nil
+}
+
+// Origins of a container env var
+const (
+	EnvOriginImage     = "image"
+	EnvOriginContainer = "container"
+)
+
+// EnvOrigin reports whether an env entry is an image default, inherited unchanged from ImageEnv,
+// or a container-level override
+func (s *ContainerSpec) EnvOrigin(entry string) string {
+	for _, imageEntry := range s.ImageEnv {
+		if entry == imageEntry {
+			return EnvOriginImage
+		}
+	}
+	return EnvOriginContainer
+}
+
+// EnvOverrides returns the env entries set on the container rather than inherited from the image
+// Without ImageEnv every entry counts as an override
+func (s *ContainerSpec) EnvOverrides() []string {
+	return envOverrides(s.Env, s.ImageEnv)
+}
+
+// envOverrides returns the entries of env that don't appear verbatim in imageEnv
+func envOverrides(env, imageEnv []string) []string {
+	defaults := make(map[string]bool, len(imageEnv))
+	for _, entry := range imageEnv {
+		defaults[entry] = true
+	}
+	var overrides []string
+	for _, entry := range env {
+		if !defaults[entry] {
+			overrides = append(overrides, entry)
+		}
+	}
+	return overrides
+}
+
+// ResolveImageDefaults records the image's entrypoint and command on the spec and fills in the ones
+// the container config leaves null, which the container inherits, so exports are complete
+// The command is only inherited along with the entrypoint, as docker drops the image's command when
+// the entrypoint is overridden
+func ResolveImageDefaults(spec *ContainerSpec, image *ImageInfo) {
+	spec.ImageEntryPoint = cloneStrings(image.EntryPoint)
+	spec.ImageCommand = cloneStrings(image.Command)
+	entryPointInherited := spec.EntryPoint == nil || equalStrings(spec.EntryPoint, image.EntryPoint)
+	if spec.EntryPoint == nil && image.EntryPoint != nil {
+		spec.EntryPoint = cloneStrings(image.EntryPoint)
+		if DetectForm(spec.EntryPoint) == FormShell {
+			spec.EntryPointForm = FormShell
+		}
+	}
+	if spec.Command == nil && image.Command != nil && entryPointInherited {
+		spec.Command = cloneStrings(image.Command)
+		if DetectForm(spec.Command) == FormShell {
+			spec.CommandForm = FormShell
+		}
+	}
+}
+
+// EntryPointInherited reports whether the spec's entrypoint is the image's, so running the image
+// needs no --entrypoint
+func (s *ContainerSpec) EntryPointInherited() bool {
+	return len(s.EntryPoint) > 0 && equalStrings(s.EntryPoint, s.ImageEntryPoint)
+}
+
+// CommandInherited reports whether the spec's command is the image's and reaches the container
+// without being given, which it only does when the entrypoint isn't overridden either
+func (s *ContainerSpec) CommandInherited() bool {
+	return len(s.Command) > 0 && equalStrings(s.Command, s.ImageCommand) && (len(s.EntryPoint) == 0 || s.EntryPointInherited())
+}
@@ -0,0 +1,295 @@
+package containerconfig
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of workload a spec is exported as
+const (
+	KubeKindDeployment = "Deployment"
+	KubeKindPod        = "Pod"
+)
+
+// KubeExportOptions controls how a spec is exported as a Kubernetes manifest
+type KubeExportOptions struct {
+	// Kind is KubeKindDeployment, the default, or KubeKindPod
+	Kind string
+	// LabelFilter drops matching container labels; labels docker bookkeeping and this tool set are
+	// always left out
+	LabelFilter *LabelFilter
+	// HeadroomPercent is added on top of observed usage for resource requests, see InferKubeResources
+	HeadroomPercent int
+	// Mounts chooses how the mounts at container paths, or AllMounts, are exported, see KubeMount;
+	// by default bind mounts become hostPath volumes and named volumes claims
+	Mounts map[string]KubeMount
+}
+
+// kubeMeta is the metadata of a Kubernetes object
+type kubeMeta struct {
+	Name        string            `yaml:"name,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// kubePod is a v1 Pod running the container once, restarted as its restart policy says
+type kubePod struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   kubeMeta    `yaml:"metadata"`
+	Spec       kubePodSpec `yaml:"spec"`
+}
+
+// kubeDeployment is an apps/v1 Deployment running the container
+type kubeDeployment struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Metadata   kubeMeta `yaml:"metadata"`
+	Spec       struct {
+		Replicas int `yaml:"replicas"`
+		Selector struct {
+			MatchLabels map[string]string `yaml:"matchLabels"`
+		} `yaml:"selector"`
+		Template struct {
+			Metadata kubeMeta    `yaml:"metadata"`
+			Spec     kubePodSpec `yaml:"spec"`
+		} `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+// kubePodSpec is the spec of the pods a workload runs
+type kubePodSpec struct {
+	RestartPolicy string          `yaml:"restartPolicy,omitempty"`
+	Containers    []kubeContainer `yaml:"containers"`
+	Volumes       []kubeVolume    `yaml:"volumes,omitempty"`
+}
+
+// kubeContainer is a container of a pod
+type kubeContainer struct {
+	Name           string            `yaml:"name"`
+	Image          string            `yaml:"image"`
+	Command        []string          `yaml:"command,omitempty"`
+	Args           []string          `yaml:"args,omitempty"`
+	WorkingDir     string            `yaml:"workingDir,omitempty"`
+	Env            []kubeEnvVar      `yaml:"env,omitempty"`
+	Ports          []kubePort        `yaml:"ports,omitempty"`
+	VolumeMounts   []kubeVolumeMount `yaml:"volumeMounts,omitempty"`
+	Resources      *KubeResources    `yaml:"resources,omitempty"`
+	LivenessProbe  *KubeProbe        `yaml:"livenessProbe,omitempty"`
+	ReadinessProbe *KubeProbe        `yaml:"readinessProbe,omitempty"`
+}
+
+// kubeEnvVar is an env var of a container
+type kubeEnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// kubePort is a container port
+type kubePort struct {
+	ContainerPort int    `yaml:"containerPort"`
+	Protocol      string `yaml:"protocol,omitempty"`
+}
+
+// invalidKubeNameChars are the characters Kubernetes object names (DNS labels) don't allow
+var invalidKubeNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// KubeName turns a container name into a valid Kubernetes object name: lowercase letters, digits and
+// dashes, at most 63 characters
+func KubeName(name string) string {
+	name = invalidKubeNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return strings.Trim(name, "-")
+}
+
+// kubeLabelName matches the name part of a label key and a label value
+var kubeLabelName = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+
+// kubeLabelPrefix matches the optional DNS subdomain prefix of a label key
+var kubeLabelPrefix = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// validKubeLabel reports whether a key and value can be a Kubernetes label as they are
+func validKubeLabel(key, value string) bool {
+	prefix, name, found := strings.Cut(key, "/")
+	if !found {
+		prefix, name = "", key
+	} else if len(prefix) > 253 || !kubeLabelPrefix.MatchString(prefix) {
+		return false
+	}
+	if len(name) > 63 || !kubeLabelName.MatchString(name) {
+		return false
+	}
+	return value == "" || (len(value) <= 63 && kubeLabelName.MatchString(value))
+}
+
+// kubeMetadata splits the container labels into Kubernetes labels and, for those whose key or
+// value a label doesn't allow, annotations; bookkeeping labels of compose and this tool are left out
+func kubeMetadata(spec *ContainerSpec, filter *LabelFilter) (labels, annotations map[string]string) {
+	for key, value := range filter.Apply(spec.Labels) {
+		if strings.HasPrefix(key, "com.docker.compose.") || strings.HasPrefix(key, toolLabelPrefix) {
+			continue
+		}
+		if validKubeLabel(key, value) {
+			if labels == nil {
+				labels = make(map[string]string)
+			}
+			labels[key] = value
+			continue
+		}
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[key] = value
+	}
+	return labels, annotations
+}
+
+// kubeRestartPolicy maps a docker restart policy to a pod's
+func kubeRestartPolicy(restart string) string {
+	switch restart {
+	case "", "no":
+		return "Never"
+	case "on-failure":
+		return "OnFailure"
+	}
+	return "Always"
+}
+
+// kubePorts returns the container ports of the spec's published ports; ranges are not expanded
+func kubePorts(spec *ContainerSpec) ([]kubePort, []Warning) {
+	var ports []kubePort
+	var warnings []Warning
+	seen := make(map[kubePort]bool)
+	for _, mapping := range spec.Ports {
+		if mapping.IsRange() {
+			warnings = append(warnings, Warning{Field: "ports", Message: fmt.Sprintf("port range %s is not exported; list its ports one by one", formatPortRange(mapping.ContainerPort, mapping.ContainerPortEnd))})
+			continue
+		}
+		port := kubePort{ContainerPort: mapping.ContainerPort}
+		if !mapping.TCP() {
+			port.Protocol = strings.ToUpper(mapping.Protocol)
+		}
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].ContainerPort < ports[j].ContainerPort })
+	return ports, warnings
+}
+
+// ExportKubernetes renders the spec as a Deployment, or a Pod, running one container with the spec's
+// image, entrypoint and command, the env vars set over the image's, the published ports as container
+// ports, resources from InferKubeResources and the healthcheck as probes from KubeProbes
+// Container labels become metadata labels, or annotations where a label can't hold them; the
+// workload is selected by the label app=<name>. A Pod restarts as the restart policy says, while a
+// Deployment always restarts its pods
+// Mounts become pod volumes as opts.Mounts chooses; each claim is a document of its own ahead of
+// the workload, marked with a TODO naming the data to copy into it
+func ExportKubernetes(spec *ContainerSpec, opts KubeExportOptions) ([]byte, []Warning, error) {
+	name := KubeName(spec.Name)
+	if name == "" {
+		return nil, nil, fmt.Errorf("container name '%s' has no characters a Kubernetes name allows", spec.Name)
+	}
+	var warnings []Warning
+
+	container := kubeContainer{
+		Name:       name,
+		Image:      spec.Image,
+		WorkingDir: spec.WorkingDir,
+	}
+	// Like docker, Kubernetes runs the image's entrypoint and command when none are given
+	if !spec.EntryPointInherited() {
+		container.Command = spec.EntryPointArgs()
+	}
+	if !spec.CommandInherited() {
+		container.Args = spec.CommandArgs()
+	}
+	for _, entry := range spec.EnvOverrides() {
+		key, value, _ := SplitEnv(entry)
+		container.Env = append(container.Env, kubeEnvVar{Name: key, Value: value})
+	}
+	ports, portWarnings := kubePorts(spec)
+	container.Ports = ports
+	warnings = append(warnings, portWarnings...)
+
+	resources, resourceWarnings := InferKubeResources(spec, opts.HeadroomPercent)
+	if !resources.Empty() {
+		container.Resources = resources
+	}
+	warnings = append(warnings, resourceWarnings...)
+
+	liveness, readiness, probeWarnings := KubeProbes(spec.Healthcheck)
+	container.LivenessProbe, container.ReadinessProbe = liveness, readiness
+	warnings = append(warnings, probeWarnings...)
+	if spec.Healthcheck == nil {
+		warnings = append(warnings, Warning{Field: "healthcheck", Message: "the container shows no healthcheck, so no probes are generated; an image healthcheck isn't converted"})
+	}
+
+	volumes := exportKubeVolumes(spec, name, opts.Mounts)
+	container.VolumeMounts = volumes.mounts
+	warnings = append(warnings, volumes.warnings...)
+
+	selector := map[string]string{"app": name}
+	labels, annotations := kubeMetadata(spec, opts.LabelFilter)
+	if _, ok := labels["app"]; ok {
+		warnings = append(warnings, Warning{Field: "labels", Message: fmt.Sprintf("label app=%s is replaced by app=%s, which selects the pods", labels["app"], name)})
+	}
+	podLabels := map[string]string{"app": name}
+	for key, value := range labels {
+		if key != "app" {
+			podLabels[key] = value
+		}
+	}
+	meta := kubeMeta{Name: name, Labels: podLabels, Annotations: annotations}
+	podSpec := kubePodSpec{Containers: []kubeContainer{container}, Volumes: volumes.volumes}
+
+	var workload any
+	switch opts.Kind {
+	case KubeKindPod:
+		podSpec.RestartPolicy = kubeRestartPolicy(spec.Restart)
+		if spec.Replicas > 1 {
+			warnings = append(warnings, Warning{Field: "replicas", Message: fmt.Sprintf("a Pod runs once; export a Deployment for the %d replicas", spec.Replicas)})
+		}
+		workload = kubePod{APIVersion: "v1", Kind: KubeKindPod, Metadata: meta, Spec: podSpec}
+	case KubeKindDeployment, "":
+		if policy := kubeRestartPolicy(spec.Restart); policy != "Always" {
+			restart := spec.Restart
+			if restart == "" {
+				restart = "no"
+			}
+			warnings = append(warnings, Warning{Field: "restart", Message: fmt.Sprintf("a Deployment always restarts its pods, unlike the container's restart policy '%s'; export a Pod to keep it", restart)})
+		}
+		deployment := kubeDeployment{APIVersion: "apps/v1", Kind: KubeKindDeployment, Metadata: meta}
+		deployment.Spec.Replicas = 1
+		if spec.Replicas > 1 {
+			deployment.Spec.Replicas = spec.Replicas
+		}
+		deployment.Spec.Selector.MatchLabels = selector
+		deployment.Spec.Template.Metadata = kubeMeta{Labels: podLabels, Annotations: annotations}
+		deployment.Spec.Template.Spec = podSpec
+		workload = deployment
+	default:
+		return nil, nil, fmt.Errorf("invalid kind '%s', expected %s or %s", opts.Kind, KubeKindDeployment, KubeKindPod)
+	}
+
+	var manifest []byte
+	for _, claim := range volumes.claims {
+		data, err := encodeYAML(claim)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal Kubernetes manifest: %w", err)
+		}
+		manifest = append(manifest, "# "+claim.todo+"\n"...)
+		manifest = append(manifest, data...)
+		manifest = append(manifest, "---\n"...)
+	}
+	data, err := encodeYAML(workload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal Kubernetes manifest: %w", err)
+	}
+	return append(manifest, data...), warnings, nil
+}
@@ -0,0 +1,176 @@
+package containerconfig_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// exportTestSpec is a web server spec with the settings both the compose and the Kubernetes export map
+func exportTestSpec() *containerconfig.ContainerSpec {
+	return &containerconfig.ContainerSpec{
+		Name:     "web",
+		Image:    "nginx:1.27",
+		Restart:  "unless-stopped",
+		Env:      containerconfig.Env{"MODE=prod", "PASSWORD_FILE=/run/secrets/pw"},
+		Ports:    []containerconfig.PortMapping{{HostPort: 8080, ContainerPort: 80}, {HostPort: 53, ContainerPort: 53, Protocol: "udp"}},
+		Volumes:  containerconfig.ParseMounts([]string{"html:/usr/share/nginx/html:ro", "/srv/conf:/etc/nginx/conf.d"}),
+		Networks: []string{"frontend"},
+		Labels:   map[string]string{"team": "web"},
+		Command:  []string{"nginx", "-g", "daemon off;"},
+		Memory:   512 << 20,
+		NanoCPUs: 1500000000,
+	}
+}
+
+func TestExportKubernetes(t *testing.T) {
+	data, warnings, err := containerconfig.ExportKubernetes(exportTestSpec(), containerconfig.KubeExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# TODO: check the size and storage class, then copy the data of volume html into the claim
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: web-usr-share-nginx-html
+spec:
+  accessModes:
+    - ReadWriteOnce
+  resources:
+    requests:
+      storage: 1Gi
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+    team: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+        team: web
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.27
+          args:
+            - nginx
+            - -g
+            - daemon off;
+          env:
+            - name: MODE
+              value: prod
+            - name: PASSWORD_FILE
+              value: /run/secrets/pw
+          ports:
+            - containerPort: 53
+              protocol: UDP
+            - containerPort: 80
+          volumeMounts:
+            - name: usr-share-nginx-html
+              mountPath: /usr/share/nginx/html
+              readOnly: true
+            - name: etc-nginx-conf-d
+              mountPath: /etc/nginx/conf.d
+          resources:
+            limits:
+              cpu: 1500m
+              memory: 512Mi
+      volumes:
+        - name: usr-share-nginx-html
+          persistentVolumeClaim:
+            claimName: web-usr-share-nginx-html
+        - name: etc-nginx-conf-d
+          hostPath:
+            path: /srv/conf
+`
+	if string(data) != want {
+		t.Errorf("got\n%s\nwant\n%s", data, want)
+	}
+	var fields []string
+	for _, warning := range warnings {
+		fields = append(fields, warning.Field)
+	}
+	if got := strings.Join(fields, ","); got != "resources,healthcheck,volumes" {
+		t.Errorf("got warnings on %s, want resources, healthcheck and volumes", got)
+	}
+}
+
+func TestExportKubernetesPod(t *testing.T) {
+	tests := []struct {
+		restart string
+		want    string
+	}{
+		{"", "restartPolicy: Never"},
+		{"no", "restartPolicy: Never"},
+		{"on-failure", "restartPolicy: OnFailure"},
+		{"always", "restartPolicy: Always"},
+		{"unless-stopped", "restartPolicy: Always"},
+	}
+	for _, tt := range tests {
+		spec := exportTestSpec()
+		spec.Restart = tt.restart
+		data, _, err := containerconfig.ExportKubernetes(spec, containerconfig.KubeExportOptions{Kind: containerconfig.KubeKindPod})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "kind: Pod\n") || !strings.Contains(string(data), tt.want+"\n") {
+			t.Errorf("restart %q exported as\n%s\nwant a pod with %s", tt.restart, data, tt.want)
+		}
+	}
+}
+
+func TestKubeName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"web", "web"},
+		{"My_App.Web-1", "my-app-web-1"},
+		{"/web", "web"},
+		{"app__db", "app-db"},
+		{strings.Repeat("a", 70), strings.Repeat("a", 63)},
+		{strings.Repeat("a", 62) + "_b", strings.Repeat("a", 62)},
+	}
+	for _, tt := range tests {
+		if got := containerconfig.KubeName(tt.name); got != tt.want {
+			t.Errorf("KubeName(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseKubeOverlay(t *testing.T) {
+	tests := []struct {
+		name    string
+		overlay string
+		// err is a substring of the expected error; empty means the overlay is valid
+		err string
+	}{
+		{"claim with a class", "mounts:\n  /data: {as: pvc, size: 10Gi, storageClass: fast}\n", ""},
+		{"every mount", "mounts:\n  '*': {as: emptyDir}\n", ""},
+		{"unknown top-level key", "mounts: {}\nvolumes: {}\n", "unknown field 'volumes'"},
+		{"unknown mount key", "mounts:\n  /data: {as: pvc, class: fast}\n", "unknown field 'class'"},
+		{"unknown kind", "mounts:\n  /data: {as: nfs}\n", "nfs"},
+		{"relative path", "mounts:\n  data: {as: emptyDir}\n", "absolute container path"},
+		{"size of a hostPath", "mounts:\n  /data: {as: hostPath, size: 1Gi}\n", "only apply to a pvc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := containerconfig.ParseKubeOverlay([]byte(tt.overlay))
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("ParseKubeOverlay: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("ParseKubeOverlay = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
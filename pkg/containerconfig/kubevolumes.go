@@ -0,0 +1,283 @@
+package containerconfig
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// How a container mount is exported to Kubernetes
+const (
+	KubeHostPath = "hostPath"
+	KubeEmptyDir = "emptyDir"
+	KubeClaim    = "pvc"
+)
+
+// DefaultClaimSize is the storage a generated PersistentVolumeClaim requests when none is chosen
+const DefaultClaimSize = "1Gi"
+
+// kubeQuantity matches the Kubernetes quantities a claim size may be given in
+var kubeQuantity = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(Ki|Mi|Gi|Ti|Pi|Ei|k|M|G|T|P|E)?$`)
+
+// KubeMount chooses how the mount at one container path is exported
+type KubeMount struct {
+	// As is KubeHostPath, KubeEmptyDir or KubeClaim
+	As string `yaml:"as"`
+	// Size and StorageClass are the request and class of a claim
+	Size         string `yaml:"size,omitempty"`
+	StorageClass string `yaml:"storageClass,omitempty"`
+}
+
+// validate checks the choice for the mount at target
+func (m KubeMount) validate(target string) error {
+	switch m.As {
+	case KubeHostPath, KubeEmptyDir, KubeClaim:
+	default:
+		return fmt.Errorf("mount %s: 'as' must be %s, %s or %s", target, KubeHostPath, KubeEmptyDir, KubeClaim)
+	}
+	if m.As != KubeClaim && (m.Size != "" || m.StorageClass != "") {
+		return fmt.Errorf("mount %s: size and storageClass only apply to a %s", target, KubeClaim)
+	}
+	if m.Size != "" && !kubeQuantity.MatchString(m.Size) {
+		return fmt.Errorf("mount %s: invalid size '%s'", target, m.Size)
+	}
+	return nil
+}
+
+// KubeOverlay is a YAML file of choices for a Kubernetes export that a spec doesn't hold
+type KubeOverlay struct {
+	// Mounts maps container paths, or AllMounts, to how their mounts are exported
+	Mounts map[string]KubeMount `yaml:"mounts"`
+}
+
+// ParseKubeOverlay parses and validates an overlay file
+func ParseKubeOverlay(data []byte) (*KubeOverlay, error) {
+	if err := ValidateSchema(SchemaOverlay, data); err != nil {
+		return nil, err
+	}
+	var overlay KubeOverlay
+	if err := yaml.Unmarshal(data, &overlay); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	for target, mount := range overlay.Mounts {
+		if target != AllMounts && !path.IsAbs(target) {
+			return nil, fmt.Errorf("mount %s: expected an absolute container path or \"%s\"", target, AllMounts)
+		}
+		if err := mount.validate(target); err != nil {
+			return nil, err
+		}
+	}
+	return &overlay, nil
+}
+
+// ParseClaimFlag parses a "target[=size]" value selecting the mount at a container path, or every
+// mount with AllMounts, to be exported as a claim
+func ParseClaimFlag(value string) (string, KubeMount, error) {
+	target, size, _ := strings.Cut(value, "=")
+	if target != AllMounts && !path.IsAbs(target) {
+		return "", KubeMount{}, fmt.Errorf("invalid --pvc '%s': expected an absolute container path or \"%s\"", value, AllMounts)
+	}
+	mount := KubeMount{As: KubeClaim, Size: size}
+	if err := mount.validate(target); err != nil {
+		return "", KubeMount{}, err
+	}
+	return target, mount, nil
+}
+
+// kubeVolume is a volume of a pod
+type kubeVolume struct {
+	Name                  string           `yaml:"name"`
+	HostPath              *kubeHostPath    `yaml:"hostPath,omitempty"`
+	EmptyDir              *kubeEmptyDir    `yaml:"emptyDir,omitempty"`
+	PersistentVolumeClaim *kubeClaimSource `yaml:"persistentVolumeClaim,omitempty"`
+}
+
+// kubeHostPath is a directory or file of the node
+type kubeHostPath struct {
+	Path string `yaml:"path"`
+}
+
+// kubeEmptyDir is scratch space living as long as the pod; the Memory medium makes it a tmpfs
+type kubeEmptyDir struct {
+	Medium    string `yaml:"medium,omitempty"`
+	SizeLimit string `yaml:"sizeLimit,omitempty"`
+}
+
+// kubeClaimSource refers a pod volume to a claim
+type kubeClaimSource struct {
+	ClaimName string `yaml:"claimName"`
+}
+
+// kubeVolumeMount mounts a pod volume into a container
+type kubeVolumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+	ReadOnly  bool   `yaml:"readOnly,omitempty"`
+}
+
+// kubeClaim is a v1 PersistentVolumeClaim, rendered with a TODO naming the data it should hold
+type kubeClaim struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Metadata   kubeMeta `yaml:"metadata"`
+	Spec       struct {
+		AccessModes      []string `yaml:"accessModes"`
+		StorageClassName string   `yaml:"storageClassName,omitempty"`
+		Resources        struct {
+			Requests map[string]string `yaml:"requests"`
+		} `yaml:"resources"`
+	} `yaml:"spec"`
+	todo string
+}
+
+// kubeVolumes holds the pod volumes, container mounts and claims a spec's mounts export to
+type kubeVolumes struct {
+	volumes  []kubeVolume
+	mounts   []kubeVolumeMount
+	claims   []kubeClaim
+	names    map[string]bool
+	warnings []Warning
+}
+
+// volumeName returns a unique pod volume name for the mount at target
+func (v *kubeVolumes) volumeName(target string) string {
+	base := KubeName(target)
+	if base == "" {
+		base = "root"
+	}
+	name := base
+	for i := 2; v.names[name]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	v.names[name] = true
+	return name
+}
+
+// add exports one mount as chosen
+func (v *kubeVolumes) add(app, target string, readOnly bool, choice KubeMount, volume kubeVolume, data string) {
+	volume.Name = v.volumeName(target)
+	if choice.As == KubeClaim {
+		claim := kubeClaim{APIVersion: "v1", Kind: "PersistentVolumeClaim", Metadata: kubeMeta{Name: KubeName(app + "-" + volume.Name)}}
+		claim.Spec.AccessModes = []string{"ReadWriteOnce"}
+		claim.Spec.StorageClassName = choice.StorageClass
+		size := choice.Size
+		if size == "" {
+			size = DefaultClaimSize
+		}
+		claim.Spec.Resources.Requests = map[string]string{"storage": size}
+		claim.todo = fmt.Sprintf("TODO: check the size and storage class, then copy %s into the claim", data)
+		v.claims = append(v.claims, claim)
+		volume = kubeVolume{Name: volume.Name, PersistentVolumeClaim: &kubeClaimSource{ClaimName: claim.Metadata.Name}}
+	}
+	v.volumes = append(v.volumes, volume)
+	v.mounts = append(v.mounts, kubeVolumeMount{Name: volume.Name, MountPath: target, ReadOnly: readOnly})
+}
+
+// mountChoice returns the choice for the mount at target: its own entry, then the AllMounts entry,
+// then the default
+func mountChoice(choices map[string]KubeMount, target, fallback string) KubeMount {
+	if choice, ok := choices[target]; ok {
+		return choice
+	}
+	if choice, ok := choices[AllMounts]; ok {
+		return choice
+	}
+	return KubeMount{As: fallback}
+}
+
+// exportKubeVolumes turns the spec's mounts into pod volumes: bind mounts into hostPath volumes,
+// named volumes into claims and tmpfs mounts into memory-backed emptyDirs, unless chosen otherwise
+func exportKubeVolumes(spec *ContainerSpec, app string, choices map[string]KubeMount) *kubeVolumes {
+	v := &kubeVolumes{names: make(map[string]bool)}
+	for _, m := range spec.Volumes {
+		source, target, readOnly := m.Source, m.Target, m.ReadOnly
+		if m.Type == MountTmpfs {
+			v.add(app, target, readOnly, KubeMount{As: KubeEmptyDir}, kubeVolume{EmptyDir: tmpfsEmptyDir(m.TmpfsOptions)}, "")
+			continue
+		}
+		if source == "" {
+			v.warnings = append(v.warnings, Warning{Field: "volumes", Message: fmt.Sprintf("anonymous volume %s is exported as an emptyDir", target)})
+			v.add(app, target, readOnly, KubeMount{As: KubeEmptyDir}, kubeVolume{EmptyDir: &kubeEmptyDir{}}, "")
+			continue
+		}
+
+		if m.Type != MountBind {
+			choice := mountChoice(choices, target, KubeClaim)
+			if choice.As == KubeHostPath {
+				v.warnings = append(v.warnings, Warning{Field: "volumes", Message: fmt.Sprintf("named volume %s has no host path; it is exported as a %s", source, KubeClaim)})
+				choice = KubeMount{As: KubeClaim}
+			}
+			if choice.As == KubeEmptyDir {
+				v.warnings = append(v.warnings, Warning{Field: "volumes", Message: fmt.Sprintf("named volume %s is exported as an emptyDir, which loses its data with the pod", source)})
+			}
+			v.add(app, target, readOnly, choice, kubeVolume{EmptyDir: &kubeEmptyDir{}}, "the data of volume "+source)
+			continue
+		}
+
+		choice := mountChoice(choices, target, KubeHostPath)
+		if choice.As == KubeHostPath && !path.IsAbs(source) {
+			v.warnings = append(v.warnings, Warning{Field: "volumes", Message: fmt.Sprintf("bind mount source %s is not an absolute path; it is exported as a %s", source, KubeClaim)})
+			choice = KubeMount{As: KubeClaim}
+		} else if _, chosen := choices[target]; choice.As == KubeHostPath && !chosen {
+			v.warnings = append(v.warnings, Warning{Field: "volumes", Message: fmt.Sprintf("bind mount %s is exported as a hostPath, which needs the path on every node the pod may run on; --pvc %s makes it a claim", source, target)})
+		}
+		v.add(app, target, readOnly, choice, kubeVolume{HostPath: &kubeHostPath{Path: source}}, "host path "+source)
+	}
+
+	for _, m := range spec.TmpfsMounts() {
+		v.add(app, m.Target, false, KubeMount{As: KubeEmptyDir}, kubeVolume{EmptyDir: tmpfsEmptyDir(m.TmpfsOptions)}, "")
+	}
+
+	var unknown []string
+	for target := range choices {
+		if target != AllMounts && !containsString(mountTargets(v.mounts), target) {
+			unknown = append(unknown, target)
+		}
+	}
+	sort.Strings(unknown)
+	for _, target := range unknown {
+		v.warnings = append(v.warnings, Warning{Field: "volumes", Message: fmt.Sprintf("the container has no mount at %s to export as chosen", target)})
+	}
+	return v
+}
+
+// tmpfsEmptyDir returns the memory-backed emptyDir for a tmpfs with the given options
+func tmpfsEmptyDir(options string) *kubeEmptyDir {
+	emptyDir := &kubeEmptyDir{Medium: "Memory"}
+	for _, option := range strings.Split(options, ",") {
+		if size, ok := strings.CutPrefix(option, "size="); ok {
+			emptyDir.SizeLimit = kubeSize(size)
+		}
+	}
+	return emptyDir
+}
+
+// mountTargets returns the container paths of the mounts
+func mountTargets(mounts []kubeVolumeMount) []string {
+	targets := make([]string, len(mounts))
+	for i, mount := range mounts {
+		targets[i] = mount.MountPath
+	}
+	return targets
+}
+
+// kubeSize turns a docker size such as "64m" into a Kubernetes quantity such as "64Mi"
+func kubeSize(size string) string {
+	if n := len(size); n > 0 {
+		switch strings.ToLower(size[n-1:]) {
+		case "k":
+			return size[:n-1] + "Ki"
+		case "m":
+			return size[:n-1] + "Mi"
+		case "g":
+			return size[:n-1] + "Gi"
+		case "b":
+			return size[:n-1]
+		}
+	}
+	return size
+}
@@ -0,0 +1,125 @@
+package containerconfig
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Labels set by docker compose on the containers it manages
+const (
+	ComposeProjectLabel   = "com.docker.compose.project"
+	ComposeServiceLabel   = "com.docker.compose.service"
+	ComposeDependsOnLabel = "com.docker.compose.depends_on"
+	// ComposeContainerNumberLabel is the replica number of a scaled service, starting at 1
+	ComposeContainerNumberLabel = "com.docker.compose.container-number"
+	// ComposeConfigFilesLabel lists the project's compose files, comma-separated, and
+	// ComposeWorkingDirLabel the directory their relative paths resolve against
+	ComposeConfigFilesLabel = "com.docker.compose.project.config_files"
+	ComposeWorkingDirLabel  = "com.docker.compose.project.working_dir"
+)
+
+// Labels set by this tool on the containers it creates, recording the create-time
+// value of settings that docker update can change afterwards
+const (
+	CreatedMemoryLabel    = "dce.created.memory"
+	CreatedCPUsLabel      = "dce.created.cpus"
+	CreatedCPUSharesLabel = "dce.created.cpu-shares"
+	CreatedRestartLabel   = "dce.created.restart"
+)
+
+// ManagedLabel marks containers created or adopted by this tool
+const ManagedLabel = "dce.managed"
+
+// ConfigHashLabel records the ConfigHash of the spec a container was created from
+const ConfigHashLabel = "dce.config-hash"
+
+// toolLabelPrefix prefixes the labels this tool sets itself
+const toolLabelPrefix = "dce."
+
+// SourceHashLabel records on a dev container the ConfigHash of the container it was created from,
+// so a later run can tell whether the source changed since
+const SourceHashLabel = "dce.source-hash"
+
+// CompanionOfLabel marks helper containers (collectors, sidecars) with the dev container they serve
+const CompanionOfLabel = "dce.companion-of"
+
+// CloneOfLabel marks dev containers with the container they were created from, or for an image
+// target of a project the target name
+const CloneOfLabel = "dce.clone-of"
+
+// OCIImageLabelPrefix prefixes the OCI image annotation keys that images carry as labels,
+// e.g. org.opencontainers.image.source and org.opencontainers.image.revision
+const OCIImageLabelPrefix = "org.opencontainers.image."
+
+// ProvenanceLabels returns the OCI image annotations found in a label set
+func ProvenanceLabels(labels map[string]string) map[string]string {
+	provenance := make(map[string]string)
+	for key, value := range labels {
+		if strings.HasPrefix(key, OCIImageLabelPrefix) {
+			provenance[key] = value
+		}
+	}
+	return provenance
+}
+
+// Provenance returns the spec's OCI image annotations (source repository, revision, version, ...)
+// Docker merges image labels into container labels, so these are usually inherited from the image
+func (s *ContainerSpec) Provenance() map[string]string {
+	return ProvenanceLabels(s.Labels)
+}
+
+// DefaultIgnoredLabels matches labels injected by compose, Kubernetes, buildkit and Docker Desktop
+// that describe how a container was managed rather than how it is configured
+var DefaultIgnoredLabels = []string{
+	"com.docker.compose.*",
+	"io.kubernetes.*",
+	"annotation.io.kubernetes.*",
+	"io.cri-containerd.*",
+	"moby.buildkit.*",
+	"com.docker.desktop.*",
+	"desktop.docker.io/*",
+}
+
+// LabelFilter drops labels whose keys match any of a set of glob patterns
+// OCI provenance labels are never dropped
+type LabelFilter struct {
+	patterns []string
+}
+
+// NewLabelFilter creates a filter from glob patterns as understood by path.Match, e.g. "com.docker.compose.*"
+func NewLabelFilter(patterns []string) (*LabelFilter, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid label pattern '%s': %w", pattern, err)
+		}
+	}
+	return &LabelFilter{patterns: patterns}, nil
+}
+
+// Ignored reports whether a label key is dropped by the filter; a nil filter ignores nothing
+func (f *LabelFilter) Ignored(key string) bool {
+	if f == nil || strings.HasPrefix(key, OCIImageLabelPrefix) {
+		return false
+	}
+	for _, pattern := range f.patterns {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns the labels that are not ignored; the input map is left untouched
+func (f *LabelFilter) Apply(labels map[string]string) map[string]string {
+	if f == nil || labels == nil {
+		return labels
+	}
+	kept := make(map[string]string, len(labels))
+	for key, value := range labels {
+		if !f.Ignored(key) {
+			kept[key] = value
+		}
+	}
+	return kept
+}
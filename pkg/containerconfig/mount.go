@@ -0,0 +1,308 @@
+package containerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Types of a container mount
+const (
+	MountBind   = "bind"
+	MountVolume = "volume"
+	MountTmpfs  = "tmpfs"
+)
+
+// Mount is a mount of a container: a bind mount of a host path, a named or anonymous volume, or a
+// tmpfs. Files hold it as the "source:target[:options]" string of docker run -v when that string
+// says everything about it, and as a mapping otherwise
+type Mount struct {
+	Type string `json:"type" yaml:"type"`
+	// Source is the host path of a bind mount or the name of a volume; empty for an anonymous volume
+	Source   string `json:"source,omitempty" yaml:"source,omitempty"`
+	Target   string `json:"target" yaml:"target"`
+	ReadOnly bool   `json:"readOnly,omitempty" yaml:"readOnly,omitempty"`
+	// Options are the other -v options, e.g. "z", "rshared" or "nocopy"
+	Options []string `json:"options,omitempty" yaml:"options,omitempty"`
+	// VolumeDriver is the driver of a volume other than "local"
+	VolumeDriver string `json:"volumeDriver,omitempty" yaml:"volumeDriver,omitempty"`
+	// TmpfsOptions are the options of a tmpfs, e.g. "size=64m,mode=1777"
+	TmpfsOptions string `json:"tmpfsOptions,omitempty" yaml:"tmpfsOptions,omitempty"`
+}
+
+// isHostPath reports whether a -v source is a host path rather than a volume name: absolute,
+// relative, home-relative or a Windows drive or UNC path
+func isHostPath(source string) bool {
+	return strings.HasPrefix(source, "/") || strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~") ||
+		strings.HasPrefix(source, `\\`) || isWindowsDrive(source)
+}
+
+// isWindowsDrive reports whether a path starts with a drive letter, e.g. C:\data or c:/data
+func isWindowsDrive(p string) bool {
+	return len(p) >= 2 && p[1] == ':' && ('a' <= p[0] && p[0] <= 'z' || 'A' <= p[0] && p[0] <= 'Z') &&
+		(len(p) == 2 || p[2] == '\\' || p[2] == '/')
+}
+
+// splitVolume splits a -v value at its colons, keeping the colon of Windows drive letters
+func splitVolume(volume string) []string {
+	var parts []string
+	for rest := volume; ; {
+		start := 0
+		if isWindowsDrive(rest) {
+			start = 2
+		}
+		i := strings.IndexByte(rest[start:], ':')
+		if i < 0 {
+			return append(parts, rest)
+		}
+		parts = append(parts, rest[:start+i])
+		rest = rest[start+i+1:]
+	}
+}
+
+// isAnonymousVolume reports whether a volume name is one docker generated for an anonymous volume,
+// 64 hex digits
+func isAnonymousVolume(name string) bool {
+	if len(name) != 64 {
+		return false
+	}
+	for _, c := range name {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseMount parses a docker run -v value, "[source:]target[:options]"; "target:options", which
+// String gives for an anonymous volume with options, is read as one too
+func ParseMount(volume string) Mount {
+	parts := splitVolume(volume)
+	if len(parts) == 1 {
+		return Mount{Type: MountVolume, Target: parts[0]}
+	}
+	if len(parts) == 2 && !strings.HasPrefix(parts[1], "/") && !isWindowsDrive(parts[1]) {
+		parts = []string{"", parts[0], parts[1]}
+	}
+	m := Mount{Type: MountVolume, Source: parts[0], Target: parts[1]}
+	if isHostPath(m.Source) {
+		m.Type = MountBind
+	}
+	if len(parts) > 2 {
+		for _, option := range strings.Split(strings.Join(parts[2:], ":"), ",") {
+			switch option {
+			case "", "rw":
+			case "ro":
+				m.ReadOnly = true
+			default:
+				m.Options = append(m.Options, option)
+			}
+		}
+	}
+	return m
+}
+
+// ParseMounts parses docker run -v values
+func ParseMounts(volumes []string) []Mount {
+	if volumes == nil {
+		return nil
+	}
+	mounts := make([]Mount, len(volumes))
+	for i, volume := range volumes {
+		mounts[i] = ParseMount(volume)
+	}
+	return mounts
+}
+
+// String renders the mount as a docker run -v value; the volume driver and tmpfs options can't be
+// part of one, and neither can the options of an anonymous volume, which render as
+// "target:options", see RunArgs
+func (m Mount) String() string {
+	if m.Type == MountTmpfs {
+		if m.TmpfsOptions != "" {
+			return m.Target + ":" + m.TmpfsOptions
+		}
+		return m.Target
+	}
+	if m.Source == "" && !m.ReadOnly && len(m.Options) == 0 {
+		return m.Target
+	}
+	volume := m.Source + ":" + m.Target
+	if m.Source == "" {
+		volume = m.Target
+	}
+	var options []string
+	if m.ReadOnly {
+		options = append(options, "ro")
+	}
+	options = append(options, m.Options...)
+	if len(options) > 0 {
+		volume += ":" + strings.Join(options, ",")
+	}
+	return volume
+}
+
+// RunArgs returns the docker run flag of the mount: --tmpfs for a tmpfs, --mount for a volume with a
+// driver or an anonymous volume with options, which -v rejects, otherwise -v
+// --mount has no equivalent of the relabeling and propagation options, so those are left out there
+func (m Mount) RunArgs() []string {
+	if m.Type == MountTmpfs {
+		return []string{"--tmpfs", m.String()}
+	}
+	if m.VolumeDriver == "" && !m.anonymousWithOptions() {
+		return []string{"-v", m.String()}
+	}
+	fields := []string{"type=volume"}
+	if m.Source != "" {
+		fields = append(fields, "source="+m.Source)
+	}
+	fields = append(fields, "target="+m.Target)
+	if m.VolumeDriver != "" {
+		fields = append(fields, "volume-driver="+m.VolumeDriver)
+	}
+	if m.ReadOnly {
+		fields = append(fields, "readonly")
+	}
+	for _, option := range m.Options {
+		if option == "nocopy" {
+			fields = append(fields, "volume-nocopy")
+		}
+	}
+	return []string{"--mount", strings.Join(fields, ",")}
+}
+
+// anonymousWithOptions reports whether the mount is an anonymous volume that is read-only or has
+// options, which neither docker run -v nor the compose short syntax can say
+func (m Mount) anonymousWithOptions() bool {
+	return m.Type == MountVolume && m.Source == "" && (m.ReadOnly || len(m.Options) > 0)
+}
+
+// plain reports whether the -v string says everything about the mount, so files can hold it as one
+func (m Mount) plain() bool {
+	if m.Type == MountTmpfs || m.VolumeDriver != "" || m.TmpfsOptions != "" {
+		return false
+	}
+	parsed := ParseMount(m.String())
+	return parsed.Type == m.Type && parsed.Source == m.Source && parsed.Target == m.Target &&
+		parsed.ReadOnly == m.ReadOnly && equalStrings(parsed.Options, m.Options)
+}
+
+// mountFields is Mount without its marshalers, for the mapping form
+type mountFields Mount
+
+// MarshalJSON implements json.Marshaler
+func (m Mount) MarshalJSON() ([]byte, error) {
+	if m.plain() {
+		return json.Marshal(m.String())
+	}
+	return json.Marshal(mountFields(m))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting the string and the mapping form
+func (m *Mount) UnmarshalJSON(data []byte) error {
+	var volume string
+	if err := json.Unmarshal(data, &volume); err == nil {
+		*m = ParseMount(volume)
+		return nil
+	}
+	var fields mountFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*m = Mount(fields)
+	return m.validate()
+}
+
+// MarshalYAML implements yaml.Marshaler
+func (m Mount) MarshalYAML() (interface{}, error) {
+	if m.plain() {
+		return m.String(), nil
+	}
+	return mountFields(m), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, accepting the string and the mapping form
+func (m *Mount) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*m = ParseMount(node.Value)
+		return nil
+	}
+	var fields mountFields
+	if err := node.Decode(&fields); err != nil {
+		return err
+	}
+	*m = Mount(fields)
+	return m.validate()
+}
+
+// validate checks a mount given in the mapping form
+func (m Mount) validate() error {
+	switch m.Type {
+	case MountBind, MountVolume, MountTmpfs:
+	default:
+		return fmt.Errorf("mount %s: invalid type '%s', expected %s, %s or %s", m.Target, m.Type, MountBind, MountVolume, MountTmpfs)
+	}
+	if m.Target == "" {
+		return fmt.Errorf("%s mount without a target", m.Type)
+	}
+	return nil
+}
+
+// Named reports whether the mount is a named volume
+func (m Mount) Named() bool {
+	return m.Type == MountVolume && m.Source != ""
+}
+
+// canonical returns the mount with clean paths and sorted options, so equal mounts compare equal
+func (m Mount) canonical() Mount {
+	if m.Type == MountBind && strings.HasPrefix(m.Source, "/") {
+		m.Source = path.Clean(m.Source)
+	}
+	if strings.HasPrefix(m.Target, "/") {
+		m.Target = path.Clean(m.Target)
+	}
+	if len(m.Options) > 0 {
+		m.Options = append([]string(nil), m.Options...)
+		sort.Strings(m.Options)
+	}
+	return m
+}
+
+// VolumeStrings returns the spec's mounts as docker run -v values, see Mount.String
+func (s *ContainerSpec) VolumeStrings() []string {
+	if s.Volumes == nil {
+		return nil
+	}
+	volumes := make([]string, len(s.Volumes))
+	for i, m := range s.Volumes {
+		volumes[i] = m.String()
+	}
+	return volumes
+}
+
+// TmpfsMounts returns the spec's tmpfs mounts as mounts
+func (s *ContainerSpec) TmpfsMounts() []Mount {
+	var mounts []Mount
+	for _, tmpfs := range s.Tmpfs {
+		target, options, _ := strings.Cut(tmpfs, ":")
+		mounts = append(mounts, Mount{Type: MountTmpfs, Target: target, TmpfsOptions: options})
+	}
+	return mounts
+}
+
+// cloneMounts copies mounts, keeping nil as nil
+func cloneMounts(mounts []Mount) []Mount {
+	if mounts == nil {
+		return nil
+	}
+	clone := make([]Mount, len(mounts))
+	for i, m := range mounts {
+		m.Options = cloneStrings(m.Options)
+		clone[i] = m
+	}
+	return clone
+}
@@ -0,0 +1,131 @@
+package containerconfig_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+func TestParseMount(t *testing.T) {
+	tests := []struct {
+		volume string
+		want   containerconfig.Mount
+	}{
+		{"/data", containerconfig.Mount{Type: containerconfig.MountVolume, Target: "/data"}},
+		{"/data:ro", containerconfig.Mount{Type: containerconfig.MountVolume, Target: "/data", ReadOnly: true}},
+		{"/data:ro,nocopy", containerconfig.Mount{Type: containerconfig.MountVolume, Target: "/data", ReadOnly: true, Options: []string{"nocopy"}}},
+		{"pgdata:/var/lib/postgresql/data", containerconfig.Mount{Type: containerconfig.MountVolume, Source: "pgdata", Target: "/var/lib/postgresql/data"}},
+		{"/srv/app:/app:ro", containerconfig.Mount{Type: containerconfig.MountBind, Source: "/srv/app", Target: "/app", ReadOnly: true}},
+		{"./src:/src:rw,z", containerconfig.Mount{Type: containerconfig.MountBind, Source: "./src", Target: "/src", Options: []string{"z"}}},
+		{"~/.ssh:/root/.ssh", containerconfig.Mount{Type: containerconfig.MountBind, Source: "~/.ssh", Target: "/root/.ssh"}},
+		{`C:\data:C:\app`, containerconfig.Mount{Type: containerconfig.MountBind, Source: `C:\data`, Target: `C:\app`}},
+		{`c:/data:/app:ro`, containerconfig.Mount{Type: containerconfig.MountBind, Source: `c:/data`, Target: "/app", ReadOnly: true}},
+		{`\\server\share:C:\share`, containerconfig.Mount{Type: containerconfig.MountBind, Source: `\\server\share`, Target: `C:\share`}},
+		{`C:\cache`, containerconfig.Mount{Type: containerconfig.MountVolume, Target: `C:\cache`}},
+	}
+	for _, tt := range tests {
+		got := containerconfig.ParseMount(tt.volume)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseMount(%q) = %+v, want %+v", tt.volume, got, tt.want)
+		}
+	}
+}
+
+func TestMountString(t *testing.T) {
+	tests := []struct {
+		mount containerconfig.Mount
+		want  string
+	}{
+		{containerconfig.Mount{Type: containerconfig.MountVolume, Target: "/data"}, "/data"},
+		{containerconfig.Mount{Type: containerconfig.MountVolume, Target: "/data", ReadOnly: true}, "/data:ro"},
+		{containerconfig.Mount{Type: containerconfig.MountVolume, Source: "pgdata", Target: "/data", Options: []string{"nocopy"}}, "pgdata:/data:nocopy"},
+		{containerconfig.Mount{Type: containerconfig.MountBind, Source: "/srv", Target: "/srv", ReadOnly: true, Options: []string{"Z"}}, "/srv:/srv:ro,Z"},
+		{containerconfig.Mount{Type: containerconfig.MountTmpfs, Target: "/run", TmpfsOptions: "size=64m"}, "/run:size=64m"},
+	}
+	for _, tt := range tests {
+		if got := tt.mount.String(); got != tt.want {
+			t.Errorf("%+v.String() = %s, want %s", tt.mount, got, tt.want)
+		}
+		if tt.mount.Type == containerconfig.MountTmpfs {
+			continue
+		}
+		if back := containerconfig.ParseMount(tt.want); !reflect.DeepEqual(back, tt.mount) {
+			t.Errorf("ParseMount(%q) = %+v, want %+v", tt.want, back, tt.mount)
+		}
+	}
+}
+
+func TestMountRunArgs(t *testing.T) {
+	tests := []struct {
+		mount containerconfig.Mount
+		want  string
+	}{
+		{containerconfig.ParseMount("/srv:/srv:ro"), "-v /srv:/srv:ro"},
+		{containerconfig.ParseMount("/data"), "-v /data"},
+		{containerconfig.ParseMount("/data:ro,nocopy"), "--mount type=volume,target=/data,readonly,volume-nocopy"},
+		{containerconfig.Mount{Type: containerconfig.MountVolume, Source: "shared", Target: "/data", VolumeDriver: "nfs", ReadOnly: true},
+			"--mount type=volume,source=shared,target=/data,volume-driver=nfs,readonly"},
+		{containerconfig.Mount{Type: containerconfig.MountTmpfs, Target: "/run", TmpfsOptions: "size=64m"}, "--tmpfs /run:size=64m"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(tt.mount.RunArgs(), " "); got != tt.want {
+			t.Errorf("%+v.RunArgs() = %s, want %s", tt.mount, got, tt.want)
+		}
+	}
+}
+
+func TestMountJSON(t *testing.T) {
+	mounts := []containerconfig.Mount{
+		containerconfig.ParseMount("/srv:/srv:ro"),
+		containerconfig.ParseMount("/data:ro"),
+		{Type: containerconfig.MountVolume, Source: "shared", Target: "/data", VolumeDriver: "nfs"},
+	}
+	data, err := json.Marshal(mounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `["/srv:/srv:ro","/data:ro",{"type":"volume","source":"shared","target":"/data","volumeDriver":"nfs"}]`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+	var back []containerconfig.Mount
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, mounts) {
+		t.Errorf("read back %+v, want %+v", back, mounts)
+	}
+}
+
+func TestParseAnonymousVolumes(t *testing.T) {
+	const inspect = `[{"Name": "/db", "Config": {"Image": "postgres"}, "Mounts": [
+		{"Type": "volume", "Name": "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", "Destination": "/var/lib/postgresql/data", "Driver": "local", "RW": false},
+		{"Type": "volume", "Name": "pgbackup", "Destination": "/backup", "Driver": "local", "RW": true}
+	]}]`
+
+	spec, warnings, err := containerconfig.ParseInspectJSONWithWarnings(inspect, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(spec.VolumeStrings(), " "); got != "/var/lib/postgresql/data:ro pgbackup:/backup" {
+		t.Errorf("got volumes %s", got)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "anonymous volume at /var/lib/postgresql/data") {
+		t.Errorf("got warnings %v, want one about the anonymous volume", warnings)
+	}
+	args := strings.Join(containerconfig.GenerateRunCommand(spec, nil), " ")
+	if !strings.Contains(args, "--mount type=volume,target=/var/lib/postgresql/data,readonly") {
+		t.Errorf("generated %s", args)
+	}
+
+	spec, err = containerconfig.ParseInspectJSONWithOptions(inspect, &containerconfig.ParseOptions{KeepAnonymousVolumes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source := spec.Volumes[0].Source; source == "" {
+		t.Error("KeepAnonymousVolumes dropped the volume name")
+	}
+}
@@ -0,0 +1,103 @@
+package containerconfig
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Kinds of names a naming template produces; each has its own character rules
+const (
+	NameContainer = "container"
+	NameVolume    = "volume"
+	NameImage     = "image"
+)
+
+// Naming holds the Go templates that name what the tool creates for a dev container, configured
+// once in a project file so a team's names stay consistent, e.g. {{.Name}}-dev-{{env "USER"}}
+type Naming struct {
+	// Container names dev containers; {{.N}} is the lowest clone number whose name is free
+	Container string `yaml:"container,omitempty"`
+	// Volume names the volume copies of ephemeral dev containers; {{.Volume}} is the copied volume
+	Volume string `yaml:"volume,omitempty"`
+	// Image tags the derived images of --tools image
+	Image string `yaml:"image,omitempty"`
+}
+
+// NameData is what naming templates reference: the original's spec fields ({{.Name}}, {{.Image}},
+// {{index .Labels "key"}}, ...) and the fields below
+type NameData struct {
+	*ContainerSpec
+	// Source is the original container's name, the same as .Name
+	Source string
+	// User is the current user, reduced to characters valid in names
+	User string
+	// N is the clone number
+	N int
+	// DevName is the dev container's name, for volume and image templates
+	DevName string
+	// Volume is the volume being copied, for volume templates
+	Volume string
+}
+
+// nameFuncs are the functions available to naming templates besides the builtins
+var nameFuncs = template.FuncMap{
+	"env":     os.Getenv,
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"replace": strings.ReplaceAll,
+}
+
+// Characters not allowed in container and volume names, and in image references
+var (
+	invalidNameChars  = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+	invalidImageChars = regexp.MustCompile(`[^a-z0-9_.:/-]+`)
+)
+
+// ParseNameTemplate parses a naming template; unknown fields are errors rather than empty names
+func ParseNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("name").Funcs(nameFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid naming template '%s': %w", text, err)
+	}
+	return tmpl, nil
+}
+
+// RenderName renders a naming template and replaces the characters the kind of name doesn't allow
+// with dashes; image references are also lowercased
+func RenderName(tmpl *template.Template, kind string, data NameData) (string, error) {
+	if data.ContainerSpec == nil {
+		data.ContainerSpec = &ContainerSpec{}
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render %s name: %w", kind, err)
+	}
+	name := b.String()
+	if kind == NameImage {
+		name = invalidImageChars.ReplaceAllString(strings.ToLower(name), "-")
+	} else {
+		name = invalidNameChars.ReplaceAllString(name, "-")
+	}
+	name = strings.Trim(name, "-.")
+	if name == "" {
+		return "", fmt.Errorf("%s name template '%s' rendered an empty name", kind, tmpl.Root.String())
+	}
+	return name, nil
+}
+
+// CurrentUser returns the name of the user running the tool, reduced to characters valid in names
+func CurrentUser() string {
+	name := os.Getenv("USER")
+	if name == "" {
+		if u, err := user.Current(); err == nil {
+			name = u.Username
+		}
+	}
+	// Windows user names come as DOMAIN\user
+	name = name[strings.LastIndex(name, `\`)+1:]
+	return strings.ToLower(invalidNameChars.ReplaceAllString(name, "-"))
+}
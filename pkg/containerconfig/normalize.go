@@ -0,0 +1,158 @@
+package containerconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Normalize puts the spec into canonical form in place: set-like lists are sorted and
+// de-duplicated, ports and mounts are canonicalized and empty entries are dropped
+// Command and entrypoint keep their order since it is significant
+func (s *ContainerSpec) Normalize() {
+	s.Env = normalizeEnv(s.Env)
+	s.Volumes = normalizeMounts(s.Volumes)
+	s.Ports = normalizePorts(s.Ports)
+	s.Networks = normalizeList(s.Networks, nil)
+	s.Devices = normalizeList(s.Devices, nil)
+	s.ExtraHosts = normalizeList(s.ExtraHosts, nil)
+	s.Links = normalizeList(s.Links, nil)
+	s.NetworkAliases = normalizeList(s.NetworkAliases, nil)
+	s.VolumesFrom = normalizeList(s.VolumesFrom, nil)
+	s.Tmpfs = normalizeList(s.Tmpfs, nil)
+	s.Ulimits = normalizeList(s.Ulimits, nil)
+	s.CapAdd = normalizeList(s.CapAdd, strings.ToUpper)
+
+	if len(s.Command) == 0 {
+		s.Command = nil
+	}
+	if s.CommandForm == FormExec {
+		s.CommandForm = ""
+	}
+	if s.EntryPointForm == FormExec {
+		s.EntryPointForm = ""
+	}
+	if len(s.EntryPoint) == 0 {
+		s.EntryPoint = nil
+	}
+	if len(s.Labels) == 0 {
+		s.Labels = nil
+	}
+	if s.WorkingDir != "" && s.WorkingDir != "/" {
+		s.WorkingDir = strings.TrimSuffix(s.WorkingDir, "/")
+	}
+	if s.Restart == "no" {
+		s.Restart = ""
+	}
+}
+
+// Equal reports whether two specs describe the same configuration once normalized
+// The informational ImageID, ImageEnv, Platform, Runtime and DevNotes are not compared
+func (s *ContainerSpec) Equal(other *ContainerSpec) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+	return s.Hash() == other.Hash()
+}
+
+// Hash returns a stable SHA-256 hex digest of the normalized spec, suitable for caching and change detection
+func (s *ContainerSpec) Hash() string {
+	// Struct fields marshal in declaration order and map keys sorted, so the JSON form is canonical
+	data, _ := json.Marshal(s.normalized())
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ConfigHash returns the Hash of the spec without the labels this tool sets, so stamping a spec
+// with its create-time values or the hash itself leaves the hash unchanged
+func (s *ContainerSpec) ConfigHash() string {
+	clone := s.Clone()
+	for key := range clone.Labels {
+		if strings.HasPrefix(key, toolLabelPrefix) {
+			delete(clone.Labels, key)
+		}
+	}
+	return clone.Hash()
+}
+
+// StampConfigHash records the spec's ConfigHash in its ConfigHashLabel, so whether a container still
+// matches the spec it was created from can be told from its labels alone
+func StampConfigHash(spec *ContainerSpec) {
+	if spec.Labels == nil {
+		spec.Labels = make(map[string]string)
+	}
+	spec.Labels[ConfigHashLabel] = spec.ConfigHash()
+}
+
+// normalized returns a normalized copy of the spec, leaving the original untouched
+func (s *ContainerSpec) normalized() *ContainerSpec {
+	normalized := s.Clone()
+	normalized.Normalize()
+	normalized.ImageID = ""
+	normalized.ImageEnv = nil
+	normalized.ImageEntryPoint = nil
+	normalized.ImageCommand = nil
+	normalized.Platform = ""
+	normalized.Runtime = nil
+	normalized.DevNotes = nil
+	return normalized
+}
+
+// normalizeList trims, canonicalizes, de-duplicates and sorts a list, returning nil when it ends up empty
+func normalizeList(values []string, canonical func(string) string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if canonical != nil && value != "" {
+			value = canonical(value)
+		}
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// normalizeEnv keeps the last value of every variable, like docker does, and sorts by name
+func normalizeEnv(env []string) []string {
+	values := make(map[string]string, len(env))
+	for _, entry := range env {
+		key, _, _ := SplitEnv(entry)
+		if strings.TrimSpace(key) == "" {
+			continue
+		}
+		values[key] = entry
+	}
+	var result []string
+	for _, entry := range values {
+		result = append(result, entry)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// normalizeMounts canonicalizes the mounts, drops duplicates and sorts them by their -v form
+func normalizeMounts(mounts []Mount) []Mount {
+	seen := make(map[string]bool, len(mounts))
+	var result []Mount
+	for _, m := range mounts {
+		if m.Target == "" {
+			continue
+		}
+		m = m.canonical()
+		key := strings.Join([]string{m.Type, m.String(), m.VolumeDriver, m.TmpfsOptions}, "\x00")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, m)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].String() < result[j].String() })
+	return result
+}
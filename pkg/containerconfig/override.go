@@ -0,0 +1,326 @@
+package containerconfig
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Override formats of suggest-override
+const (
+	OverrideFormatCompose = "compose"
+	OverrideFormatFlags   = "flags"
+)
+
+// Override is the part of a dev spec that differs from the spec it was derived from, for users
+// who apply the dev modifications to a compose- or k8s-managed container themselves
+type Override struct {
+	Env        []string
+	Volumes    []string
+	Ports      []string
+	ExtraHosts []string
+	CapAdd     []string
+	Tmpfs      []string
+	Ulimits    []string
+	Labels     map[string]string
+	// EntryPoint and Command are set only when the dev spec replaces them
+	EntryPoint []string
+	Command    []string
+	WorkingDir string
+	// Restart is "no" when the dev spec drops the restart policy
+	Restart string
+	// NoHealthcheck is set when the dev spec disables the healthcheck
+	NoHealthcheck  bool
+	NetworkAliases []string
+	// Notes are the dev spec's DevNotes, saying why each value was added
+	Notes map[string]string
+}
+
+// NewOverride returns the modifications that turn base into dev; opts may loosen read-only mounts
+// and override the working directory as in the generated command. Removals other than the restart policy can't be expressed and are dropped
+func NewOverride(base, dev *ContainerSpec, opts *RunOptions) *Override {
+	if opts == nil {
+		opts = &RunOptions{}
+	}
+	o := &Override{Notes: cloneMap(dev.DevNotes)}
+
+	for _, env := range dev.Env {
+		key, value, _ := SplitEnv(env)
+		if old, ok := base.Env.Get(key); !ok || old != value {
+			o.Env = append(o.Env, env)
+		}
+	}
+
+	baseVolumes := make(map[string]bool)
+	for _, m := range base.Volumes {
+		baseVolumes[m.String()] = true
+	}
+	for _, m := range dev.Volumes {
+		if opts.writable(m.Target) {
+			m.ReadOnly = false
+		}
+		if !baseVolumes[m.String()] {
+			o.Volumes = append(o.Volumes, m.String())
+		}
+	}
+
+	o.Ports = addedStrings(base.PortStrings(), dev.PortStrings())
+	o.ExtraHosts = addedStrings(base.ExtraHosts, dev.ExtraHosts)
+	o.CapAdd = addedStrings(base.CapAdd, dev.CapAdd)
+	o.Tmpfs = addedStrings(base.Tmpfs, dev.Tmpfs)
+	o.Ulimits = addedStrings(base.Ulimits, dev.Ulimits)
+	o.NetworkAliases = addedStrings(base.NetworkAliases, dev.NetworkAliases)
+
+	for key, value := range dev.Labels {
+		if old, ok := base.Labels[key]; !ok || old != value {
+			if o.Labels == nil {
+				o.Labels = make(map[string]string)
+			}
+			o.Labels[key] = value
+		}
+	}
+
+	if !equalStrings(base.EntryPointArgs(), dev.EntryPointArgs()) {
+		o.EntryPoint = dev.EntryPointArgs()
+	}
+	// Replacing the entrypoint resets the image's command, so the command is restated with it
+	if o.EntryPoint != nil || !equalStrings(base.CommandArgs(), dev.CommandArgs()) {
+		o.Command = dev.CommandArgs()
+	}
+	if dev.WorkingDir != base.WorkingDir {
+		o.WorkingDir = dev.WorkingDir
+	}
+	if opts.WorkingDirOverride != "" && opts.WorkingDirOverride != base.WorkingDir {
+		o.WorkingDir = opts.WorkingDirOverride
+		if o.Notes == nil {
+			o.Notes = make(map[string]string)
+		}
+		o.Notes["-w "+o.WorkingDir] = "working directory override"
+	}
+	if dev.Restart != base.Restart {
+		o.Restart = dev.Restart
+		if o.Restart == "" {
+			o.Restart = "no"
+		}
+	}
+	o.NoHealthcheck = dev.Healthcheck.Disabled() && !base.Healthcheck.Disabled()
+	return o
+}
+
+// parseLimit parses a ulimit value, which FormatUlimit wrote
+func parseLimit(value string) int64 {
+	limit, _ := strconv.ParseInt(value, 10, 64)
+	return limit
+}
+
+// addedStrings returns the values of dev not in base
+func addedStrings(base, dev []string) []string {
+	seen := make(map[string]bool, len(base))
+	for _, value := range base {
+		seen[value] = true
+	}
+	var added []string
+	for _, value := range dev {
+		if !seen[value] {
+			added = append(added, value)
+		}
+	}
+	return added
+}
+
+// equalStrings reports whether two string slices have the same values in the same order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty reports whether the override changes nothing
+func (o *Override) Empty() bool {
+	return len(o.Env) == 0 && len(o.Volumes) == 0 && len(o.Ports) == 0 && len(o.ExtraHosts) == 0 &&
+		len(o.CapAdd) == 0 && len(o.Tmpfs) == 0 && len(o.Ulimits) == 0 && len(o.Labels) == 0 && o.EntryPoint == nil && o.Command == nil && o.WorkingDir == "" &&
+		o.Restart == "" && !o.NoHealthcheck && len(o.NetworkAliases) == 0
+}
+
+// composeOverrideService is a service in a docker-compose.override.yml; compose merges ports,
+// volumes (by container path), environment, labels and extra_hosts into the base service and
+// replaces entrypoint and command
+type composeOverrideService struct {
+	Entrypoint  []string          `yaml:"entrypoint,omitempty"`
+	Command     []string          `yaml:"command,omitempty"`
+	WorkingDir  string            `yaml:"working_dir,omitempty"`
+	Environment map[string]string `yaml:"environment,omitempty"`
+	Ports       []string          `yaml:"ports,omitempty"`
+	Volumes     []string          `yaml:"volumes,omitempty"`
+	ExtraHosts  []string          `yaml:"extra_hosts,omitempty"`
+	CapAdd      []string          `yaml:"cap_add,omitempty"`
+	Tmpfs       []string          `yaml:"tmpfs,omitempty"`
+	Ulimits     map[string]any    `yaml:"ulimits,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Restart     string            `yaml:"restart,omitempty"`
+	Healthcheck *composeDisable   `yaml:"healthcheck,omitempty"`
+}
+
+// composeDisable is a compose healthcheck turned off
+type composeDisable struct {
+	Disable bool `yaml:"disable"`
+}
+
+// ComposeYAML renders the override as a docker-compose.override.yml for the given service
+// Network aliases are left out: compose sets them per network, which the override doesn't know
+func (o *Override) ComposeYAML(service string) ([]byte, error) {
+	svc := composeOverrideService{
+		Entrypoint: o.EntryPoint,
+		Command:    o.Command,
+		WorkingDir: o.WorkingDir,
+		Ports:      o.Ports,
+		Volumes:    o.Volumes,
+		ExtraHosts: o.ExtraHosts,
+		CapAdd:     o.CapAdd,
+		Tmpfs:      o.Tmpfs,
+		Labels:     o.Labels,
+		Restart:    o.Restart,
+	}
+	if o.NoHealthcheck {
+		svc.Healthcheck = &composeDisable{Disable: true}
+	}
+	// Compose takes a single number when the soft and hard limits are equal
+	for _, ulimit := range o.Ulimits {
+		name, limits, _ := strings.Cut(ulimit, "=")
+		soft, hard, found := strings.Cut(limits, ":")
+		if svc.Ulimits == nil {
+			svc.Ulimits = make(map[string]any)
+		}
+		if found {
+			svc.Ulimits[name] = composeUlimit{Soft: parseLimit(soft), Hard: parseLimit(hard)}
+		} else {
+			svc.Ulimits[name] = parseLimit(soft)
+		}
+	}
+	if len(o.Env) > 0 {
+		svc.Environment = Env(o.Env).Map()
+	}
+
+	data, err := annotatedYAML(map[string]map[string]composeOverrideService{"services": {service: svc}}, o.composeComment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal compose override: %w", err)
+	}
+	return data, nil
+}
+
+// composeFlags maps the fields of a compose override service to the run flag of their values
+var composeFlags = map[string]string{
+	"working_dir": "-w",
+	"environment": "-e",
+	"ports":       "-p",
+	"volumes":     "-v",
+	"extra_hosts": "--add-host",
+	"cap_add":     "--cap-add",
+	"tmpfs":       "--tmpfs",
+	"ulimits":     "--ulimit",
+	"labels":      "-l",
+	"restart":     "--restart",
+}
+
+// composeComment returns the note of a value in the compose override, looked up by its run flag
+func (o *Override) composeComment(path []string, value *yaml.Node) string {
+	if len(path) < 3 {
+		return ""
+	}
+	field := path[2]
+	var key string
+	switch {
+	case field == "entrypoint" && len(path) == 3 && len(value.Content) > 0:
+		key = "--entrypoint " + value.Content[0].Value
+	case field == "command" && len(path) == 3:
+		key = CommandNoteKey
+	case field == "healthcheck" && len(path) == 3:
+		key = "--no-healthcheck"
+	case field == "environment" || field == "labels" || field == "ulimits":
+		if len(path) != 4 {
+			return ""
+		}
+		key = composeFlags[field] + " " + path[3] + "=" + value.Value
+	case composeFlags[field] != "" && (len(path) == 4 || value.Kind == yaml.ScalarNode):
+		key = composeFlags[field] + " " + value.Value
+	default:
+		return ""
+	}
+	if note := o.Notes[key]; note != "" {
+		return "added by dce: " + note
+	}
+	return ""
+}
+
+// RunFlags renders the override as docker run flags to add to an existing command
+func (o *Override) RunFlags() []string {
+	var args []string
+	for _, env := range o.Env {
+		args = append(args, "-e", env)
+	}
+	for _, vol := range o.Volumes {
+		args = append(args, "-v", vol)
+	}
+	for _, port := range o.Ports {
+		args = append(args, "-p", port)
+	}
+	for _, host := range o.ExtraHosts {
+		args = append(args, "--add-host", host)
+	}
+	for _, capability := range o.CapAdd {
+		args = append(args, "--cap-add", capability)
+	}
+	for _, tmpfs := range o.Tmpfs {
+		args = append(args, "--tmpfs", tmpfs)
+	}
+	for _, ulimit := range o.Ulimits {
+		args = append(args, "--ulimit", ulimit)
+	}
+	for _, alias := range o.NetworkAliases {
+		args = append(args, "--network-alias", alias)
+	}
+	keys := make([]string, 0, len(o.Labels))
+	for key := range o.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--label", key+"="+o.Labels[key])
+	}
+	if o.WorkingDir != "" {
+		args = append(args, "-w", o.WorkingDir)
+	}
+	if o.Restart != "" {
+		args = append(args, "--restart", o.Restart)
+	}
+	if o.NoHealthcheck {
+		args = append(args, "--no-healthcheck")
+	}
+	// docker run takes a single --entrypoint executable; the rest of the argv moves to the command
+	if len(o.EntryPoint) > 0 {
+		args = append(args, "--entrypoint", o.EntryPoint[0])
+	}
+	return args
+}
+
+// RunCommandArgs returns the arguments that follow the image in docker run, or nil when the
+// override keeps the container's entrypoint and command
+func (o *Override) RunCommandArgs() []string {
+	if o.EntryPoint == nil && o.Command == nil {
+		return nil
+	}
+	var args []string
+	if len(o.EntryPoint) > 1 {
+		args = append(args, o.EntryPoint[1:]...)
+	}
+	return append(args, o.Command...)
+}
@@ -3,30 +3,42 @@ package containerconfig
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 )
 
 // InspectData represents the structure of docker inspect JSON output
 type InspectData struct {
-	Name   string `json:"Name"`
-	Config struct {
-		Image      string            `json:"Image"`
-		Env        []string          `json:"Env"`
-		Cmd        []string          `json:"Cmd"`
-		Entrypoint []string          `json:"Entrypoint"`
-		Labels     map[string]string `json:"Labels"`
-		WorkingDir string            `json:"WorkingDir"`
+	ID       string `json:"Id"`
+	Name     string `json:"Name"`
+	Image    string `json:"Image"`
+	Platform string `json:"Platform"`
+	// AppArmorProfile is the profile the container was started with, "docker-default" unless set
+	AppArmorProfile string `json:"AppArmorProfile"`
+	Config          struct {
+		Image       string              `json:"Image"`
+		User        string              `json:"User"`
+		Env         []string            `json:"Env"`
+		Cmd         []string            `json:"Cmd"`
+		Entrypoint  []string            `json:"Entrypoint"`
+		Labels      map[string]string   `json:"Labels"`
+		WorkingDir  string              `json:"WorkingDir"`
+		Healthcheck *inspectHealthcheck `json:"Healthcheck"`
 	} `json:"Config"`
 	Mounts []struct {
 		Type        string `json:"Type"`
+		Name        string `json:"Name"`
 		Source      string `json:"Source"`
 		Destination string `json:"Destination"`
+		Driver      string `json:"Driver"`
 		Mode        string `json:"Mode"`
 		RW          bool   `json:"RW"`
 	} `json:"Mounts"`
 	NetworkSettings struct {
-		Networks map[string]interface{} `json:"Networks"`
-		Ports    map[string][]struct {
+		Networks map[string]struct {
+			Aliases []string `json:"Aliases"`
+		} `json:"Networks"`
+		Ports map[string][]struct {
 			HostIP   string `json:"HostIp"`
 			HostPort string `json:"HostPort"`
 		} `json:"Ports"`
@@ -37,82 +49,325 @@ type InspectData struct {
 			PathInContainer   string `json:"PathInContainer"`
 			CgroupPermissions string `json:"CgroupPermissions"`
 		} `json:"Devices"`
-		RestartPolicy struct {
+		DeviceRequests []deviceRequest   `json:"DeviceRequests"`
+		Annotations    map[string]string `json:"Annotations"`
+		RestartPolicy  struct {
 			Name              string `json:"Name"`
 			MaximumRetryCount int    `json:"MaximumRetryCount"`
 		} `json:"RestartPolicy"`
-		ExtraHosts []string `json:"ExtraHosts"`
+		ExtraHosts  []string          `json:"ExtraHosts"`
+		Links       []string          `json:"Links"`
+		NetworkMode string            `json:"NetworkMode"`
+		VolumesFrom []string          `json:"VolumesFrom"`
+		Tmpfs       map[string]string `json:"Tmpfs"`
+		Ulimits     []struct {
+			Name string `json:"Name"`
+			Soft int64  `json:"Soft"`
+			Hard int64  `json:"Hard"`
+		} `json:"Ulimits"`
+		// Mounts are the --mount options; only tmpfs ones are read, the rest show up in .Mounts
+		Mounts []struct {
+			Type         string `json:"Type"`
+			Target       string `json:"Target"`
+			TmpfsOptions *struct {
+				SizeBytes int64  `json:"SizeBytes"`
+				Mode      uint32 `json:"Mode"`
+			} `json:"TmpfsOptions"`
+		} `json:"Mounts"`
+		Memory    int64 `json:"Memory"`
+		NanoCpus  int64 `json:"NanoCpus"`
+		CpuShares int64 `json:"CpuShares"`
+		// MemorySwappiness is null, or -1 on older daemons, when not set
+		MemorySwappiness  *int64   `json:"MemorySwappiness"`
+		KernelMemory      int64    `json:"KernelMemory"`
+		MemorySwap        int64    `json:"MemorySwap"`
+		MemoryReservation int64    `json:"MemoryReservation"`
+		CpuQuota          int64    `json:"CpuQuota"`
+		CpuPeriod         int64    `json:"CpuPeriod"`
+		Privileged        bool     `json:"Privileged"`
+		CapAdd            []string `json:"CapAdd"`
 	} `json:"HostConfig"`
 }
 
+// ParseOptions controls optional parser behavior
+type ParseOptions struct {
+	// LabelFilter drops matching labels from the parsed spec; nil keeps all labels
+	LabelFilter *LabelFilter
+	// KeepAnonymousVolumes keeps the generated names of anonymous volumes, for recreating the same
+	// container with its data; otherwise they are left unnamed, so a copy gets new, empty ones
+	// instead of sharing the original's
+	KeepAnonymousVolumes bool
+}
+
 // ParseInspectJSON parses docker inspect JSON output and returns ContainerSpec
 func ParseInspectJSON(jsonData string) (*ContainerSpec, error) {
+	return ParseInspectJSONWithOptions(jsonData, nil)
+}
+
+// ParseInspectJSONWithOptions parses docker inspect JSON output using the given options
+func ParseInspectJSONWithOptions(jsonData string, opts *ParseOptions) (*ContainerSpec, error) {
+	spec, _, err := ParseInspectJSONWithWarnings(jsonData, opts)
+	return spec, err
+}
+
+// ParseInspectJSONWithWarnings parses docker inspect JSON output like ParseInspectJSONWithOptions
+// and also returns a warning for every setting of the container the spec leaves out or simplifies
+func ParseInspectJSONWithWarnings(jsonData string, opts *ParseOptions) (*ContainerSpec, []Warning, error) {
 	var inspectArray []InspectData
 	if err := json.Unmarshal([]byte(jsonData), &inspectArray); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
 	if len(inspectArray) == 0 {
-		return nil, fmt.Errorf("empty inspect data")
+		return nil, nil, fmt.Errorf("empty inspect data")
+	}
+
+	var warnings []Warning
+	add := func(field, format string, args ...interface{}) {
+		warnings = append(warnings, Warning{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	if len(inspectArray) > 1 {
+		add("inspect", "the input holds %d containers; only the first, '%s', is parsed", len(inspectArray), strings.TrimPrefix(inspectArray[0].Name, "/"))
 	}
 
 	data := inspectArray[0]
 	spec := &ContainerSpec{
-		Name:       strings.TrimPrefix(data.Name, "/"),
-		Image:      data.Config.Image,
-		Env:        data.Config.Env,
-		Command:    data.Config.Cmd,
-		EntryPoint: data.Config.Entrypoint,
-		Labels:     data.Config.Labels,
-		WorkingDir: data.Config.WorkingDir,
+		Name:        strings.TrimPrefix(data.Name, "/"),
+		Image:       data.Config.Image,
+		Env:         data.Config.Env,
+		Command:     data.Config.Cmd,
+		EntryPoint:  data.Config.Entrypoint,
+		Labels:      data.Config.Labels,
+		WorkingDir:  data.Config.WorkingDir,
+		User:        data.Config.User,
+		ImageID:     data.Image,
+		Platform:    data.Platform,
+		Privileged:  data.HostConfig.Privileged,
+		CapAdd:      data.HostConfig.CapAdd,
+		Healthcheck: data.Config.Healthcheck.healthcheck(),
+
+		AppArmorProfile: appArmorProfile(data.AppArmorProfile, data.HostConfig.Privileged),
+	}
+
+	// Docker keeps shell-form instructions as "/bin/sh -c <string>"; remember the form for exports
+	if DetectForm(spec.Command) == FormShell {
+		spec.CommandForm = FormShell
+	}
+	if DetectForm(spec.EntryPoint) == FormShell {
+		spec.EntryPointForm = FormShell
 	}
 
 	// Parse volumes from mounts
 	for _, mount := range data.Mounts {
-		var volumeStr string
-		if mount.Type == "bind" {
-			volumeStr = fmt.Sprintf("%s:%s", mount.Source, mount.Destination)
-		} else if mount.Type == "volume" {
-			volumeStr = fmt.Sprintf("%s:%s", mount.Source, mount.Destination)
-		}
-		if volumeStr != "" {
-			if !mount.RW {
-				volumeStr += ":ro"
+		m := Mount{Type: mount.Type, Target: mount.Destination, ReadOnly: !mount.RW}
+		switch mount.Type {
+		case MountBind:
+			m.Source = mount.Source
+			if relabel := selinuxMountOption(mount.Mode); relabel != "" {
+				m.Options = append(m.Options, relabel)
+			}
+		case MountVolume:
+			if isAnonymousVolume(mount.Name) && (opts == nil || !opts.KeepAnonymousVolumes) {
+				add("mounts", "anonymous volume at %s is left unnamed; a container created from the spec gets a new, empty one", mount.Destination)
+			} else {
+				m.Source = mount.Name
+			}
+			if mount.Driver != "" && mount.Driver != "local" {
+				m.VolumeDriver = mount.Driver
 			}
-			spec.Volumes = append(spec.Volumes, volumeStr)
+		case MountTmpfs:
+			// tmpfs mounts are skipped here and read from HostConfig, which has their options
+			continue
+		default:
+			add("mounts", "%s mount at %s is skipped; only bind mounts, volumes and tmpfs are kept", mount.Type, mount.Destination)
+			continue
 		}
+		if dropped := droppedMountOptions(mount.Mode); dropped != "" {
+			add("mounts", "mount options '%s' of %s are dropped", dropped, mount.Destination)
+		}
+		spec.Volumes = append(spec.Volumes, m)
 	}
 
 	// Parse ports
-	for containerPort, bindings := range data.NetworkSettings.Ports {
-		if len(bindings) > 0 {
-			for _, binding := range bindings {
-				if binding.HostPort != "" {
-					portStr := fmt.Sprintf("%s:%s", binding.HostPort, strings.Split(containerPort, "/")[0])
-					spec.Ports = append(spec.Ports, portStr)
-				}
+	containerPorts := make([]string, 0, len(data.NetworkSettings.Ports))
+	for containerPort := range data.NetworkSettings.Ports {
+		containerPorts = append(containerPorts, containerPort)
+	}
+	sort.Strings(containerPorts)
+	for _, containerPort := range containerPorts {
+		published := false
+		for _, binding := range data.NetworkSettings.Ports[containerPort] {
+			if binding.HostPort == "" {
+				continue
+			}
+			published = true
+			port, err := ParsePortMapping(hostIPPrefix(binding.HostIP) + binding.HostPort + ":" + containerPort)
+			if err != nil {
+				add("ports", "%s is skipped: %v", containerPort, err)
+				continue
+			}
+			// Docker binds the IPv4 and IPv6 wildcard addresses together; both are one mapping
+			if !containsPort(spec.Ports, port) {
+				spec.Ports = append(spec.Ports, port)
 			}
 		}
+		if !published {
+			add("ports", "%s is exposed but not published; only the image's EXPOSE keeps it", containerPort)
+		}
 	}
 
-	// Parse networks
+	// Parse networks and the aliases on the first, skipping the ones docker derives from the name
+	// and ID; docker run gives --network-alias only on the first network, so the others' are dropped
 	for networkName := range data.NetworkSettings.Networks {
 		spec.Networks = append(spec.Networks, networkName)
 	}
+	sort.Strings(spec.Networks)
+	for i, networkName := range spec.Networks {
+		var aliases []string
+		for _, alias := range data.NetworkSettings.Networks[networkName].Aliases {
+			if !isImplicitAlias(alias, spec.Name, data.ID) {
+				aliases = appendUnique(aliases, alias)
+			}
+		}
+		if i == 0 {
+			spec.NetworkAliases = aliases
+		} else if len(aliases) > 0 {
+			add("networkAliases", "aliases %s on network %s are dropped; only the first network, %s, keeps its aliases", strings.Join(aliases, ", "), networkName, spec.Networks[0])
+		}
+	}
+	sort.Strings(spec.NetworkAliases)
 
 	// Parse devices
 	for _, device := range data.HostConfig.Devices {
 		deviceStr := fmt.Sprintf("%s:%s", device.PathOnHost, device.PathInContainer)
+		if device.CgroupPermissions != "" && device.CgroupPermissions != "rwm" {
+			add("devices", "cgroup permissions '%s' of %s are dropped", device.CgroupPermissions, device.PathOnHost)
+		}
 		spec.Devices = append(spec.Devices, deviceStr)
 	}
+	// GPUs and other accelerators requested with --gpus, a CDI --device or CDI annotations
+	for _, request := range data.HostConfig.DeviceRequests {
+		for _, device := range request.cdiDevices(add) {
+			spec.Devices = appendUnique(spec.Devices, device)
+		}
+	}
+	for _, device := range cdiAnnotationDevices(data.HostConfig.Annotations) {
+		spec.Devices = appendUnique(spec.Devices, device)
+	}
 
 	// Parse restart policy
 	if data.HostConfig.RestartPolicy.Name != "" && data.HostConfig.RestartPolicy.Name != "no" {
 		spec.Restart = data.HostConfig.RestartPolicy.Name
+		if retries := data.HostConfig.RestartPolicy.MaximumRetryCount; retries > 0 {
+			add("restart", "maximum retry count %d of the %s policy is dropped", retries, spec.Restart)
+		}
 	}
 
 	// Parse extra hosts
 	spec.ExtraHosts = data.HostConfig.ExtraHosts
 
-	return spec, nil
+	// Parse links, stored by docker as "/target:/container/alias"
+	for _, link := range data.HostConfig.Links {
+		target, alias, found := strings.Cut(link, ":")
+		if !found {
+			add("links", "link '%s' is skipped: expected /target:/container/alias", link)
+			continue
+		}
+		target = strings.TrimPrefix(target, "/")
+		alias = alias[strings.LastIndex(alias, "/")+1:]
+		spec.Links = append(spec.Links, fmt.Sprintf("%s:%s", target, alias))
+	}
+
+	// Parse network mode when it shares another container's network stack
+	if strings.HasPrefix(data.HostConfig.NetworkMode, "container:") {
+		spec.NetworkMode = data.HostConfig.NetworkMode
+	} else if strings.HasPrefix(data.HostConfig.NetworkMode, "service:") {
+		add("networkMode", "network mode %s is skipped; it only exists under compose", data.HostConfig.NetworkMode)
+	}
+
+	// Parse volumes-from
+	spec.VolumesFrom = data.HostConfig.VolumesFrom
+
+	// Parse tmpfs mounts, given with --tmpfs or --mount type=tmpfs
+	for path, options := range data.HostConfig.Tmpfs {
+		if options != "" {
+			path += ":" + options
+		}
+		spec.Tmpfs = append(spec.Tmpfs, path)
+	}
+	for _, mount := range data.HostConfig.Mounts {
+		if mount.Type != "tmpfs" {
+			continue
+		}
+		var options []string
+		if mount.TmpfsOptions != nil && mount.TmpfsOptions.SizeBytes > 0 {
+			options = append(options, fmt.Sprintf("size=%d", mount.TmpfsOptions.SizeBytes))
+		}
+		if mount.TmpfsOptions != nil && mount.TmpfsOptions.Mode != 0 {
+			options = append(options, fmt.Sprintf("mode=%o", mount.TmpfsOptions.Mode))
+		}
+		path := mount.Target
+		if len(options) > 0 {
+			path += ":" + strings.Join(options, ",")
+		}
+		spec.Tmpfs = append(spec.Tmpfs, path)
+	}
+	sort.Strings(spec.Tmpfs)
+
+	// Parse resource limits
+	for _, ulimit := range data.HostConfig.Ulimits {
+		spec.Ulimits = append(spec.Ulimits, FormatUlimit(ulimit.Name, ulimit.Soft, ulimit.Hard))
+	}
+	spec.Memory = Bytes(data.HostConfig.Memory)
+	spec.NanoCPUs = CPUs(data.HostConfig.NanoCpus)
+	spec.CPUShares = data.HostConfig.CpuShares
+	if swappiness := data.HostConfig.MemorySwappiness; swappiness != nil && *swappiness >= 0 {
+		spec.MemorySwappiness = swappiness
+	}
+	spec.KernelMemory = Bytes(data.HostConfig.KernelMemory)
+	// Docker sets the swap limit to twice the memory limit itself unless --memory-swap says otherwise;
+	// without a memory limit there is no swap limit to keep
+	if swap := Bytes(data.HostConfig.MemorySwap); spec.Memory > 0 && swap != 2*spec.Memory {
+		spec.MemorySwap = swap
+	}
+	spec.MemoryReservation = Bytes(data.HostConfig.MemoryReservation)
+	// --cpus and a CFS quota or period exclude each other; a daemon that reports both is read as --cpus
+	if spec.NanoCPUs == 0 {
+		spec.CPUQuota = data.HostConfig.CpuQuota
+		spec.CPUPeriod = data.HostConfig.CpuPeriod
+	}
+
+	// Drop ignored labels
+	if opts != nil {
+		spec.Labels = opts.LabelFilter.Apply(spec.Labels)
+	}
+
+	return spec, warnings, nil
+}
+
+// droppedMountOptions returns the options of a mount's mode the spec can't express, such as
+// propagation; read-only is kept through RW and SELinux relabeling ("z", "Z") as a volume option
+func droppedMountOptions(mode string) string {
+	var dropped []string
+	for _, option := range strings.Split(mode, ",") {
+		if option != "" && option != "rw" && option != "ro" && option != SELinuxShared && option != SELinuxPrivate {
+			dropped = append(dropped, option)
+		}
+	}
+	return strings.Join(dropped, ",")
+}
+
+// isImplicitAlias reports whether docker added the alias itself: the container name or a prefix of its ID
+func isImplicitAlias(alias, name, id string) bool {
+	return alias == name || (len(alias) >= 12 && strings.HasPrefix(id, alias))
+}
+
+// appendUnique appends a value unless the list already contains it
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
 }
@@ -0,0 +1,80 @@
+package containerconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Platform is an OS and CPU architecture in docker's terms, e.g. linux/arm64/v8
+type Platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// archAliases maps the machine names uname and some registries use to the architecture names docker uses
+var archAliases = map[string]string{
+	"x86_64":  "amd64",
+	"x86-64":  "amd64",
+	"aarch64": "arm64",
+	"armhf":   "arm",
+	"armel":   "arm",
+	"i386":    "386",
+	"i686":    "386",
+}
+
+// ParsePlatform parses "os/arch[/variant]" as docker --platform takes it
+func ParsePlatform(value string) (Platform, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(value)), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return Platform{}, fmt.Errorf("invalid platform '%s' (expected os/arch[/variant])", value)
+	}
+	p := Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p.Normalize(), nil
+}
+
+// Normalize returns the platform with docker's architecture name, e.g. amd64 for x86_64
+func (p Platform) Normalize() Platform {
+	p.OS = strings.ToLower(p.OS)
+	p.Architecture = strings.ToLower(p.Architecture)
+	if arch, ok := archAliases[p.Architecture]; ok {
+		p.Architecture = arch
+	}
+	// v8 is the only arm64 variant, so images omit it as often as they set it
+	if p.Architecture == "arm64" && p.Variant == "v8" {
+		p.Variant = ""
+	}
+	return p
+}
+
+// String formats the platform as docker --platform takes it
+func (p Platform) String() string {
+	if p.Variant != "" {
+		return p.OS + "/" + p.Architecture + "/" + p.Variant
+	}
+	return p.OS + "/" + p.Architecture
+}
+
+// RunsNatively reports whether a daemon on the host platform runs an image of platform p without
+// emulation; amd64 hosts also run 386 images, and a variant is only compared when both name one
+func (p Platform) RunsNatively(host Platform) bool {
+	p, host = p.Normalize(), host.Normalize()
+	if p.OS != host.OS {
+		return false
+	}
+	if p.Architecture == "386" && host.Architecture == "amd64" {
+		return true
+	}
+	if p.Architecture != host.Architecture {
+		return false
+	}
+	return p.Variant == "" || host.Variant == "" || p.Variant == host.Variant
+}
+
+// EmulationWarning explains what running an image under QEMU user-mode emulation means for debugging
+const EmulationWarning = "the container would run under QEMU emulation: it is many times slower, and " +
+	"debuggers rely on ptrace, which qemu-user does not emulate, so dlv attach and dlv exec usually fail " +
+	"and core dumps describe the emulator rather than the app"
@@ -0,0 +1,239 @@
+package containerconfig
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PortMapping is a published port, or range of ports, of a container. Files hold it as the
+// "[ip:][hostPort:]containerPort[/protocol]" string of docker run -p, where either port may be a
+// range such as 7000-7005
+type PortMapping struct {
+	// HostIP is the address the port listens on; empty for all interfaces
+	HostIP string
+	// HostPort is 0 when docker picks the host port
+	HostPort      int
+	ContainerPort int
+	// HostPortEnd and ContainerPortEnd end a range; 0 for a single port
+	HostPortEnd      int
+	ContainerPortEnd int
+	// Protocol is "udp" or "sctp"; empty for tcp
+	Protocol string
+}
+
+// ParsePortMapping parses a docker run -p value
+func ParsePortMapping(value string) (PortMapping, error) {
+	var p PortMapping
+	rest, protocol, _ := strings.Cut(value, "/")
+	switch protocol {
+	case "", "tcp":
+	case "udp", "sctp":
+		p.Protocol = protocol
+	default:
+		return PortMapping{}, fmt.Errorf("invalid port '%s': unknown protocol '%s'", value, protocol)
+	}
+
+	// A bracketed IPv6 host IP holds colons of its own
+	if strings.HasPrefix(rest, "[") {
+		end := strings.Index(rest, "]:")
+		if end < 0 {
+			return PortMapping{}, fmt.Errorf("invalid port '%s': unterminated IPv6 address", value)
+		}
+		p.HostIP, rest = rest[1:end], rest[end+2:]
+	}
+	parts := strings.Split(rest, ":")
+	if p.HostIP != "" && len(parts) != 2 {
+		return PortMapping{}, fmt.Errorf("invalid port '%s'", value)
+	}
+	var host string
+	switch len(parts) {
+	case 1:
+	case 2:
+		host = parts[0]
+	case 3:
+		p.HostIP, host = parts[0], parts[1]
+	default:
+		return PortMapping{}, fmt.Errorf("invalid port '%s'", value)
+	}
+	if p.HostIP == "0.0.0.0" || p.HostIP == "::" {
+		p.HostIP = ""
+	}
+
+	var err error
+	if p.ContainerPort, p.ContainerPortEnd, err = parsePortRange(parts[len(parts)-1]); err != nil || p.ContainerPort == 0 {
+		return PortMapping{}, fmt.Errorf("invalid port '%s': bad container port", value)
+	}
+	if host != "" {
+		if p.HostPort, p.HostPortEnd, err = parsePortRange(host); err != nil {
+			return PortMapping{}, fmt.Errorf("invalid port '%s': bad host port", value)
+		}
+	}
+	// A host range for a single container port lets docker pick one of the host ports
+	if p.HostPortEnd != 0 && p.ContainerPortEnd != 0 && p.HostPortEnd-p.HostPort != p.ContainerPortEnd-p.ContainerPort {
+		return PortMapping{}, fmt.Errorf("invalid port '%s': the host and container ranges differ in size", value)
+	}
+	return p, nil
+}
+
+// parsePortRange parses "port" or "start-end"; end is 0 for a single port
+func parsePortRange(value string) (start, end int, err error) {
+	first, last, isRange := strings.Cut(value, "-")
+	if start, err = parsePortNumber(first); err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		return start, 0, nil
+	}
+	if end, err = parsePortNumber(last); err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("range %s ends before it starts", value)
+	}
+	if end == start {
+		end = 0
+	}
+	return start, end, nil
+}
+
+// parsePortNumber parses a port number between 0 and 65535
+func parsePortNumber(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port number '%s'", value)
+	}
+	return port, nil
+}
+
+// hostIPPrefix returns the "ip:" a -p value starts with, brackets around an IPv6 address; empty
+// for all interfaces
+func hostIPPrefix(ip string) string {
+	switch {
+	case ip == "":
+		return ""
+	case strings.Contains(ip, ":"):
+		return "[" + ip + "]:"
+	}
+	return ip + ":"
+}
+
+// formatPortRange renders a port or range
+func formatPortRange(start, end int) string {
+	if end == 0 {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d-%d", start, end)
+}
+
+// String renders the mapping as a docker run -p value
+func (p PortMapping) String() string {
+	value := hostIPPrefix(p.HostIP)
+	if p.HostPort != 0 {
+		value += formatPortRange(p.HostPort, p.HostPortEnd) + ":"
+	} else if p.HostIP != "" {
+		value += ":"
+	}
+	value += formatPortRange(p.ContainerPort, p.ContainerPortEnd)
+	if p.Protocol != "" && p.Protocol != "tcp" {
+		value += "/" + p.Protocol
+	}
+	return value
+}
+
+// MarshalText implements encoding.TextMarshaler, so JSON and YAML hold the -p string
+func (p PortMapping) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (p *PortMapping) UnmarshalText(text []byte) error {
+	parsed, err := ParsePortMapping(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// TCP reports whether the mapping publishes TCP ports
+func (p PortMapping) TCP() bool {
+	return p.Protocol == "" || p.Protocol == "tcp"
+}
+
+// IsRange reports whether the mapping publishes a range of ports
+func (p PortMapping) IsRange() bool {
+	return p.ContainerPortEnd != 0
+}
+
+// HostPorts returns the fixed host ports of the mapping, a range expanded; none when docker picks
+func (p PortMapping) HostPorts() []int {
+	if p.HostPort == 0 {
+		return nil
+	}
+	last := p.HostPortEnd
+	if last == 0 {
+		last = p.HostPort
+	}
+	var ports []int
+	for port := p.HostPort; port <= last; port++ {
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// HostPortFor returns the fixed host port the mapping publishes a container port on, or 0
+func (p PortMapping) HostPortFor(containerPort int) int {
+	last := p.ContainerPortEnd
+	if last == 0 {
+		last = p.ContainerPort
+	}
+	if p.HostPort == 0 || containerPort < p.ContainerPort || containerPort > last {
+		return 0
+	}
+	return p.HostPort + containerPort - p.ContainerPort
+}
+
+// containsPort reports whether ports contains port
+func containsPort(ports []PortMapping, port PortMapping) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// PortStrings returns the spec's published ports as docker run -p values
+func (s *ContainerSpec) PortStrings() []string {
+	if s.Ports == nil {
+		return nil
+	}
+	ports := make([]string, len(s.Ports))
+	for i, p := range s.Ports {
+		ports[i] = p.String()
+	}
+	return ports
+}
+
+// normalizePorts drops duplicate mappings and sorts them by their -p form
+func normalizePorts(ports []PortMapping) []PortMapping {
+	seen := make(map[PortMapping]bool, len(ports))
+	var result []PortMapping
+	for _, p := range ports {
+		if p.Protocol == "tcp" {
+			p.Protocol = ""
+		}
+		if p.HostIP == "0.0.0.0" || p.HostIP == "::" {
+			p.HostIP = ""
+		}
+		if p.ContainerPort == 0 || seen[p] {
+			continue
+		}
+		seen[p] = true
+		result = append(result, p)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].String() < result[j].String() })
+	return result
+}
@@ -0,0 +1,81 @@
+package containerconfig_test
+
+import (
+	"testing"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+func TestParsePortMapping(t *testing.T) {
+	tests := []struct {
+		value string
+		want  containerconfig.PortMapping
+		// canonical is the String of the result, when it differs from value
+		canonical string
+	}{
+		{value: "80", want: containerconfig.PortMapping{ContainerPort: 80}},
+		{value: "8080:80", want: containerconfig.PortMapping{HostPort: 8080, ContainerPort: 80}},
+		{value: "8080:80/tcp", want: containerconfig.PortMapping{HostPort: 8080, ContainerPort: 80}, canonical: "8080:80"},
+		{value: "53:53/udp", want: containerconfig.PortMapping{HostPort: 53, ContainerPort: 53, Protocol: "udp"}},
+		{value: "127.0.0.1:8080:80", want: containerconfig.PortMapping{HostIP: "127.0.0.1", HostPort: 8080, ContainerPort: 80}},
+		{value: "127.0.0.1::80", want: containerconfig.PortMapping{HostIP: "127.0.0.1", ContainerPort: 80}},
+		{value: "0.0.0.0:8080:80", want: containerconfig.PortMapping{HostPort: 8080, ContainerPort: 80}, canonical: "8080:80"},
+		{value: "[::1]:8080:80", want: containerconfig.PortMapping{HostIP: "::1", HostPort: 8080, ContainerPort: 80}},
+		{value: "[::]:8080:80", want: containerconfig.PortMapping{HostPort: 8080, ContainerPort: 80}, canonical: "8080:80"},
+		{value: "7000-7005:7000-7005", want: containerconfig.PortMapping{HostPort: 7000, HostPortEnd: 7005, ContainerPort: 7000, ContainerPortEnd: 7005}},
+		{value: "8000-8010:80", want: containerconfig.PortMapping{HostPort: 8000, HostPortEnd: 8010, ContainerPort: 80}},
+		{value: "9000-9000:9000", want: containerconfig.PortMapping{HostPort: 9000, ContainerPort: 9000}, canonical: "9000:9000"},
+		{value: "3868:3868/sctp", want: containerconfig.PortMapping{HostPort: 3868, ContainerPort: 3868, Protocol: "sctp"}},
+	}
+	for _, tt := range tests {
+		got, err := containerconfig.ParsePortMapping(tt.value)
+		if err != nil {
+			t.Errorf("ParsePortMapping(%q): %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePortMapping(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+		canonical := tt.canonical
+		if canonical == "" {
+			canonical = tt.value
+		}
+		if s := got.String(); s != canonical {
+			t.Errorf("ParsePortMapping(%q).String() = %s, want %s", tt.value, s, canonical)
+		}
+	}
+}
+
+func TestParsePortMappingErrors(t *testing.T) {
+	for _, value := range []string{
+		"", "http", "80/icmp", "70000", "0", "8080:", "1:2:3:4",
+		"[::1:8080:80", "[::1]:80", "9000-8000:80", "7000-7005:8000-8001",
+	} {
+		if p, err := containerconfig.ParsePortMapping(value); err == nil {
+			t.Errorf("ParsePortMapping(%q) = %+v, want an error", value, p)
+		}
+	}
+}
+
+func TestPortMappingHostPortFor(t *testing.T) {
+	tests := []struct {
+		port          string
+		containerPort int
+		want          int
+	}{
+		{"8080:80", 80, 8080},
+		{"8080:80", 81, 0},
+		{"80", 80, 0},
+		{"7000-7005:8000-8005", 8003, 7003},
+		{"7000-7005:8000-8005", 8006, 0},
+	}
+	for _, tt := range tests {
+		p, err := containerconfig.ParsePortMapping(tt.port)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.HostPortFor(tt.containerPort); got != tt.want {
+			t.Errorf("%s.HostPortFor(%d) = %d, want %d", tt.port, tt.containerPort, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package containerconfig
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Docker's healthcheck defaults, which probes spell out as Kubernetes defaults differ
+const (
+	defaultHealthInterval = Duration(30 * time.Second)
+	defaultHealthTimeout  = Duration(30 * time.Second)
+	defaultHealthRetries  = 3
+)
+
+// KubeProbe is a Kubernetes container probe, running a command or an HTTP GET
+type KubeProbe struct {
+	Exec                *KubeExecAction    `json:"exec,omitempty" yaml:"exec,omitempty"`
+	HTTPGet             *KubeHTTPGetAction `json:"httpGet,omitempty" yaml:"httpGet,omitempty"`
+	InitialDelaySeconds int64              `json:"initialDelaySeconds,omitempty" yaml:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int64              `json:"periodSeconds,omitempty" yaml:"periodSeconds,omitempty"`
+	TimeoutSeconds      int64              `json:"timeoutSeconds,omitempty" yaml:"timeoutSeconds,omitempty"`
+	FailureThreshold    int                `json:"failureThreshold,omitempty" yaml:"failureThreshold,omitempty"`
+}
+
+// KubeExecAction runs a command in the container; exit status 0 is healthy
+type KubeExecAction struct {
+	Command []string `json:"command" yaml:"command"`
+}
+
+// KubeHTTPGetAction requests a path on a container port; a 2xx or 3xx status is healthy
+type KubeHTTPGetAction struct {
+	Path   string `json:"path,omitempty" yaml:"path,omitempty"`
+	Port   int    `json:"port" yaml:"port"`
+	Scheme string `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+}
+
+// httpProbeFlags are the curl and wget options a health test may use without changing what the
+// check means, mapped to whether they take a value
+var httpProbeFlags = map[string]bool{
+	"-f": false, "--fail": false, "-s": false, "--silent": false, "-S": false, "--show-error": false,
+	"-sf": false, "-fs": false, "-fsS": false, "-sS": false, "-fsSL": false, "-L": false, "--location": false,
+	"-k": false, "--insecure": false, "-q": false, "--quiet": false, "--spider": false, "--no-verbose": false,
+	"-o": true, "--output": true, "-O": true, "-m": true, "--max-time": true, "-T": true, "--timeout": true,
+	"-t": true, "--tries": true, "-O-": false, "-qO-": false, "-qO": true,
+}
+
+// localHosts are the addresses a health test reaches the container itself at
+var localHosts = map[string]bool{"localhost": true, "127.0.0.1": true, "0.0.0.0": true, "::1": true}
+
+// httpProbe recognizes a curl or wget request to the container itself, optionally followed by
+// "|| exit 1", and returns it as an HTTP GET; anything else returns nil
+func httpProbe(argv []string) *KubeHTTPGetAction {
+	if n := len(argv); n > 3 && argv[n-3] == "||" && argv[n-2] == "exit" {
+		argv = argv[:n-3]
+	} else if n > 2 && argv[n-2] == "||" && argv[n-1] == "false" {
+		argv = argv[:n-2]
+	}
+	if len(argv) < 2 || (path.Base(argv[0]) != "curl" && path.Base(argv[0]) != "wget") {
+		return nil
+	}
+
+	var target string
+	for i := 1; i < len(argv); i++ {
+		arg := argv[i]
+		if takesValue, ok := httpProbeFlags[arg]; ok {
+			if takesValue {
+				i++
+			}
+			continue
+		}
+		if strings.HasPrefix(arg, "-") || target != "" {
+			return nil
+		}
+		target = arg
+	}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !localHosts[u.Hostname()] {
+		return nil
+	}
+	action := &KubeHTTPGetAction{Path: u.RequestURI(), Port: 80}
+	if u.Scheme == "https" {
+		action.Scheme, action.Port = "HTTPS", 443
+	}
+	if u.Port() != "" {
+		if action.Port, err = strconv.Atoi(u.Port()); err != nil {
+			return nil
+		}
+	}
+	return action
+}
+
+// KubeProbes converts a healthcheck into a liveness and a readiness probe with the same check: an
+// HTTP GET when the test is curl or wget against the container itself, otherwise the test command
+// Docker's timings carry over with its defaults spelled out; the start period delays the liveness
+// probe only, as a container that isn't ready yet shouldn't get traffic either
+func KubeProbes(h *Healthcheck) (liveness, readiness *KubeProbe, warnings []Warning) {
+	if h == nil || h.Disabled() || len(h.Test) < 2 {
+		return nil, nil, nil
+	}
+
+	probe := &KubeProbe{}
+	var argv []string
+	switch h.Test[0] {
+	case HealthCmd:
+		argv = h.Test[1:]
+		if probe.HTTPGet = httpProbe(argv); probe.HTTPGet == nil {
+			probe.Exec = &KubeExecAction{Command: cloneStrings(argv)}
+		}
+	case HealthCmdShell:
+		command := h.Test[1]
+		argv = splitCommandLine(command)
+		// Only a plain command line is understood; "|| exit 1" is the usual way to end one
+		plain := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(command), "|| exit 1"), "|| false")
+		if !strings.ContainsAny(plain, ";&|<>$`()") {
+			probe.HTTPGet = httpProbe(argv)
+		}
+		if probe.HTTPGet == nil {
+			probe.Exec = &KubeExecAction{Command: []string{"/bin/sh", "-c", command}}
+		}
+	default:
+		return nil, nil, []Warning{{Field: "healthcheck", Message: fmt.Sprintf("unknown test kind '%s'; no probes are generated", h.Test[0])}}
+	}
+
+	interval, timeout, retries := h.Interval, h.Timeout, h.Retries
+	if interval == 0 {
+		interval = defaultHealthInterval
+	}
+	if timeout == 0 {
+		timeout = defaultHealthTimeout
+	}
+	if retries == 0 {
+		retries = defaultHealthRetries
+	}
+	probe.PeriodSeconds = interval.Seconds()
+	probe.TimeoutSeconds = timeout.Seconds()
+	probe.FailureThreshold = retries
+
+	ready := *probe
+	live := *probe
+	live.InitialDelaySeconds = h.StartPeriod.Seconds()
+	if h.StartInterval != 0 {
+		warnings = append(warnings, Warning{Field: "healthcheck", Message: "the start interval has no probe equivalent and is dropped"})
+	}
+	if probe.Exec != nil && len(argv) > 0 && (path.Base(argv[0]) == "curl" || path.Base(argv[0]) == "wget") {
+		warnings = append(warnings, Warning{Field: "healthcheck", Message: "the curl/wget check is kept as a command, as it is not a plain GET of the container itself; the image must ship the tool"})
+	}
+	return &live, &ready, warnings
+}
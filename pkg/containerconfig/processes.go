@@ -0,0 +1,118 @@
+package containerconfig
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// TopArgs are the ps options docker top is run with, producing the columns ParseTop reads
+var TopArgs = []string{"-eo", "pid,ppid,user,args"}
+
+// Process is one line of docker top; PIDs are as seen on the docker host, not inside the container
+type Process struct {
+	PID     int    `json:"pid"`
+	PPID    int    `json:"ppid"`
+	User    string `json:"user"`
+	Command string `json:"command"`
+}
+
+// Binary returns the executable of the process as given on its command line
+func (p Process) Binary() string {
+	fields := strings.Fields(p.Command)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// ParseTop parses the output of docker top run with TopArgs
+func ParseTop(output string) ([]Process, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) == 0 || !strings.HasPrefix(strings.TrimSpace(lines[0]), "PID") {
+		return nil, fmt.Errorf("unexpected docker top output: missing PID header")
+	}
+	var processes []Process
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected docker top line '%s'", line)
+		}
+		ppid, _ := strconv.Atoi(fields[1])
+		processes = append(processes, Process{PID: pid, PPID: ppid, User: fields[2], Command: strings.Join(fields[3:], " ")})
+	}
+	return processes, nil
+}
+
+// wrapperBinaries are processes that only start or supervise the app, so dlv attach to them
+// debugs the wrong program
+var wrapperBinaries = map[string]bool{
+	"sh": true, "bash": true, "ash": true, "dash": true, "zsh": true,
+	"tini": true, "dumb-init": true, "docker-init": true, "catatonit": true,
+	"s6-svscan": true, "s6-supervise": true, "runsvdir": true, "runsv": true,
+	"supervisord": true, "entr": true, "reflex": true, "air": true, "sleep": true,
+}
+
+// AttachSuggestion is the process dlv attach should debug
+type AttachSuggestion struct {
+	// Process is the app process, with host PIDs
+	Process Process `json:"process"`
+	// IsInit is set when the app is the container's PID 1, so it can be attached to by number
+	IsInit bool `json:"isInit"`
+	// Reason says why this process was picked
+	Reason string `json:"reason"`
+}
+
+// SuggestAttach picks the process to attach the debugger to: the container's main process
+// (initPID, the host PID of the container's PID 1), or where that is a shell, an init or a
+// supervisor, the first of its descendants that isn't
+func SuggestAttach(processes []Process, initPID int) (*AttachSuggestion, error) {
+	byPID := make(map[int]Process, len(processes))
+	for _, p := range processes {
+		byPID[p.PID] = p
+	}
+	current, ok := byPID[initPID]
+	if !ok {
+		return nil, fmt.Errorf("the container's main process %d is not in the process list", initPID)
+	}
+
+	var skipped []string
+	for wrapperBinaries[path.Base(current.Binary())] {
+		skipped = append(skipped, path.Base(current.Binary()))
+		next, found := Process{}, false
+		for _, p := range processes {
+			if p.PPID == current.PID {
+				next, found = p, true
+				if !wrapperBinaries[path.Base(p.Binary())] {
+					break
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("the container only runs %s; start the app before attaching", strings.Join(skipped, " > "))
+		}
+		current = next
+	}
+
+	suggestion := &AttachSuggestion{Process: current, IsInit: current.PID == initPID}
+	if suggestion.IsInit {
+		suggestion.Reason = "the app is the container's main process"
+	} else {
+		suggestion.Reason = fmt.Sprintf("PID 1 is %s, which runs the app", strings.Join(skipped, " > "))
+	}
+	return suggestion, nil
+}
+
+// AttachCommand returns the command attaching delve to the process in the given container; as
+// docker top shows host PIDs, a process other than PID 1 is found by name with pidof
+func (s *AttachSuggestion) AttachCommand(container string) string {
+	if s.IsInit {
+		return fmt.Sprintf("docker exec -it %s dlv attach 1", container)
+	}
+	return fmt.Sprintf("docker exec -it %s sh -c 'dlv attach $(pidof -s %s)'", container, path.Base(s.Process.Binary()))
+}
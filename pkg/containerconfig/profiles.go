@@ -0,0 +1,208 @@
+package containerconfig
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProfileOptions configures the built-in dev container profiles
+type ProfileOptions struct {
+	// PprofPort is the port net/http/pprof listens on (default 6060)
+	PprofPort int
+	// PprofEnv names an env var set to "true" so the app enables net/http/pprof
+	PprofEnv string
+	// PprofArgs are appended to the command to enable pprof, e.g. "--pprof-addr=:6060"
+	PprofArgs []string
+	// GoMaxProcs sets GOMAXPROCS when not empty
+	GoMaxProcs string
+	// GoDebug sets GODEBUG in the pprof profile and is merged into it by the go-debug profile
+	GoDebug string
+
+	// OtelEndpoint is the OTLP endpoint traces are exported to (default http://localhost:4317)
+	OtelEndpoint string
+	// OtelServiceName is the service name reported in traces (default: the container name)
+	OtelServiceName string
+	// OtelResourceAttributes are extra "key=value" resource attributes
+	OtelResourceAttributes []string
+
+	// WatchDir is the source directory the watch profile rebuilds from (default: the spec's source directory)
+	WatchDir string
+	// WatchPackage is the Go package built by the watch profile (default ".")
+	WatchPackage string
+	// WatchInterval is how often the watch profile checks for changes (default 1s)
+	WatchInterval time.Duration
+
+	// CoreDir is the absolute host directory the cores profile mounts at CoreDumpDir; empty uses an anonymous volume
+	CoreDir string
+}
+
+// CoreDumpDir is the container directory the cores profile collects core dumps in
+const CoreDumpDir = "/cores"
+
+// profileFunc applies a profile's modifications to a spec builder
+type profileFunc func(b *SpecBuilder, opts ProfileOptions) error
+
+// profiles maps profile names to their implementation
+var profiles = map[string]profileFunc{
+	"pprof":    pprofProfile,
+	"otel":     otelProfile,
+	"watch":    watchProfile,
+	"tools":    toolsProfile,
+	"cores":    coresProfile,
+	"go-debug": goDebugProfile,
+}
+
+// profilePackages lists the packages installed in the started dev container for a profile
+var profilePackages = map[string][]string{
+	"tools": {"procps", "curl", "strace"},
+}
+
+// ProfilePackages returns the packages the named profile installs in the started dev container
+func ProfilePackages(name string) []string {
+	return profilePackages[name]
+}
+
+// ProfileNames returns the names of the available profiles, sorted
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyProfile applies the named profile to the builder
+func ApplyProfile(b *SpecBuilder, name string, opts ProfileOptions) error {
+	profile, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile '%s' (available: %v)", name, ProfileNames())
+	}
+	if err := profile(b, opts); err != nil {
+		return err
+	}
+	b.Record(name + " profile")
+	return nil
+}
+
+// pprofProfile exposes the pprof port and sets the Go runtime env for performance debugging
+func pprofProfile(b *SpecBuilder, opts ProfileOptions) error {
+	port := opts.PprofPort
+	if port == 0 {
+		port = 6060
+	}
+	b.WithPort(PortMapping{HostPort: port, ContainerPort: port})
+
+	if opts.GoMaxProcs != "" {
+		if _, err := strconv.Atoi(opts.GoMaxProcs); err != nil {
+			return fmt.Errorf("invalid GOMAXPROCS value '%s'", opts.GoMaxProcs)
+		}
+		b.WithEnv("GOMAXPROCS", opts.GoMaxProcs)
+	}
+	if opts.GoDebug != "" {
+		b.WithEnv("GODEBUG", opts.GoDebug)
+	}
+	if opts.PprofEnv != "" {
+		b.WithEnv(opts.PprofEnv, "true")
+	}
+	if len(opts.PprofArgs) > 0 {
+		b.WithCommandArgs(opts.PprofArgs...)
+	}
+	return nil
+}
+
+// otelProfile points the OpenTelemetry SDK env at an OTLP endpoint so traces from the dev container show up immediately
+func otelProfile(b *SpecBuilder, opts ProfileOptions) error {
+	endpoint := opts.OtelEndpoint
+	if endpoint == "" {
+		endpoint = "http://localhost:4317"
+	}
+	serviceName := opts.OtelServiceName
+	if serviceName == "" {
+		serviceName = b.spec.Name
+	}
+
+	for _, attr := range opts.OtelResourceAttributes {
+		if !strings.Contains(attr, "=") {
+			return fmt.Errorf("invalid resource attribute '%s', expected key=value", attr)
+		}
+	}
+	attributes := append([]string{"deployment.environment=dev"}, opts.OtelResourceAttributes...)
+
+	b.WithEnv("OTEL_EXPORTER_OTLP_ENDPOINT", endpoint)
+	b.WithEnv("OTEL_SERVICE_NAME", serviceName)
+	b.WithEnv("OTEL_RESOURCE_ATTRIBUTES", strings.Join(attributes, ","))
+	b.WithEnv("OTEL_TRACES_EXPORTER", "otlp")
+	return nil
+}
+
+// coresProfile lets crashing processes dump core into CoreDumpDir: it lifts the core size limit,
+// gives the directory a mount and makes Go programs dump core on a fatal error
+// Where the kernel writes cores is the host-wide kernel.core_pattern, which a container can't set
+func coresProfile(b *SpecBuilder, opts ProfileOptions) error {
+	b.WithUlimit("core", -1, -1)
+	if opts.CoreDir != "" {
+		b.WithVolume(opts.CoreDir + ":" + CoreDumpDir)
+	} else {
+		b.WithVolume(CoreDumpDir)
+	}
+	b.WithEnv("GOTRACEBACK", "crash")
+	return nil
+}
+
+// tracebackLevels orders the GOTRACEBACK settings from least to most output
+var tracebackLevels = map[string]int{"none": 0, "0": 0, "single": 1, "1": 2, "all": 2, "2": 3, "system": 3, "crash": 4}
+
+// goDebugProfile makes Go panics and runtime traces as informative as possible: GOTRACEBACK=all
+// prints every goroutine's stack on a panic, unless the spec already asks for more (the cores
+// profile's crash); GODEBUG settings are merged into the app's own, overriding the same keys
+func goDebugProfile(b *SpecBuilder, opts ProfileOptions) error {
+	env := b.spec.Env.Map()
+	if current, ok := tracebackLevels[env["GOTRACEBACK"]]; !ok || current < tracebackLevels["all"] {
+		b.WithEnv("GOTRACEBACK", "all")
+	}
+	if opts.GoDebug != "" {
+		godebug, err := mergeGoDebug(env["GODEBUG"], opts.GoDebug)
+		if err != nil {
+			return err
+		}
+		b.WithEnv("GODEBUG", godebug)
+	}
+	return nil
+}
+
+// mergeGoDebug merges comma-separated key=value GODEBUG settings, the extra ones overriding
+func mergeGoDebug(current, extra string) (string, error) {
+	var keys []string
+	values := make(map[string]string)
+	for i, settings := range []string{current, extra} {
+		for _, setting := range strings.Split(settings, ",") {
+			if setting = strings.TrimSpace(setting); setting == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(setting, "=")
+			if !ok && i == 1 {
+				return "", fmt.Errorf("invalid GODEBUG setting '%s', expected key=value", setting)
+			}
+			if _, seen := values[key]; !seen {
+				keys = append(keys, key)
+			}
+			values[key] = value
+		}
+	}
+	merged := make([]string, len(keys))
+	for i, key := range keys {
+		merged[i] = key + "=" + values[key]
+	}
+	return strings.Join(merged, ","), nil
+}
+
+// toolsProfile prepares the dev container for the debugging tools it installs (ps, curl, strace):
+// strace needs SYS_PTRACE
+func toolsProfile(b *SpecBuilder, opts ProfileOptions) error {
+	b.WithCapAdd("SYS_PTRACE")
+	return nil
+}
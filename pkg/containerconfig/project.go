@@ -0,0 +1,270 @@
+package containerconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFileName is the project file looked up in the working directory
+const ProjectFileName = "dce.yaml"
+
+// Sync rule modes
+const (
+	// SyncMount bind-mounts the host path, so edits show up in the container immediately
+	SyncMount = "mount"
+	// SyncCopy copies the host path into the container before it starts; up copies it again when
+	// it changes
+	SyncCopy = "copy"
+)
+
+// Project declares several related targets whose dev versions are brought up together
+type Project struct {
+	Targets map[string]*ProjectTarget `yaml:"targets"`
+	// Naming names the dev containers, volume copies and derived images of every target
+	Naming Naming `yaml:"naming,omitempty"`
+	// Order lists the target names in file order
+	Order []string `yaml:"-"`
+}
+
+// ProjectTarget is a container, or an image, whose dev version a project brings up
+// Relative paths are relative to the project file
+type ProjectTarget struct {
+	// Container is the original container the dev container is cloned from
+	Container string `yaml:"container,omitempty"`
+	// Image starts the dev container from an image with its defaults when there is no container
+	Image string `yaml:"image,omitempty"`
+	// Name is the dev container's name; defaults to the container or target name followed by -dev
+	Name       string     `yaml:"name,omitempty"`
+	SwapDir    string     `yaml:"swapDir,omitempty"`
+	Profiles   []string   `yaml:"profiles,omitempty"`
+	Source     string     `yaml:"source,omitempty"`
+	Aliases    []string   `yaml:"aliases,omitempty"`
+	HostAccess bool       `yaml:"hostAccess,omitempty"`
+	Writable   []string   `yaml:"writable,omitempty"`
+	Packages   []string   `yaml:"packages,omitempty"`
+	Inject     []string   `yaml:"inject,omitempty"`
+	Deps       string     `yaml:"deps,omitempty"`
+	Sync       []SyncRule `yaml:"sync,omitempty"`
+	// Context is the docker context or daemon endpoint the container is read from and the dev
+	// container runs on; empty means the current context
+	Context string `yaml:"context,omitempty"`
+	// Ports are the container ports targets on the current context reach this one on when it runs
+	// elsewhere; defaults to the original container's published ports
+	Ports []int `yaml:"ports,omitempty"`
+}
+
+// SyncRule brings a host path into the dev container, mounted (the default) or copied
+type SyncRule struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+	Mode string `yaml:"mode,omitempty"`
+}
+
+// ReadProjectFile reads a project file, validates it and resolves its relative paths
+func ReadProjectFile(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project file '%s': %w", path, err)
+	}
+	project, err := ParseProject(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project file '%s': %w", path, err)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve '%s': %w", path, err)
+	}
+	project.resolvePaths(dir)
+	return project, nil
+}
+
+// ParseProject parses and validates project YAML; paths are left as written
+func ParseProject(data []byte) (*Project, error) {
+	if err := ValidateSchema(SchemaProject, data); err != nil {
+		return nil, err
+	}
+	var project Project
+	if err := yaml.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	// Maps lose the file order, which is the order targets come up in
+	var doc struct {
+		Targets yaml.Node `yaml:"targets"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	for i := 0; i+1 < len(doc.Targets.Content); i += 2 {
+		project.Order = append(project.Order, doc.Targets.Content[i].Value)
+	}
+
+	if err := project.validate(); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// validate checks the targets for missing or conflicting settings
+func (p *Project) validate() error {
+	if len(p.Targets) == 0 {
+		return fmt.Errorf("no targets declared")
+	}
+	for _, text := range []string{p.Naming.Volume, p.Naming.Image} {
+		if text == "" {
+			continue
+		}
+		if _, err := ParseNameTemplate(text); err != nil {
+			return fmt.Errorf("naming: %w", err)
+		}
+	}
+	devNames := make(map[string]string)
+	for _, name := range p.Order {
+		target := p.Targets[name]
+		if target == nil {
+			return fmt.Errorf("target '%s' is empty", name)
+		}
+		if (target.Container == "") == (target.Image == "") {
+			return fmt.Errorf("target '%s' needs exactly one of container and image", name)
+		}
+		switch target.Deps {
+		case "", "ask", "attach", "clone":
+		default:
+			return fmt.Errorf("target '%s': invalid deps '%s' (expected ask, attach or clone)", name, target.Deps)
+		}
+		for _, rule := range target.Sync {
+			if rule.From == "" || !strings.HasPrefix(rule.To, "/") {
+				return fmt.Errorf("target '%s': sync rules need a host path in from and an absolute container path in to", name)
+			}
+			if rule.Mode != "" && rule.Mode != SyncMount && rule.Mode != SyncCopy {
+				return fmt.Errorf("target '%s': invalid sync mode '%s' (expected mount or copy)", name, rule.Mode)
+			}
+		}
+		for _, port := range target.Ports {
+			if port < 1 || port > 65535 {
+				return fmt.Errorf("target '%s': invalid port %d", name, port)
+			}
+		}
+		devName, err := p.devName(name)
+		if err != nil {
+			return fmt.Errorf("target '%s': naming: %w", name, err)
+		}
+		if other, ok := devNames[devName]; ok {
+			return fmt.Errorf("targets '%s' and '%s' both create dev container '%s'", other, name, devName)
+		}
+		devNames[devName] = name
+	}
+	return nil
+}
+
+// DevName returns the name of the dev container of a target: its name, the naming template, or
+// the container or target name followed by -dev
+func (p *Project) DevName(name string) string {
+	devName, _ := p.devName(name)
+	return devName
+}
+
+// devName is DevName with the error of the naming template; in a project file the template sees
+// the target's container (or target) name and image, as no container has been inspected yet
+func (p *Project) devName(name string) (string, error) {
+	target := p.Targets[name]
+	source := target.Container
+	if source == "" {
+		source = name
+	}
+	switch {
+	case target.Name != "":
+		return target.Name, nil
+	case p.Naming.Container != "":
+		tmpl, err := ParseNameTemplate(p.Naming.Container)
+		if err != nil {
+			return "", err
+		}
+		spec := &ContainerSpec{Name: source, Image: target.Image}
+		return RenderName(tmpl, NameContainer, NameData{ContainerSpec: spec, Source: source, User: CurrentUser(), N: 1})
+	default:
+		return source + "-dev", nil
+	}
+}
+
+// Hostnames returns the names other targets may use to reach a target: its name, its container's
+// name and its network aliases
+func (p *Project) Hostnames(name string) []string {
+	target := p.Targets[name]
+	hostnames := []string{name}
+	for _, hostname := range append([]string{target.Container}, target.Aliases...) {
+		if hostname != "" && hostname != name {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	return hostnames
+}
+
+// Remote reports whether a target runs on another docker context than the current one
+func (p *Project) Remote(name string) bool {
+	return p.Targets[name].Context != ""
+}
+
+// resolvePaths makes the targets' relative host paths absolute against dir; docker would take a
+// relative bind mount source for a volume name
+func (p *Project) resolvePaths(dir string) {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+	for _, target := range p.Targets {
+		target.SwapDir = resolve(target.SwapDir)
+		if target.Source != "" {
+			// Same split as --source: a drive letter is not a separator
+			local, containerDir := target.Source, ""
+			if i := strings.LastIndex(local, ":"); i > 1 && strings.HasPrefix(local[i+1:], "/") {
+				local, containerDir = local[:i], local[i:]
+			}
+			target.Source = resolve(local) + containerDir
+		}
+		for i := range target.Sync {
+			target.Sync[i].From = resolve(target.Sync[i].From)
+		}
+	}
+}
+
+// Select returns the named targets in the given order, or all targets in file order when none are
+// named; targets on other contexts come first, so the local ones can reach them from the start
+func (p *Project) Select(names []string) ([]string, error) {
+	if len(names) == 0 {
+		names = p.Order
+	}
+	for _, name := range names {
+		if p.Targets[name] == nil {
+			return nil, fmt.Errorf("unknown target '%s' (declared: %s)", name, strings.Join(p.Order, ", "))
+		}
+	}
+	var remote, local []string
+	for _, name := range names {
+		if p.Remote(name) {
+			remote = append(remote, name)
+		} else {
+			local = append(local, name)
+		}
+	}
+	return append(remote, local...), nil
+}
+
+// ContainerPorts returns the TCP container ports of the spec's published ports
+func (s *ContainerSpec) ContainerPorts() []int {
+	var ports []int
+	for _, port := range s.Ports {
+		// A range such as 8000-8010 is skipped; tunnels are per port
+		if port.TCP() && !port.IsRange() {
+			ports = append(ports, port.ContainerPort)
+		}
+	}
+	return ports
+}
@@ -0,0 +1,226 @@
+package containerconfig
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CommandNoteKey is the DevNotes key of a replaced entrypoint or command
+const CommandNoteKey = "command"
+
+// runFlagSources maps the flags of GenerateRunCommand to the docker inspect field they reproduce
+var runFlagSources = map[string]string{
+	"--name":                  ".Name",
+	"--rm":                    ".HostConfig.AutoRemove",
+	"-e":                      ".Config.Env",
+	"-v":                      ".HostConfig.Binds, .Mounts",
+	"--mount":                 ".Mounts.*.Driver",
+	"-p":                      ".HostConfig.PortBindings",
+	"--network":               ".HostConfig.NetworkMode, .NetworkSettings.Networks",
+	"--network-alias":         ".NetworkSettings.Networks.*.Aliases",
+	"--link":                  ".HostConfig.Links",
+	"--volumes-from":          ".HostConfig.VolumesFrom",
+	"--tmpfs":                 ".HostConfig.Tmpfs, .Mounts",
+	"-w":                      ".Config.WorkingDir",
+	"-l":                      ".Config.Labels",
+	"--device":                ".HostConfig.Devices, .HostConfig.DeviceRequests",
+	"--add-host":              ".HostConfig.ExtraHosts",
+	"--restart":               ".HostConfig.RestartPolicy",
+	"--user":                  ".Config.User",
+	"--privileged":            ".HostConfig.Privileged",
+	"--cap-add":               ".HostConfig.CapAdd",
+	"--security-opt":          ".AppArmorProfile",
+	"--memory":                ".HostConfig.Memory",
+	"--cpus":                  ".HostConfig.NanoCpus",
+	"--cpu-shares":            ".HostConfig.CpuShares",
+	"--memory-swappiness":     ".HostConfig.MemorySwappiness",
+	"--kernel-memory":         ".HostConfig.KernelMemory",
+	"--memory-swap":           ".HostConfig.MemorySwap",
+	"--memory-reservation":    ".HostConfig.MemoryReservation",
+	"--cpu-quota":             ".HostConfig.CpuQuota",
+	"--cpu-period":            ".HostConfig.CpuPeriod",
+	"--ulimit":                ".HostConfig.Ulimits",
+	"--no-healthcheck":        ".Config.Healthcheck",
+	"--health-cmd":            ".Config.Healthcheck.Test",
+	"--health-interval":       ".Config.Healthcheck.Interval",
+	"--health-timeout":        ".Config.Healthcheck.Timeout",
+	"--health-start-period":   ".Config.Healthcheck.StartPeriod",
+	"--health-start-interval": ".Config.Healthcheck.StartInterval",
+	"--health-retries":        ".Config.Healthcheck.Retries",
+	"--entrypoint":            ".Config.Entrypoint",
+}
+
+// booleanRunFlags are the flags of GenerateRunCommand that take no value
+var booleanRunFlags = map[string]bool{"--rm": true, "--privileged": true, "--no-healthcheck": true}
+
+// RunArg is a flag of a generated docker run command with its value, or the image, or the command
+// that follows it, together with where it came from
+type RunArg struct {
+	Args []string
+	// Source is the docker inspect field the argument reproduces
+	Source string
+	// Note says why the dev modifications added the argument; empty for arguments of the original
+	Note string
+}
+
+// Comment returns the provenance comment of the argument, e.g. "from .Config.Env" or
+// "added by dce: debug port"
+func (a RunArg) Comment() string {
+	if a.Note != "" {
+		return "added by dce: " + a.Note
+	}
+	if a.Source == "" {
+		return ""
+	}
+	return "from " + a.Source
+}
+
+// groupRunArgs splits the arguments of GenerateRunCommand into each flag with its value, the image,
+// and the command following the image
+func groupRunArgs(args []string) []RunArg {
+	var groups []RunArg
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case booleanRunFlags[arg]:
+			groups = append(groups, RunArg{Args: args[i : i+1], Source: runFlagSources[arg]})
+		case strings.HasPrefix(arg, "-") && i+1 < len(args):
+			groups = append(groups, RunArg{Args: args[i : i+2], Source: runFlagSources[arg]})
+			i++
+		default:
+			groups = append(groups, RunArg{Args: args[i : i+1], Source: ".Config.Image"})
+			if i+1 < len(args) {
+				groups = append(groups, RunArg{Args: args[i+1:], Source: ".Config.Cmd"})
+			}
+			return groups
+		}
+	}
+	return groups
+}
+
+// key returns the DevNotes key of the argument
+func (a RunArg) key() string {
+	if a.Source == ".Config.Cmd" {
+		return CommandNoteKey
+	}
+	return strings.Join(a.Args, " ")
+}
+
+// runArgKeys maps the spec's generated run arguments to their DevNotes keys; the command is
+// identified by its arguments so that only a changed command counts as added
+func runArgKeys(spec *ContainerSpec) map[string]string {
+	keys := make(map[string]string)
+	for _, arg := range groupRunArgs(GenerateRunCommand(spec, nil)) {
+		keys[strings.Join(arg.Args, " ")] = arg.key()
+	}
+	return keys
+}
+
+// AnnotateRunArgs groups the run arguments generated for the spec and tags each group with the
+// inspect field it came from and the spec's dev note
+func AnnotateRunArgs(spec *ContainerSpec, opts *RunOptions) []RunArg {
+	groups := groupRunArgs(GenerateRunCommand(spec, opts))
+	for i := range groups {
+		groups[i].Note = spec.DevNotes[groups[i].key()]
+		if opts != nil && opts.WorkingDirOverride != "" && groups[i].Args[0] == "-w" {
+			groups[i].Note = "working directory override"
+		}
+	}
+	return groups
+}
+
+// commentPrefix returns the comment syntax of a shell
+func commentPrefix(shell string) string {
+	if shell == ShellCmd {
+		return "REM "
+	}
+	return "# "
+}
+
+// FormatAnnotatedCommand formats the run command generated for the spec over several lines like
+// FormatCommandMultiline, preceded by a comment per line saying where it came from
+// Shells allow no comments between continued lines, so the comments come as a block above the command
+func FormatAnnotatedCommand(prefix []string, spec *ContainerSpec, opts *RunOptions, shell string) string {
+	quote, _ := quoteFor(shell)
+	groups := AnnotateRunArgs(spec, opts)
+	lines := make([]string, len(groups))
+	width := 0
+	for i, group := range groups {
+		quoted := make([]string, len(group.Args))
+		for j, arg := range group.Args {
+			quoted[j] = quote(arg)
+		}
+		lines[i] = strings.Join(quoted, " ")
+		width = max(width, len(lines[i]))
+	}
+
+	var b strings.Builder
+	for i, group := range groups {
+		if comment := group.Comment(); comment != "" {
+			fmt.Fprintf(&b, "%s%-*s  %s\n", commentPrefix(shell), width, lines[i], comment)
+		}
+	}
+	var args []string
+	args = append(args, prefix...)
+	args = append(args, GenerateRunCommand(spec, opts)...)
+	b.WriteString(FormatCommandMultiline(args, shell))
+	return b.String()
+}
+
+// setLineComments walks a YAML document and sets the line comment of each scalar, or of each
+// key for a mapping or sequence value, to what comment returns for its path, e.g. ["services", "web", "ports", "0"]
+func setLineComments(node *yaml.Node, path []string, comment func(path []string, value *yaml.Node) string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			setLineComments(child, path, comment)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := append(path[:len(path):len(path)], key.Value)
+			if text := comment(childPath, value); text != "" {
+				if value.Kind == yaml.ScalarNode {
+					value.LineComment = text
+				} else {
+					key.LineComment = text
+				}
+			}
+			setLineComments(value, childPath, comment)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			itemPath := append(path[:len(path):len(path)], fmt.Sprint(i))
+			if item.Kind == yaml.ScalarNode {
+				item.LineComment = comment(itemPath, item)
+			}
+			setLineComments(item, itemPath, comment)
+		}
+	}
+}
+
+// encodeYAML encodes a document with two-space indentation
+func encodeYAML(value any) ([]byte, error) {
+	var buf strings.Builder
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return []byte(buf.String()), nil
+}
+
+// annotatedYAML encodes value as YAML with the line comments comment returns
+func annotatedYAML(value any, comment func(path []string, value *yaml.Node) string) ([]byte, error) {
+	var doc yaml.Node
+	if err := doc.Encode(value); err != nil {
+		return nil, err
+	}
+	setLineComments(&doc, nil, comment)
+	return encodeYAML(&doc)
+}
@@ -0,0 +1,121 @@
+package containerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PortBinding is a container port published on the docker host, as a running container reports it
+type PortBinding struct {
+	ContainerPort int    `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+	HostIP        string `json:"hostIP,omitempty"`
+	HostPort      int    `json:"hostPort"`
+}
+
+// Host returns the address to reach the binding at from the docker host: localhost when the port
+// listens on all interfaces
+func (b PortBinding) Host() string {
+	switch b.HostIP {
+	case "", "0.0.0.0", "::":
+		return "localhost"
+	}
+	if strings.Contains(b.HostIP, ":") {
+		return "[" + b.HostIP + "]"
+	}
+	return b.HostIP
+}
+
+// connectTemplates are the connection strings of well-known container ports; %s is the host and
+// %d the host port
+var connectTemplates = map[int]string{
+	80:    "http://%s:%d",
+	443:   "https://%s:%d",
+	3000:  "http://%s:%d",
+	5000:  "http://%s:%d",
+	8000:  "http://%s:%d",
+	8080:  "http://%s:%d",
+	8443:  "https://%s:%d",
+	9090:  "http://%s:%d",
+	3306:  "mysql -h %s -P %d",
+	5432:  "psql -h %s -p %d",
+	6379:  "redis-cli -h %s -p %d",
+	27017: "mongodb://%s:%d",
+}
+
+// ConnectionString returns a ready-made way to connect to the binding: dlv connect for the
+// debugger port, a URL or client command for well-known ports, otherwise host:port
+func (b PortBinding) ConnectionString(debugPort int) string {
+	host := b.Host()
+	if b.Protocol == "udp" || b.Protocol == "sctp" {
+		return fmt.Sprintf("%s:%d/%s", host, b.HostPort, b.Protocol)
+	}
+	if b.ContainerPort == debugPort {
+		return fmt.Sprintf("dlv connect %s:%d", host, b.HostPort)
+	}
+	if template, ok := connectTemplates[b.ContainerPort]; ok {
+		if !strings.Contains(template, "://") {
+			// Client commands take the host and port as separate arguments, without IPv6 brackets
+			host = strings.Trim(host, "[]")
+		}
+		return fmt.Sprintf(template, host, b.HostPort)
+	}
+	return fmt.Sprintf("%s:%d", host, b.HostPort)
+}
+
+// ParsePortBindings returns the ports a container publishes from its docker inspect output, sorted
+// by container port; a port published on several host addresses has a binding for each, except
+// that the IPv4 and IPv6 wildcard bindings docker creates together are one binding on localhost
+func ParsePortBindings(jsonData string) ([]PortBinding, error) {
+	var inspectArray []InspectData
+	if err := json.Unmarshal([]byte(jsonData), &inspectArray); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if len(inspectArray) == 0 {
+		return nil, fmt.Errorf("empty inspect data")
+	}
+
+	var bindings []PortBinding
+	for port, hosts := range inspectArray[0].NetworkSettings.Ports {
+		number, protocol, _ := strings.Cut(port, "/")
+		containerPort, err := strconv.Atoi(number)
+		if err != nil {
+			return nil, fmt.Errorf("invalid container port '%s'", port)
+		}
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		for _, host := range hosts {
+			hostPort, err := strconv.Atoi(host.HostPort)
+			if err != nil {
+				continue
+			}
+			bindings = append(bindings, PortBinding{ContainerPort: containerPort, Protocol: protocol, HostIP: host.HostIP, HostPort: hostPort})
+		}
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		a, b := bindings[i], bindings[j]
+		if a.ContainerPort != b.ContainerPort {
+			return a.ContainerPort < b.ContainerPort
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.HostIP < b.HostIP
+	})
+	unique := bindings[:0]
+	for i, binding := range bindings {
+		if i > 0 {
+			last := unique[len(unique)-1]
+			if last.ContainerPort == binding.ContainerPort && last.Protocol == binding.Protocol &&
+				last.HostPort == binding.HostPort && last.Host() == binding.Host() {
+				continue
+			}
+		}
+		unique = append(unique, binding)
+	}
+	return unique, nil
+}
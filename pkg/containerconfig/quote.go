@@ -0,0 +1,174 @@
+package containerconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Shells a generated command can be quoted for
+const (
+	ShellPOSIX      = "sh"
+	ShellPowerShell = "powershell"
+	ShellCmd        = "cmd"
+)
+
+// shellSafe lists the characters that never need quoting in a POSIX shell word
+const shellSafe = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%+=:,./-_"
+
+// powerShellSafe lists the characters that never need quoting in a PowerShell argument
+// "," builds arrays and a leading "@" splats, so both are quoted; "\" is a plain path separator
+const powerShellSafe = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789%+=:./-_\\"
+
+// cmdSafe lists the characters that never need escaping in a cmd.exe argument
+const cmdSafe = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@+=:,./-_\\"
+
+// cmdSpecial lists the characters cmd.exe interprets; the quote is among them since it would
+// switch cmd into a quoted string, where carets are literal
+const cmdSpecial = "^&|<>()%!\""
+
+// QuoteShellArg quotes a single argument for a POSIX shell
+func QuoteShellArg(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if strings.Trim(arg, shellSafe) == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+}
+
+// QuotePowerShellArg quotes a single argument for PowerShell, using a double-quoted string
+// with backtick escapes so "$", "`" and quotes stay literal
+func QuotePowerShellArg(arg string) string {
+	if arg == "" {
+		return `""`
+	}
+	if strings.Trim(arg, powerShellSafe) == "" {
+		return arg
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range arg {
+		if r == '"' || r == '$' || r == '`' {
+			b.WriteByte('`')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// QuoteCmdArg quotes a single argument for cmd.exe in two layers: first for the program's argument
+// parser, which takes an argument with spaces or quotes in double quotes, then for cmd itself, which
+// gets every metacharacter caret-escaped, quotes included, so it never enters a quoted string where
+// carets are literal and & runs a command; "^%" keeps %VAR% from expanding on the command line
+func QuoteCmdArg(arg string) string {
+	if arg == "" {
+		return `^"^"`
+	}
+	if strings.Trim(arg, cmdSafe) == "" {
+		return arg
+	}
+	var b strings.Builder
+	for _, r := range quoteProgramArg(arg) {
+		if strings.ContainsRune(cmdSpecial, r) {
+			b.WriteByte('^')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// quoteProgramArg quotes an argument for the Windows C runtime's argument parser: backslashes are
+// only special before a quote, so those runs are doubled, and an inner quote is written \"
+// Doubling it ("") would read differently in parsers that follow the pre-2008 rule, Go's among
+// them, so docker.exe would split the argument
+func quoteProgramArg(arg string) string {
+	if !strings.ContainsAny(arg, " \t\"") {
+		return arg
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	backslashes := 0
+	for _, r := range arg {
+		switch r {
+		case '\\':
+			backslashes++
+		case '"':
+			b.WriteString(strings.Repeat(`\`, 2*backslashes+1) + `"`)
+			backslashes = 0
+		default:
+			b.WriteString(strings.Repeat(`\`, backslashes))
+			b.WriteRune(r)
+			backslashes = 0
+		}
+	}
+	b.WriteString(strings.Repeat(`\`, 2*backslashes))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// ValidateShell checks that a shell name is one of ShellPOSIX, ShellPowerShell or ShellCmd
+func ValidateShell(shell string) error {
+	switch shell {
+	case ShellPOSIX, ShellPowerShell, ShellCmd:
+		return nil
+	}
+	return fmt.Errorf("unsupported shell '%s' (supported: %s, %s, %s)", shell, ShellPOSIX, ShellPowerShell, ShellCmd)
+}
+
+// DefaultShell returns the shell commands for a container are usually run from:
+// PowerShell for Windows containers, a POSIX shell otherwise
+func (s *ContainerSpec) DefaultShell() string {
+	if strings.EqualFold(s.Platform, "windows") {
+		return ShellPowerShell
+	}
+	return ShellPOSIX
+}
+
+// quoteFor returns the quoting function and line continuation of a shell
+func quoteFor(shell string) (func(string) string, string) {
+	switch shell {
+	case ShellPowerShell:
+		return QuotePowerShellArg, "`"
+	case ShellCmd:
+		return QuoteCmdArg, "^"
+	default:
+		return QuoteShellArg, `\`
+	}
+}
+
+// FormatShellCommand joins a command and its arguments into a single POSIX shell command line
+func FormatShellCommand(args []string) string {
+	return FormatCommand(args, ShellPOSIX)
+}
+
+// FormatCommand joins a command and its arguments into a single command line for the given shell
+func FormatCommand(args []string, shell string) string {
+	quote, _ := quoteFor(shell)
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// FormatCommandMultiline formats a command over several lines, one flag (with its value) per line,
+// joined by the shell's line continuation: "\" for sh, "`" for PowerShell and "^" for cmd
+func FormatCommandMultiline(args []string, shell string) string {
+	quote, continuation := quoteFor(shell)
+	var lines []string
+	var line []string
+	for _, arg := range args {
+		// A flag starts a new line, its value stays on the flag's line
+		if strings.HasPrefix(arg, "-") && len(line) > 0 {
+			lines = append(lines, strings.Join(line, " "))
+			line = nil
+		}
+		line = append(line, quote(arg))
+	}
+	if len(line) > 0 {
+		lines = append(lines, strings.Join(line, " "))
+	}
+	return strings.Join(lines, " "+continuation+"\n  ")
+}
@@ -0,0 +1,187 @@
+package containerconfig_test
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+func TestQuoteShellArg(t *testing.T) {
+	tests := []struct {
+		arg, want string
+	}{
+		{"plain", "plain"},
+		{"", "''"},
+		{"/usr/local/bin:/usr/bin", "/usr/local/bin:/usr/bin"},
+		{"KEY=value,other@host%1", "KEY=value,other@host%1"},
+		{"a b", "'a b'"},
+		{"$HOME", "'$HOME'"},
+		{"it's", `'it'"'"'s'`},
+		{`say "hi" & exit`, `'say "hi" & exit'`},
+		{"*.go", "'*.go'"},
+		{"line\nbreak`cmd`", "'line\nbreak`cmd`'"},
+	}
+	for _, tt := range tests {
+		if got := containerconfig.QuoteShellArg(tt.arg); got != tt.want {
+			t.Errorf("QuoteShellArg(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+// TestQuoteShellArgRoundTrip has sh print each quoted argument back
+func TestQuoteShellArgRoundTrip(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh in PATH")
+	}
+	for _, arg := range []string{"", "a b", "it's", `"$(id)"`, "`id`; exit 1", "tab\there\nnewline", "*", "~", "!x"} {
+		out, err := exec.Command(sh, "-c", "printf %s "+containerconfig.QuoteShellArg(arg)).Output()
+		if err != nil {
+			t.Errorf("sh rejected %s: %v", containerconfig.QuoteShellArg(arg), err)
+			continue
+		}
+		if string(out) != arg {
+			t.Errorf("QuoteShellArg(%q) = %s, which sh reads as %q", arg, containerconfig.QuoteShellArg(arg), out)
+		}
+	}
+}
+
+func TestQuotePowerShellArg(t *testing.T) {
+	tests := []struct {
+		arg, want string
+	}{
+		{"plain", "plain"},
+		{"", `""`},
+		{`C:\Program_Files\app`, `C:\Program_Files\app`},
+		{"a b", `"a b"`},
+		{"$env:PATH", "\"`$env:PATH\""},
+		{`say "hi"`, "\"say `\"hi`\"\""},
+		{"tick`", "\"tick``\""},
+		{"a,b", `"a,b"`},
+		{"@args", `"@args"`},
+		{"it's", `"it's"`},
+		{"x;calc", `"x;calc"`},
+	}
+	for _, tt := range tests {
+		if got := containerconfig.QuotePowerShellArg(tt.arg); got != tt.want {
+			t.Errorf("QuotePowerShellArg(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteCmdArg(t *testing.T) {
+	tests := []struct {
+		arg, want string
+	}{
+		{"plain", "plain"},
+		{"", `^"^"`},
+		{"C:\\data", "C:\\data"},
+		{"a b", `^"a b^"`},
+		{"a&b", "a^&b"},
+		{"^", "^^"},
+		{"100%!", "100^%^!"},
+		{"%PATH%", "^%PATH^%"},
+		{`MSG=say "hi & calc & echo" done`, `^"MSG=say \^"hi ^& calc ^& echo\^" done^"`},
+		{`x"&calc`, `^"x\^"^&calc^"`},
+		{`x\"^&calc`, `^"x\\\^"^^^&calc^"`},
+		{`a\"b`, `^"a\\\^"b^"`},
+		{`C:\Program Files\app\`, `^"C:\Program Files\app\\^"`},
+		{"(a|b)<c>", "^(a^|b^)^<c^>"},
+	}
+	for _, tt := range tests {
+		if got := containerconfig.QuoteCmdArg(tt.arg); got != tt.want {
+			t.Errorf("QuoteCmdArg(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+// TestQuoteCmdArgRoundTrip runs each quoted argument through cmd.exe's escape handling and the
+// argument parser of a Go program such as docker.exe, which must give back the argument unchanged
+func TestQuoteCmdArgRoundTrip(t *testing.T) {
+	args := []string{
+		"", "plain", "a b", `say "hi"`, `MSG=say "hi & calc & echo" done`, `x"&calc`, `x\"^&calc`,
+		`a\\"b c`, `C:\Program Files\app\`, `trailing\\`, "%PATH% and %USERPROFILE%", "tab\there", `""`, `"`,
+	}
+	for _, arg := range args {
+		quoted := containerconfig.QuoteCmdArg(arg)
+		line, err := cmdLine(quoted)
+		if err != nil {
+			t.Errorf("QuoteCmdArg(%q) = %s: %v", arg, quoted, err)
+			continue
+		}
+		if got := windowsArgs(line); len(got) != 1 || got[0] != arg {
+			t.Errorf("QuoteCmdArg(%q) = %s, which the program reads as %q", arg, quoted, got)
+		}
+	}
+}
+
+// cmdLine applies cmd.exe's handling of a command line to it: it fails on a % that could expand and
+// on an operator cmd would act on, and removes the escaping carets outside quoted strings
+func cmdLine(line string) (string, error) {
+	var b strings.Builder
+	inQuote := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '%' && (i == 0 || line[i-1] != '^'):
+			return "", fmt.Errorf("unescaped %% at %d", i)
+		case c == '^' && !inQuote && i+1 < len(line):
+			i++
+			b.WriteByte(line[i])
+			continue
+		case c == '"':
+			inQuote = !inQuote
+		case strings.IndexByte("&|<>", c) >= 0 && !inQuote:
+			return "", fmt.Errorf("unescaped %c at %d", c, i)
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
+
+// windowsArgs splits a command line the way Go programs on Windows do, with the pre-2008 rule
+// for doubled quotes
+func windowsArgs(line string) []string {
+	var args []string
+	for line != "" {
+		var arg []byte
+		inQuote, slashes := false, 0
+		i := 0
+	scan:
+		for ; i < len(line); i++ {
+			c := line[i]
+			switch c {
+			case ' ', '\t':
+				if !inQuote {
+					break scan
+				}
+			case '\\':
+				slashes++
+				continue
+			case '"':
+				arg = append(arg, strings.Repeat(`\`, slashes/2)...)
+				if slashes%2 == 0 {
+					if inQuote && i+1 < len(line) && line[i+1] == '"' {
+						arg = append(arg, '"')
+						i++
+					}
+					inQuote = !inQuote
+				} else {
+					arg = append(arg, '"')
+				}
+				slashes = 0
+				continue
+			}
+			arg = append(arg, strings.Repeat(`\`, slashes)...)
+			slashes = 0
+			arg = append(arg, c)
+		}
+		arg = append(arg, strings.Repeat(`\`, slashes)...)
+		args = append(args, string(arg))
+		line = strings.TrimLeft(line[min(i, len(line)):], " \t")
+	}
+	return args
+}
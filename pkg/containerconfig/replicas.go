@@ -0,0 +1,87 @@
+package containerconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// replicaGroup identifies the compose service a container is a replica of; empty for other containers
+func (s *ContainerSpec) replicaGroup() string {
+	project, service := s.Labels[ComposeProjectLabel], s.Labels[ComposeServiceLabel]
+	if project == "" || service == "" {
+		return ""
+	}
+	return project + "/" + service
+}
+
+// replicaNumber returns the compose container number, or 0 when unknown
+func (s *ContainerSpec) replicaNumber() int {
+	number, _ := strconv.Atoi(s.Labels[ComposeContainerNumberLabel])
+	return number
+}
+
+// GroupReplicas collapses the replicas of each scaled compose service into one representative spec,
+// the lowest-numbered container, with Replicas set to the group size
+// Specs keep the order in which their group first appears; the input specs are not modified
+func GroupReplicas(specs []*ContainerSpec) []*ContainerSpec {
+	var result []*ContainerSpec
+	index := make(map[string]int)
+	counts := make(map[string]int)
+	for _, spec := range specs {
+		group := spec.replicaGroup()
+		if group == "" {
+			result = append(result, spec)
+			continue
+		}
+		counts[group]++
+		i, seen := index[group]
+		if !seen {
+			index[group] = len(result)
+			result = append(result, spec)
+			continue
+		}
+		if number := spec.replicaNumber(); number > 0 && (result[i].replicaNumber() == 0 || number < result[i].replicaNumber()) {
+			result[i] = spec
+		}
+	}
+
+	for group, i := range index {
+		if counts[group] > 1 {
+			representative := result[i].Clone()
+			representative.Replicas = counts[group]
+			result[i] = representative
+		}
+	}
+	return result
+}
+
+// ExpandReplicas returns one spec per replica, named like compose names them (project-service-N)
+// and with the container number label updated; specs without replicas are returned as is
+func (s *ContainerSpec) ExpandReplicas() []*ContainerSpec {
+	if s.Replicas <= 1 {
+		return []*ContainerSpec{s}
+	}
+
+	base, separator := s.Name, "-"
+	if number := s.Labels[ComposeContainerNumberLabel]; number != "" {
+		for _, sep := range []string{"-", "_"} {
+			if strings.HasSuffix(s.Name, sep+number) {
+				base, separator = strings.TrimSuffix(s.Name, sep+number), sep
+				break
+			}
+		}
+	}
+
+	replicas := make([]*ContainerSpec, 0, s.Replicas)
+	for i := 1; i <= s.Replicas; i++ {
+		replica := s.Clone()
+		replica.Replicas = 0
+		replica.Name = fmt.Sprintf("%s%s%d", base, separator, i)
+		if _, ok := replica.Labels[ComposeContainerNumberLabel]; ok {
+			replica.Labels[ComposeContainerNumberLabel] = strconv.Itoa(i)
+		}
+		replicas = append(replicas, replica)
+	}
+	return replicas
+}
@@ -0,0 +1,300 @@
+package containerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report formats supported by Report.Write
+const (
+	ReportFormatJSON     = "json"
+	ReportFormatMarkdown = "md"
+	ReportFormatHTML     = "html"
+)
+
+// Report is an audit report covering one or more containers
+type Report struct {
+	GeneratedAt time.Time         `json:"generatedAt"`
+	Host        string            `json:"host,omitempty"`
+	Containers  []ContainerReport `json:"containers"`
+}
+
+// ContainerReport holds the redacted configuration, image provenance and findings of one container
+type ContainerReport struct {
+	Spec       *ContainerSpec    `json:"spec"`
+	Image      *ImageInfo        `json:"image,omitempty"`
+	Provenance map[string]string `json:"provenance,omitempty"`
+	Findings   []Finding         `json:"findings"`
+	// Vulnerabilities is the image scan result, when a scanner was used
+	Vulnerabilities *VulnerabilitySummary `json:"vulnerabilities,omitempty"`
+	// Processes is the docker top listing of a running container
+	Processes []Process `json:"processes,omitempty"`
+	// Attach is the process a debugger should attach to, when one could be picked
+	Attach *AttachSuggestion `json:"attach,omitempty"`
+}
+
+// AddProcesses attaches a running container's process list and the process to debug
+func (c *ContainerReport) AddProcesses(processes []Process, initPID int) {
+	c.Processes = processes
+	if attach, err := SuggestAttach(processes, initPID); err == nil {
+		c.Attach = attach
+	}
+}
+
+// AddVulnerabilities attaches an image scan result and reports critical and high counts as findings
+func (c *ContainerReport) AddVulnerabilities(summary *VulnerabilitySummary) {
+	c.Vulnerabilities = summary
+	c.Findings = append(summary.findings(), c.Findings...)
+}
+
+// NewContainerReport builds the report section for a container; the spec is redacted before inclusion
+func NewContainerReport(spec *ContainerSpec, image *ImageInfo) ContainerReport {
+	provenance := spec.Provenance()
+	if image != nil {
+		for key, value := range ProvenanceLabels(image.Labels) {
+			if _, ok := provenance[key]; !ok {
+				provenance[key] = value
+			}
+		}
+	}
+
+	return ContainerReport{
+		Spec:       Redact(spec),
+		Image:      image,
+		Provenance: provenance,
+		Findings:   SecurityFindings(spec),
+	}
+}
+
+// Write renders the report in the given format
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case ReportFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case ReportFormatMarkdown:
+		return r.writeMarkdown(w)
+	case ReportFormatHTML:
+		return reportTemplate.Execute(w, r)
+	default:
+		return fmt.Errorf("unsupported report format '%s' (supported: json, md, html)", format)
+	}
+}
+
+// writeMarkdown renders the report as Markdown
+func (r *Report) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Container Configuration Report\n\n")
+	fmt.Fprintf(&b, "Generated: %s\n", r.GeneratedAt.Format(time.RFC3339))
+	if r.Host != "" {
+		fmt.Fprintf(&b, "Host: %s\n", r.Host)
+	}
+
+	for _, c := range r.Containers {
+		spec := c.Spec
+		fmt.Fprintf(&b, "\n## %s\n\n", spec.Name)
+
+		b.WriteString("### Image Provenance\n\n")
+		fmt.Fprintf(&b, "- Image: `%s`\n", spec.Image)
+		if c.Image != nil {
+			fmt.Fprintf(&b, "- ID: `%s`\n", c.Image.ID)
+			for _, digest := range c.Image.RepoDigests {
+				fmt.Fprintf(&b, "- Digest: `%s`\n", digest)
+			}
+			if c.Image.Created != "" {
+				fmt.Fprintf(&b, "- Created: %s\n", c.Image.Created)
+			}
+		}
+		for _, key := range sortedKeys(c.Provenance) {
+			fmt.Fprintf(&b, "- %s: `%s`\n", strings.TrimPrefix(key, OCIImageLabelPrefix), c.Provenance[key])
+		}
+
+		b.WriteString("\n### Security Findings\n\n")
+		if len(c.Findings) == 0 {
+			b.WriteString("No findings.\n")
+		}
+		for _, f := range c.Findings {
+			fmt.Fprintf(&b, "- **%s**: %s\n", strings.ToUpper(f.Severity), f.Message)
+		}
+
+		if v := c.Vulnerabilities; v != nil {
+			b.WriteString("\n### Vulnerabilities\n\n")
+			fmt.Fprintf(&b, "%s in `%s` (%s, %s)\n", v, v.Target, v.Scanner, v.ScannedAt.Format(time.RFC3339))
+		}
+
+		if len(c.Processes) > 0 {
+			b.WriteString("\n### Processes\n\n")
+			b.WriteString("| PID (host) | PPID | User | Command |\n|---|---|---|---|\n")
+			for _, p := range c.Processes {
+				fmt.Fprintf(&b, "| %d | %d | %s | `%s` |\n", p.PID, p.PPID, p.User, strings.ReplaceAll(p.Command, "|", "\\|"))
+			}
+			if a := c.Attach; a != nil {
+				fmt.Fprintf(&b, "\nDebug `%s` (%s): `%s`\n", a.Process.Binary(), a.Reason, a.AttachCommand(spec.Name))
+			}
+		}
+
+		b.WriteString("\n### Configuration\n\n")
+		b.WriteString("| Setting | Value |\n|---|---|\n")
+		for _, row := range specRows(spec) {
+			fmt.Fprintf(&b, "| %s | %s |\n", row[0], strings.ReplaceAll(row[1], "|", "\\|"))
+		}
+
+		if c.Image != nil && len(c.Image.Labels) > 0 {
+			b.WriteString("\n### Image Labels\n\n")
+			for _, key := range sortedKeys(c.Image.Labels) {
+				fmt.Fprintf(&b, "- `%s` = `%s`\n", key, c.Image.Labels[key])
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// specRows flattens a spec into setting/value pairs for tabular output; unset settings are omitted
+func specRows(spec *ContainerSpec) [][2]string {
+	var rows [][2]string
+	add := func(name, value string) {
+		if value != "" {
+			rows = append(rows, [2]string{name, value})
+		}
+	}
+	list := func(name string, values []string) {
+		for _, value := range values {
+			add(name, value)
+		}
+	}
+
+	add("Image", spec.Image)
+	add("User", spec.User)
+	add("Working directory", spec.WorkingDir)
+	add("Entrypoint", strings.Join(spec.EntryPoint, " "))
+	add("Command", strings.Join(spec.Command, " "))
+	add("Healthcheck", spec.Healthcheck.String())
+	add("Restart policy", spec.Restart)
+	add("Network mode", spec.NetworkMode)
+	if spec.Privileged {
+		add("Privileged", "true")
+	}
+	if spec.Memory > 0 {
+		add("Memory limit", spec.Memory.Human())
+	}
+	if spec.NanoCPUs > 0 {
+		add("CPU limit", spec.NanoCPUs.String())
+	}
+	if tz := spec.Timezone(); !tz.Empty() {
+		add("Timezone", tz.String())
+	}
+	if spec.Replicas > 1 {
+		add("Replicas", fmt.Sprintf("%d", spec.Replicas))
+	}
+	if r := spec.Runtime; r != nil {
+		add("Status", r.Status)
+		add("Started at", r.StartedAt)
+		if r.RestartCount > 0 {
+			add("Restarts", fmt.Sprintf("%d", r.RestartCount))
+		}
+		if r.OOMKilled {
+			add("OOM killed", "true")
+		}
+		add("CPU usage", fmt.Sprintf("%.2f%%", r.CPUPercent))
+		add("Memory usage", fmt.Sprintf("%s / %s", FormatBytes(r.MemoryUsage), FormatBytes(r.MemoryLimit)))
+		add("Network I/O", fmt.Sprintf("%s in / %s out", FormatBytes(r.NetRx), FormatBytes(r.NetTx)))
+		add("Block I/O", fmt.Sprintf("%s read / %s written", FormatBytes(r.BlockRead), FormatBytes(r.BlockWrite)))
+		add("Snapshot taken", r.CapturedAt.Format(time.RFC3339))
+	}
+	list("Environment", spec.Env)
+	list("Volume", spec.VolumeStrings())
+	list("Port", spec.PortStrings())
+	list("Network", spec.Networks)
+	list("Network alias", spec.NetworkAliases)
+	list("Device", spec.Devices)
+	list("Capability", spec.CapAdd)
+	list("Extra host", spec.ExtraHosts)
+	list("Link", spec.Links)
+	list("Volumes from", spec.VolumesFrom)
+	list("Tmpfs", spec.Tmpfs)
+	list("Ulimit", spec.Ulimits)
+	for _, key := range sortedKeys(spec.Labels) {
+		add("Label", key+"="+spec.Labels[key])
+	}
+	return rows
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reportTemplate renders the report as a standalone HTML page
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"rows":  specRows,
+	"upper": strings.ToUpper,
+	"keys":  sortedKeys,
+	"date":  func(t time.Time) string { return t.Format(time.RFC3339) },
+	"trimOCI": func(key string) string {
+		return strings.TrimPrefix(key, OCIImageLabelPrefix)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Container Configuration Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+code { font-size: 0.9em; }
+.critical, .high { color: #b00020; font-weight: bold; }
+.medium { color: #b36b00; }
+</style>
+</head>
+<body>
+<h1>Container Configuration Report</h1>
+<p>Generated: {{date .GeneratedAt}}{{if .Host}}<br>Host: {{.Host}}{{end}}</p>
+{{range .Containers}}
+<h2>{{.Spec.Name}}</h2>
+<h3>Image Provenance</h3>
+<ul>
+<li>Image: <code>{{.Spec.Image}}</code></li>
+{{with .Image}}<li>ID: <code>{{.ID}}</code></li>
+{{range .RepoDigests}}<li>Digest: <code>{{.}}</code></li>
+{{end}}{{if .Created}}<li>Created: {{.Created}}</li>{{end}}{{end}}
+{{$provenance := .Provenance}}{{range keys .Provenance}}<li>{{trimOCI .}}: <code>{{index $provenance .}}</code></li>
+{{end}}</ul>
+<h3>Security Findings</h3>
+{{if .Findings}}<ul>
+{{range .Findings}}<li class="{{.Severity}}">{{upper .Severity}}: {{.Message}}</li>
+{{end}}</ul>{{else}}<p>No findings.</p>{{end}}
+{{with .Vulnerabilities}}<h3>Vulnerabilities</h3>
+<p>{{.}} in <code>{{.Target}}</code> ({{.Scanner}}, {{date .ScannedAt}})</p>
+{{end}}{{$name := .Spec.Name}}{{if .Processes}}<h3>Processes</h3>
+<table>
+<tr><th>PID (host)</th><th>PPID</th><th>User</th><th>Command</th></tr>
+{{range .Processes}}<tr><td>{{.PID}}</td><td>{{.PPID}}</td><td>{{.User}}</td><td><code>{{.Command}}</code></td></tr>
+{{end}}</table>
+{{with .Attach}}<p>Debug <code>{{.Process.Binary}}</code> ({{.Reason}}): <code>{{.AttachCommand $name}}</code></p>{{end}}
+{{end}}<h3>Configuration</h3>
+<table>
+<tr><th>Setting</th><th>Value</th></tr>
+{{range rows .Spec}}<tr><td>{{index . 0}}</td><td><code>{{index . 1}}</code></td></tr>
+{{end}}</table>
+{{with .Image}}{{if .Labels}}<h3>Image Labels</h3>
+<ul>
+{{$labels := .Labels}}{{range keys .Labels}}<li><code>{{.}}</code> = <code>{{index $labels .}}</code></li>
+{{end}}</ul>{{end}}{{end}}
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,111 @@
+package containerconfig
+
+import (
+	"fmt"
+	"math"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultHeadroomPercent is the margin added on top of observed usage when inferring resource requests
+const DefaultHeadroomPercent = 20
+
+// Lower bounds of inferred requests, so a container that was idle at snapshot time isn't starved later
+const (
+	minCPUMillis   = 10
+	minMemoryBytes = 16 << 20
+)
+
+// KubeResources are the resources.requests and resources.limits of a Kubernetes container
+// Values are Kubernetes quantities such as "250m" or "128Mi"; a missing key is left unset
+type KubeResources struct {
+	Requests map[string]string `json:"requests,omitempty" yaml:"requests,omitempty"`
+	Limits   map[string]string `json:"limits,omitempty" yaml:"limits,omitempty"`
+}
+
+// Empty reports whether neither requests nor limits are set
+func (r *KubeResources) Empty() bool {
+	return r == nil || (len(r.Requests) == 0 && len(r.Limits) == 0)
+}
+
+// InferKubeResources derives Kubernetes resources for a spec
+// Limits come from the container's memory and CPU limits. Requests come from the runtime snapshot's
+// usage plus headroomPercent, capped at the limits; without a snapshot they are left to default to the limits
+func InferKubeResources(spec *ContainerSpec, headroomPercent int) (*KubeResources, []Warning) {
+	var warnings []Warning
+	resources := &KubeResources{}
+	set := func(m *map[string]string, key, value string) {
+		if *m == nil {
+			*m = make(map[string]string)
+		}
+		(*m)[key] = value
+	}
+
+	if spec.NanoCPUs > 0 {
+		set(&resources.Limits, "cpu", spec.NanoCPUs.Quantity())
+	}
+	if spec.Memory > 0 {
+		set(&resources.Limits, "memory", spec.Memory.Quantity())
+	}
+
+	runtime := spec.Runtime
+	switch {
+	case runtime == nil:
+		warnings = append(warnings, Warning{Field: "resources", Message: "no stats snapshot; requests are not inferred (extract with --stats)"})
+	case runtime.Status != "running":
+		warnings = append(warnings, Warning{Field: "resources", Message: fmt.Sprintf("container was %s when the snapshot was taken; requests are not inferred", runtime.Status)})
+	default:
+		factor := 1 + float64(headroomPercent)/100
+		cpuMillis := int64(math.Ceil(runtime.CPUPercent * 10 * factor))
+		if cpuMillis < minCPUMillis {
+			cpuMillis = minCPUMillis
+		}
+		if spec.NanoCPUs > 0 && cpuMillis > spec.NanoCPUs.Millis() {
+			cpuMillis = spec.NanoCPUs.Millis()
+		}
+		memory := Bytes(math.Ceil(float64(runtime.MemoryUsage) * factor))
+		if memory < minMemoryBytes {
+			memory = minMemoryBytes
+		}
+		if spec.Memory > 0 && memory > spec.Memory {
+			memory = spec.Memory
+		}
+		set(&resources.Requests, "cpu", FormatCPUQuantity(cpuMillis))
+		set(&resources.Requests, "memory", memory.Quantity())
+		warnings = append(warnings, Warning{Field: "resources", Message: fmt.Sprintf("requests are based on a single snapshot taken at %s; check them against peak load", runtime.CapturedAt.Format("2006-01-02 15:04"))})
+	}
+
+	if spec.Memory == 0 && spec.NanoCPUs == 0 {
+		warnings = append(warnings, Warning{Field: "resources", Message: "the container has no memory or CPU limit, so no limits are set"})
+	}
+	return resources, warnings
+}
+
+// AnnotatedYAML renders the resources as a "resources:" stanza with comments saying where each
+// value came from; headroomPercent is the one they were inferred with
+func (r *KubeResources) AnnotatedYAML(headroomPercent int) ([]byte, error) {
+	sources := map[string]string{
+		"limits.cpu":      "from .HostConfig.NanoCpus",
+		"limits.memory":   "from .HostConfig.Memory",
+		"requests.cpu":    fmt.Sprintf("from .Runtime.CPUPercent + %d%% headroom", headroomPercent),
+		"requests.memory": fmt.Sprintf("from .Runtime.MemoryUsage + %d%% headroom", headroomPercent),
+	}
+	data, err := annotatedYAML(map[string]*KubeResources{"resources": r}, func(path []string, _ *yaml.Node) string {
+		if len(path) != 3 {
+			return ""
+		}
+		return sources[path[1]+"."+path[2]]
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode resources: %w", err)
+	}
+	return data, nil
+}
+
+// FormatCPUQuantity formats millicores as a Kubernetes CPU quantity, e.g. "250m" or "2"
+func FormatCPUQuantity(millis int64) string {
+	if millis%1000 == 0 {
+		return fmt.Sprintf("%d", millis/1000)
+	}
+	return fmt.Sprintf("%dm", millis)
+}
@@ -0,0 +1,79 @@
+package containerconfig
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// RuntimeUpdate describes a setting that was changed with docker update after the container was created
+type RuntimeUpdate struct {
+	Field    string `json:"field"`
+	Original string `json:"original"`
+	Current  string `json:"current"`
+}
+
+// String formats the update as a single line
+func (u RuntimeUpdate) String() string {
+	return fmt.Sprintf("%s: %s at create time, %s now (changed with docker update)", u.Field, displayValue(u.Original), displayValue(u.Current))
+}
+
+// runtimeField is a setting docker update can change, together with the label recording its create-time value
+type runtimeField struct {
+	name  string
+	label string
+	value func(*ContainerSpec) string
+}
+
+// runtimeFields lists the settings tracked for runtime updates
+var runtimeFields = []runtimeField{
+	{"memory", CreatedMemoryLabel, func(s *ContainerSpec) string { return strconv.FormatInt(int64(s.Memory), 10) }},
+	{"cpus", CreatedCPUsLabel, func(s *ContainerSpec) string { return s.NanoCPUs.String() }},
+	{"cpuShares", CreatedCPUSharesLabel, func(s *ContainerSpec) string { return strconv.FormatInt(s.CPUShares, 10) }},
+	{"restart", CreatedRestartLabel, func(s *ContainerSpec) string { return s.Restart }},
+}
+
+// IsRuntimeUpdatable reports whether a spec field can be changed with docker update
+func IsRuntimeUpdatable(field string) bool {
+	for _, f := range runtimeFields {
+		if f.name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// StampCreateValues records the current value of every runtime-updatable setting in the spec's labels,
+// so later inspections can tell which settings were changed with docker update
+// The Docker API only reports current values, so this is the only way to keep the originals
+func StampCreateValues(spec *ContainerSpec) {
+	if spec.Labels == nil {
+		spec.Labels = make(map[string]string)
+	}
+	for _, f := range runtimeFields {
+		spec.Labels[f.label] = f.value(spec)
+	}
+}
+
+// RuntimeUpdates compares the create-time values recorded by StampCreateValues with the spec's current values
+// Containers that weren't stamped report no updates
+func RuntimeUpdates(spec *ContainerSpec) []RuntimeUpdate {
+	var updates []RuntimeUpdate
+	for _, f := range runtimeFields {
+		original, ok := spec.Labels[f.label]
+		if !ok {
+			continue
+		}
+		if current := f.value(spec); current != original {
+			updates = append(updates, RuntimeUpdate{Field: f.name, Original: original, Current: current})
+		}
+	}
+	return updates
+}
+
+// displayValue renders an empty or zero setting as "unset"
+func displayValue(value string) string {
+	if value == "" || value == "0" {
+		return "unset"
+	}
+	return value
+}
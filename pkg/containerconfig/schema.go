@@ -0,0 +1,351 @@
+package containerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema names accepted by SchemaFor
+const (
+	SchemaSpec    = "spec"
+	SchemaProject = "project"
+	SchemaOverlay = "overlay"
+)
+
+// Schema is the subset of JSON Schema used to describe the spec, project and overlay file formats
+type Schema struct {
+	Schema     string             `json:"$schema,omitempty"`
+	Title      string             `json:"title,omitempty"`
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Enum       []string           `json:"enum,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	// AdditionalProperties is false for structs and the value schema for maps
+	AdditionalProperties any     `json:"additionalProperties,omitempty"`
+	Items                *Schema `json:"items,omitempty"`
+	// OneOf lists the alternative forms of a value, such as a mount given as a string or a mapping
+	OneOf []*Schema `json:"oneOf,omitempty"`
+}
+
+// schemaEnums lists the allowed values of string fields, keyed by type and field name
+var schemaEnums = map[string][]string{
+	"ContainerSpec.CommandForm":    {FormExec, FormShell},
+	"ContainerSpec.EntryPointForm": {FormExec, FormShell},
+	"ProjectTarget.Deps":           {"ask", "attach", "clone"},
+	"mountFields.Type":             {MountBind, MountVolume, MountTmpfs},
+	"SyncRule.Mode":                {SyncMount, SyncCopy},
+	"KubeMount.As":                 {KubeHostPath, KubeEmptyDir, KubeClaim},
+}
+
+// Types held as strings in files: timestamps as RFC 3339, durations as Go duration strings, ports
+// as -p strings and mounts as -v strings or mappings
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(Duration(0))
+	portType     = reflect.TypeOf(PortMapping{})
+	mountType    = reflect.TypeOf(Mount{})
+)
+
+// SchemaFor returns the JSON Schema of a file format, derived from the Go types so it never
+// falls behind them
+func SchemaFor(name string) (*Schema, error) {
+	var schema *Schema
+	switch name {
+	case SchemaSpec:
+		schema = schemaOf(reflect.TypeOf(ContainerSpec{}))
+		schema.Title = "docker-config-extractor container spec"
+	case SchemaProject:
+		schema = schemaOf(reflect.TypeOf(Project{}))
+		schema.Title = "docker-config-extractor project file (" + ProjectFileName + ")"
+	case SchemaOverlay:
+		schema = schemaOf(reflect.TypeOf(KubeOverlay{}))
+		schema.Title = "docker-config-extractor Kubernetes overlay file"
+	default:
+		return nil, fmt.Errorf("unknown schema '%s' (expected %s, %s or %s)", name, SchemaSpec, SchemaProject, SchemaOverlay)
+	}
+	schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+	return schema, nil
+}
+
+// MarshalSchema renders a schema as indented JSON
+func MarshalSchema(schema *Schema) ([]byte, error) {
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// schemaOf builds the schema of a Go type from its yaml tags; fields without omitempty are required
+func schemaOf(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType, t == portType:
+		return &Schema{Type: "string"}
+	case t == mountType:
+		return &Schema{OneOf: []*Schema{{Type: "string"}, schemaOf(reflect.TypeOf(mountFields{}))}}
+	case t.Kind() == reflect.String:
+		return &Schema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return &Schema{Type: "number"}
+	case t.Kind() == reflect.Slice:
+		return &Schema{Type: "array", Items: schemaOf(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem())}
+	case t.Kind() == reflect.Struct:
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("yaml")
+			name, options, _ := strings.Cut(tag, ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			property := schemaOf(field.Type)
+			property.Enum = schemaEnums[t.Name()+"."+field.Name]
+			schema.Properties[name] = property
+			if !strings.Contains(options, "omitempty") {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		return schema
+	}
+	return &Schema{}
+}
+
+// SchemaError is a place where a file doesn't match its schema
+type SchemaError struct {
+	Line    int
+	Column  int
+	Path    string
+	Message string
+}
+
+// Error formats the error with its location, e.g. "line 3, column 1: env: expected an array"
+func (e SchemaError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("line %d, column %d: %s: %s", e.Line, e.Column, e.Path, e.Message)
+}
+
+// SchemaErrors are all the mismatches found in a file, in file order
+type SchemaErrors []SchemaError
+
+// Error lists the mismatches one per line
+func (errs SchemaErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidateSchema checks a JSON or YAML document against the named schema and returns SchemaErrors
+// locating every unknown field, wrong type and missing required field
+func ValidateSchema(name string, data []byte) error {
+	schema, err := SchemaFor(name)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return SchemaErrors{{Line: 1, Column: 1, Message: "the file is empty"}}
+	}
+	var errs SchemaErrors
+	validateNode(doc.Content[0], schema, "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Column < errs[j].Column
+	})
+	return errs
+}
+
+// validateNode checks a node against its schema, appending mismatches to errs
+func validateNode(node *yaml.Node, schema *Schema, path string, errs *SchemaErrors) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	fail := func(node *yaml.Node, path, format string, args ...any) {
+		*errs = append(*errs, SchemaError{Line: node.Line, Column: node.Column, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	// An empty value leaves the field at its zero value, as omitempty writes it
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	if len(schema.OneOf) > 0 {
+		var types []string
+		for _, alternative := range schema.OneOf {
+			if nodeFits(node, alternative) {
+				validateNode(node, alternative, path, errs)
+				return
+			}
+			types = append(types, alternative.Type)
+		}
+		fail(node, path, "expected %s, got %s", strings.Join(types, " or "), nodeDescription(node))
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			fail(node, path, "expected a mapping, got %s", nodeDescription(node))
+			return
+		}
+		seen := make(map[string]bool)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			seen[key.Value] = true
+			childPath := joinSchemaPath(path, key.Value)
+			if property, ok := schema.Properties[key.Value]; ok {
+				validateNode(value, property, childPath, errs)
+			} else if additional, ok := schema.AdditionalProperties.(*Schema); ok {
+				validateNode(value, additional, childPath, errs)
+			} else if suggestion := closestProperty(key.Value, schema.Properties); suggestion != "" {
+				fail(key, path, "unknown field '%s' (did you mean '%s'?)", key.Value, suggestion)
+			} else {
+				fail(key, path, "unknown field '%s'", key.Value)
+			}
+		}
+		for _, required := range schema.Required {
+			if !seen[required] {
+				fail(node, path, "missing required field '%s'", required)
+			}
+		}
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			fail(node, path, "expected a list, got %s", nodeDescription(node))
+			return
+		}
+		for i, item := range node.Content {
+			validateNode(item, schema.Items, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case "string":
+		if node.Kind != yaml.ScalarNode {
+			fail(node, path, "expected a string, got %s", nodeDescription(node))
+			return
+		}
+		if len(schema.Enum) > 0 && !containsString(schema.Enum, node.Value) {
+			fail(node, path, "invalid value '%s' (expected one of %s)", node.Value, strings.Join(schema.Enum, ", "))
+		}
+	case "integer":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			fail(node, path, "expected an integer, got %s", nodeDescription(node))
+		}
+	case "number":
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!int" && node.Tag != "!!float") {
+			fail(node, path, "expected a number, got %s", nodeDescription(node))
+		}
+	case "boolean":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			fail(node, path, "expected true or false, got %s", nodeDescription(node))
+		}
+	}
+}
+
+// nodeFits reports whether a node is of the kind a schema's type needs: a mapping for an object, a
+// list for an array and a scalar otherwise
+func nodeFits(node *yaml.Node, schema *Schema) bool {
+	switch schema.Type {
+	case "object":
+		return node.Kind == yaml.MappingNode
+	case "array":
+		return node.Kind == yaml.SequenceNode
+	}
+	return node.Kind == yaml.ScalarNode
+}
+
+// nodeDescription describes what a node holds, for error messages
+func nodeDescription(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	default:
+		return fmt.Sprintf("'%s'", node.Value)
+	}
+}
+
+// joinSchemaPath appends a field name to a dotted path
+func joinSchemaPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// closestProperty returns the property an unknown field name was most likely meant to be: one it
+// starts with or that starts with it (enviroment for env), or one a few typos away, or ""
+func closestProperty(name string, properties map[string]*Schema) string {
+	lower := strings.ToLower(name)
+	best, bestDistance := "", 3
+	for property := range properties {
+		candidate := strings.ToLower(property)
+		distance := editDistance(lower, candidate)
+		if strings.HasPrefix(lower, candidate) || strings.HasPrefix(candidate, lower) {
+			distance = min(distance, 1)
+		}
+		if distance < bestDistance || (distance == bestDistance && property < best) {
+			best, bestDistance = property, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,148 @@
+package containerconfig
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Finding severities, from most to least severe
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+)
+
+// RedactedValue replaces sensitive values in redacted specs
+const RedactedValue = "********"
+
+// Finding is a security-relevant observation about a container configuration
+type Finding struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// sensitiveKeyParts mark env var and label names whose values are treated as secrets
+var sensitiveKeyParts = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "APIKEY", "API_KEY", "ACCESS_KEY", "PRIVATE_KEY", "CREDENTIAL", "AUTH"}
+
+// sensitiveHostPaths are host paths that give a container control over the host when mounted writable
+var sensitiveHostPaths = []string{"/", "/etc", "/root", "/proc", "/sys", "/boot", "/var/lib/docker"}
+
+// dangerousCapabilities are capabilities that effectively break container isolation
+var dangerousCapabilities = []string{"ALL", "SYS_ADMIN", "SYS_PTRACE", "SYS_MODULE", "NET_ADMIN", "DAC_READ_SEARCH"}
+
+// IsSensitiveKey reports whether an env var or label name looks like it holds a secret
+func IsSensitiveKey(key string) bool {
+	upper := strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(upper, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValue hides secrets in a single value: fully for sensitive keys, otherwise only URL passwords
+func redactValue(key, value string) string {
+	if value == "" {
+		return value
+	}
+	if IsSensitiveKey(key) {
+		return RedactedValue
+	}
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if password, hasPassword := u.User.Password(); hasPassword && password != "" {
+			return strings.Replace(value, ":"+password+"@", ":"+RedactedValue+"@", 1)
+		}
+	}
+	return value
+}
+
+// Redact returns a copy of the spec with secret-looking env var and label values replaced
+// The original spec is left untouched
+func Redact(spec *ContainerSpec) *ContainerSpec {
+	redacted := *spec
+
+	redacted.Env = make(Env, 0, len(spec.Env))
+	for _, env := range spec.Env {
+		if key, value, hasValue := SplitEnv(env); hasValue {
+			env = key + "=" + redactValue(key, value)
+		}
+		redacted.Env = append(redacted.Env, env)
+	}
+
+	if spec.Labels != nil {
+		redacted.Labels = make(map[string]string, len(spec.Labels))
+		for key, value := range spec.Labels {
+			redacted.Labels[key] = redactValue(key, value)
+		}
+	}
+
+	return &redacted
+}
+
+// SecurityFindings inspects a spec for risky settings, most severe first
+func SecurityFindings(spec *ContainerSpec) []Finding {
+	var findings []Finding
+	add := func(severity, format string, args ...interface{}) {
+		findings = append(findings, Finding{Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if spec.Privileged {
+		add(SeverityCritical, "container runs privileged and has full access to the host")
+	}
+
+	for _, m := range spec.BindMounts() {
+		if m.Source == "/var/run/docker.sock" || m.Source == "/run/docker.sock" {
+			add(SeverityHigh, "docker socket is mounted at %s, giving root-equivalent access to the host", m.Target)
+			continue
+		}
+		for _, path := range sensitiveHostPaths {
+			if m.Source == path && !m.ReadOnly {
+				add(SeverityHigh, "sensitive host path %s is mounted writable at %s", m.Source, m.Target)
+			}
+		}
+	}
+
+	for _, capability := range spec.CapAdd {
+		for _, dangerous := range dangerousCapabilities {
+			if strings.TrimPrefix(strings.ToUpper(capability), "CAP_") == dangerous {
+				add(SeverityHigh, "dangerous capability %s is added", capability)
+			}
+		}
+	}
+
+	for _, network := range spec.Networks {
+		if network == "host" {
+			add(SeverityMedium, "container shares the host network namespace")
+		}
+	}
+
+	var secrets []string
+	for _, env := range spec.Env {
+		if key, value, _ := SplitEnv(env); value != "" && IsSensitiveKey(key) {
+			secrets = append(secrets, key)
+		}
+	}
+	if len(secrets) > 0 {
+		add(SeverityMedium, "secrets are passed as plain environment variables: %s", strings.Join(secrets, ", "))
+	}
+
+	if spec.User == "" || spec.User == "root" || spec.User == "0" || strings.HasPrefix(spec.User, "0:") {
+		add(SeverityLow, "container runs as root")
+	}
+
+	if spec.Memory == 0 {
+		add(SeverityLow, "no memory limit is set")
+	}
+
+	if !strings.Contains(spec.Image, "@sha256:") {
+		tagged := strings.LastIndex(spec.Image, ":") > strings.LastIndex(spec.Image, "/")
+		if !tagged || strings.HasSuffix(spec.Image, ":latest") {
+			add(SeverityLow, "image %s is referenced by a mutable tag", spec.Image)
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,87 @@
+package containerconfig
+
+import (
+	"path"
+	"strings"
+)
+
+// SELinux relabel options of a bind mount
+const (
+	// SELinuxShared labels the host path so that all containers can use it
+	SELinuxShared = "z"
+	// SELinuxPrivate labels the host path for this container only, locking out all others
+	SELinuxPrivate = "Z"
+)
+
+// relabelExcluded are host directories docker refuses to relabel, since relabeling them breaks the host
+var relabelExcluded = map[string]bool{
+	"/": true, "/bin": true, "/boot": true, "/dev": true, "/etc": true, "/home": true, "/lib": true,
+	"/lib64": true, "/media": true, "/opt": true, "/proc": true, "/root": true, "/run": true, "/sbin": true,
+	"/srv": true, "/sys": true, "/tmp": true, "/usr": true, "/var": true, "/var/lib": true, "/var/log": true,
+}
+
+// hasSELinuxLabel reports whether a mount carries the z or Z relabel option
+func hasSELinuxLabel(m Mount) bool {
+	return containsString(m.Options, SELinuxShared) || containsString(m.Options, SELinuxPrivate)
+}
+
+// selinuxMountOption returns the relabel option in a mount's mode from docker inspect, if any
+func selinuxMountOption(mode string) string {
+	for _, option := range strings.Split(mode, ",") {
+		if option == SELinuxShared || option == SELinuxPrivate {
+			return option
+		}
+	}
+	return ""
+}
+
+// Relabelable reports whether docker relabels a bind mount's host path; system directories
+// and the files in /proc, /sys and /dev are refused
+func Relabelable(source string) bool {
+	source = path.Clean(source)
+	if relabelExcluded[source] {
+		return false
+	}
+	for _, prefix := range []string{"/proc/", "/sys/", "/dev/", "/run/", "/var/run/"} {
+		if strings.HasPrefix(source, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// WithVolumeOption adds a mount option such as "z" to the volume mounted at the given container
+// path; a dev note on the volume moves along, so the mount keeps the reason it was added for
+func (b *SpecBuilder) WithVolumeOption(target, option string) *SpecBuilder {
+	for i, m := range b.spec.Volumes {
+		if m.Target != target {
+			continue
+		}
+		volume := m.String()
+		m.Options = append(cloneStrings(m.Options), option)
+		b.spec.Volumes[i] = m
+		if note, ok := b.spec.DevNotes["-v "+volume]; ok {
+			delete(b.spec.DevNotes, "-v "+volume)
+			b.spec.DevNotes["-v "+m.String()] = note
+		}
+	}
+	return b
+}
+
+// RelabelBindMounts adds an SELinux relabel option to the bind mounts the dev modifications added,
+// such as the dev-swap directory and the source checkout, so an SELinux host lets the container
+// read them; the original's own mounts keep the options they were created with
+func RelabelBindMounts(original *ContainerSpec, mode string) DevTransform {
+	return NewDevTransform("SELinux relabel", func(b *SpecBuilder) {
+		existing := make(map[string]bool, len(original.Volumes))
+		for _, m := range original.Volumes {
+			existing[m.String()] = true
+		}
+		for _, m := range b.spec.BindMounts() {
+			if existing[m.String()] || hasSELinuxLabel(m) || !Relabelable(m.Source) {
+				continue
+			}
+			b.WithVolumeOption(m.Target, mode)
+		}
+	})
+}
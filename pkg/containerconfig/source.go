@@ -0,0 +1,38 @@
+package containerconfig
+
+import "strings"
+
+// SourceDirLabel names the directory holding the application source inside the image,
+// for images that don't follow one of the conventional layouts
+const SourceDirLabel = "dce.source-dir"
+
+// conventionalSourceDirs are working directories images commonly build and run their app from
+var conventionalSourceDirs = []string{"/app", "/usr/src/app", "/src", "/code", "/workspace", "/opt/app", "/srv/app", "/home/app"}
+
+// conventionalSourcePrefixes are parents of per-project source directories, e.g. /go/src/example.com/app
+var conventionalSourcePrefixes = []string{"/go/src/", "/usr/src/"}
+
+// SourceDir returns the directory the container's source code lives in: the dce.source-dir label
+// when set, otherwise the working directory when it follows a common source layout
+func (s *ContainerSpec) SourceDir() (string, bool) {
+	if dir := s.Labels[SourceDirLabel]; dir != "" {
+		return dir, true
+	}
+	dir := strings.TrimSuffix(s.WorkingDir, "/")
+	for _, conventional := range conventionalSourceDirs {
+		if dir == conventional {
+			return dir, true
+		}
+	}
+	for _, prefix := range conventionalSourcePrefixes {
+		if strings.HasPrefix(dir, prefix) && len(dir) > len(prefix) {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// WithSourceMount bind-mounts a local checkout over a directory, replacing whatever was mounted there
+func (b *SpecBuilder) WithSourceMount(localDir, containerDir string) *SpecBuilder {
+	return b.WithVolume(localDir + ":" + containerDir)
+}
@@ -2,22 +2,134 @@ package containerconfig
 
 // ContainerSpec represents the configuration of a Docker container
 type ContainerSpec struct {
-	Name       string
-	Image      string
-	Env        []string
-	Volumes    []string
-	Ports      []string
-	Networks   []string
-	Command    []string
-	WorkingDir string
-	Labels     map[string]string
-	EntryPoint []string
-	Devices    []string
-	ExtraHosts []string
-	Restart    string
+	Name       string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Image      string            `json:"image" yaml:"image"`
+	Env        Env               `json:"env,omitempty" yaml:"env,omitempty"`
+	Volumes    []Mount           `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+	Ports      []PortMapping     `json:"ports,omitempty" yaml:"ports,omitempty"`
+	Networks   []string          `json:"networks,omitempty" yaml:"networks,omitempty"`
+	Command    []string          `json:"command,omitempty" yaml:"command,omitempty"`
+	WorkingDir string            `json:"workingDir,omitempty" yaml:"workingDir,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	EntryPoint []string          `json:"entryPoint,omitempty" yaml:"entryPoint,omitempty"`
+	Devices    []string          `json:"devices,omitempty" yaml:"devices,omitempty"`
+	ExtraHosts []string          `json:"extraHosts,omitempty" yaml:"extraHosts,omitempty"`
+	Restart    string            `json:"restart,omitempty" yaml:"restart,omitempty"`
+
+	// Links holds legacy container links as "name:alias"
+	Links []string `json:"links,omitempty" yaml:"links,omitempty"`
+	// NetworkMode is set only for modes that aren't expressed by Networks, e.g. "container:db"
+	NetworkMode string `json:"networkMode,omitempty" yaml:"networkMode,omitempty"`
+	// NetworkAliases are extra DNS names of the container on its first network; docker run can't
+	// give aliases on the others, so the parser keeps only the first network's
+	NetworkAliases []string `json:"networkAliases,omitempty" yaml:"networkAliases,omitempty"`
+	// CommandForm and EntryPointForm record whether Command and EntryPoint are exec form or
+	// shell form ("/bin/sh -c <string>"); empty means exec form
+	CommandForm    string `json:"commandForm,omitempty" yaml:"commandForm,omitempty"`
+	EntryPointForm string `json:"entryPointForm,omitempty" yaml:"entryPointForm,omitempty"`
+	// VolumesFrom lists containers whose volumes are mounted, optionally suffixed with ":ro"
+	VolumesFrom []string `json:"volumesFrom,omitempty" yaml:"volumesFrom,omitempty"`
+	// Tmpfs lists in-memory mounts as "path[:options]", e.g. "/scratch:size=1g"
+	Tmpfs []string `json:"tmpfs,omitempty" yaml:"tmpfs,omitempty"`
+	// Ulimits lists resource limits as "name=soft[:hard]", e.g. "core=-1" where -1 is unlimited
+	Ulimits []string `json:"ulimits,omitempty" yaml:"ulimits,omitempty"`
+
+	// Memory is the memory limit in bytes, 0 means unlimited
+	Memory Bytes `json:"memory,omitempty" yaml:"memory,omitempty"`
+	// NanoCPUs is the CPU limit in billionths of a CPU, 0 means unlimited
+	NanoCPUs CPUs `json:"nanoCpus,omitempty" yaml:"nanoCpus,omitempty"`
+	// CPUShares is the relative CPU weight, 0 means the daemon default
+	CPUShares int64 `json:"cpuShares,omitempty" yaml:"cpuShares,omitempty"`
+	// MemorySwappiness is the kernel's tendency to swap out the container's pages, 0 to 100; nil
+	// means the host's setting
+	MemorySwappiness *int64 `json:"memorySwappiness,omitempty" yaml:"memorySwappiness,omitempty"`
+	// KernelMemory is the kernel memory limit in bytes, 0 means unlimited; cgroup v1 only
+	KernelMemory Bytes `json:"kernelMemory,omitempty" yaml:"kernelMemory,omitempty"`
+	// MemorySwap limits memory plus swap in bytes; -1 means unlimited swap and 0 docker's default of
+	// twice the memory limit
+	MemorySwap Bytes `json:"memorySwap,omitempty" yaml:"memorySwap,omitempty"`
+	// MemoryReservation is the soft memory limit in bytes the kernel reclaims down to under pressure
+	MemoryReservation Bytes `json:"memoryReservation,omitempty" yaml:"memoryReservation,omitempty"`
+	// CPUQuota is the CPU time in microseconds the container gets per CPUPeriod; the older way to
+	// say what NanoCPUs says
+	CPUQuota int64 `json:"cpuQuota,omitempty" yaml:"cpuQuota,omitempty"`
+	// CPUPeriod is the CFS period in microseconds, 0 means the kernel's 100000
+	CPUPeriod int64 `json:"cpuPeriod,omitempty" yaml:"cpuPeriod,omitempty"`
+
+	// User is the user (and optional group) the container process runs as
+	User string `json:"user,omitempty" yaml:"user,omitempty"`
+	// Privileged gives the container full access to the host
+	Privileged bool `json:"privileged,omitempty" yaml:"privileged,omitempty"`
+	// CapAdd lists the Linux capabilities added on top of the defaults
+	CapAdd []string `json:"capAdd,omitempty" yaml:"capAdd,omitempty"`
+	// AppArmorProfile is the AppArmor profile confining the container; empty means docker's default
+	AppArmorProfile string `json:"appArmorProfile,omitempty" yaml:"appArmorProfile,omitempty"`
+
+	// Healthcheck overrides the image's healthcheck; nil keeps the image's
+	Healthcheck *Healthcheck `json:"healthcheck,omitempty" yaml:"healthcheck,omitempty"`
+
+	// Replicas is the number of identical containers of a scaled compose service this spec stands for;
+	// 0 or 1 means a single container
+	Replicas int `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	// ImageEnv is the env baked into the image; Env entries equal to one of these are image defaults
+	ImageEnv []string `json:"imageEnv,omitempty" yaml:"imageEnv,omitempty"`
+	// ImageEntryPoint and ImageCommand are the image's entrypoint and command; an EntryPoint or
+	// Command equal to them is inherited from the image, see EntryPointInherited
+	ImageEntryPoint []string `json:"imageEntryPoint,omitempty" yaml:"imageEntryPoint,omitempty"`
+	ImageCommand    []string `json:"imageCommand,omitempty" yaml:"imageCommand,omitempty"`
+	// Runtime is a snapshot of the container's state and resource usage at extraction time; informational only
+	Runtime *RuntimeSnapshot `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+	// Platform is the OS the container runs on ("linux" or "windows"); informational only
+	Platform string `json:"platform,omitempty" yaml:"platform,omitempty"`
+	// ImageID is the ID of the image the container was created from; informational only
+	ImageID string `json:"imageId,omitempty" yaml:"imageId,omitempty"`
+	// DevNotes says why the dev modifications added a run flag, keyed by the flag and its value
+	// (e.g. "-p 2345:2345") or "command"; informational only
+	DevNotes map[string]string `json:"devNotes,omitempty" yaml:"devNotes,omitempty"`
 }
 
 // RunOptions contains options for generating docker run command
 type RunOptions struct {
 	Name string
+	// LabelFilter drops matching labels from the generated command; nil keeps all labels
+	LabelFilter *LabelFilter
+	// EnvOverridesOnly emits only the env vars that differ from the image defaults; docker
+	// applies the image env itself, so the container ends up with the same environment
+	EnvOverridesOnly bool
+	// OmitImageDefaults leaves out the entrypoint and command inherited from the image, which
+	// docker applies itself, see EntryPointInherited and CommandInherited
+	OmitImageDefaults bool
+	// Remove adds --rm so docker removes the container when it exits
+	Remove bool
+	// MakeMountsWritable lists container paths whose read-only mounts are made writable;
+	// "*" loosens every read-only mount
+	MakeMountsWritable []string
+	// WorkingDirOverride replaces the spec's working directory in the generated command
+	WorkingDirOverride string
+}
+
+// AllMounts selects every mount in RunOptions.MakeMountsWritable
+const AllMounts = "*"
+
+// NamedVolumes returns the names of the named volumes referenced by the spec's volume mounts
+// Bind mounts and anonymous volumes are skipped
+func (s *ContainerSpec) NamedVolumes() []string {
+	var names []string
+	for _, m := range s.Volumes {
+		if m.Named() {
+			names = append(names, m.Source)
+		}
+	}
+	return names
+}
+
+// BindMounts returns the volume mounts of the spec whose source is a host path
+func (s *ContainerSpec) BindMounts() []Mount {
+	var binds []Mount
+	for _, m := range s.Volumes {
+		if m.Type == MountBind {
+			binds = append(binds, m)
+		}
+	}
+	return binds
 }
@@ -0,0 +1,166 @@
+package containerconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SpecFileExt is the file extension used for exported spec files
+const SpecFileExt = ".json"
+
+// specFileExts lists the extensions recognized when reading a spec directory
+var specFileExts = map[string]bool{".json": true, ".yaml": true, ".yml": true}
+
+// ComposeFileName is the name a compose export is written under next to the spec files
+const ComposeFileName = "docker-compose.yml"
+
+// isComposeFile reports whether a file name is one compose reads, such as compose.yaml or
+// docker-compose.override.yml, rather than a spec
+func isComposeFile(name string) bool {
+	ext := filepath.Ext(name)
+	if ext != ".yml" && ext != ".yaml" {
+		return false
+	}
+	base := strings.TrimSuffix(name, ext)
+	for _, prefix := range []string{"compose", "docker-compose"} {
+		if base == prefix || strings.HasPrefix(base, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalSpec serializes a ContainerSpec into its exported file format
+func MarshalSpec(spec *ContainerSpec) ([]byte, error) {
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal spec '%s': %w", spec.Name, err)
+	}
+	return append(data, '\n'), nil
+}
+
+// MarshalSpecYAML serializes a ContainerSpec as YAML
+func MarshalSpecYAML(spec *ContainerSpec) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(spec); err != nil {
+		return nil, fmt.Errorf("failed to marshal spec '%s': %w", spec.Name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalSpec validates and parses a spec in either the JSON or the YAML format
+func UnmarshalSpec(data []byte) (*ContainerSpec, error) {
+	if err := ValidateSchema(SchemaSpec, data); err != nil {
+		return nil, err
+	}
+	var spec ContainerSpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+	if spec.Image == "" {
+		return nil, fmt.Errorf("spec has no image")
+	}
+	return &spec, nil
+}
+
+// WriteSpecFile writes a spec to the given path
+func WriteSpecFile(path string, spec *ContainerSpec) error {
+	data, err := MarshalSpec(spec)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write spec file '%s': %w", path, err)
+	}
+	return nil
+}
+
+// ReadSpecFile reads a single spec file
+func ReadSpecFile(path string) (*ContainerSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec file '%s': %w", path, err)
+	}
+	spec, err := UnmarshalSpec(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec file '%s': %w", path, err)
+	}
+	if spec.Name == "" {
+		spec.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return spec, nil
+}
+
+// composeFileEnvFiles returns the env files the services of a compose file name, relative to its
+// directory; a file that can't be read or parsed names none
+func composeFileEnvFiles(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var file struct {
+		Services map[string]struct {
+			EnvFile composeEnvFileNode `yaml:"env_file"`
+		} `yaml:"services"`
+	}
+	if yaml.Unmarshal(data, &file) != nil {
+		return nil
+	}
+	var envFiles []string
+	for _, service := range file.Services {
+		for _, envFile := range service.EnvFile {
+			envFiles = append(envFiles, filepath.Clean(envFile))
+		}
+	}
+	return envFiles
+}
+
+// ReadSpecDir reads every spec file in a directory, sorted by file name; the compose file and env
+// files of an export-all --compose, which share the directory, are skipped
+func ReadSpecDir(dir string) ([]*ContainerSpec, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec directory '%s': %w", dir, err)
+	}
+
+	var names []string
+	envFiles := make(map[string]bool)
+	for _, entry := range entries {
+		switch {
+		case entry.IsDir() || !specFileExts[filepath.Ext(entry.Name())]:
+		case isComposeFile(entry.Name()):
+			for _, envFile := range composeFileEnvFiles(filepath.Join(dir, entry.Name())) {
+				envFiles[envFile] = true
+			}
+		default:
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var specs []*ContainerSpec
+	for _, name := range names {
+		if envFiles[name] {
+			continue
+		}
+		spec, err := ReadSpecFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no spec files found in '%s'", dir)
+	}
+	return specs, nil
+}
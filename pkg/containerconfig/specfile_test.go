@@ -0,0 +1,84 @@
+package containerconfig_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// TestReadSpecDirAfterComposeExport writes a directory the way export-all --compose does, specs
+// next to the compose file and its env files, and reads it back the way apply does
+func TestReadSpecDirAfterComposeExport(t *testing.T) {
+	web := &containerconfig.ContainerSpec{Name: "web", Image: "nginx:1.27"}
+	for i := 0; i < containerconfig.EnvFileThreshold; i++ {
+		web.Env.Set(fmt.Sprintf("WEB_%d", i), "x")
+	}
+	db := &containerconfig.ContainerSpec{Name: "db", Image: "postgres:16", Env: containerconfig.Env{"POSTGRES_DB=app"}}
+	specs := []*containerconfig.ContainerSpec{web, db}
+
+	dir := t.TempDir()
+	for _, spec := range specs {
+		if err := containerconfig.WriteSpecFile(filepath.Join(dir, spec.Name+containerconfig.SpecFileExt), spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An env file compose started db with keeps its name, spec extension and all
+	known := map[string][]containerconfig.EnvFile{"db": {{Name: "db-settings.json", Entries: []string{"POSTGRES_DB=app"}}}}
+	data, envFiles, err := containerconfig.ExportCompose(specs, containerconfig.ComposeExportOptions{EnvFiles: known})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(envFiles) != 2 {
+		t.Fatalf("got %d env files, want 2", len(envFiles))
+	}
+	for _, envFile := range envFiles {
+		if err := os.WriteFile(filepath.Join(dir, envFile.Name), envFile.Bytes(), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, containerconfig.ComposeFileName), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := containerconfig.ReadSpecDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*containerconfig.ContainerSpec{db, web}
+	if len(read) != len(want) {
+		t.Fatalf("read %d specs, want %d", len(read), len(want))
+	}
+	for i, spec := range read {
+		got, _ := containerconfig.MarshalSpec(spec)
+		expected, _ := containerconfig.MarshalSpec(want[i])
+		if !bytes.Equal(got, expected) {
+			t.Errorf("spec %d read back as\n%s\nwant\n%s", i, got, expected)
+		}
+	}
+}
+
+func TestReadSpecDirSkipsComposeFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.yaml":                    "name: app\nimage: alpine\n",
+		"compose.yaml":                "services:\n  app:\n    image: alpine\n",
+		"docker-compose.override.yml": "services:\n  app:\n    ports: [\"80:80\"]\n",
+		"app.env":                     "A=1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	specs, err := containerconfig.ReadSpecDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 1 || specs[0].Name != "app" {
+		t.Errorf("got %d specs, want only app", len(specs))
+	}
+}
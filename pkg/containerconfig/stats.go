@@ -0,0 +1,189 @@
+package containerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RuntimeSnapshot records a container's state and resource usage at one point in time
+// It is informational: it describes what the container was doing, not how to recreate it
+type RuntimeSnapshot struct {
+	CapturedAt   time.Time `json:"capturedAt" yaml:"capturedAt"`
+	Status       string    `json:"status" yaml:"status"`
+	StartedAt    string    `json:"startedAt,omitempty" yaml:"startedAt,omitempty"`
+	RestartCount int       `json:"restartCount,omitempty" yaml:"restartCount,omitempty"`
+	OOMKilled    bool      `json:"oomKilled,omitempty" yaml:"oomKilled,omitempty"`
+
+	// CPUPercent is the share of one CPU in use, so a busy 2-CPU container shows up to 200
+	CPUPercent float64 `json:"cpuPercent" yaml:"cpuPercent"`
+	// MemoryUsage and MemoryLimit are in bytes; the limit is the host memory when none is set
+	MemoryUsage int64 `json:"memoryUsage" yaml:"memoryUsage"`
+	MemoryLimit int64 `json:"memoryLimit,omitempty" yaml:"memoryLimit,omitempty"`
+	// NetRx, NetTx, BlockRead and BlockWrite are cumulative byte counts since the container started
+	NetRx      int64 `json:"netRx" yaml:"netRx"`
+	NetTx      int64 `json:"netTx" yaml:"netTx"`
+	BlockRead  int64 `json:"blockRead" yaml:"blockRead"`
+	BlockWrite int64 `json:"blockWrite" yaml:"blockWrite"`
+	PIDs       int   `json:"pids,omitempty" yaml:"pids,omitempty"`
+}
+
+// inspectState is the State section of docker inspect output
+type inspectState struct {
+	Status       string `json:"Status"`
+	StartedAt    string `json:"StartedAt"`
+	OOMKilled    bool   `json:"OOMKilled"`
+	RestartCount int    `json:"RestartCount"`
+}
+
+// statsLine is one line of docker stats --no-stream --format '{{json .}}'
+type statsLine struct {
+	CPUPerc  string `json:"CPUPerc"`
+	MemUsage string `json:"MemUsage"`
+	NetIO    string `json:"NetIO"`
+	BlockIO  string `json:"BlockIO"`
+	PIDs     string `json:"PIDs"`
+}
+
+// ParseRuntimeSnapshot builds a snapshot from docker inspect's State ({{json .State}}) and a line of
+// docker stats --no-stream --format '{{json .}}'; statsJSON may be empty for a stopped container
+func ParseRuntimeSnapshot(stateJSON, statsJSON string, capturedAt time.Time) (*RuntimeSnapshot, error) {
+	var state inspectState
+	if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
+		return nil, fmt.Errorf("failed to parse container state: %w", err)
+	}
+	snapshot := &RuntimeSnapshot{
+		CapturedAt:   capturedAt.UTC(),
+		Status:       state.Status,
+		RestartCount: state.RestartCount,
+		OOMKilled:    state.OOMKilled,
+	}
+	if state.Status == "running" {
+		snapshot.StartedAt = state.StartedAt
+	}
+
+	statsJSON = strings.TrimSpace(statsJSON)
+	if statsJSON == "" {
+		return snapshot, nil
+	}
+	var stats statsLine
+	if err := json.Unmarshal([]byte(statsJSON), &stats); err != nil {
+		return nil, fmt.Errorf("failed to parse container stats: %w", err)
+	}
+
+	var err error
+	if snapshot.CPUPercent, err = parsePercent(stats.CPUPerc); err != nil {
+		return nil, err
+	}
+	if snapshot.MemoryUsage, snapshot.MemoryLimit, err = parseSizePair(stats.MemUsage); err != nil {
+		return nil, err
+	}
+	if snapshot.NetRx, snapshot.NetTx, err = parseSizePair(stats.NetIO); err != nil {
+		return nil, err
+	}
+	if snapshot.BlockRead, snapshot.BlockWrite, err = parseSizePair(stats.BlockIO); err != nil {
+		return nil, err
+	}
+	if stats.PIDs != "" && stats.PIDs != "--" {
+		if snapshot.PIDs, err = strconv.Atoi(stats.PIDs); err != nil {
+			return nil, fmt.Errorf("invalid PID count '%s'", stats.PIDs)
+		}
+	}
+	return snapshot, nil
+}
+
+// InspectStateJSON extracts the State section from docker inspect output, for ParseRuntimeSnapshot
+func InspectStateJSON(inspectJSON string) (string, error) {
+	var containers []struct {
+		State json.RawMessage `json:"State"`
+	}
+	if err := json.Unmarshal([]byte(inspectJSON), &containers); err != nil {
+		return "", fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if len(containers) == 0 || len(containers[0].State) == 0 {
+		return "", fmt.Errorf("no container state in inspect output")
+	}
+	return string(containers[0].State), nil
+}
+
+// String formats the snapshot as a single line
+func (r *RuntimeSnapshot) String() string {
+	if r == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s, CPU %.2f%%, memory %s, net %s in / %s out", r.Status, r.CPUPercent,
+		FormatBytes(r.MemoryUsage), FormatBytes(r.NetRx), FormatBytes(r.NetTx))
+}
+
+// parsePercent parses a docker stats percentage such as "12.50%"; "--" means no data
+func parsePercent(value string) (float64, error) {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "%")
+	if value == "" || value == "--" {
+		return 0, nil
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage '%s'", value)
+	}
+	return n, nil
+}
+
+// parseSizePair parses a docker stats "used / total" or "in / out" pair such as "10.5MiB / 1.944GiB"
+func parseSizePair(value string) (int64, int64, error) {
+	first, second, _ := strings.Cut(value, "/")
+	a, err := parseStatsSize(first)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := parseStatsSize(second)
+	if err != nil {
+		return 0, 0, err
+	}
+	return a, b, nil
+}
+
+// statsUnits maps the units docker stats prints to their size in bytes; memory uses binary
+// units (MiB), network and block IO decimal ones (MB)
+var statsUnits = []struct {
+	suffix string
+	size   float64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"kB", 1e3}, {"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// parseStatsSize parses a single docker stats size such as "1.2kB"; an empty value or "--" is 0
+func parseStatsSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "--" {
+		return 0, nil
+	}
+	for _, unit := range statsUnits {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			if err != nil {
+				break
+			}
+			return int64(n * unit.size), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid size '%s'", value)
+}
+
+// FormatBytes formats a byte count with a binary unit, e.g. "12.3MiB"
+func FormatBytes(n int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(n)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%dB", n)
+	}
+	return fmt.Sprintf("%.1f%s", value, units[unit])
+}
@@ -0,0 +1,19 @@
+package containerconfig
+
+// Defaults of the terminal env preset: C.UTF-8 ships with glibc and musl images alike, and every
+// terminfo database has xterm-256color
+const (
+	DefaultLocale = "C.UTF-8"
+	DefaultTerm   = "xterm-256color"
+)
+
+// WithTerminalEnv sets LANG, LC_ALL and TERM so interactive shells and TUIs in the container handle
+// UTF-8 and colors; variables the spec already sets are kept, since the app may depend on its locale
+func (b *SpecBuilder) WithTerminalEnv(locale, term string) *SpecBuilder {
+	for _, env := range [][2]string{{"LANG", locale}, {"LC_ALL", locale}, {"TERM", term}} {
+		if !b.spec.Env.Has(env[0]) && env[1] != "" {
+			b.WithEnv(env[0], env[1])
+		}
+	}
+	return b
+}
@@ -0,0 +1,82 @@
+package containerconfig
+
+import "strings"
+
+// TimezoneEnv is the variable libc, Go and most runtimes take the timezone from, ahead of /etc/localtime
+const TimezoneEnv = "TZ"
+
+// timezonePaths are the container paths timezone data is mounted at, usually from the host
+var timezonePaths = []string{"/etc/localtime", "/etc/timezone", "/usr/share/zoneinfo"}
+
+// Timezone is where a container's local time comes from
+type Timezone struct {
+	// Env is the TZ value, or empty
+	Env string `json:"env,omitempty"`
+	// Mounts are the volumes providing /etc/localtime, /etc/timezone or the zoneinfo database
+	Mounts []string `json:"mounts,omitempty"`
+}
+
+// Timezone returns the TZ env and timezone mounts of the spec
+func (s *ContainerSpec) Timezone() Timezone {
+	var tz Timezone
+	tz.Env, _ = s.Env.Get(TimezoneEnv)
+	for _, m := range s.Volumes {
+		if isTimezonePath(m.Target) {
+			tz.Mounts = append(tz.Mounts, m.String())
+		}
+	}
+	return tz
+}
+
+// isTimezonePath reports whether a container path holds timezone data
+func isTimezonePath(target string) bool {
+	for _, path := range timezonePaths {
+		if target == path || strings.HasPrefix(target, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Empty reports whether the container uses the image's default timezone, usually UTC
+func (t Timezone) Empty() bool {
+	return t.Env == "" && len(t.Mounts) == 0
+}
+
+// String describes the timezone, e.g. "TZ=Europe/Berlin, /etc/localtime:/etc/localtime:ro"
+func (t Timezone) String() string {
+	var parts []string
+	if t.Env != "" {
+		parts = append(parts, TimezoneEnv+"="+t.Env)
+	}
+	parts = append(parts, t.Mounts...)
+	if len(parts) == 0 {
+		return "image default"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// WithTimezoneOf restores the TZ env and timezone mounts of the original spec that modifications
+// replaced or removed, so a dev copy keeps the original's local time
+func (b *SpecBuilder) WithTimezoneOf(original *ContainerSpec) *SpecBuilder {
+	tz := original.Timezone()
+	if tz.Env != "" && b.spec.Timezone().Env != tz.Env {
+		b.WithEnv(TimezoneEnv, tz.Env)
+	}
+	kept := make(map[string]bool)
+	for _, m := range b.spec.Volumes {
+		kept[m.String()] = true
+	}
+	for _, volume := range tz.Mounts {
+		if !kept[volume] {
+			b.WithVolume(volume)
+		}
+	}
+	return b
+}
+
+// WithTimezone sets TZ, which takes precedence over a mounted /etc/localtime; the name must be in
+// the container's zoneinfo database, or be a POSIX TZ string such as "EST5EDT"
+func (b *SpecBuilder) WithTimezone(tz string) *SpecBuilder {
+	return b.WithEnv(TimezoneEnv, tz)
+}
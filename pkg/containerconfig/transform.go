@@ -0,0 +1,141 @@
+package containerconfig
+
+// DevSwapDir is the container path the dev-swap directory is mounted at
+const DevSwapDir = "/dev-swap"
+
+// DefaultDebugPort is the port delve listens on in dev containers
+const DefaultDebugPort = 2345
+
+// DevTransform is one named modification turning a faithful spec into a dev spec
+// Transforms compose: a dev container is Extract, then the transforms in order, then Generate;
+// each records its name as the reason for the run flags it adds
+type DevTransform struct {
+	// Name is recorded in the spec's DevNotes for the run flags the transform adds
+	Name  string
+	apply func(b *SpecBuilder)
+}
+
+// NewDevTransform returns a transform applying fn under the given name, for modifications the
+// built-in transforms don't cover
+func NewDevTransform(name string, fn func(b *SpecBuilder)) DevTransform {
+	return DevTransform{Name: name, apply: fn}
+}
+
+// Apply runs the transform on the builder and records it
+func (t DevTransform) Apply(b *SpecBuilder) *SpecBuilder {
+	t.apply(b)
+	return b.Record(t.Name)
+}
+
+// ApplyDevTransforms returns a copy of the spec with the transforms applied in order; the spec
+// itself is never mutated
+func ApplyDevTransforms(spec *ContainerSpec, transforms ...DevTransform) *ContainerSpec {
+	b := spec.Builder()
+	for _, t := range transforms {
+		t.Apply(b)
+	}
+	return b.Build()
+}
+
+// AddSwapMount mounts a host directory at DevSwapDir, for swapping binaries into the container
+func AddSwapMount(hostDir string) DevTransform {
+	return NewDevTransform("dev-swap directory", func(b *SpecBuilder) {
+		b.WithVolume(hostDir + ":" + DevSwapDir)
+	})
+}
+
+// AddSourceMount bind-mounts a local checkout over the container's source directory
+func AddSourceMount(hostDir, containerDir string) DevTransform {
+	return NewDevTransform("source checkout", func(b *SpecBuilder) {
+		b.WithSourceMount(hostDir, containerDir)
+	})
+}
+
+// AddMount adds a "source:target[:mode]" volume
+func AddMount(volume string) DevTransform {
+	return NewDevTransform("mount", func(b *SpecBuilder) {
+		b.WithVolume(volume)
+	})
+}
+
+// AddExtraHost adds a "host:address" entry to /etc/hosts
+func AddExtraHost(host string) DevTransform {
+	return NewDevTransform("extra host", func(b *SpecBuilder) {
+		b.WithExtraHost(host)
+	})
+}
+
+// ReplaceNetworkAliases drops the inherited network aliases, which would make the dev container
+// answer for the original's service name, and sets the given ones
+func ReplaceNetworkAliases(aliases ...string) DevTransform {
+	return NewDevTransform("network alias", func(b *SpecBuilder) {
+		b.WithoutNetworkAliases()
+		for _, alias := range aliases {
+			b.WithNetworkAlias(alias)
+		}
+	})
+}
+
+// StripRestart drops the restart policy, which would restart a process that crashed or was
+// stopped in the debugger
+func StripRestart() DevTransform {
+	return NewDevTransform("restart policy dropped", func(b *SpecBuilder) {
+		b.WithRestart("")
+	})
+}
+
+// DisableHealthcheck turns off the healthcheck, which marks a process paused at a breakpoint unhealthy
+func DisableHealthcheck() DevTransform {
+	return NewDevTransform("healthcheck disabled", func(b *SpecBuilder) {
+		b.WithoutHealthcheck()
+	})
+}
+
+// AddHostAccess maps host.docker.internal to the docker host
+func AddHostAccess() DevTransform {
+	return NewDevTransform("host access", func(b *SpecBuilder) {
+		b.WithHostAccess()
+	})
+}
+
+// AddScratchTmpfs mounts a tmpfs with the given options at a container path
+func AddScratchTmpfs(path, options string) DevTransform {
+	return NewDevTransform("scratch space", func(b *SpecBuilder) {
+		b.WithTmpfs(path, options)
+	})
+}
+
+// KeepTimezone restores the original's TZ and timezone mounts that earlier transforms replaced
+func KeepTimezone(original *ContainerSpec) DevTransform {
+	return NewDevTransform("original timezone", func(b *SpecBuilder) {
+		b.WithTimezoneOf(original)
+	})
+}
+
+// SetTimezone sets TZ
+func SetTimezone(tz string) DevTransform {
+	return NewDevTransform("timezone override", func(b *SpecBuilder) {
+		b.WithTimezone(tz)
+	})
+}
+
+// AddTerminalEnv sets LANG, LC_ALL and TERM where the spec leaves them unset
+func AddTerminalEnv(locale, term string) DevTransform {
+	return NewDevTransform("terminal env", func(b *SpecBuilder) {
+		b.WithTerminalEnv(locale, term)
+	})
+}
+
+// AddDebugPort publishes the debugger's port on the same host port
+func AddDebugPort(port int) DevTransform {
+	return NewDevTransform("debug port", func(b *SpecBuilder) {
+		b.WithPort(PortMapping{HostPort: port, ContainerPort: port})
+	})
+}
+
+// LoosenSecurity adds SYS_PTRACE, without which delve can't attach to the app's process
+func LoosenSecurity() DevTransform {
+	return NewDevTransform("debugger", func(b *SpecBuilder) {
+		b.WithCapAdd("SYS_PTRACE")
+	})
+}
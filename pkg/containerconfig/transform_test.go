@@ -0,0 +1,167 @@
+package containerconfig_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// devTestSpec is a faithful spec of a service, as extracted before any dev transform
+func devTestSpec() *containerconfig.ContainerSpec {
+	return &containerconfig.ContainerSpec{
+		Name:           "api",
+		Image:          "api:1.4",
+		Restart:        "always",
+		Ports:          []containerconfig.PortMapping{{HostPort: 8080, ContainerPort: 80}},
+		Volumes:        containerconfig.ParseMounts([]string{"/srv/config:/config:ro", "/srv/certs:/certs:ro", "data:/data"}),
+		Networks:       []string{"backend"},
+		NetworkAliases: []string{"api"},
+		Command:        []string{"/app/server"},
+	}
+}
+
+// hasArgs reports whether the run arguments hold the arguments of want as a consecutive group
+func hasArgs(args []string, want string) bool {
+	return strings.Contains(" "+strings.Join(args, " ")+" ", " "+want+" ")
+}
+
+func TestDevTransforms(t *testing.T) {
+	tests := []struct {
+		name       string
+		transforms []containerconfig.DevTransform
+		// opts are the dev container's run options; the original's command is generated without any
+		opts *containerconfig.RunOptions
+		// added are argument groups only the dev spec generates, noted with the transform's name
+		added   []string
+		removed []string
+	}{
+		{
+			name:       "debug port",
+			transforms: []containerconfig.DevTransform{containerconfig.AddDebugPort(containerconfig.DefaultDebugPort)},
+			added:      []string{"-p 2345:2345"},
+		},
+		{
+			name:       "debugger",
+			transforms: []containerconfig.DevTransform{containerconfig.LoosenSecurity()},
+			added:      []string{"--cap-add SYS_PTRACE"},
+		},
+		{
+			name:       "dlv exec",
+			transforms: []containerconfig.DevTransform{containerconfig.DlvExec("/app/server", []string{"--listen", ":80"}, 2345)},
+			added:      []string{"-e DCE_DEBUG_PORT=2345", "--entrypoint /bin/sh"},
+		},
+		{
+			name:       "restart policy dropped",
+			transforms: []containerconfig.DevTransform{containerconfig.StripRestart()},
+			removed:    []string{"--restart always"},
+		},
+		{
+			name:       "network alias",
+			transforms: []containerconfig.DevTransform{containerconfig.ReplaceNetworkAliases("api-dev")},
+			added:      []string{"--network-alias api-dev"},
+			removed:    []string{"--network-alias api"},
+		},
+		{
+			name:       "dev-swap directory",
+			transforms: []containerconfig.DevTransform{containerconfig.AddSwapMount("/tmp/swap")},
+			added:      []string{"-v /tmp/swap:/dev-swap"},
+		},
+		{
+			name:    "one mount made writable",
+			opts:    &containerconfig.RunOptions{MakeMountsWritable: []string{"/config"}},
+			added:   []string{"-v /srv/config:/config"},
+			removed: []string{"-v /srv/config:/config:ro"},
+		},
+		{
+			name:    "every mount made writable",
+			opts:    &containerconfig.RunOptions{MakeMountsWritable: []string{containerconfig.AllMounts}},
+			added:   []string{"-v /srv/config:/config", "-v /srv/certs:/certs"},
+			removed: []string{"-v /srv/config:/config:ro", "-v /srv/certs:/certs:ro"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := devTestSpec()
+			dev := containerconfig.ApplyDevTransforms(spec, tt.transforms...)
+			if !reflect.DeepEqual(spec, devTestSpec()) {
+				t.Fatalf("the transforms changed the original spec: %+v", spec)
+			}
+
+			before := containerconfig.GenerateRunCommand(spec, nil)
+			after := containerconfig.GenerateRunCommand(dev, tt.opts)
+			for _, args := range tt.added {
+				if hasArgs(before, args) || !hasArgs(after, args) {
+					t.Errorf("%q is not added:\nbefore %s\nafter  %s", args, strings.Join(before, " "), strings.Join(after, " "))
+				}
+			}
+			for _, args := range tt.removed {
+				if !hasArgs(before, args) || hasArgs(after, args) {
+					t.Errorf("%q is not removed:\nbefore %s\nafter  %s", args, strings.Join(before, " "), strings.Join(after, " "))
+				}
+			}
+			if !hasArgs(after, "-v data:/data") {
+				t.Errorf("the named volume is lost: %s", strings.Join(after, " "))
+			}
+			if tt.opts != nil {
+				if loosened := containerconfig.LoosenedMounts(dev, tt.opts); len(loosened) != len(tt.removed) {
+					t.Errorf("LoosenedMounts = %v, want the %d mounts made writable", loosened, len(tt.removed))
+				}
+			}
+
+			if len(tt.transforms) == 0 {
+				return
+			}
+			notes := make(map[string]string)
+			for _, group := range containerconfig.AnnotateRunArgs(dev, tt.opts) {
+				notes[strings.Join(group.Args, " ")] = group.Note
+			}
+			for _, args := range tt.added {
+				if notes[args] != tt.transforms[0].Name {
+					t.Errorf("%q is noted as %q, want %q", args, notes[args], tt.transforms[0].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestDlvExecCommand(t *testing.T) {
+	dev := containerconfig.ApplyDevTransforms(devTestSpec(), containerconfig.DlvExec("/app/server", []string{"--listen", ":80"}, 2345))
+	args := containerconfig.GenerateRunCommand(dev, nil)
+
+	// The script and its $0 go ahead of the app's command, which the script passes on to dlv exec
+	image := -1
+	for i, arg := range args {
+		if arg == "api:1.4" {
+			image = i
+		}
+	}
+	if image < 0 {
+		t.Fatalf("no image in %s", strings.Join(args, " "))
+	}
+	tail := args[image+1:]
+	if len(tail) != 6 || tail[0] != "-c" || !strings.Contains(tail[1], "exec dlv exec --headless") || tail[2] != "dce-dlv-exec" {
+		t.Fatalf("got command %q", tail)
+	}
+	if got := strings.Join(tail[3:], " "); got != "/app/server --listen :80" {
+		t.Errorf("app command is %q, want the binary and its args", got)
+	}
+}
+
+func TestDevTransformsCompose(t *testing.T) {
+	dev := containerconfig.ApplyDevTransforms(devTestSpec(),
+		containerconfig.StripRestart(),
+		containerconfig.AddDebugPort(containerconfig.DefaultDebugPort),
+		containerconfig.LoosenSecurity(),
+	)
+	args := containerconfig.GenerateRunCommand(dev, nil)
+	for _, want := range []string{"-p 8080:80", "-p 2345:2345", "--cap-add SYS_PTRACE", "-v /srv/config:/config:ro"} {
+		if !hasArgs(args, want) {
+			t.Errorf("missing %q in %s", want, strings.Join(args, " "))
+		}
+	}
+	if hasArgs(args, "--restart always") {
+		t.Errorf("restart policy kept: %s", strings.Join(args, " "))
+	}
+}
@@ -0,0 +1,162 @@
+package containerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Bytes is a memory size in bytes, as docker inspect reports it; spec files hold the plain number
+type Bytes int64
+
+// memoryUnits are the suffixes docker accepts for memory sizes, largest first
+var memoryUnits = []struct {
+	suffix string
+	size   Bytes
+}{
+	{"t", 1 << 40},
+	{"g", 1 << 30},
+	{"m", 1 << 20},
+	{"k", 1 << 10},
+}
+
+// ParseBytes parses a docker memory size such as "512m", "1g" or "1.5GB" into bytes
+func ParseBytes(value string) (Bytes, error) {
+	number := strings.ToLower(strings.TrimSpace(value))
+	number = strings.TrimSuffix(number, "b")
+
+	multiplier := Bytes(1)
+	for _, unit := range memoryUnits {
+		if strings.HasSuffix(number, unit.suffix) {
+			number, multiplier = strings.TrimSuffix(number, unit.suffix), unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid memory size '%s'", value)
+	}
+	return Bytes(n * float64(multiplier)), nil
+}
+
+// String formats the size the way docker run --memory takes it, in the largest unit that divides it
+// exactly, e.g. "512m" or "1g"
+func (b Bytes) String() string {
+	for _, unit := range memoryUnits {
+		if b != 0 && b%unit.size == 0 {
+			return strconv.FormatInt(int64(b/unit.size), 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(int64(b), 10)
+}
+
+// Quantity formats the size as a Kubernetes memory quantity, rounded up to whole Mi (or Gi when
+// exact), e.g. "128Mi" or "2Gi"
+func (b Bytes) Quantity() string {
+	mebibytes := (b + (1<<20 - 1)) >> 20
+	if mebibytes%1024 == 0 && mebibytes > 0 {
+		return fmt.Sprintf("%dGi", mebibytes/1024)
+	}
+	return fmt.Sprintf("%dMi", mebibytes)
+}
+
+// Human formats the size with a binary unit for display, e.g. "12.3MiB"
+func (b Bytes) Human() string {
+	return FormatBytes(int64(b))
+}
+
+// CPUs is a CPU count in billionths of a CPU, as docker inspect reports it (NanoCpus)
+type CPUs int64
+
+// ParseCPUs parses a --cpus value such as "1.5"
+func ParseCPUs(value string) (CPUs, error) {
+	cpus, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || cpus < 0 {
+		return 0, fmt.Errorf("invalid cpus value '%s'", value)
+	}
+	return CPUs(cpus * 1e9), nil
+}
+
+// Float returns the count in whole CPUs, e.g. 1.5
+func (c CPUs) Float() float64 {
+	return float64(c) / 1e9
+}
+
+// Millis returns the count in millicores, the unit of Kubernetes and ECS CPU values
+func (c CPUs) Millis() int64 {
+	return int64(c) / 1e6
+}
+
+// String formats the count the way docker run --cpus takes it, e.g. "1.5"
+func (c CPUs) String() string {
+	return strconv.FormatFloat(c.Float(), 'f', -1, 64)
+}
+
+// Quantity formats the count as a Kubernetes CPU quantity, e.g. "250m" or "2"
+func (c CPUs) Quantity() string {
+	return FormatCPUQuantity(c.Millis())
+}
+
+// Duration is a time span that spec files hold as a Go duration string such as "30s"; zero means unset
+type Duration time.Duration
+
+// ParseDuration parses a Go duration string such as "1m30s"; empty is zero
+func ParseDuration(value string) (Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration '%s': %w", value, err)
+	}
+	return Duration(d), nil
+}
+
+// String formats the duration as the docker run --health-* flags take it, e.g. "1m30s"
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// Seconds returns the duration in whole seconds, rounded up, for formats that count in seconds
+func (d Duration) Seconds() int64 {
+	return int64((time.Duration(d) + time.Second - 1) / time.Second)
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON reads a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid duration %s: expected a string such as \"30s\"", data)
+	}
+	parsed, err := ParseDuration(value)
+	*d = parsed
+	return err
+}
+
+// MarshalYAML writes the duration as a string
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// UnmarshalYAML reads a duration string
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: expected a duration such as \"30s\"", node.Line)
+	}
+	parsed, err := ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*d = parsed
+	return nil
+}
@@ -0,0 +1,87 @@
+package containerconfig_test
+
+import (
+	"testing"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		value    string
+		want     containerconfig.Bytes
+		str      string
+		quantity string
+	}{
+		{"512m", 512 << 20, "512m", "512Mi"},
+		{"1g", 1 << 30, "1g", "1Gi"},
+		{"1.5GB", 3 << 29, "1536m", "1536Mi"},
+		{"2048k", 2 << 20, "2m", "2Mi"},
+		{"100", 100, "100", "1Mi"},
+		{" 4M ", 4 << 20, "4m", "4Mi"},
+		{"1t", 1 << 40, "1t", "1024Gi"},
+		{"0", 0, "0", "0Mi"},
+	}
+	for _, tt := range tests {
+		got, err := containerconfig.ParseBytes(tt.value)
+		if err != nil {
+			t.Errorf("ParseBytes(%q): %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseBytes(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+		if s := got.String(); s != tt.str {
+			t.Errorf("Bytes(%d).String() = %s, want %s", got, s, tt.str)
+		}
+		if q := got.Quantity(); q != tt.quantity {
+			t.Errorf("Bytes(%d).Quantity() = %s, want %s", got, q, tt.quantity)
+		}
+	}
+
+	for _, value := range []string{"", "m", "-1g", "lots", "1x"} {
+		if got, err := containerconfig.ParseBytes(value); err == nil {
+			t.Errorf("ParseBytes(%q) = %d, want an error", value, got)
+		}
+	}
+}
+
+func TestParseCPUs(t *testing.T) {
+	tests := []struct {
+		value    string
+		want     containerconfig.CPUs
+		str      string
+		millis   int64
+		quantity string
+	}{
+		{"1.5", 1500000000, "1.5", 1500, "1500m"},
+		{"2", 2000000000, "2", 2000, "2"},
+		{"0.25", 250000000, "0.25", 250, "250m"},
+		{" 0.5 ", 500000000, "0.5", 500, "500m"},
+	}
+	for _, tt := range tests {
+		got, err := containerconfig.ParseCPUs(tt.value)
+		if err != nil {
+			t.Errorf("ParseCPUs(%q): %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseCPUs(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+		if s := got.String(); s != tt.str {
+			t.Errorf("CPUs(%d).String() = %s, want %s", got, s, tt.str)
+		}
+		if m := got.Millis(); m != tt.millis {
+			t.Errorf("CPUs(%d).Millis() = %d, want %d", got, m, tt.millis)
+		}
+		if q := got.Quantity(); q != tt.quantity {
+			t.Errorf("CPUs(%d).Quantity() = %s, want %s", got, q, tt.quantity)
+		}
+	}
+
+	for _, value := range []string{"", "-1", "two"} {
+		if got, err := containerconfig.ParseCPUs(value); err == nil {
+			t.Errorf("ParseCPUs(%q) = %d, want an error", value, got)
+		}
+	}
+}
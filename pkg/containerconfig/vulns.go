@@ -0,0 +1,116 @@
+package containerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// VulnerabilitySummary counts the known vulnerabilities of an image by severity
+type VulnerabilitySummary struct {
+	Scanner string `json:"scanner"`
+	// Target is the image reference that was scanned, a digest when one is known
+	Target    string    `json:"target"`
+	ScannedAt time.Time `json:"scannedAt"`
+	Critical  int       `json:"critical"`
+	High      int       `json:"high"`
+	Medium    int       `json:"medium"`
+	Low       int       `json:"low"`
+	Unknown   int       `json:"unknown,omitempty"`
+}
+
+// Scanner finds the known vulnerabilities of an image; implementations wrap tools such as trivy
+type Scanner interface {
+	// Name identifies the scanner in reports
+	Name() string
+	// Scan scans an image reference
+	Scan(image string) (*VulnerabilitySummary, error)
+}
+
+// String formats the counts, e.g. "2 critical, 5 high, 11 medium, 3 low"
+func (v *VulnerabilitySummary) String() string {
+	if v == nil {
+		return ""
+	}
+	parts := []string{
+		fmt.Sprintf("%d critical", v.Critical),
+		fmt.Sprintf("%d high", v.High),
+		fmt.Sprintf("%d medium", v.Medium),
+		fmt.Sprintf("%d low", v.Low),
+	}
+	if v.Unknown > 0 {
+		parts = append(parts, fmt.Sprintf("%d unknown", v.Unknown))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// findings turns the summary into report findings: critical and high vulnerabilities are
+// reported at their own severity
+func (v *VulnerabilitySummary) findings() []Finding {
+	if v == nil {
+		return nil
+	}
+	var findings []Finding
+	if v.Critical > 0 {
+		findings = append(findings, Finding{Severity: SeverityCritical, Message: fmt.Sprintf("critical vulnerabilities in the image: %d (%s)", v.Critical, v.Scanner)})
+	}
+	if v.High > 0 {
+		findings = append(findings, Finding{Severity: SeverityHigh, Message: fmt.Sprintf("high-severity vulnerabilities in the image: %d (%s)", v.High, v.Scanner)})
+	}
+	return findings
+}
+
+// trivyReport is the part of trivy's JSON output the summary is built from
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID string `json:"VulnerabilityID"`
+			PkgName         string `json:"PkgName"`
+			Severity        string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// ParseTrivyJSON counts the vulnerabilities in `trivy image --format json` output; a
+// vulnerability reported for the same package in several results is counted once
+func ParseTrivyJSON(data []byte) (*VulnerabilitySummary, error) {
+	var report trivyReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse trivy output: %w", err)
+	}
+
+	summary := &VulnerabilitySummary{Scanner: "trivy"}
+	seen := make(map[string]bool)
+	for _, result := range report.Results {
+		for _, vuln := range result.Vulnerabilities {
+			key := vuln.VulnerabilityID + "/" + vuln.PkgName
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			switch strings.ToUpper(vuln.Severity) {
+			case "CRITICAL":
+				summary.Critical++
+			case "HIGH":
+				summary.High++
+			case "MEDIUM":
+				summary.Medium++
+			case "LOW":
+				summary.Low++
+			default:
+				summary.Unknown++
+			}
+		}
+	}
+	return summary, nil
+}
+
+// ScanTarget returns the reference an image is scanned by: its first repo digest, so the
+// result belongs to exact content, falling back to the image ID
+func (i *ImageInfo) ScanTarget() string {
+	if len(i.RepoDigests) > 0 {
+		return i.RepoDigests[0]
+	}
+	return i.ID
+}
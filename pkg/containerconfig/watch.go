@@ -0,0 +1,84 @@
+package containerconfig
+
+import (
+	"fmt"
+	"time"
+)
+
+// watchScript rebuilds the Go program whenever a .go file or go.mod changes and restarts it,
+// under dlv once the debugger is installed; the program's arguments are the script's arguments
+// It polls with find -newer, which works on bind mounts from any host, unlike inotify
+const watchScript = `bin=/tmp/dce-watch-app
+stamp=/tmp/dce-watch-stamp
+pid=""
+debug=0
+build() { go build -gcflags='all=-N -l' -o "$bin" "$DCE_WATCH_PACKAGE"; }
+start() {
+  debug=0
+  if command -v dlv >/dev/null 2>&1; then
+    debug=1
+    dlv exec --headless --listen=:2345 --api-version=2 --accept-multiclient --continue "$bin" -- "$@" &
+  else
+    "$bin" "$@" &
+  fi
+  pid=$!
+}
+stop() {
+  if [ -n "$pid" ]; then kill "$pid" 2>/dev/null; wait "$pid" 2>/dev/null; fi
+  pid=""
+}
+trap 'stop; exit 0' TERM INT
+touch "$stamp"
+if build; then start "$@"; else echo "dce-watch: build failed, waiting for changes"; fi
+while true; do
+  sleep "$DCE_WATCH_INTERVAL"
+  if [ -n "$pid" ] && [ "$debug" = 0 ] && command -v dlv >/dev/null 2>&1; then
+    echo "dce-watch: debugger installed, restarting under dlv"
+    stop; start "$@"
+  fi
+  if [ -n "$(find . \( -name '*.go' -o -name go.mod -o -name go.sum \) -newer "$stamp" -print 2>/dev/null | head -n 1)" ]; then
+    touch "$stamp"
+    echo "dce-watch: change detected, rebuilding"
+    if build; then stop; start "$@"; else echo "dce-watch: build failed, keeping the running binary"; fi
+  fi
+done`
+
+// watchProfile replaces the container's process with a loop that rebuilds the Go program from the
+// mounted source and restarts it on every change; the original arguments are passed to the rebuilt binary
+func watchProfile(b *SpecBuilder, opts ProfileOptions) error {
+	dir := opts.WatchDir
+	if dir == "" {
+		dir, _ = b.spec.SourceDir()
+	}
+	if dir == "" {
+		dir = b.spec.WorkingDir
+	}
+	if dir == "" {
+		return fmt.Errorf("the watch profile needs the source directory; mount it with --source")
+	}
+
+	pkg := opts.WatchPackage
+	if pkg == "" {
+		pkg = "."
+	}
+	interval := opts.WatchInterval
+	if interval == 0 {
+		interval = time.Second
+	}
+	if interval < 100*time.Millisecond {
+		return fmt.Errorf("watch interval %s is too short", interval)
+	}
+
+	// The original argv starts with the production binary, which the rebuilt one replaces
+	var args []string
+	if original := append(cloneStrings(b.spec.EntryPointArgs()), b.spec.CommandArgs()...); DetectForm(original) == FormExec && len(original) > 1 {
+		args = original[1:]
+	}
+
+	b.WithEntryPoint("/bin/sh", "-c", watchScript, "dce-watch")
+	b.WithCommand(args...)
+	b.spec.WorkingDir = dir
+	b.WithEnv("DCE_WATCH_PACKAGE", pkg)
+	b.WithEnv("DCE_WATCH_INTERVAL", fmt.Sprintf("%g", interval.Seconds()))
+	return nil
+}
@@ -0,0 +1,159 @@
+// Package dap implements a Debug Adapter Protocol proxy that lets any DAP-speaking editor talk to
+// a debug adapter inside a container while translating source paths between host and container
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strconv"
+	"sync"
+
+	"github.com/lhc03/docker-config-extractor/pkg/debugconfig"
+)
+
+// pathKeys are the message properties holding file system paths that need translation
+var pathKeys = map[string]bool{"path": true, "program": true, "cwd": true}
+
+// Proxy forwards DAP connections to a debug adapter and translates paths in both directions
+type Proxy struct {
+	// Target is the host:port address of the debug adapter (dlv, debugpy, ...)
+	Target string
+	// PathMappings translate source paths between the editor's host and the container
+	PathMappings debugconfig.PathMappings
+	Logger       *log.Logger
+}
+
+// Serve accepts editor connections on the listener and proxies each to the target until the
+// listener is closed
+func (p *Proxy) Serve(listener net.Listener) error {
+	for {
+		client, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go p.handle(client)
+	}
+}
+
+// handle proxies one editor connection
+func (p *Proxy) handle(client net.Conn) {
+	defer client.Close()
+
+	server, err := net.Dial("tcp", p.Target)
+	if err != nil {
+		p.logf("Failed to connect to debug adapter at %s: %v", p.Target, err)
+		return
+	}
+	defer server.Close()
+	p.logf("Editor %s connected, forwarding to %s", client.RemoteAddr(), p.Target)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		p.pump(server, client, p.PathMappings.ToRemote)
+		server.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		p.pump(client, server, p.PathMappings.ToLocal)
+		client.Close()
+	}()
+	wg.Wait()
+	p.logf("Editor %s disconnected", client.RemoteAddr())
+}
+
+// pump copies DAP messages from src to dst, rewriting paths with translate
+func (p *Proxy) pump(dst io.Writer, src io.Reader, translate func(string) (string, bool)) {
+	reader := bufio.NewReader(src)
+	for {
+		body, err := ReadMessage(reader)
+		if err != nil {
+			if err != io.EOF {
+				p.logf("Warning: %v", err)
+			}
+			return
+		}
+		if err := WriteMessage(dst, TranslatePaths(body, translate)); err != nil {
+			return
+		}
+	}
+}
+
+// logf logs through the configured logger, if any
+func (p *Proxy) logf(format string, args ...interface{}) {
+	if p.Logger != nil {
+		p.Logger.Printf(format, args...)
+	}
+}
+
+// ReadMessage reads one Content-Length framed DAP message and returns its JSON body
+func ReadMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid DAP header: missing or bad Content-Length")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("failed to read DAP message: %w", err)
+	}
+	return body, nil
+}
+
+// WriteMessage writes a JSON body as a Content-Length framed DAP message
+func WriteMessage(w io.Writer, body []byte) error {
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// TranslatePaths rewrites path-valued properties (path, program, cwd) anywhere in a DAP message with
+// translate; messages that aren't valid JSON or need no change are returned as is
+func TranslatePaths(body []byte, translate func(string) (string, bool)) []byte {
+	var message interface{}
+	if err := json.Unmarshal(body, &message); err != nil {
+		return body
+	}
+	if !translateValue(message, translate) {
+		return body
+	}
+	translated, err := json.Marshal(message)
+	if err != nil {
+		return body
+	}
+	return translated
+}
+
+// translateValue rewrites path properties in a decoded JSON value in place and reports whether anything changed
+func translateValue(value interface{}, translate func(string) (string, bool)) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if s, ok := child.(string); ok && pathKeys[key] {
+				if translated, ok := translate(s); ok && translated != s {
+					v[key] = translated
+					changed = true
+				}
+				continue
+			}
+			changed = translateValue(child, translate) || changed
+		}
+	case []interface{}:
+		for _, child := range v {
+			changed = translateValue(child, translate) || changed
+		}
+	}
+	return changed
+}
@@ -0,0 +1,142 @@
+// Package debugconfig generates IDE debugger configurations that attach to the headless
+// delve server running inside a dev container
+package debugconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// DefaultDelvePort is the port delve listens on inside dev containers
+const DefaultDelvePort = 2345
+
+// Target describes the debugger endpoint of a dev container
+type Target struct {
+	// Name is the dev container name, used to name the configuration
+	Name string
+	// Host and Port are where the delve server is reachable from the IDE
+	Host string
+	Port int
+	// PathMappings translate source paths between the host and the container
+	PathMappings PathMappings
+}
+
+// withDefaults fills in the host and port when they are not set
+func (t Target) withDefaults() Target {
+	if t.Host == "" {
+		t.Host = "127.0.0.1"
+	}
+	if t.Port == 0 {
+		t.Port = DefaultDelvePort
+	}
+	return t
+}
+
+// configurationName is the name shown in the IDE's run/debug configuration list
+func (t Target) configurationName() string {
+	return fmt.Sprintf("Attach to %s", t.Name)
+}
+
+// vscodeLaunch is the launch.json document
+type vscodeLaunch struct {
+	Version        string                `json:"version"`
+	Configurations []vscodeConfiguration `json:"configurations"`
+}
+
+// vscodeConfiguration is a Go remote attach configuration of the VS Code Go extension
+type vscodeConfiguration struct {
+	Name           string                 `json:"name"`
+	Type           string                 `json:"type"`
+	Request        string                 `json:"request"`
+	Mode           string                 `json:"mode"`
+	Host           string                 `json:"host"`
+	Port           int                    `json:"port"`
+	SubstitutePath []vscodeSubstitutePath `json:"substitutePath,omitempty"`
+}
+
+// vscodeSubstitutePath maps a host path to the path delve reports inside the container
+type vscodeSubstitutePath struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// VSCodeLaunch renders a .vscode/launch.json attaching to the target
+func VSCodeLaunch(t Target) ([]byte, error) {
+	t = t.withDefaults()
+	config := vscodeConfiguration{
+		Name:    t.configurationName(),
+		Type:    "go",
+		Request: "attach",
+		Mode:    "remote",
+		Host:    t.Host,
+		Port:    t.Port,
+	}
+	for _, mapping := range t.PathMappings {
+		config.SubstitutePath = append(config.SubstitutePath, vscodeSubstitutePath{From: mapping.Local, To: mapping.Remote})
+	}
+
+	data, err := json.MarshalIndent(vscodeLaunch{Version: "0.2.0", Configurations: []vscodeConfiguration{config}}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal launch.json: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// golandComponent is the root element of a shared GoLand run configuration (.run/*.run.xml)
+type golandComponent struct {
+	XMLName       xml.Name            `xml:"component"`
+	Name          string              `xml:"name,attr"`
+	Configuration golandConfiguration `xml:"configuration"`
+}
+
+// golandConfiguration is a "Go Remote" run configuration
+type golandConfiguration struct {
+	Default     bool   `xml:"default,attr"`
+	Name        string `xml:"name,attr"`
+	Type        string `xml:"type,attr"`
+	FactoryName string `xml:"factoryName,attr"`
+	Host        string `xml:"host,attr"`
+	Port        int    `xml:"port,attr"`
+	Comment     string `xml:",comment"`
+	Disconnect  struct {
+		Value string `xml:"value,attr"`
+	} `xml:"disconnect"`
+	Method struct {
+		V string `xml:"v,attr"`
+	} `xml:"method"`
+}
+
+// GoLandRunConfig renders a GoLand "Go Remote" run configuration attaching to the target
+// GoLand matches remote files to project files by path suffix, so the path mappings are only
+// recorded in a comment for reference
+func GoLandRunConfig(t Target) ([]byte, error) {
+	t = t.withDefaults()
+	config := golandConfiguration{
+		Name:        t.configurationName(),
+		Type:        "GoRemoteDebugConfigurationType",
+		FactoryName: "Go Remote",
+		Host:        t.Host,
+		Port:        t.Port,
+	}
+	if len(t.PathMappings) > 0 {
+		var mappings []string
+		for _, mapping := range t.PathMappings {
+			mappings = append(mappings, mapping.Local+" -> "+mapping.Remote)
+		}
+		config.Comment = fmt.Sprintf(" sources: %s ", strings.Join(mappings, ", "))
+	}
+	config.Disconnect.Value = "LEAVE"
+	config.Method.V = "2"
+
+	var buf bytes.Buffer
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(golandComponent{Name: "ProjectRunConfigurationManager", Configuration: config}); err != nil {
+		return nil, fmt.Errorf("failed to marshal run configuration: %w", err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
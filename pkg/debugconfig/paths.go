@@ -0,0 +1,108 @@
+package debugconfig
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// PathMapping pairs a source directory on the host with the same directory inside the container
+type PathMapping struct {
+	Local  string `json:"local"`
+	Remote string `json:"remote"`
+}
+
+// String formats the mapping as local=remote, the form accepted by ParsePathMapping
+func (m PathMapping) String() string {
+	return m.Local + "=" + m.Remote
+}
+
+// ParsePathMapping parses a "local=remote" mapping
+func ParsePathMapping(value string) (PathMapping, error) {
+	local, remote, found := strings.Cut(value, "=")
+	if !found || local == "" || remote == "" {
+		return PathMapping{}, fmt.Errorf("invalid path mapping '%s', expected local=remote", value)
+	}
+	return PathMapping{Local: strings.TrimSuffix(local, "/"), Remote: strings.TrimSuffix(remote, "/")}, nil
+}
+
+// PathMappings translates source paths between host and container; when several mappings match a
+// path the most specific one wins
+type PathMappings []PathMapping
+
+// DerivePathMappings maps every bind mount of the spec to its host directory, and the working
+// directory to localRoot unless a bind mount already covers it
+func DerivePathMappings(spec *containerconfig.ContainerSpec, localRoot string) PathMappings {
+	var mappings PathMappings
+	for _, m := range spec.BindMounts() {
+		if !strings.HasPrefix(m.Source, "/") {
+			continue
+		}
+		mappings = append(mappings, PathMapping{Local: strings.TrimSuffix(m.Source, "/"), Remote: strings.TrimSuffix(m.Target, "/")})
+	}
+	if localRoot != "" && spec.WorkingDir != "" {
+		if _, covered := mappings.ToLocal(spec.WorkingDir); !covered {
+			mappings = append(mappings, PathMapping{Local: strings.TrimSuffix(localRoot, "/"), Remote: strings.TrimSuffix(spec.WorkingDir, "/")})
+		}
+	}
+	return mappings
+}
+
+// With returns the mappings with overrides applied: an override replaces any mapping for the same
+// container path and is added otherwise
+func (m PathMappings) With(overrides ...PathMapping) PathMappings {
+	result := append(PathMappings(nil), m...)
+	for _, override := range overrides {
+		replaced := false
+		for i := range result {
+			if result[i].Remote == override.Remote {
+				result[i] = override
+				replaced = true
+			}
+		}
+		if !replaced {
+			result = append(result, override)
+		}
+	}
+	return result
+}
+
+// ToRemote translates a host path to the container path
+func (m PathMappings) ToRemote(path string) (string, bool) {
+	return m.translate(path, func(p PathMapping) (string, string) { return p.Local, p.Remote })
+}
+
+// ToLocal translates a container path to the host path
+func (m PathMappings) ToLocal(path string) (string, bool) {
+	return m.translate(path, func(p PathMapping) (string, string) { return p.Remote, p.Local })
+}
+
+// translate rewrites the path using the mapping with the longest matching from root
+func (m PathMappings) translate(path string, sides func(PathMapping) (string, string)) (string, bool) {
+	best, bestFrom := "", ""
+	found := false
+	for _, mapping := range m {
+		from, to := sides(mapping)
+		if translated, ok := replaceRoot(path, from, to); ok && (!found || len(from) > len(bestFrom)) {
+			best, bestFrom, found = translated, from, true
+		}
+	}
+	if !found {
+		return path, false
+	}
+	return best, true
+}
+
+// replaceRoot replaces the from root of a path with the to root, only on whole path components
+func replaceRoot(path, from, to string) (string, bool) {
+	from = strings.TrimSuffix(from, "/")
+	to = strings.TrimSuffix(to, "/")
+	if path == from {
+		return to, true
+	}
+	if strings.HasPrefix(path, from+"/") {
+		return to + path[len(from):], true
+	}
+	return path, false
+}
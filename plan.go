@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// DevPlanKind identifies plan files, so apply can tell them from spec files
+const DevPlanKind = "dev-plan"
+
+// Kinds of plan actions
+const (
+	ActionRemove  = "remove"
+	ActionPull    = "pull"
+	ActionBuild   = "build"
+	ActionCreate  = "create"
+	ActionConnect = "connect"
+	ActionCopy    = "copy"
+	ActionStart   = "start"
+	ActionRun     = "run"
+	ActionExec    = "exec"
+)
+
+// Plan output formats
+const (
+	PlanFormatText = "text"
+	PlanFormatJSON = "json"
+)
+
+// PlanAction is a single step of a plan, carried out through the engine client
+type PlanAction struct {
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	// Container is the container the action acts on, if any
+	Container string `json:"container,omitempty"`
+	// Image is the image pulled, or the base image of a build, for registry authentication
+	Image string `json:"image,omitempty"`
+	// Args are the docker CLI arguments the action amounts to, without the docker binary and the
+	// context flag; plans print them, and the CLI client runs those of a create
+	Args []string `json:"args"`
+	// Create is the container a create or run action creates
+	Create *containerCreate `json:"create,omitempty"`
+	// Network is the network a connect action connects
+	Network string `json:"network,omitempty"`
+	// Copy is what a copy action copies into the container
+	Copy *copyRequest `json:"copy,omitempty"`
+	// Build is the image a build action builds
+	Build *buildRequest `json:"build,omitempty"`
+	// Exec is the command an exec action runs
+	Exec *execRequest `json:"exec,omitempty"`
+	// ExitCodes are the exit codes of an exec that count as success; empty means 0
+	ExitCodes []int `json:"exitCodes,omitempty"`
+	// Optional actions provision the container: a failure is reported but doesn't stop the plan
+	Optional bool `json:"optional,omitempty"`
+}
+
+// copyAction returns the action copying into a container
+func copyAction(description, containerName string, copy copyRequest) PlanAction {
+	return PlanAction{Kind: ActionCopy, Description: description, Container: containerName, Args: copy.args(containerName), Copy: &copy}
+}
+
+// execAction returns the action running a command in a container
+func execAction(description, containerName string, exec execRequest) PlanAction {
+	return PlanAction{Kind: ActionExec, Description: description, Container: containerName, Args: exec.args(containerName), Exec: &exec}
+}
+
+// pullAction returns the action pulling an image
+func pullAction(image string) PlanAction {
+	return PlanAction{Kind: ActionPull, Description: fmt.Sprintf("pull image '%s'", image), Image: image, Args: []string{"pull", image}}
+}
+
+// validate reports an action missing what its kind needs, e.g. in a hand-edited plan
+func (a PlanAction) validate() error {
+	missing := ""
+	switch {
+	case a.Container == "" && a.Kind != ActionPull && a.Kind != ActionBuild:
+		missing = "container"
+	case a.Kind == ActionPull && a.Image == "":
+		missing = "image"
+	case (a.Kind == ActionCreate || a.Kind == ActionRun) && a.Create == nil:
+		missing = "create"
+	case a.Kind == ActionConnect && a.Network == "":
+		missing = "network"
+	case a.Kind == ActionCopy && a.Copy == nil:
+		missing = "copy"
+	case a.Kind == ActionBuild && a.Build == nil:
+		missing = "build"
+	case a.Kind == ActionExec && a.Exec == nil:
+		missing = "exec"
+	}
+	if missing != "" {
+		return fmt.Errorf("%s action '%s' has no %s", a.Kind, a.Description, missing)
+	}
+	return nil
+}
+
+// containerCreate returns the container of a create or run action, with the docker create
+// arguments that follow "create" or "run -d" in its Args
+func (a PlanAction) containerCreate() containerCreate {
+	create := *a.Create
+	prefix := 1
+	if a.Kind == ActionRun {
+		prefix = 2
+	}
+	if len(a.Args) >= prefix {
+		create.Args = a.Args[prefix:]
+	}
+	return create
+}
+
+// runAction carries out an action through the engine client; an exec shows its output
+func (m *Manager) runAction(action PlanAction) error {
+	if err := action.validate(); err != nil {
+		return err
+	}
+	engine := m.engineClient()
+	var err error
+	switch action.Kind {
+	case ActionRemove:
+		return m.removeResource("container", action.Container)
+	case ActionPull:
+		return registryError(action.Description, action.Image, engine.Pull(action.Image))
+	case ActionBuild:
+		return registryError(action.Description, action.Image, engine.Build(*action.Build))
+	case ActionCreate, ActionRun:
+		var id string
+		if id, err = engine.CreateContainer(action.containerCreate()); err == nil {
+			m.logger.Printf("Container created: %s", id)
+		}
+		if err == nil && action.Kind == ActionRun {
+			err = engine.Start(action.Container)
+		}
+	case ActionConnect:
+		err = engine.Connect(action.Network, action.Container)
+	case ActionCopy:
+		err = engine.CopyTo(action.Container, *action.Copy)
+	case ActionStart:
+		err = engine.Start(action.Container)
+	case ActionExec:
+		return m.runExecAction(action)
+	default:
+		return fmt.Errorf("unknown action kind '%s'", action.Kind)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action.Description, err)
+	}
+	return nil
+}
+
+// DevPlan is every action creating a dev container, in execution order
+type DevPlan struct {
+	Kind         string `json:"kind"`
+	Container    string `json:"container"`
+	DevContainer string `json:"devContainer"`
+	Context      string `json:"context,omitempty"`
+	// Spec is the dev container's spec, recorded in the history once the plan is applied
+	Spec    *containerconfig.ContainerSpec `json:"spec"`
+	Actions []PlanAction                   `json:"actions"`
+}
+
+// PlanDevContainer works out the actions CreateDevContainer would take, without taking any
+// The original container is probed for the architecture and package manager of the provisioning
+// steps, so it has to be running when those are planned
+func (m *Manager) PlanDevContainer(devContainerName string, enableDebugger bool, inject []InjectStep) (*DevPlan, error) {
+	switch {
+	case m.devOptions.Ephemeral:
+		return nil, fmt.Errorf("--ephemeral dev containers live as long as the command and can't be planned")
+	case m.devOptions.StartOtelCollector:
+		return nil, fmt.Errorf("--otel-collector can't be planned; start the collector yourself and pass --otel-endpoint")
+	case m.devOptions.Dependencies == DepsClone:
+		return nil, fmt.Errorf("--deps clone can't be planned; only attaching to the existing dependencies is")
+	case m.devOptions.Tools == ToolsSidecar:
+		return nil, fmt.Errorf("--tools sidecar can't be planned; use --tools image")
+	}
+
+	spec, err := m.GetContainerConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container config: %w", err)
+	}
+	if m.devOptions.Offline {
+		if err := m.checkOffline(spec, enableDebugger); err != nil {
+			return nil, err
+		}
+	}
+
+	builder := spec.Builder().WithName(devContainerName).WithLabel(containerconfig.ManagedLabel, "true").
+		WithLabel(containerconfig.CloneOfLabel, m.containerName).WithLabel(containerconfig.SourceHashLabel, spec.ConfigHash()).
+		Record("dev container")
+	if err := m.applyDevModifications(spec, builder, enableDebugger); err != nil {
+		return nil, err
+	}
+	if err := m.applyProfiles(builder, m.profileOptions()); err != nil {
+		return nil, err
+	}
+	if m.devOptions.NameTemplate != "" && m.devOptions.PortStep > 0 {
+		if err := m.offsetHostPorts(builder); err != nil {
+			return nil, err
+		}
+	}
+	devSpec := builder.Build()
+
+	plan := &DevPlan{Kind: DevPlanKind, Container: spec.Name, DevContainer: devContainerName, Context: m.dockerContext}
+	add := func(actions ...PlanAction) {
+		plan.Actions = append(plan.Actions, actions...)
+	}
+
+	exists, err := m.CheckDevContainerExists(devContainerName)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		add(PlanAction{Kind: ActionRemove, Description: "remove existing dev container", Container: devContainerName, Args: []string{"rm", "-f", devContainerName}})
+	}
+
+	if !m.resourceExists("image", devSpec.Image) {
+		if m.devOptions.Tools == ToolsImage {
+			return nil, fmt.Errorf("image '%s' has to be pulled before a derived tools image can be planned", devSpec.Image)
+		}
+		add(pullAction(devSpec.Image))
+	}
+	if m.devOptions.Tools == ToolsImage {
+		if !m.resourceExists("image", m.toolboxImage()) {
+			add(pullAction(m.toolboxImage()))
+		}
+		action, tag, err := m.toolsImageAction(devContainerName, devSpec.Image)
+		if err != nil {
+			return nil, err
+		}
+		add(action)
+		devSpec.Image = tag
+	}
+
+	containerconfig.StampCreateValues(devSpec)
+	containerconfig.StampConfigHash(devSpec)
+	opts, err := m.devRunOptions(devContainerName, devSpec)
+	if err != nil {
+		return nil, err
+	}
+	if m.devOptions.UseRun {
+		create, err := newContainerCreate(devSpec, opts)
+		if err != nil {
+			return nil, err
+		}
+		add(PlanAction{Kind: ActionRun, Description: "run container", Container: devContainerName,
+			Args: append([]string{"run", "-d"}, create.Args...), Create: &create})
+	} else {
+		actions, err := createActions(devSpec, opts, m.devOptions.Copies)
+		if err != nil {
+			return nil, err
+		}
+		add(actions...)
+	}
+
+	if enableDebugger {
+		if m.devOptions.DebuggerDir != "" {
+			actions, err := m.copyDebuggerActions(devContainerName, spec.Name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to plan the debugger copy: %w", err)
+			}
+			for i := range actions {
+				actions[i].Optional = true
+			}
+			add(actions...)
+		} else {
+			action := execAction("install debugger", devContainerName, m.goInstallDebugger())
+			action.Optional = true
+			add(action)
+		}
+	}
+	if packages := m.devOptions.devPackages(); len(packages) > 0 {
+		action, err := m.installPackagesAction(devContainerName, spec.Name, packages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan the package install: %w", err)
+		}
+		action.Optional = true
+		add(action)
+	}
+	for i, step := range inject {
+		action := execAction(fmt.Sprintf("run inject step %d", i+1), devContainerName, m.shellExec(step.Command))
+		action.ExitCodes, action.Optional = step.ExpectedExitCodes, true
+		add(action)
+	}
+
+	plan.Spec = devSpec
+	return plan, nil
+}
+
+// ExecutePlan runs the plan's actions in order, waits for the dev container after starting it and
+// records its spec in the history
+// Failed provisioning actions are reported and the rest of the plan still runs
+func (m *Manager) ExecutePlan(plan *DevPlan) error {
+	var failed int
+	var startedAt time.Time
+	for i, action := range plan.Actions {
+		m.logger.Printf("[%d/%d] %s", i+1, len(plan.Actions), action.Description)
+		if action.Kind == ActionCreate || action.Kind == ActionRun {
+			startedAt = time.Now()
+		}
+
+		err := m.runAction(action)
+		if err != nil && action.Optional {
+			m.logger.Warnf("%v", err)
+			failed++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		switch action.Kind {
+		case ActionCreate, ActionRun:
+			m.track("container", action.Container)
+		}
+		if action.Kind == ActionStart || action.Kind == ActionRun {
+			if err := m.waitForContainer(action.Container, startedAt, 10*time.Second); err != nil {
+				return fmt.Errorf("container failed to start: %w", err)
+			}
+		}
+	}
+
+	if history, err := openHistory(); err == nil && plan.Spec != nil {
+		if _, err := history.Save(plan.Spec); err != nil {
+			m.logger.Warnf("failed to save spec snapshot: %v", err)
+		}
+	}
+	if failed > 0 {
+		m.logger.Warnf("%d provisioning action(s) failed", failed)
+	}
+	return nil
+}
+
+// runExecAction runs an exec action with its output shown and checks its exit code
+func (m *Manager) runExecAction(action PlanAction) error {
+	result := &ExecResult{Command: strings.Join(action.Exec.Cmd, " ")}
+	var err error
+	result.ExitCode, err = m.engineClient().Exec(action.Container, *action.Exec, commandIO{Stdout: os.Stdout, Stderr: os.Stderr})
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action.Description, err)
+	}
+	if !result.Expected(action.ExitCodes) {
+		return fmt.Errorf("failed to %s: exited with code %d", action.Description, result.ExitCode)
+	}
+	return nil
+}
+
+// Write renders the plan as numbered actions with their docker commands, or as JSON
+func (p *DevPlan) Write(w io.Writer, format string) error {
+	if format == PlanFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(p)
+	}
+
+	var b strings.Builder
+	b.WriteString(tr("plan.dev", p.DevContainer, p.Container) + "\n")
+	for i, action := range p.Actions {
+		optional := ""
+		if action.Optional {
+			optional = " (may fail)"
+		}
+		fmt.Fprintf(&b, "  %2d. %-8s %s%s\n", i+1, action.Kind, action.Description, optional)
+		fmt.Fprintf(&b, "      docker %s\n", containerconfig.FormatShellCommand(action.Args))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// readPlanFile reads a plan written by plan --format json, or returns nil when the file isn't one
+func readPlanFile(path string) (*DevPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read '%s': %w", path, err)
+	}
+	if !bytes.Contains(data, []byte(DevPlanKind)) {
+		return nil, nil
+	}
+
+	var plan DevPlan
+	if err := json.Unmarshal(data, &plan); err != nil || plan.Kind != DevPlanKind {
+		return nil, nil
+	}
+	return &plan, nil
+}
+
+// runPlan implements the plan subcommand
+func runPlan(args []string) error {
+	fs := newFlagSet("plan")
+	var devOpts DevOptions
+	addDevFlags(fs, &devOpts)
+	format := fs.String("format", PlanFormatText, "output format: text or json")
+	output := fs.String("output", "", "write the plan to a file instead of stdout")
+	dockerContext := fs.String("context", "", "docker context of the container")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 || len(positional) > 3 {
+		return fmt.Errorf("usage: plan [dev flags] <container> [dev-name] [swap-dir] [--format text|json] [--output file]")
+	}
+	if *format != PlanFormatText && *format != PlanFormatJSON {
+		return fmt.Errorf("unsupported format '%s' (expected text or json)", *format)
+	}
+	if err := devOpts.validate(); err != nil {
+		return err
+	}
+
+	devSwapDir := ""
+	if len(positional) >= 3 {
+		devSwapDir = positional[2]
+	}
+
+	manager := NewManager(positional[0], devSwapDir)
+	manager.SetDockerContext(*dockerContext)
+	manager.SetDevOptions(devOpts)
+	manager.logger.SetOutput(os.Stderr)
+
+	devContainerName, err := manager.devContainerName(positional)
+	if err != nil {
+		return err
+	}
+
+	// Same defaults as creating the dev container directly
+	inject := devOpts.Inject
+	if len(inject) == 0 {
+		inject = []InjectStep{{Command: "echo 'Dev container is ready for development!'"}}
+	}
+	plan, err := manager.PlanDevContainer(devContainerName, true, inject)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return plan.Write(os.Stdout, *format)
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create '%s': %w", *output, err)
+	}
+	defer file.Close()
+	if err := plan.Write(file, *format); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	successf(os.Stderr, "✓ Wrote the plan to %s; run apply %s to execute it", *output, *output)
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// daemonPlatform returns the platform of the docker daemon's host
+func (m *Manager) daemonPlatform() (containerconfig.Platform, error) {
+	out, err := m.engineClient().ServerPlatform()
+	if err != nil {
+		return containerconfig.Platform{}, fmt.Errorf("failed to query daemon platform: %w", err)
+	}
+	return containerconfig.ParsePlatform(out)
+}
+
+// imagePlatform returns the platform of a local image
+func (m *Manager) imagePlatform(image string) (containerconfig.Platform, error) {
+	out, err := m.engineClient().Inspect("image", image)
+	if err != nil {
+		return containerconfig.Platform{}, fmt.Errorf("failed to inspect image platform: %w", err)
+	}
+	var inspected []struct{ Os, Architecture, Variant string }
+	if err := json.Unmarshal([]byte(out), &inspected); err != nil {
+		return containerconfig.Platform{}, fmt.Errorf("failed to parse image inspect JSON for '%s': %w", image, err)
+	}
+	if len(inspected) == 0 {
+		return containerconfig.Platform{}, fmt.Errorf("empty image inspect data for '%s'", image)
+	}
+	platform := inspected[0]
+	return containerconfig.ParsePlatform(strings.TrimSuffix(platform.Os+"/"+platform.Architecture+"/"+platform.Variant, "/"))
+}
+
+// checkPlatform refuses to run an image the daemon can only run under emulation, unless
+// --allow-emulation is set; the check is skipped with a warning when either platform is unknown
+func (m *Manager) checkPlatform(image string) error {
+	host, err := m.daemonPlatform()
+	if err != nil {
+		m.logger.Warnf("skipping the platform check: %v", err)
+		return nil
+	}
+	platform, err := m.imagePlatform(image)
+	if err != nil {
+		m.logger.Warnf("skipping the platform check: %v", err)
+		return nil
+	}
+	if platform.RunsNatively(host) {
+		return nil
+	}
+
+	if !m.devOptions.AllowEmulation {
+		return fmt.Errorf("image '%s' is built for %s but the docker host is %s; %s. Use a %s build of the image, or pass --allow-emulation to run it anyway",
+			image, platform, host, containerconfig.EmulationWarning, host)
+	}
+	m.logger.Warnf("image '%s' is built for %s but the docker host is %s; %s", image, platform, host, containerconfig.EmulationWarning)
+	return nil
+}
+
+// Values of --selinux-relabel besides the z and Z options themselves
+const (
+	SELinuxAuto = "auto"
+	SELinuxOff  = "off"
+)
+
+// daemonInfo queries the capabilities of the docker daemon once
+func (m *Manager) daemonInfo() (*containerconfig.DaemonInfo, error) {
+	if m.daemon != nil {
+		return m.daemon, nil
+	}
+	out, err := m.engineClient().Info()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daemon info: %w", err)
+	}
+	info, err := containerconfig.ParseDaemonInfo(out)
+	if err != nil {
+		return nil, err
+	}
+	m.daemon = info
+	return info, nil
+}
+
+// selinuxRelabel returns the relabel option for the bind mounts the dev modifications add, or ""
+// for none; auto asks the daemon whether SELinux is enabled
+func (m *Manager) selinuxRelabel() string {
+	switch mode := m.devOptions.SELinuxRelabel; mode {
+	case SELinuxOff:
+		return ""
+	case containerconfig.SELinuxShared, containerconfig.SELinuxPrivate:
+		m.logger.Printf("Relabeling added bind mounts with :%s", mode)
+		return mode
+	}
+	info, err := m.daemonInfo()
+	if err != nil {
+		m.logger.Warnf("can't tell whether the daemon uses SELinux, bind mounts are not relabeled: %v", err)
+		return ""
+	}
+	if !info.SELinux() {
+		return ""
+	}
+	m.logger.Printf("SELinux is enabled on the daemon; relabeling added bind mounts with :%s", containerconfig.SELinuxShared)
+	return containerconfig.SELinuxShared
+}
+
+// preflight warns about the settings of the specs that the daemon can't apply, before any of them
+// is created, and leaves out the limits its cgroup version can't represent; a daemon that can't be
+// queried is only reported
+func (m *Manager) preflight(specs ...*containerconfig.ContainerSpec) {
+	info, err := m.daemonInfo()
+	if err != nil {
+		m.logger.Warnf("skipping the daemon capability check: %v", err)
+		return
+	}
+	m.logger.Printf("Docker daemon: %s", info)
+	for _, spec := range specs {
+		for _, warning := range info.AdaptResources(spec) {
+			m.logger.Warnf("%s: %s", spec.Name, warning)
+		}
+		for _, warning := range info.Check(spec) {
+			m.logger.Warnf("%s: %s", spec.Name, warning)
+		}
+		m.checkAppArmorProfile(info, spec)
+	}
+}
+
+// checkAppArmorProfile warns when the spec's AppArmor profile isn't loaded on the docker host, which
+// makes the container fail to start; the loaded profiles can only be read when the daemon runs on
+// this host, and usually only by root, so the check is skipped otherwise
+func (m *Manager) checkAppArmorProfile(info *containerconfig.DaemonInfo, spec *containerconfig.ContainerSpec) {
+	profile := spec.AppArmorProfile
+	if profile == "" || profile == containerconfig.AppArmorUnconfined {
+		return
+	}
+	if !info.AppArmor() {
+		m.logger.Warnf("%s: the daemon doesn't use AppArmor, so profile '%s' is not applied", spec.Name, profile)
+		return
+	}
+	if hostname, err := os.Hostname(); err != nil || hostname != info.Name {
+		return
+	}
+	data, err := os.ReadFile(containerconfig.AppArmorProfilesPath)
+	if err != nil {
+		return
+	}
+	if !containerconfig.ParseAppArmorProfiles(string(data))[profile] {
+		m.logger.Warnf("%s: AppArmor profile '%s' is not loaded on this host and the container will fail to start; load it with apparmor_parser -r /etc/apparmor.d/%s first",
+			spec.Name, profile, profile)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// Processes lists the processes of a running container with docker top, together with the host PID
+// of the container's PID 1; a container that isn't running has no processes and no error
+func (m *Manager) Processes(containerName string) ([]containerconfig.Process, int, error) {
+	state, err := m.readContainerState(containerName)
+	if err != nil {
+		return nil, 0, err
+	}
+	if state.State.Status != "running" {
+		return nil, 0, nil
+	}
+	out, err := m.engineClient().Top(containerName, containerconfig.TopArgs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list processes of '%s': %w", containerName, err)
+	}
+	processes, err := containerconfig.ParseTop(out)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list processes of '%s': %w", containerName, err)
+	}
+	return processes, state.State.Pid, nil
+}
+
+// SuggestAttach returns the process of a running container delve should attach to
+func (m *Manager) SuggestAttach(containerName string) (*containerconfig.AttachSuggestion, error) {
+	processes, initPID, err := m.Processes(containerName)
+	if err != nil {
+		return nil, err
+	}
+	if len(processes) == 0 {
+		return nil, fmt.Errorf("container '%s' is not running", containerName)
+	}
+	suggestion, err := containerconfig.SuggestAttach(processes, initPID)
+	if err != nil {
+		return nil, fmt.Errorf("no process of '%s' to attach to: %w", containerName, err)
+	}
+	m.logger.Printf("Debugger target: %s (%s)", strings.TrimSpace(suggestion.Process.Command), suggestion.Reason)
+	return suggestion, nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// spinnerFrames animate the step currently running
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// stepResult records the outcome, start time and duration of one step
+type stepResult struct {
+	name    string
+	started time.Time
+	elapsed time.Duration
+	err     error
+}
+
+// progress renders named steps with a spinner, elapsed time and ✓/✗ result on a terminal, and
+// falls back to plain log lines otherwise
+type progress struct {
+	out     io.Writer
+	tty     bool
+	logger  *cliLogger
+	mu      sync.Mutex
+	results []stepResult
+	// steps holds every result of the run, which Summary doesn't reset
+	steps []stepResult
+
+	// running tracks the steps started with RunConcurrent that haven't finished yet
+	running  map[string]time.Time
+	previous io.Writer
+	stop     chan struct{}
+}
+
+// newProgress creates a renderer writing to out; the spinner is only used when out is a terminal
+func newProgress(out *os.File, logger *cliLogger) *progress {
+	return &progress{out: out, tty: isTerminal(out), logger: logger}
+}
+
+// isTerminal reports whether the file is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Run executes a step and records its result; on a terminal the logger is silenced while the
+// spinner runs, so log lines don't tear the display
+func (p *progress) Run(name string, fn func() error) error {
+	start := time.Now()
+	if !p.tty {
+		p.logger.Printf("==> %s", name)
+		err := fn()
+		p.record(name, start, time.Since(start), err)
+		if err != nil {
+			p.logger.Printf("✗ %s failed after %s: %v", name, formatElapsed(time.Since(start)), err)
+		} else {
+			p.logger.Printf("✓ %s (%s)", name, formatElapsed(time.Since(start)))
+		}
+		return err
+	}
+
+	previous := p.logger.Writer()
+	p.logger.SetOutput(io.Discard)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for frame := 0; ; frame++ {
+			fmt.Fprintf(p.out, "\r\033[K%s %s %s", spinnerFrames[frame%len(spinnerFrames)], name, formatElapsed(time.Since(start)))
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	err := fn()
+	close(done)
+	<-stopped
+	p.logger.SetOutput(previous)
+
+	elapsed := time.Since(start)
+	p.record(name, start, elapsed, err)
+	if err != nil {
+		fmt.Fprintf(p.out, "\r\033[K✗ %s %s\n    %v\n", name, formatElapsed(elapsed), err)
+	} else {
+		fmt.Fprintf(p.out, "\r\033[K✓ %s %s\n", name, formatElapsed(elapsed))
+	}
+	return err
+}
+
+// RunConcurrent executes a step that may run alongside other concurrent steps; on a terminal a
+// single spinner line lists the running steps and each result is printed as it finishes
+func (p *progress) RunConcurrent(name string, fn func() error) error {
+	if !p.tty {
+		return p.Run(name, fn)
+	}
+
+	start := time.Now()
+	p.begin(name, start)
+	err := fn()
+	elapsed := time.Since(start)
+	p.record(name, start, elapsed, err)
+	p.end(name, elapsed, err)
+	return err
+}
+
+// begin registers a running concurrent step, starting the shared spinner with the first one
+func (p *progress) begin(name string, start time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.running) == 0 {
+		p.running = make(map[string]time.Time)
+		p.previous = p.logger.Writer()
+		p.logger.SetOutput(io.Discard)
+		p.stop = make(chan struct{})
+		go p.spin(start, p.stop)
+	}
+	p.running[name] = start
+}
+
+// end prints a concurrent step's result and stops the shared spinner after the last one
+func (p *progress) end(name string, elapsed time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.running, name)
+	if err != nil {
+		fmt.Fprintf(p.out, "\r\033[K✗ %s %s\n    %v\n", name, formatElapsed(elapsed), err)
+	} else {
+		fmt.Fprintf(p.out, "\r\033[K✓ %s %s\n", name, formatElapsed(elapsed))
+	}
+	if len(p.running) == 0 {
+		close(p.stop)
+		p.logger.SetOutput(p.previous)
+	}
+}
+
+// spin redraws the names of the running concurrent steps until stop is closed; stop is closed
+// under the lock, so nothing is drawn after the last step's result
+func (p *progress) spin(start time.Time, stop chan struct{}) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for frame := 0; ; frame++ {
+		p.mu.Lock()
+		select {
+		case <-stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		names := make([]string, 0, len(p.running))
+		for name := range p.running {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(p.out, "\r\033[K%s %s %s", spinnerFrames[frame%len(spinnerFrames)], strings.Join(names, ", "), formatElapsed(time.Since(start)))
+		p.mu.Unlock()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// record appends a step result
+func (p *progress) record(name string, started time.Time, elapsed time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := stepResult{name: name, started: started, elapsed: elapsed, err: err}
+	p.results = append(p.results, result)
+	p.steps = append(p.steps, result)
+}
+
+// Steps returns every step run so far, including those already summarized
+func (p *progress) Steps() []stepResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]stepResult(nil), p.steps...)
+}
+
+// Summary prints the start time and duration of every step run so far and the total, then starts over
+// The total is the wall time from the first step's start to the last step's end, since steps run
+// with RunConcurrent overlap and their durations add up to more than the time they took
+func (p *progress) Summary() {
+	p.mu.Lock()
+	results := p.results
+	p.results = nil
+	p.mu.Unlock()
+	if len(results) == 0 {
+		return
+	}
+
+	first, last := results[0].started, results[0].started
+	fmt.Fprintln(p.out, "\nTiming summary:")
+	for _, result := range results {
+		mark := "✓"
+		if result.err != nil {
+			mark = "✗"
+		}
+		fmt.Fprintf(p.out, "  %s %-28s %s %8s\n", mark, result.name, result.started.Format(time.TimeOnly), formatElapsed(result.elapsed))
+		if result.started.Before(first) {
+			first = result.started
+		}
+		if end := result.started.Add(result.elapsed); end.After(last) {
+			last = end
+		}
+	}
+	fmt.Fprintf(p.out, "    %-28s %8s %8s\n", "Total", "", formatElapsed(last.Sub(first)))
+}
+
+// formatElapsed formats a duration with one decimal of seconds
+func formatElapsed(d time.Duration) string {
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
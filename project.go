@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// targetOptions applies a project target's settings on top of the dev flags given on the command line
+func targetOptions(base DevOptions, target *containerconfig.ProjectTarget) (DevOptions, error) {
+	opts := base
+	opts.Profiles = append(append([]string(nil), base.Profiles...), target.Profiles...)
+	opts.Aliases = append(append([]string(nil), base.Aliases...), target.Aliases...)
+	opts.Writable = append(append([]string(nil), base.Writable...), target.Writable...)
+	opts.Packages = append(append([]string(nil), base.Packages...), target.Packages...)
+	opts.Copies = append([]string(nil), base.Copies...)
+	opts.Inject = append([]InjectStep(nil), base.Inject...)
+	opts.HostAccess = base.HostAccess || target.HostAccess
+	if target.Source != "" {
+		opts.Source = target.Source
+	}
+	if target.Deps != "" {
+		opts.Dependencies = target.Deps
+	}
+	for _, command := range target.Inject {
+		step, err := ParseInjectStep(command)
+		if err != nil {
+			return opts, err
+		}
+		opts.Inject = append(opts.Inject, step)
+	}
+	for _, rule := range target.Sync {
+		if rule.Mode == containerconfig.SyncCopy {
+			opts.Copies = append(opts.Copies, rule.From+":"+rule.To)
+		} else {
+			opts.Mounts = append(opts.Mounts, rule.From+":"+rule.To)
+		}
+	}
+	return opts, opts.validate()
+}
+
+// projectUp is a target of a project being brought up
+type projectUp struct {
+	target  string
+	devName string
+	manager *Manager
+}
+
+// tunnel makes a dev container on another context reachable from the current one on the target's
+// ports, or the original container's published ports
+func (up projectUp) tunnel(target *containerconfig.ProjectTarget) error {
+	ports := target.Ports
+	if len(ports) == 0 {
+		spec, err := up.manager.GetContainerConfig()
+		if err != nil {
+			return err
+		}
+		ports = spec.ContainerPorts()
+	}
+	if len(ports) == 0 {
+		up.manager.logger.Warnf("'%s' publishes no ports; list the ports to tunnel under ports", up.devName)
+		return nil
+	}
+	return up.manager.startTunnel(up.devName, ports, NewManager("", "").tunnelBindAddress())
+}
+
+// UpProject creates the dev containers of the selected targets in order and supervises them all
+// until interrupted; when one fails to come up, those already created are torn down
+func UpProject(project *containerconfig.Project, names []string, base DevOptions, opts SuperviseOptions) error {
+	var ups []projectUp
+	teardown := func() {
+		for _, up := range ups {
+			up.manager.Teardown()
+		}
+	}
+
+	// Targets on the current context reach those on other contexts by name through extra hosts
+	var remoteHosts []string
+	hasLocal := false
+	for _, name := range names {
+		hasLocal = hasLocal || !project.Remote(name)
+	}
+	for _, name := range names {
+		if !hasLocal || !project.Remote(name) {
+			continue
+		}
+		remote := NewManager("", "")
+		remote.SetDockerContext(project.Targets[name].Context)
+		address, err := remote.remoteHostEntry()
+		if err != nil {
+			return fmt.Errorf("target '%s': %w", name, err)
+		}
+		for _, hostname := range project.Hostnames(name) {
+			remoteHosts = append(remoteHosts, hostname+":"+address)
+		}
+	}
+
+	for _, name := range names {
+		target := project.Targets[name]
+		devOpts, err := targetOptions(base, target)
+		if err != nil {
+			teardown()
+			return fmt.Errorf("target '%s': %w", name, err)
+		}
+		devOpts.Supervised = true
+		devOpts.VolumeTemplate = project.Naming.Volume
+		devOpts.ImageTemplate = project.Naming.Image
+		if !project.Remote(name) {
+			devOpts.ExtraHosts = append(devOpts.ExtraHosts, remoteHosts...)
+		}
+
+		source := target.Container
+		if source == "" {
+			source = name
+		}
+		up := projectUp{target: name, devName: project.DevName(name), manager: NewManager(source, target.SwapDir)}
+		up.manager.SetDockerContext(target.Context)
+		up.manager.SetSourceImage(target.Image)
+		up.manager.SetDevOptions(devOpts)
+		ups = append(ups, up)
+
+		up.manager.logger.Printf("Bringing up target '%s' as '%s'", name, up.devName)
+		if err := up.manager.replaceDevContainer(up.devName, opts.Replace); err != nil {
+			teardown()
+			return err
+		}
+		if err := up.manager.CreateDevContainer(up.devName, true, devOpts.Inject); err != nil {
+			teardown()
+			return fmt.Errorf("failed to bring up target '%s': %w", name, err)
+		}
+		if hasLocal && project.Remote(name) {
+			if err := up.tunnel(target); err != nil {
+				teardown()
+				return fmt.Errorf("target '%s': %w", name, err)
+			}
+		}
+	}
+
+	successf(os.Stdout, "\n✓ Supervising %d dev container(s) (restart: %s). Press Ctrl+C to stop and clean up.\n", len(ups), opts.Restart)
+
+	// Every supervisor gets the interrupt and tears its own dev container down
+	var wg sync.WaitGroup
+	errs := make([]error, len(ups))
+	for i, up := range ups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := up.manager.Supervise(up.devName, opts); err != nil {
+				errs[i] = fmt.Errorf("target '%s': %w", up.target, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// loadProject returns the project file to use for up: the --project file, or dce.yaml in the
+// working directory when every positional argument names one of its targets
+func loadProject(path string, positional []string) (*containerconfig.Project, error) {
+	explicit := path != ""
+	if !explicit {
+		if _, err := os.Stat(containerconfig.ProjectFileName); err != nil {
+			return nil, nil
+		}
+		path = containerconfig.ProjectFileName
+	}
+
+	project, err := containerconfig.ReadProjectFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if explicit {
+		return project, nil
+	}
+	for _, name := range positional {
+		if project.Targets[name] == nil {
+			return nil, nil
+		}
+	}
+	return project, nil
+}
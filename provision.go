@@ -0,0 +1,401 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// ProvisionStep is one step of setting up a started dev container
+type ProvisionStep struct {
+	Name string
+	// Key identifies the step across runs in the provisioning state; empty means the step always runs
+	Key string
+	// After names the steps that must succeed before this one starts
+	After []string
+	Run   func() error
+}
+
+// validateProvisionSteps checks that step names are unique and every dependency exists and is
+// acyclic, so no step can wait forever
+func validateProvisionSteps(steps []ProvisionStep) error {
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		if _, ok := index[step.Name]; ok {
+			return fmt.Errorf("duplicate provisioning step '%s'", step.Name)
+		}
+		index[step.Name] = i
+	}
+	for _, step := range steps {
+		for _, dep := range step.After {
+			if _, ok := index[dep]; !ok {
+				return fmt.Errorf("provisioning step '%s' depends on unknown step '%s'", step.Name, dep)
+			}
+		}
+	}
+
+	// Depth-first search; a step met again while still on the stack closes a cycle
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(steps))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("provisioning step '%s' is part of a dependency cycle", steps[i].Name)
+		case visited:
+			return nil
+		}
+		state[i] = visiting
+		for _, dep := range steps[i].After {
+			if err := visit(index[dep]); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		return nil
+	}
+	for i := range steps {
+		if err := visit(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// provision runs the steps concurrently, each starting once the steps it comes after have
+// succeeded; a step whose dependency failed is skipped. The errors of failed and skipped steps
+// are returned by step name. Steps the state records as completed are not run again, and steps
+// that succeed are recorded in it; state may be nil
+func (m *Manager) provision(steps []ProvisionStep, state *provisionState) (map[string]error, error) {
+	if err := validateProvisionSteps(steps); err != nil {
+		return nil, err
+	}
+
+	done := make(map[string]chan struct{}, len(steps))
+	for _, step := range steps {
+		done[step.Name] = make(chan struct{})
+	}
+	var mu sync.Mutex
+	failed := make(map[string]error)
+
+	var wg sync.WaitGroup
+	for _, step := range steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[step.Name])
+
+			for _, dep := range step.After {
+				<-done[dep]
+				mu.Lock()
+				_, depFailed := failed[dep]
+				mu.Unlock()
+				if depFailed {
+					m.logger.Warnf("skipped %s: %s failed", step.Name, dep)
+					mu.Lock()
+					failed[step.Name] = fmt.Errorf("skipped because '%s' failed", dep)
+					mu.Unlock()
+					return
+				}
+			}
+
+			if state.completed(step.Key) {
+				m.logger.Printf("%s already completed, skipping", step.Name)
+				return
+			}
+			if err := m.progress.RunConcurrent(step.Name, step.Run); err != nil {
+				mu.Lock()
+				failed[step.Name] = err
+				mu.Unlock()
+				return
+			}
+			if err := state.complete(step.Key); err != nil {
+				m.logger.Warnf("%v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	return failed, nil
+}
+
+// devProvisionSteps returns the provisioning steps of a dev container: the debugger install, the
+// package install and the inject steps. The inject steps come after the package install and each
+// after the previous one, since later commands often use what earlier ones installed; their keys
+// hold their position, so a repeated command is still recorded once per run of it
+func (m *Manager) devProvisionSteps(containerName string, enableDebugger bool, inject []InjectStep) []ProvisionStep {
+	var steps []ProvisionStep
+	if enableDebugger {
+		steps = append(steps, ProvisionStep{Name: "Install debugger", Key: "debugger", Run: func() error {
+			return m.installDebugger(containerName)
+		}})
+	}
+	previous := ""
+	if packages := m.devOptions.devPackages(); len(packages) > 0 {
+		steps = append(steps, ProvisionStep{Name: "Install packages", Key: "packages:" + strings.Join(packages, ","), Run: func() error {
+			return m.InstallPackages(containerName, packages...)
+		}})
+		previous = "Install packages"
+	}
+	for i, step := range inject {
+		provisionStep := ProvisionStep{Name: fmt.Sprintf("Run inject step %d", i+1), Key: fmt.Sprintf("inject:%d:%s", i, step.Command), Run: func() error {
+			_, err := m.runInjectStep(containerName, step)
+			return err
+		}}
+		if previous != "" {
+			provisionStep.After = []string{previous}
+		}
+		steps = append(steps, provisionStep)
+		previous = provisionStep.Name
+	}
+	return steps
+}
+
+// provisionDevContainer runs the dev container's provisioning steps concurrently and records the
+// completed ones; with resume, steps completed by an earlier run in the same container are skipped
+func (m *Manager) provisionDevContainer(containerName string, enableDebugger bool, inject []InjectStep, resume bool) error {
+	container, err := m.readContainerState(containerName)
+	if err != nil {
+		return err
+	}
+	var state *provisionState
+	if resume {
+		state, err = loadProvisionState(containerName)
+		if err != nil {
+			return err
+		}
+	}
+	if state == nil || state.ContainerID != container.ID {
+		state = newProvisionState(containerName, container.ID)
+		if err := state.save(); err != nil {
+			m.logger.Warnf("%v", err)
+		}
+	}
+
+	target, err := m.toolsTarget(containerName)
+	var noShell *errNoShell
+	if errors.As(err, &noShell) {
+		m.logger.Warnf("skipping provisioning: %v", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	m.checkProxyReachable()
+	steps := m.devProvisionSteps(target, enableDebugger, inject)
+	failed, err := m.provision(steps, state)
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		m.logger.Warnf("%d of %d provisioning step(s) failed or were skipped", len(failed), len(steps))
+	}
+	return nil
+}
+
+// provisionState records the provisioning steps completed in a dev container, so provisioning
+// can resume from a failed step instead of recreating the container
+type provisionState struct {
+	ContainerID string   `json:"containerId"`
+	Completed   []string `json:"completed,omitempty"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// provisionStatePath returns the state file of a dev container in DCE_STATE_DIR, or in the user
+// config directory
+func provisionStatePath(containerName string) (string, error) {
+	dir := os.Getenv("DCE_STATE_DIR")
+	if dir == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to locate state directory: %w", err)
+		}
+		dir = filepath.Join(configDir, "docker-config-extractor", "provision")
+	}
+	return filepath.Join(dir, containerName+".json"), nil
+}
+
+// newProvisionState starts an empty state for the given container
+func newProvisionState(containerName, containerID string) *provisionState {
+	path, _ := provisionStatePath(containerName)
+	return &provisionState{ContainerID: containerID, path: path}
+}
+
+// loadProvisionState reads the state of a dev container; nil means none was recorded
+func loadProvisionState(containerName string) (*provisionState, error) {
+	path, err := provisionStatePath(containerName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provisioning state: %w", err)
+	}
+	state := &provisionState{path: path}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse provisioning state '%s': %w", path, err)
+	}
+	return state, nil
+}
+
+// completed reports whether the step with the given key has completed
+func (s *provisionState) completed(key string) bool {
+	if s == nil || key == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, done := range s.Completed {
+		if done == key {
+			return true
+		}
+	}
+	return false
+}
+
+// complete records a completed step and saves the state
+func (s *provisionState) complete(key string) error {
+	if s == nil || key == "" {
+		return nil
+	}
+	s.mu.Lock()
+	s.Completed = append(s.Completed, key)
+	s.mu.Unlock()
+	return s.save()
+}
+
+// save writes the state file
+func (s *provisionState) save() error {
+	if s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode provisioning state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write provisioning state: %w", err)
+	}
+	return nil
+}
+
+// PendingProvisioning returns the names of the dev container's provisioning steps that an earlier
+// run didn't complete; nothing is pending when no state was recorded for the container as it is now
+func (m *Manager) PendingProvisioning(containerName string, enableDebugger bool, inject []InjectStep) ([]string, error) {
+	state, err := loadProvisionState(containerName)
+	if err != nil || state == nil {
+		return nil, err
+	}
+	container, err := m.readContainerState(containerName)
+	if err != nil {
+		return nil, err
+	}
+	if container.ID != state.ContainerID {
+		return nil, nil
+	}
+
+	var pending []string
+	for _, step := range m.devProvisionSteps(containerName, enableDebugger, inject) {
+		if !state.completed(step.Key) {
+			pending = append(pending, step.Name)
+		}
+	}
+	return pending, nil
+}
+
+// ResumeProvisioning starts the dev container if needed and runs the provisioning steps an
+// earlier run didn't complete
+func (m *Manager) ResumeProvisioning(containerName string, enableDebugger bool, inject []InjectStep) error {
+	m.logger.Printf("Resuming provisioning of '%s'...", containerName)
+	defer m.progress.Summary()
+
+	if err := m.ensureRunning(containerName); err != nil {
+		return err
+	}
+	return m.provisionDevContainer(containerName, enableDebugger, inject, true)
+}
+
+// ReuseDevContainer keeps an existing dev container instead of recreating it: it is started if
+// needed, the --copy files are copied in again and every provisioning step runs again
+func (m *Manager) ReuseDevContainer(containerName string, enableDebugger bool, inject []InjectStep) error {
+	m.logger.Printf("Reusing dev container '%s'...", containerName)
+	defer m.progress.Summary()
+
+	if err := m.ensureRunning(containerName); err != nil {
+		return err
+	}
+	for _, copy := range m.devOptions.Copies {
+		source, target, _ := strings.Cut(copy, ":")
+		if err := m.progress.Run(fmt.Sprintf("Copy %s", source), func() error {
+			if err := m.engineClient().CopyTo(containerName, copyRequest{Source: source, Target: target}); err != nil {
+				return fmt.Errorf("failed to copy '%s' to '%s': %w", source, target, err)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return m.provisionDevContainer(containerName, enableDebugger, inject, false)
+}
+
+// ensureRunning starts the container unless it is running and waits for it to come up
+func (m *Manager) ensureRunning(containerName string) error {
+	container, err := m.readContainerState(containerName)
+	if err != nil {
+		return err
+	}
+	if container.State.Status == "running" {
+		return nil
+	}
+	startedAt := time.Now()
+	if err := m.progress.Run("Start container", func() error {
+		if err := m.engineClient().Start(containerName); err != nil {
+			return fmt.Errorf("failed to start container: %w", err)
+		}
+		return m.waitForContainer(containerName, startedAt, 10*time.Second)
+	}); err != nil {
+		return fmt.Errorf("container failed to start: %w", err)
+	}
+	return nil
+}
+
+// SourceChanged reports whether the source container's config changed since the dev container was
+// created from it, comparing its ConfigHash with the one recorded on the dev container; a dev
+// container without the record counts as changed
+func (m *Manager) SourceChanged(devContainerName string) (bool, error) {
+	dev, err := m.InspectContainer(devContainerName)
+	if err != nil {
+		return false, err
+	}
+	recorded := dev.Labels[containerconfig.SourceHashLabel]
+	if recorded == "" {
+		m.logger.Printf("'%s' doesn't record the config of '%s' it was created from", devContainerName, m.containerName)
+		return true, nil
+	}
+	source, err := m.GetContainerConfig()
+	if err != nil {
+		return false, err
+	}
+	return source.ConfigHash() != recorded, nil
+}
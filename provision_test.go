@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testManager returns a manager whose log output is discarded, for code that never reaches docker
+func testManager() *Manager {
+	m := NewManager("app", "")
+	m.logger.SetOutput(io.Discard)
+	return m
+}
+
+func TestValidateProvisionSteps(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []ProvisionStep
+		// err is a substring of the expected error; empty means the steps are valid
+		err string
+	}{
+		{"empty", nil, ""},
+		{"independent", []ProvisionStep{{Name: "a"}, {Name: "b"}}, ""},
+		{"chain", []ProvisionStep{{Name: "c", After: []string{"b"}}, {Name: "b", After: []string{"a"}}, {Name: "a"}}, ""},
+		{"diamond", []ProvisionStep{{Name: "a"}, {Name: "b", After: []string{"a"}}, {Name: "c", After: []string{"a"}}, {Name: "d", After: []string{"b", "c"}}}, ""},
+		{"duplicate", []ProvisionStep{{Name: "a"}, {Name: "a"}}, "duplicate provisioning step 'a'"},
+		{"unknown dependency", []ProvisionStep{{Name: "a", After: []string{"missing"}}}, "depends on unknown step 'missing'"},
+		{"self cycle", []ProvisionStep{{Name: "a", After: []string{"a"}}}, "dependency cycle"},
+		{"cycle", []ProvisionStep{{Name: "a", After: []string{"c"}}, {Name: "b", After: []string{"a"}}, {Name: "c", After: []string{"b"}}}, "dependency cycle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateProvisionSteps(tt.steps)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("validateProvisionSteps: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("validateProvisionSteps = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestProvision(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name string
+		// steps are "name" or "name<dep,dep"; a step named fail* fails
+		steps []string
+		// completed are the keys the state records as completed, a step's key being its name
+		completed []string
+		// ran are the steps that ran, failed the steps reported failed or skipped
+		ran    []string
+		failed []string
+	}{
+		{
+			name:  "all succeed",
+			steps: []string{"a", "b<a", "c"},
+			ran:   []string{"a", "b", "c"},
+		},
+		{
+			name:   "dependents of a failed step are skipped",
+			steps:  []string{"fail", "b<fail", "c<b", "d"},
+			ran:    []string{"d", "fail"},
+			failed: []string{"b", "c", "fail"},
+		},
+		{
+			name:   "a step waits for all its dependencies",
+			steps:  []string{"a", "fail", "c<a,fail"},
+			ran:    []string{"a", "fail"},
+			failed: []string{"c", "fail"},
+		},
+		{
+			name:      "completed steps are not run again",
+			steps:     []string{"a", "b<a", "c<b"},
+			completed: []string{"a", "b"},
+			ran:       []string{"c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var ran []string
+			var steps []ProvisionStep
+			for _, spec := range tt.steps {
+				name, deps, _ := strings.Cut(spec, "<")
+				step := ProvisionStep{Name: name, Key: name, Run: func() error {
+					mu.Lock()
+					ran = append(ran, name)
+					mu.Unlock()
+					if strings.HasPrefix(name, "fail") {
+						return errFailed
+					}
+					return nil
+				}}
+				if deps != "" {
+					step.After = strings.Split(deps, ",")
+				}
+				steps = append(steps, step)
+			}
+			state := &provisionState{Completed: tt.completed}
+
+			failed, err := testManager().provision(steps, state)
+			if err != nil {
+				t.Fatalf("provision: %v", err)
+			}
+			sort.Strings(ran)
+			if !reflect.DeepEqual(ran, tt.ran) {
+				t.Errorf("ran %v, want %v", ran, tt.ran)
+			}
+			var failedNames []string
+			for name := range failed {
+				failedNames = append(failedNames, name)
+			}
+			sort.Strings(failedNames)
+			if !reflect.DeepEqual(failedNames, tt.failed) {
+				t.Errorf("failed %v, want %v", failedNames, tt.failed)
+			}
+			if err, ok := failed["fail"]; ok && !errors.Is(err, errFailed) {
+				t.Errorf("failed step error = %v, want %v", err, errFailed)
+			}
+		})
+	}
+}
+
+func TestProvisionInvalidSteps(t *testing.T) {
+	steps := []ProvisionStep{{Name: "a", After: []string{"b"}, Run: func() error { return nil }}, {Name: "b", After: []string{"a"}, Run: func() error { return nil }}}
+	if _, err := testManager().provision(steps, nil); err == nil {
+		t.Error("provision ran steps with a dependency cycle")
+	}
+}
+
+func TestDevProvisionStepsInjectOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		packages []string
+		inject   []string
+		// after maps each step to the steps it comes after
+		after map[string][]string
+		keys  []string
+	}{
+		{
+			name:   "inject steps are chained",
+			inject: []string{"make", "make test", "make lint"},
+			after: map[string][]string{
+				"Run inject step 1": nil,
+				"Run inject step 2": {"Run inject step 1"},
+				"Run inject step 3": {"Run inject step 2"},
+			},
+			keys: []string{"inject:0:make", "inject:1:make test", "inject:2:make lint"},
+		},
+		{
+			name:     "the first inject step comes after the package install",
+			packages: []string{"curl"},
+			inject:   []string{"curl -f localhost"},
+			after: map[string][]string{
+				"Install packages":  nil,
+				"Run inject step 1": {"Install packages"},
+			},
+			keys: []string{"packages:curl", "inject:0:curl -f localhost"},
+		},
+		{
+			name:   "identical commands have their own keys",
+			inject: []string{"touch /tmp/x", "touch /tmp/x"},
+			after: map[string][]string{
+				"Run inject step 1": nil,
+				"Run inject step 2": {"Run inject step 1"},
+			},
+			keys: []string{"inject:0:touch /tmp/x", "inject:1:touch /tmp/x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testManager()
+			m.devOptions.Packages = tt.packages
+			var inject []InjectStep
+			for _, command := range tt.inject {
+				inject = append(inject, InjectStep{Command: command})
+			}
+
+			steps := m.devProvisionSteps("app-dev", false, inject)
+			after := make(map[string][]string)
+			var keys []string
+			for _, step := range steps {
+				after[step.Name] = step.After
+				keys = append(keys, step.Key)
+			}
+			if !reflect.DeepEqual(after, tt.after) {
+				t.Errorf("dependencies = %v, want %v", after, tt.after)
+			}
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Errorf("keys = %v, want %v", keys, tt.keys)
+			}
+		})
+	}
+}
+
+func TestProvisionRunsChainedStepsInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(name string, delay time.Duration) func() error {
+		return func() error {
+			// A later step that didn't wait would finish first
+			time.Sleep(delay)
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+	steps := []ProvisionStep{
+		{Name: "3", After: []string{"2"}, Run: record("3", 0)},
+		{Name: "1", Run: record("1", 20*time.Millisecond)},
+		{Name: "2", After: []string{"1"}, Run: record("2", 10*time.Millisecond)},
+	}
+	failed, err := testManager().provision(steps, nil)
+	if err != nil || len(failed) > 0 {
+		t.Fatalf("provision = %v, %v", failed, err)
+	}
+	if want := []string{"1", "2", "3"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"strings"
+)
+
+// proxyEnvNames are the proxy variables passed to commands that install tools in the container
+var proxyEnvNames = []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY"}
+
+// proxySettings returns the proxy variables for tool installs: configured values first, then the
+// proxies the docker CLI's config.json gives containers, then the host's environment in either case
+func (m *Manager) proxySettings() map[string]string {
+	configured := map[string]string{
+		"HTTP_PROXY":  m.devOptions.HTTPProxy,
+		"HTTPS_PROXY": m.devOptions.HTTPSProxy,
+		"NO_PROXY":    m.devOptions.NoProxy,
+	}
+	cli := m.configProxies()
+	fromCLI := map[string]string{"HTTP_PROXY": cli.HTTPProxy, "HTTPS_PROXY": cli.HTTPSProxy, "NO_PROXY": cli.NoProxy}
+	settings := make(map[string]string)
+	for _, name := range proxyEnvNames {
+		value := configured[name]
+		if value == "" {
+			value = fromCLI[name]
+		}
+		if value == "" {
+			value = os.Getenv(name)
+		}
+		if value == "" {
+			value = os.Getenv(strings.ToLower(name))
+		}
+		if value != "" {
+			settings[name] = value
+		}
+	}
+	return settings
+}
+
+// proxyEnv returns the exec env setting the proxy variables in both cases, since tools differ in
+// which one they read
+func (m *Manager) proxyEnv() []string {
+	settings := m.proxySettings()
+	var env []string
+	for _, name := range proxyEnvNames {
+		value, ok := settings[name]
+		if !ok {
+			continue
+		}
+		env = append(env, name+"="+value, strings.ToLower(name)+"="+value)
+	}
+	return env
+}
+
+// checkProxyReachable warns about a proxy on the host's loopback, which the container can't reach
+func (m *Manager) checkProxyReachable() {
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY"} {
+		value, ok := m.proxySettings()[name]
+		if !ok {
+			continue
+		}
+		proxyURL, err := url.Parse(value)
+		if err != nil || proxyURL.Host == "" {
+			continue
+		}
+		switch proxyURL.Hostname() {
+		case "localhost", "127.0.0.1", "::1":
+			m.logger.Warnf("%s %s points at the host's loopback, which the container can't reach; use host.docker.internal with --host-access", name, value)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types/registry"
+)
+
+// registryOf returns the registry host of an image reference; unqualified names are on Docker Hub
+func registryOf(image string) string {
+	first, _, found := strings.Cut(image, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return first
+	}
+	return "docker.io"
+}
+
+// authFailure reports whether docker's stderr says registry credentials are missing or rejected
+func authFailure(stderr string) bool {
+	stderr = strings.ToLower(stderr)
+	for _, marker := range []string{"unauthorized", "authentication required", "denied", "no basic auth credentials"} {
+		if strings.Contains(stderr, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultDockerConfigDir returns the docker CLI's config directory
+func defaultDockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".docker")
+}
+
+// dockerHubAuthKey is the key docker login stores Docker Hub credentials under
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// registryAuthPath returns the config.json --registry-auth names, directly or by its directory
+func (m *Manager) registryAuthPath() string {
+	source := m.devOptions.RegistryAuth
+	if info, err := os.Stat(source); err == nil && info.IsDir() {
+		source = filepath.Join(source, "config.json")
+	}
+	return source
+}
+
+// registryConfig returns the config.json registry credentials come from: the --registry-auth one
+// when given, otherwise the CLI's
+func (m *Manager) registryConfig() (*dockerCLIConfig, error) {
+	if m.devOptions.RegistryAuth == "" {
+		return m.dockerConfig(), nil
+	}
+	data, err := os.ReadFile(m.registryAuthPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry auth: %w", err)
+	}
+	var config dockerCLIConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse registry auth: %w", err)
+	}
+	return &config, nil
+}
+
+// registryAuth returns the credentials for the registry of an image, encoded for the Engine API;
+// an empty string means there are none and the registry is accessed anonymously
+func (m *Manager) registryAuth(image string) (string, error) {
+	auth, err := m.registryCredentials(registryOf(image))
+	if err != nil || auth == nil {
+		return "", err
+	}
+	return registry.EncodeAuthConfig(*auth)
+}
+
+// registryCredentials looks up a registry's credentials the way docker does: from the registry's
+// credential helper, else the credentials store, else the auths entry docker login wrote
+func (m *Manager) registryCredentials(host string) (*registry.AuthConfig, error) {
+	config, err := m.registryConfig()
+	if err != nil {
+		return nil, err
+	}
+	key, helperKey := host, host
+	if host == "docker.io" {
+		key, helperKey = dockerHubAuthKey, "index.docker.io"
+	}
+
+	helper := config.CredHelpers[helperKey]
+	if helper == "" {
+		helper = config.CredsStore
+	}
+	if helper != "" {
+		auth, err := credentialHelper(helper, key)
+		if errors.Is(err, exec.ErrNotFound) {
+			m.logger.Warnf("%v; accessing registry '%s' without credentials", err, host)
+			return nil, nil
+		}
+		return auth, err
+	}
+
+	entry, ok := config.Auths[key]
+	if !ok {
+		// Older logins stored the registry as a URL
+		for stored, candidate := range config.Auths {
+			if strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(stored, "https://"), "http://"), "/") == host {
+				entry, ok = candidate, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, nil
+	}
+	auth := &registry.AuthConfig{Username: entry.Username, Password: entry.Password, IdentityToken: entry.IdentityToken, ServerAddress: key}
+	if entry.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credentials for registry '%s' in docker config: %w", host, err)
+		}
+		var found bool
+		auth.Username, auth.Password, found = strings.Cut(string(decoded), ":")
+		if !found {
+			return nil, fmt.Errorf("invalid credentials for registry '%s' in docker config: no username", host)
+		}
+	}
+	return auth, nil
+}
+
+// credentialHelper gets a registry's credentials from docker-credential-<helper>; a helper that
+// has none for the registry means no credentials
+func credentialHelper(helper, serverURL string) (*registry.AuthConfig, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(stdout.String()+stderr.String(), "credentials not found") {
+			return nil, nil
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("credential helper docker-credential-%s not found: %w", helper, err)
+		}
+		return nil, fmt.Errorf("credential helper docker-credential-%s failed: %w, stderr: %s", helper, err, stderr.String())
+	}
+	var creds struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse the output of docker-credential-%s: %w", helper, err)
+	}
+	// An identity token is stored under this username instead of a password
+	if creds.Username == "<token>" {
+		return &registry.AuthConfig{IdentityToken: creds.Secret, ServerAddress: serverURL}, nil
+	}
+	return &registry.AuthConfig{Username: creds.Username, Password: creds.Secret, ServerAddress: serverURL}, nil
+}
+
+// registryAuthConfigDir prepares a docker config directory holding the --registry-auth config.json,
+// with the user's contexts linked in so --context keeps working and the user's current context and
+// proxies added where it sets none; the returned function removes it
+func (m *Manager) registryAuthConfigDir() (string, func(), error) {
+	data, err := os.ReadFile(m.registryAuthPath())
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read registry auth: %w", err)
+	}
+	data, err = m.mergeCLIConfig(data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "dce-docker-config-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create docker config directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), data, 0o600); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write docker config: %w", err)
+	}
+	contexts := filepath.Join(defaultDockerConfigDir(), "contexts")
+	if _, err := os.Stat(contexts); err == nil {
+		if err := os.Symlink(contexts, filepath.Join(dir, "contexts")); err != nil {
+			m.logger.Warnf("failed to link docker contexts: %v", err)
+		}
+	}
+	return dir, cleanup, nil
+}
+
+// registryError explains an authentication failure of a pull of image, or a build from it, instead
+// of passing it through; pulls and builds use the --registry-auth credentials when given, and the
+// CLI's own config.json and credential helpers otherwise
+func registryError(description, image string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if authFailure(err.Error()) {
+		host := registryOf(image)
+		return fmt.Errorf("failed to %s: registry '%s' rejected the credentials; run 'docker login %s' or pass --registry-auth with a config.json for it: %w",
+			description, host, host, err)
+	}
+	return fmt.Errorf("failed to %s: %w", description, err)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// InspectImage retrieves image metadata using docker image inspect; results are cached
+func (m *Manager) InspectImage(image string) (*containerconfig.ImageInfo, error) {
+	if info, ok := m.cachedImage(image); ok {
+		return info, nil
+	}
+	out, err := m.engineClient().Inspect("image", image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image '%s': %w", image, err)
+	}
+
+	info, err := containerconfig.ParseImageInspectJSON(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image inspect JSON for '%s': %w", image, err)
+	}
+	m.cacheImage(image, info)
+	return info, nil
+}
+
+// BuildReport inspects the given containers and their images and assembles an audit report
+// With withStats, each container's runtime snapshot is included as well; with a scanner, each
+// image's vulnerability counts. Every image is scanned once
+func (m *Manager) BuildReport(names []string, withStats bool, scanner containerconfig.Scanner) (*containerconfig.Report, error) {
+	scans := make(map[string]*containerconfig.VulnerabilitySummary)
+	report := &containerconfig.Report{
+		GeneratedAt: time.Now().UTC(),
+		Host:        m.dockerContext,
+	}
+
+	for _, name := range names {
+		spec, err := m.InspectContainer(name)
+		if err != nil {
+			return nil, err
+		}
+
+		// Prefer the exact image ID; the tag may point elsewhere by now
+		imageRef := spec.ImageID
+		if imageRef == "" {
+			imageRef = spec.Image
+		}
+		image, err := m.InspectImage(imageRef)
+		if err != nil {
+			m.logger.Warnf("%v", err)
+		}
+		if withStats {
+			if spec.Runtime, err = m.CaptureRuntime(name); err != nil {
+				m.logger.Warnf("%v", err)
+			}
+		}
+
+		containerReport := containerconfig.NewContainerReport(spec, image)
+		if processes, initPID, err := m.Processes(name); err != nil {
+			m.logger.Warnf("%v", err)
+		} else if len(processes) > 0 {
+			containerReport.AddProcesses(processes, initPID)
+		}
+		if scanner != nil && image != nil {
+			target := image.ScanTarget()
+			summary, ok := scans[target]
+			if !ok {
+				m.logger.Printf("Scanning image '%s' with %s...", target, scanner.Name())
+				if summary, err = scanner.Scan(target); err != nil {
+					m.logger.Warnf("%v", err)
+				}
+				scans[target] = summary
+			}
+			if summary != nil {
+				containerReport.AddVulnerabilities(summary)
+			}
+		}
+		report.Containers = append(report.Containers, containerReport)
+	}
+	return report, nil
+}
+
+// runReport implements the report subcommand
+func runReport(args []string) error {
+	fs := newFlagSet("report")
+	all := fs.Bool("all", false, "report on every container on the host")
+	format := fs.String("format", containerconfig.ReportFormatMarkdown, "report format: html, md or json")
+	output := fs.String("output", "", "write the report to a file instead of stdout")
+	dockerContext := fs.String("context", "", "docker context of the containers")
+	withStats := fs.Bool("stats", false, "include each container's state and a docker stats snapshot")
+	scan := fs.String("scan", "", "include each image's vulnerability counts from a scanner: trivy")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if (len(positional) == 0 && !*all) || (len(positional) > 0 && *all) {
+		return fmt.Errorf("usage: report <container...|--all> [--format html|md|json] [--output file] [--stats] [--scan trivy]")
+	}
+
+	manager := NewManager("", "")
+	manager.SetDockerContext(*dockerContext)
+	manager.logger.SetOutput(os.Stderr)
+
+	names := positional
+	if *all {
+		if names, err = manager.ListContainers(true); err != nil {
+			return err
+		}
+	}
+
+	var scanner containerconfig.Scanner
+	if *scan != "" {
+		if scanner, err = manager.newScanner(*scan); err != nil {
+			return err
+		}
+	}
+
+	report, err := manager.BuildReport(names, *withStats, scanner)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create report file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := report.Write(w, *format); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if *output != "" {
+		successf(os.Stderr, "✓ Report for %d container(s) written to %s", len(report.Containers), *output)
+	}
+	return nil
+}
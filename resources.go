@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// runResources implements the resources subcommand: Kubernetes requests/limits inferred from a
+// container's limits and a stats snapshot, printed as a resources stanza
+func runResources(args []string) error {
+	fs := newFlagSet("resources")
+	dockerContext := fs.String("context", "", "docker context of the container")
+	headroom := fs.Int("headroom", containerconfig.DefaultHeadroomPercent, "percentage added on top of observed usage for requests")
+	from := fs.String("from", "", "read a spec extracted with --stats instead of inspecting a container")
+	annotate := fs.Bool("annotate", false, "comment each value with the inspect field or stats it came from")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if (len(positional) != 1) == (*from == "") || *headroom < 0 {
+		return fmt.Errorf("usage: resources <container>|--from spec.json [--headroom percent] [--annotate] [--context name]")
+	}
+
+	var spec *containerconfig.ContainerSpec
+	if *from != "" {
+		data, err := readInput(*from)
+		if err != nil {
+			return err
+		}
+		if spec, err = containerconfig.UnmarshalSpec(data); err != nil {
+			return err
+		}
+	} else {
+		manager := NewManager(positional[0], "")
+		manager.SetDockerContext(*dockerContext)
+		manager.logger.SetOutput(os.Stderr)
+		if spec, err = manager.GetContainerConfig(); err != nil {
+			return err
+		}
+		if spec.Runtime, err = manager.CaptureRuntime(positional[0]); err != nil {
+			return err
+		}
+	}
+
+	resources, warnings := containerconfig.InferKubeResources(spec, *headroom)
+	for _, warning := range warnings {
+		warnf(os.Stderr, "%s", warning)
+	}
+	if *annotate {
+		data, err := resources.AnnotatedYAML(*headroom)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string]*containerconfig.KubeResources{"resources": resources}); err != nil {
+		return fmt.Errorf("failed to encode resources: %w", err)
+	}
+	return encoder.Close()
+}
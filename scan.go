@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// trivyScanner is the reference Scanner, running the trivy CLI against the docker daemon's images
+type trivyScanner struct {
+	// dockerHost is passed to trivy as the endpoint of the docker context, which trivy can't resolve itself
+	dockerHost string
+}
+
+// Name implements containerconfig.Scanner
+func (s *trivyScanner) Name() string {
+	return "trivy"
+}
+
+// Scan implements containerconfig.Scanner
+func (s *trivyScanner) Scan(image string) (*containerconfig.VulnerabilitySummary, error) {
+	args := []string{"image", "--format", "json", "--quiet"}
+	if s.dockerHost != "" {
+		args = append(args, "--docker-host", s.dockerHost)
+	}
+	cmd := exec.Command("trivy", append(args, image)...)
+	var out, errOut bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to scan image '%s' with trivy: %w, stderr: %s", image, err, errOut.String())
+	}
+	summary, err := containerconfig.ParseTrivyJSON(out.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	summary.Target = image
+	summary.ScannedAt = time.Now().UTC()
+	return summary, nil
+}
+
+// newScanner returns the named vulnerability scanner for the manager's docker host
+func (m *Manager) newScanner(name string) (containerconfig.Scanner, error) {
+	switch name {
+	case "trivy":
+		if _, err := exec.LookPath("trivy"); err != nil {
+			return nil, fmt.Errorf("trivy not found in PATH; install it from https://trivy.dev")
+		}
+		scanner := &trivyScanner{}
+		// trivy knows no docker contexts, so a context's endpoint is resolved for it
+		if endpoint, err := m.contextEndpoint(); err == nil {
+			scanner.dockerHost = endpoint.String()
+		} else {
+			m.logger.Warnf("trivy uses its default docker host: %v", err)
+		}
+		return scanner, nil
+	default:
+		return nil, fmt.Errorf("unknown scanner '%s' (available: trivy)", name)
+	}
+}
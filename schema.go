@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+//go:generate go run . schema spec --output schemas/spec.schema.json
+//go:generate go run . schema project --output schemas/project.schema.json
+//go:generate go run . schema overlay --output schemas/overlay.schema.json
+
+// runSchema implements the schema subcommand: the JSON Schema of spec, project or overlay files,
+// for editor completion and validation; the same schemas are checked whenever a file is loaded
+func runSchema(args []string) error {
+	fs := newFlagSet("schema")
+	output := fs.String("output", "", "write the schema to a file instead of stdout")
+	validate := fs.String("validate", "", "check a file against the schema instead of printing it")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: schema spec|project|overlay [--output file] [--validate file]")
+	}
+
+	if *validate != "" {
+		data, err := readInput(*validate)
+		if err != nil {
+			return err
+		}
+		if err := containerconfig.ValidateSchema(positional[0], data); err != nil {
+			return fmt.Errorf("%s does not match the %s schema:\n%w", *validate, positional[0], err)
+		}
+		successf(os.Stderr, "✓ %s matches the %s schema", *validate, positional[0])
+		return nil
+	}
+
+	schema, err := containerconfig.SchemaFor(positional[0])
+	if err != nil {
+		return err
+	}
+	data, err := containerconfig.MarshalSchema(schema)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write schema file '%s': %w", *output, err)
+	}
+	return nil
+}
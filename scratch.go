@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// defaultScratchPath is where --scratch-tmpfs mounts its tmpfs
+const defaultScratchPath = "/scratch"
+
+// tmpfsSizePattern matches a tmpfs size such as 512m, 1g or 50%
+var tmpfsSizePattern = regexp.MustCompile(`^[0-9]+[kKmMgG%]?$`)
+
+// tmpfsValueOptions and tmpfsFlagOptions are the tmpfs mount options docker passes through
+var (
+	tmpfsValueOptions = map[string]bool{"size": true, "mode": true, "uid": true, "gid": true, "nr_inodes": true, "nr_blocks": true}
+	tmpfsFlagOptions  = map[string]bool{"exec": true, "noexec": true, "suid": true, "nosuid": true, "dev": true, "nodev": true, "rw": true, "ro": true}
+)
+
+// validateTmpfsOptions checks comma-separated tmpfs options such as "size=1g,noexec"
+func validateTmpfsOptions(options string) error {
+	for _, option := range strings.Split(options, ",") {
+		key, value, hasValue := strings.Cut(option, "=")
+		switch {
+		case !hasValue && tmpfsFlagOptions[key]:
+		case hasValue && key == "size" && !tmpfsSizePattern.MatchString(value):
+			return fmt.Errorf("invalid size '%s' (expected e.g. 512m, 1g or 50%%)", value)
+		case hasValue && tmpfsValueOptions[key] && value != "":
+		default:
+			return fmt.Errorf("unknown tmpfs option '%s'", option)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+)
+
+// copyFromContainer streams a container path as a tar archive; with follow, a symlink is streamed
+// as the file it points to
+func (e sdkEngine) copyFromContainer(ctx context.Context, name, srcPath string, follow bool) (io.ReadCloser, container.PathStat, error) {
+	if follow {
+		stat, err := e.client.ContainerStatPath(ctx, name, srcPath)
+		if err != nil {
+			return nil, container.PathStat{}, err
+		}
+		if stat.Mode&os.ModeSymlink != 0 {
+			target := stat.LinkTarget
+			if !path.IsAbs(target) {
+				target = path.Join(path.Dir(srcPath), target)
+			}
+			srcPath = target
+		}
+	}
+	return e.client.CopyFromContainer(ctx, name, srcPath)
+}
+
+func (e sdkEngine) ReadArchive(name, source string, follow bool, read func(io.Reader) error) error {
+	content, _, err := e.copyFromContainer(context.Background(), name, source, follow)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	return read(content)
+}
+
+// CopyFrom copies a followed symlink under the link's name
+func (e sdkEngine) CopyFrom(name, srcPath, dstPath string, follow bool) error {
+	rootName := path.Base(srcPath)
+	content, stat, err := e.copyFromContainer(context.Background(), name, srcPath, follow)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	// Into an existing directory under the source's name, otherwise as the destination itself
+	dir, base := dstPath, rootName
+	info, err := os.Stat(dstPath)
+	switch {
+	case err == nil && info.IsDir():
+	case err == nil && stat.Mode.IsDir():
+		return fmt.Errorf("cannot copy a directory to file '%s'", dstPath)
+	case err == nil || os.IsNotExist(err):
+		if os.IsNotExist(err) && strings.HasSuffix(dstPath, string(os.PathSeparator)) && !stat.Mode.IsDir() {
+			return fmt.Errorf("destination directory '%s' does not exist", dstPath)
+		}
+		dir, base = filepath.Dir(filepath.Clean(dstPath)), filepath.Base(filepath.Clean(dstPath))
+	default:
+		return err
+	}
+	return extractCopy(content, path.Base(stat.Name), base, dir)
+}
+
+// extractCopy extracts a docker cp archive into dir, renaming its root entry from rootName to name
+func extractCopy(archive io.Reader, rootName, name, dir string) error {
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		entry := path.Clean(header.Name)
+		if rest, ok := strings.CutPrefix(entry, rootName); ok && (rest == "" || strings.HasPrefix(rest, "/")) {
+			entry = name + rest
+		}
+		if entry == ".." || strings.HasPrefix(entry, "../") || path.IsAbs(entry) {
+			return fmt.Errorf("archive entry '%s' points outside the destination", header.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(entry))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, header.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeCopiedFile(target, header, tr); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			os.Remove(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+			continue
+		case tar.TypeLink:
+			link := path.Clean(header.Linkname)
+			if rest, ok := strings.CutPrefix(link, rootName); ok && (rest == "" || strings.HasPrefix(rest, "/")) {
+				link = name + rest
+			}
+			os.Remove(target)
+			if err := os.Link(filepath.Join(dir, filepath.FromSlash(link)), target); err != nil {
+				return err
+			}
+		default:
+			// Devices and fifos need privileges the copy doesn't assume
+			continue
+		}
+		os.Chtimes(target, header.ModTime, header.ModTime)
+	}
+}
+
+// writeCopiedFile writes a regular file of a docker cp archive
+func writeCopiedFile(target string, header *tar.Header, content io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, header.FileInfo().Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// CopyTo extracts an archive into the target directory; a symlink given as the source is copied as
+// the link, as docker cp does without -L
+func (e sdkEngine) CopyTo(name string, copy copyRequest) error {
+	ctx := context.Background()
+	if copy.Source == "" {
+		return e.client.CopyToContainer(ctx, name, copy.Target, strings.NewReader(copy.Archive), container.CopyToContainerOptions{})
+	}
+	srcPath, dstPath := copy.Source, copy.Target
+	info, err := os.Lstat(srcPath)
+	if err != nil {
+		return err
+	}
+
+	// Into an existing directory under the source's name, otherwise as the destination itself
+	dir, base := dstPath, filepath.Base(srcPath)
+	dstStat, err := e.client.ContainerStatPath(ctx, name, dstPath)
+	switch {
+	case err == nil && dstStat.Mode.IsDir():
+	case err == nil && info.IsDir():
+		return fmt.Errorf("cannot copy a directory to file '%s'", dstPath)
+	case err == nil || cerrdefs.IsNotFound(err):
+		if err != nil && strings.HasSuffix(dstPath, "/") && !info.IsDir() {
+			return fmt.Errorf("destination directory '%s' does not exist", dstPath)
+		}
+		dir, base = path.Dir(path.Clean(dstPath)), path.Base(path.Clean(dstPath))
+	default:
+		return err
+	}
+
+	archive, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeCopyArchive(writer, srcPath, base, info))
+	}()
+	defer archive.Close()
+	return e.client.CopyToContainer(ctx, name, dir, archive, container.CopyToContainerOptions{})
+}
+
+// writeCopyArchive writes a host file or directory tree as a tar stream whose root is named name;
+// symlinks below the root are kept as links
+func writeCopyArchive(w io.Writer, root, name string, rootInfo fs.FileInfo) error {
+	tw := tar.NewWriter(w)
+	add := func(p, entry string, info fs.FileInfo) error {
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			var err error
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = entry
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		header.Uname, header.Gname = "", ""
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	}
+
+	if err := add(root, name, rootInfo); err != nil {
+		return err
+	}
+	if rootInfo.IsDir() {
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || p == root {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			return add(p, name+"/"+filepath.ToSlash(rel), info)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
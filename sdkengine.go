@@ -0,0 +1,371 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-units"
+)
+
+// create creates the container and returns its ID; like docker create, a missing image is pulled
+// and the create tried again
+func (e sdkEngine) create(ctx context.Context, create containerCreate) (string, error) {
+	created, err := e.client.ContainerCreate(ctx, create.Config, create.HostConfig, create.Networking, nil, create.Name)
+	if cerrdefs.IsNotFound(err) {
+		if err := e.Pull(create.Config.Image); err != nil {
+			return "", err
+		}
+		created, err = e.client.ContainerCreate(ctx, create.Config, create.HostConfig, create.Networking, nil, create.Name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+func (e sdkEngine) CreateContainer(create containerCreate) (string, error) {
+	return e.create(context.Background(), create)
+}
+
+// RunContainer attaches and waits before starting, so neither output nor the exit is missed
+func (e sdkEngine) RunContainer(create containerCreate, stdio commandIO) (int, error) {
+	ctx := context.Background()
+	id, err := e.create(ctx, create)
+	if err != nil {
+		return 0, err
+	}
+	attach, err := e.client.ContainerAttach(ctx, id, container.AttachOptions{Stream: true, Stdout: true, Stderr: true})
+	if err != nil {
+		return 0, err
+	}
+	defer attach.Close()
+	condition := container.WaitConditionNextExit
+	if create.HostConfig.AutoRemove {
+		condition = container.WaitConditionRemoved
+	}
+	waited, waitErr := e.client.ContainerWait(ctx, id, condition)
+	if err := e.client.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+		return 0, err
+	}
+	copied := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(writerOrDiscard(stdio.Stdout), writerOrDiscard(stdio.Stderr), attach.Reader)
+		copied <- err
+	}()
+
+	select {
+	case result := <-waited:
+		<-copied
+		if result.Error != nil {
+			return 0, fmt.Errorf("failed to wait for container: %s", result.Error.Message)
+		}
+		return int(result.StatusCode), nil
+	case err := <-waitErr:
+		return 0, err
+	}
+}
+
+// writerOrDiscard returns w, or a writer discarding everything for nil
+func writerOrDiscard(w io.Writer) io.Writer {
+	if w == nil {
+		return io.Discard
+	}
+	return w
+}
+
+func (e sdkEngine) Connect(network, container string) error {
+	return e.client.NetworkConnect(context.Background(), network, container, nil)
+}
+
+func (e sdkEngine) Exec(name string, exec execRequest, stdio commandIO) (int, error) {
+	ctx := context.Background()
+	options := container.ExecOptions{
+		User:         exec.User,
+		Env:          exec.Env,
+		AttachStdin:  stdio.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          exec.Cmd,
+	}
+	created, err := e.client.ContainerExecCreate(ctx, name, options)
+	if err != nil {
+		return 0, err
+	}
+	attach, err := e.client.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer attach.Close()
+	if options.AttachStdin {
+		go func() {
+			io.Copy(attach.Conn, stdio.Stdin)
+			attach.CloseWrite()
+		}()
+	}
+	if _, err := stdcopy.StdCopy(writerOrDiscard(stdio.Stdout), writerOrDiscard(stdio.Stderr), attach.Reader); err != nil {
+		return 0, err
+	}
+
+	inspect, err := e.client.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return 0, err
+	}
+	return inspect.ExitCode, nil
+}
+
+// Pull pulls with the registry credentials docker would use; the progress is read to its end, where
+// a failed pull reports its error
+func (e sdkEngine) Pull(ref string) error {
+	auth, err := e.m.registryAuth(ref)
+	if err != nil {
+		return err
+	}
+	progress, err := e.client.ImagePull(context.Background(), ref, image.PullOptions{RegistryAuth: auth})
+	if err != nil {
+		return err
+	}
+	defer progress.Close()
+	return jsonmessage.DisplayJSONMessagesStream(progress, io.Discard, 0, false, nil)
+}
+
+// Build sends the Dockerfile as the only file of the build context
+func (e sdkEngine) Build(request buildRequest) error {
+	var buildContext bytes.Buffer
+	tw := tar.NewWriter(&buildContext)
+	dockerfile := []byte(request.Dockerfile)
+	if err := tw.WriteHeader(&tar.Header{Name: "Dockerfile", Mode: 0o644, Size: int64(len(dockerfile)), ModTime: time.Now()}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(dockerfile); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	options := build.ImageBuildOptions{
+		Tags:        request.Tags,
+		Labels:      request.Labels,
+		Dockerfile:  "Dockerfile",
+		Remove:      true,
+		Version:     build.BuilderV1,
+		AuthConfigs: make(map[string]registry.AuthConfig),
+	}
+	for _, base := range dockerfileBaseImages(dockerfile) {
+		host := registryOf(base)
+		auth, err := e.m.registryCredentials(host)
+		if err != nil {
+			return err
+		}
+		if auth != nil {
+			key := host
+			if host == "docker.io" {
+				key = dockerHubAuthKey
+			}
+			options.AuthConfigs[key] = *auth
+		}
+	}
+
+	resp, err := e.client.ImageBuild(context.Background(), &buildContext, options)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return jsonmessage.DisplayJSONMessagesStream(resp.Body, io.Discard, 0, false, nil)
+}
+
+// dockerfileBaseImages returns the images the FROM lines of a Dockerfile name, without earlier stages
+func dockerfileBaseImages(dockerfile []byte) []string {
+	var images []string
+	stages := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(dockerfile))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+		fields = fields[1:]
+		for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if !stages[strings.ToLower(fields[0])] && fields[0] != "scratch" {
+			images = append(images, fields[0])
+		}
+		if len(fields) == 3 && strings.EqualFold(fields[1], "AS") {
+			stages[strings.ToLower(fields[2])] = true
+		}
+	}
+	return images
+}
+
+func (e sdkEngine) Logs(ctx context.Context, name string, request logsRequest, stdio commandIO) error {
+	inspect, err := e.client.ContainerInspect(ctx, name)
+	if err != nil {
+		return err
+	}
+	options := container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: request.Follow}
+	if !request.Since.IsZero() {
+		options.Since = request.Since.Format(time.RFC3339Nano)
+	}
+	if request.Tail > 0 {
+		options.Tail = strconv.Itoa(request.Tail)
+	}
+	logs, err := e.client.ContainerLogs(ctx, name, options)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+	// A TTY container's log is a single raw stream
+	if inspect.Config != nil && inspect.Config.Tty {
+		_, err = io.Copy(writerOrDiscard(stdio.Stdout), logs)
+	} else {
+		_, err = stdcopy.StdCopy(writerOrDiscard(stdio.Stdout), writerOrDiscard(stdio.Stderr), logs)
+	}
+	return err
+}
+
+func (e sdkEngine) Wait(name string) (int, error) {
+	waited, waitErr := e.client.ContainerWait(context.Background(), name, container.WaitConditionNotRunning)
+	select {
+	case result := <-waited:
+		if result.Error != nil {
+			return 0, fmt.Errorf("failed to wait for container: %s", result.Error.Message)
+		}
+		return int(result.StatusCode), nil
+	case err := <-waitErr:
+		return 0, err
+	}
+}
+
+func (e sdkEngine) Events(ctx context.Context, name string, since time.Time, actions []string) (<-chan containerEvent, <-chan error) {
+	filter := filters.NewArgs(filters.Arg("type", "container"), filters.Arg("container", name))
+	for _, action := range actions {
+		filter.Add("event", action)
+	}
+	options := events.ListOptions{Since: fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond()), Filters: filter}
+	messages, errs := e.client.Events(ctx, options)
+
+	converted := make(chan containerEvent)
+	go func() {
+		for {
+			select {
+			case message := <-messages:
+				select {
+				case converted <- containerEvent{Action: string(message.Action), Attributes: message.Actor.Attributes}:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return converted, errs
+}
+
+// statsLine is the line docker stats --format '{{json .}}' prints for a container
+type statsLine struct {
+	BlockIO   string
+	CPUPerc   string
+	Container string
+	ID        string
+	MemPerc   string
+	MemUsage  string
+	Name      string
+	NetIO     string
+	PIDs      string
+}
+
+// Stats computes the usage the way docker stats does on Linux
+func (e sdkEngine) Stats(name string) ([]byte, error) {
+	resp, err := e.client.ContainerStats(context.Background(), name, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var stats container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("failed to parse stats: %w", err)
+	}
+
+	var cpu float64
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	cpus := float64(stats.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		cpu = cpuDelta / systemDelta * cpus * 100
+	}
+	// The page cache the kernel can reclaim doesn't count, under its cgroup v1 or v2 name
+	memory := float64(stats.MemoryStats.Usage)
+	if cache, ok := stats.MemoryStats.Stats["total_inactive_file"]; ok && float64(cache) < memory {
+		memory -= float64(cache)
+	} else if cache := stats.MemoryStats.Stats["inactive_file"]; float64(cache) < memory {
+		memory -= float64(cache)
+	}
+	limit := float64(stats.MemoryStats.Limit)
+	var memoryPercent float64
+	if limit != 0 {
+		memoryPercent = memory / limit * 100
+	}
+	var rx, tx, read, write float64
+	for _, n := range stats.Networks {
+		rx += float64(n.RxBytes)
+		tx += float64(n.TxBytes)
+	}
+	for _, entry := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			read += float64(entry.Value)
+		case "write":
+			write += float64(entry.Value)
+		}
+	}
+
+	return json.Marshal(statsLine{
+		BlockIO:   units.HumanSizeWithPrecision(read, 3) + " / " + units.HumanSizeWithPrecision(write, 3),
+		CPUPerc:   fmt.Sprintf("%.2f%%", cpu),
+		Container: name,
+		ID:        stats.ID,
+		MemPerc:   fmt.Sprintf("%.2f%%", memoryPercent),
+		MemUsage:  units.BytesSize(memory) + " / " + units.BytesSize(limit),
+		Name:      strings.TrimPrefix(stats.Name, "/"),
+		NetIO:     units.HumanSizeWithPrecision(rx, 3) + " / " + units.HumanSizeWithPrecision(tx, 3),
+		PIDs:      strconv.FormatUint(stats.PidsStats.Current, 10),
+	})
+}
+
+// Top prints the processes as tab-separated columns under their titles
+func (e sdkEngine) Top(name string, psArgs []string) (string, error) {
+	top, err := e.client.ContainerTop(context.Background(), name, psArgs)
+	if err != nil {
+		return "", err
+	}
+	lines := []string{strings.Join(top.Titles, "\t")}
+	for _, process := range top.Processes {
+		lines = append(lines, strings.Join(process, "\t"))
+	}
+	return strings.Join(lines, "\n"), nil
+}
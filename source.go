@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// parseSourceFlag splits a --source value "local-dir[:container-dir]"; a drive letter is not a separator
+func parseSourceFlag(value string) (string, string) {
+	if i := strings.LastIndex(value, ":"); i > 1 && strings.HasPrefix(value[i+1:], "/") {
+		return value[:i], value[i+1:]
+	}
+	return value, ""
+}
+
+// validateSource checks that the --source checkout is an existing directory
+func validateSource(value string) error {
+	local, _ := parseSourceFlag(value)
+	info, err := os.Stat(local)
+	if err != nil {
+		return fmt.Errorf("invalid --source: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid --source: '%s' is not a directory", local)
+	}
+	return nil
+}
+
+// mountSource returns the transform bind-mounting the --source checkout over the container's source
+// directory, taken from the flag, the dce.source-dir label or a conventional working directory such as /app
+func (m *Manager) mountSource(spec *containerconfig.ContainerSpec) (containerconfig.DevTransform, error) {
+	local, dir := parseSourceFlag(m.devOptions.Source)
+	if dir == "" {
+		var ok bool
+		if dir, ok = spec.SourceDir(); !ok {
+			return containerconfig.DevTransform{}, fmt.Errorf("can't tell where the source lives in the container (working directory '%s'); pass --source %s:/path/in/container", spec.WorkingDir, local)
+		}
+	}
+	abs, err := filepath.Abs(local)
+	if err != nil {
+		return containerconfig.DevTransform{}, fmt.Errorf("failed to resolve '%s': %w", local, err)
+	}
+	m.logger.Printf("Mounting source %s over %s", abs, dir)
+	return containerconfig.AddSourceMount(abs, dir), nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sshDialer returns a dialer for an ssh://[user@]host[:port] endpoint that reaches the daemon the
+// way the docker CLI does: through docker system dial-stdio on the remote host, over an ssh
+// session, so only ssh is needed locally
+func sshDialer(endpoint string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh endpoint '%s': %w", endpoint, err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid ssh endpoint '%s': no host", endpoint)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return nil, fmt.Errorf("invalid ssh endpoint '%s': extra path after the host", endpoint)
+	}
+	args := []string{"-o", "ConnectTimeout=30", "-T"}
+	if u.User != nil {
+		args = append(args, "-l", u.User.Username())
+	}
+	if port := u.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	args = append(args, "--", u.Hostname(), "docker", "system", "dial-stdio")
+	return func(context.Context, string, string) (net.Conn, error) {
+		return dialCommand("ssh", args...)
+	}, nil
+}
+
+// commandConn is a connection over a command's stdin and stdout
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr *lockedBuffer
+}
+
+// dialCommand starts a command and connects to it; the command outlives the dial, until Close
+func dialCommand(name string, args ...string) (net.Conn, error) {
+	cmd := exec.Command(name, args...)
+	c := &commandConn{cmd: cmd, stderr: &lockedBuffer{}}
+	var err error
+	if c.stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	if c.stdout, err = cmd.StdoutPipe(); err != nil {
+		return nil, err
+	}
+	cmd.Stderr = c.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run %s: %w", name, err)
+	}
+	return c, nil
+}
+
+func (c *commandConn) Read(p []byte) (int, error) {
+	n, err := c.stdout.Read(p)
+	if err == io.EOF {
+		if stderr := strings.TrimSpace(c.stderr.String()); stderr != "" {
+			return n, fmt.Errorf("connection closed by %s: %s", c.cmd.Path, stderr)
+		}
+	}
+	return n, err
+}
+
+func (c *commandConn) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+// CloseWrite closes the command's stdin, which the SDK does after sending an exec's input
+func (c *commandConn) CloseWrite() error {
+	return c.stdin.Close()
+}
+
+func (c *commandConn) Close() error {
+	c.stdin.Close()
+	c.cmd.Process.Kill()
+	c.cmd.Wait()
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr                { return commandAddr{} }
+func (c *commandConn) RemoteAddr() net.Addr               { return commandAddr{} }
+func (c *commandConn) SetDeadline(t time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// commandAddr is the address of both ends of a commandConn
+type commandAddr struct{}
+
+func (commandAddr) Network() string { return "command" }
+func (commandAddr) String() string  { return "command" }
+
+// lockedBuffer is a buffer safe to write from the command while the connection reads it
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
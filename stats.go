@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// CaptureRuntime takes a snapshot of a container's state and, when it is running, its resource usage
+func (m *Manager) CaptureRuntime(containerName string) (*containerconfig.RuntimeSnapshot, error) {
+	m.logger.Printf("Capturing runtime stats of '%s'...", containerName)
+	data, err := m.inspectContainerJSON(containerName)
+	if err != nil {
+		return nil, err
+	}
+	state, err := containerconfig.InspectStateJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	capturedAt := time.Now()
+
+	snapshot, err := containerconfig.ParseRuntimeSnapshot(state, "", capturedAt)
+	if err != nil || snapshot.Status != "running" {
+		return snapshot, err
+	}
+	stats, err := m.engineClient().Stats(containerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stats of '%s': %w", containerName, err)
+	}
+	return containerconfig.ParseRuntimeSnapshot(state, string(stats), capturedAt)
+}
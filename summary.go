@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// createdResource is a docker object created for the dev container
+type createdResource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// ID is empty for volumes, which docker names but doesn't number, and for removed objects
+	ID string `json:"id,omitempty"`
+}
+
+// resourceLog lists the docker objects created during a run, in creation order
+type resourceLog struct {
+	mu        sync.Mutex
+	resources []createdResource
+}
+
+// add records a created object
+func (l *resourceLog) add(kind, name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.resources = append(l.resources, createdResource{Kind: kind, Name: name})
+}
+
+// list returns the recorded objects
+func (l *resourceLog) list() []createdResource {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]createdResource(nil), l.resources...)
+}
+
+// summaryPort is a port the dev container publishes, with a ready-made way to connect to it
+type summaryPort struct {
+	containerconfig.PortBinding
+	Connect string `json:"connect"`
+}
+
+// summaryStep is a progress step of the run
+type summaryStep struct {
+	Name     string    `json:"name"`
+	Started  time.Time `json:"started"`
+	Duration string    `json:"duration"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// runSummary is what a dev container run did: its steps, the docker objects it created, the ports
+// the dev container publishes and the commands to use it with; --summary-json writes it as JSON
+type runSummary struct {
+	Container string            `json:"container"`
+	Source    string            `json:"source"`
+	Started   time.Time         `json:"started"`
+	Finished  time.Time         `json:"finished"`
+	Duration  string            `json:"duration"`
+	Error     string            `json:"error,omitempty"`
+	Steps     []summaryStep     `json:"steps"`
+	Resources []createdResource `json:"resources"`
+	Ports     []summaryPort     `json:"ports"`
+	Next      []string          `json:"next"`
+}
+
+// objectID returns the ID of a docker object, or "" when it can't be inspected
+func (m *Manager) objectID(kind, name string) string {
+	out, err := m.engineClient().Inspect(kind, name)
+	if err != nil {
+		return ""
+	}
+	var inspected []struct{ Id string }
+	if json.Unmarshal([]byte(out), &inspected) != nil || len(inspected) == 0 {
+		return ""
+	}
+	return inspected[0].Id
+}
+
+// Summary collects the run's summary; the IDs of created objects are read from the daemon, and
+// objects removed since, e.g. by an ephemeral teardown, keep an empty ID. The published ports come
+// from a fresh inspect of the dev container, as ports published on random host ports are only
+// known once it runs
+func (m *Manager) Summary(devContainerName string, started time.Time, runErr error, next []string) *runSummary {
+	finished := time.Now()
+	summary := &runSummary{
+		Container: devContainerName,
+		Source:    m.containerName,
+		Started:   started,
+		Finished:  finished,
+		Duration:  formatElapsed(finished.Sub(started)),
+		Steps:     []summaryStep{},
+		Resources: []createdResource{},
+		Ports:     []summaryPort{},
+		Next:      append([]string{}, next...),
+	}
+	if m.sourceImage != "" {
+		summary.Source = m.sourceImage
+	}
+	if runErr != nil {
+		summary.Error = runErr.Error()
+	}
+	for _, step := range m.progress.Steps() {
+		s := summaryStep{Name: step.name, Started: step.started, Duration: formatElapsed(step.elapsed)}
+		if step.err != nil {
+			s.Error = step.err.Error()
+		}
+		summary.Steps = append(summary.Steps, s)
+	}
+	for _, resource := range m.created.list() {
+		if resource.Kind != "volume" {
+			resource.ID = m.objectID(resource.Kind, resource.Name)
+		}
+		summary.Resources = append(summary.Resources, resource)
+	}
+	if runErr == nil {
+		m.forget(devContainerName)
+		data, err := m.inspectContainerJSON(devContainerName)
+		if err == nil {
+			var bindings []containerconfig.PortBinding
+			bindings, err = containerconfig.ParsePortBindings(data)
+			for _, binding := range bindings {
+				summary.Ports = append(summary.Ports, summaryPort{binding, binding.ConnectionString(containerconfig.DefaultDebugPort)})
+			}
+		}
+		if err != nil {
+			m.logger.Warnf("published ports unavailable: %v", err)
+		}
+	}
+	return summary
+}
+
+// Print writes the created objects and published ports; the steps are in the timing summary
+func (s *runSummary) Print(w io.Writer) {
+	fmt.Fprintf(w, "\nRun summary: %s - %s (%s)\n", s.Started.Format(time.TimeOnly), s.Finished.Format(time.TimeOnly), s.Duration)
+	if len(s.Resources) > 0 {
+		fmt.Fprintln(w, "  Created:")
+		for _, resource := range s.Resources {
+			id := resource.ID
+			if len(id) > 12 {
+				id = id[:12]
+			}
+			fmt.Fprintf(w, "    %-10s %-40s %s\n", resource.Kind, resource.Name, id)
+		}
+	}
+	if len(s.Ports) > 0 {
+		fmt.Fprintln(w, "  Ports:")
+		fmt.Fprintf(w, "    %-14s %-22s %s\n", "CONTAINER", "HOST", "CONNECT")
+		for _, port := range s.Ports {
+			fmt.Fprintf(w, "    %-14s %-22s %s\n",
+				fmt.Sprintf("%d/%s", port.ContainerPort, port.Protocol),
+				fmt.Sprintf("%s:%d", port.Host(), port.HostPort),
+				port.Connect)
+		}
+	}
+}
+
+// WriteJSON writes the summary to path as indented JSON
+func (s *runSummary) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run summary: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write run summary: %w", err)
+	}
+	return nil
+}
+
+// writeSummaryJSON writes the summary to path when one is given, warning when it can't
+func writeSummaryJSON(path string, summary *runSummary) {
+	if path == "" {
+		return
+	}
+	if err := summary.WriteJSON(path); err != nil {
+		warnf(os.Stderr, "%v", err)
+	}
+}
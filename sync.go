@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// syncedCopy is a --copy pair up copies into the dev container again when its host files change
+type syncedCopy struct {
+	source string
+	// target is where the copy is made again: for a directory, the container directory its
+	// contents went to, which docker cp nests under the target when that already existed
+	target string
+	dir    bool
+	stamp  string
+}
+
+// workspaceSync keeps the --copy sources of a supervised dev container in sync by polling their
+// host files; bind-mounted sources need none, since the container sees them live
+// Files deleted on the host stay in the container
+type workspaceSync struct {
+	m         *Manager
+	container string
+	copies    []*syncedCopy
+}
+
+// newWorkspaceSync starts from the host files as they were copied when the container was created
+func (m *Manager) newWorkspaceSync(containerName string) (*workspaceSync, error) {
+	s := &workspaceSync{m: m, container: containerName}
+	for _, copy := range m.devOptions.Copies {
+		source, target, _ := strings.Cut(copy, ":")
+		info, err := os.Stat(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sync '%s': %w", source, err)
+		}
+		stamp, err := sourceStamp(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sync '%s': %w", source, err)
+		}
+		c := &syncedCopy{source: source, target: target, dir: info.IsDir(), stamp: stamp}
+		if c.dir {
+			nested := path.Join(target, filepath.Base(source))
+			if _, err := m.ListFiles(containerName, nested, false); err == nil {
+				c.target = nested
+			}
+		}
+		s.copies = append(s.copies, c)
+	}
+	return s, nil
+}
+
+// sync copies the sources whose host files changed since they were last copied and returns them
+func (s *workspaceSync) sync() ([]string, error) {
+	var synced []string
+	for _, c := range s.copies {
+		stamp, err := sourceStamp(c.source)
+		if err != nil {
+			return synced, fmt.Errorf("failed to sync '%s': %w", c.source, err)
+		}
+		if stamp == c.stamp {
+			continue
+		}
+		// A directory's contents go into the target, as docker cp does for source/.
+		source := c.source
+		if c.dir {
+			source += string(filepath.Separator) + "."
+		}
+		if err := s.m.engineClient().CopyTo(s.container, copyRequest{Source: source, Target: c.target}); err != nil {
+			return synced, fmt.Errorf("failed to sync '%s' to '%s': %w", c.source, c.target, err)
+		}
+		c.stamp = stamp
+		synced = append(synced, c.source)
+	}
+	return synced, nil
+}
+
+// run makes one sync pass and reports whether anything was copied; failures, e.g. of a file caught
+// mid-write, are warned about and retried on the next pass
+func (s *workspaceSync) run() bool {
+	synced, err := s.sync()
+	for _, source := range synced {
+		s.m.logger.Printf("Synced %s into '%s'", source, s.container)
+	}
+	if err != nil {
+		s.m.logger.Warnf("%v", err)
+	}
+	return len(synced) > 0
+}
+
+// sourceStamp fingerprints a host file or tree by the paths, sizes, modes and modification times in
+// it, so polling notices edits without reading file contents
+func sourceStamp(root string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00%s\n", p, info.Size(), info.ModTime().UnixNano(), info.Mode())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSourceStamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(dir string) error
+		changed bool
+	}{
+		{"untouched", func(string) error { return nil }, false},
+		{"edited file", func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "app.conf"), []byte("x=22\n"), 0o644)
+		}, true},
+		{"touched file", func(dir string) error {
+			later := time.Now().Add(time.Hour)
+			return os.Chtimes(filepath.Join(dir, "app.conf"), later, later)
+		}, true},
+		{"added file", func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "sub", "new.conf"), nil, 0o644)
+		}, true},
+		{"removed file", func(dir string) error { return os.Remove(filepath.Join(dir, "app.conf")) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "app.conf"), []byte("x=1\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			before, err := sourceStamp(dir)
+			if err != nil {
+				t.Fatalf("sourceStamp: %v", err)
+			}
+			if err := tt.change(dir); err != nil {
+				t.Fatal(err)
+			}
+			after, err := sourceStamp(dir)
+			if err != nil {
+				t.Fatalf("sourceStamp: %v", err)
+			}
+			if changed := after != before; changed != tt.changed {
+				t.Errorf("stamp changed = %v, want %v", changed, tt.changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// Ways of getting tools into a dev container whose image has no shell
+const (
+	// ToolsAuto detects a missing shell and offers the sidecar
+	ToolsAuto = ""
+	// ToolsSidecar runs provisioning in a toolbox container sharing the dev container's namespaces
+	ToolsSidecar = "sidecar"
+	// ToolsImage creates the dev container from a derived image with the toolbox's static busybox added
+	ToolsImage = "image"
+	// ToolsNone never adds tools
+	ToolsNone = "none"
+)
+
+// defaultToolboxImage provides a static busybox, so it works as a sidecar and can be copied into any image
+const defaultToolboxImage = "busybox:stable-musl"
+
+// Shells found in a container
+const (
+	shellFull    = "full"
+	shellMinimal = "minimal"
+	shellNone    = "none"
+)
+
+// errNoShell reports a container without a shell, with what to do about it
+type errNoShell struct {
+	container string
+}
+
+// Error implements error
+func (e *errNoShell) Error() string {
+	return fmt.Sprintf("'%s' has no shell (distroless or scratch image), so the debugger install, packages and inject steps can't run in it. Re-run with:\n"+
+		"  --tools sidecar  run them in a toolbox container sharing its process and network namespaces\n"+
+		"  --tools image    create the dev container from a derived image with a static busybox shell added", e.container)
+}
+
+// probeShell reports whether the container has a shell with a package manager, a bare shell
+// such as BusyBox's, or no shell at all
+func (m *Manager) probeShell(containerName string) (string, error) {
+	var out, errOut bytes.Buffer
+	probe := execRequest{Cmd: []string{"sh", "-c", "command -v apk apt-get dnf microdnf yum || true"}}
+	code, err := m.engineClient().Exec(containerName, probe, commandIO{Stdout: &out, Stderr: &errOut})
+	if err != nil {
+		return "", fmt.Errorf("failed to probe shell of '%s': %w", containerName, withStderr(err, errOut.String()))
+	}
+	// The daemon reports a missing sh on stdout to the SDK, and the CLI on stderr
+	output := out.String() + errOut.String()
+	if code != 0 {
+		if code == 126 || code == 127 || strings.Contains(output, "executable file not found") || strings.Contains(output, "no such file") {
+			return shellNone, nil
+		}
+		return "", fmt.Errorf("failed to probe shell of '%s': %w", containerName, withStderr(fmt.Errorf("exit status %d", code), errOut.String()))
+	}
+	if strings.TrimSpace(out.String()) == "" {
+		return shellMinimal, nil
+	}
+	return shellFull, nil
+}
+
+// toolboxImage returns the image the sidecar and derived-image modes take their tools from:
+// --toolbox-image, then DCE_TOOLBOX_IMAGE, so an organization can point every user at an
+// internally blessed image
+func (m *Manager) toolboxImage() string {
+	if m.devOptions.ToolboxImage != "" {
+		return m.devOptions.ToolboxImage
+	}
+	if image := os.Getenv("DCE_TOOLBOX_IMAGE"); image != "" {
+		return image
+	}
+	return defaultToolboxImage
+}
+
+// toolsSidecarName returns the name of a dev container's toolbox sidecar
+func toolsSidecarName(devContainerName string) string {
+	return devContainerName + "-tools"
+}
+
+// sidecarCreate returns a container running the command in the target container's process and
+// network namespaces, with SYS_PTRACE and any further capabilities, labeled as the target's companion
+func sidecarCreate(name, target, image string, command []string, capAdd ...string) containerCreate {
+	capAdd = append([]string{"SYS_PTRACE"}, capAdd...)
+	args := []string{"--name", name, "--pid", "container:" + target, "--network", "container:" + target}
+	for _, capability := range capAdd {
+		args = append(args, "--cap-add", capability)
+	}
+	args = append(args, "--label", containerconfig.CompanionOfLabel+"="+target, image)
+	return containerCreate{
+		Name: name,
+		Config: &container.Config{
+			Image:  image,
+			Cmd:    command,
+			Labels: map[string]string{containerconfig.CompanionOfLabel: target},
+		},
+		HostConfig: &container.HostConfig{
+			PidMode:     container.PidMode("container:" + target),
+			NetworkMode: container.NetworkMode("container:" + target),
+			CapAdd:      capAdd,
+		},
+		Args: append(args, command...),
+	}
+}
+
+// startToolsSidecar runs the toolbox image next to the dev container, sharing its process and
+// network namespaces so tools in it see the app's processes and ports; SYS_PTRACE lets dlv and
+// strace attach across containers
+func (m *Manager) startToolsSidecar(devContainerName string) (string, error) {
+	name := toolsSidecarName(devContainerName)
+	if err := m.ensureImage(m.toolboxImage()); err != nil {
+		return "", err
+	}
+	m.logger.Printf("Starting toolbox sidecar '%s' from '%s'...", name, m.toolboxImage())
+	engine := m.engineClient()
+	if _, err := engine.CreateContainer(sidecarCreate(name, devContainerName, m.toolboxImage(), []string{"sleep", "infinity"})); err != nil {
+		return "", fmt.Errorf("failed to start toolbox sidecar: %w", err)
+	}
+	m.track("container", name)
+	if err := engine.Start(name); err != nil {
+		return "", fmt.Errorf("failed to start toolbox sidecar: %w", err)
+	}
+	return name, nil
+}
+
+// toolsTarget returns the container provisioning steps run in: the dev container when it has a
+// shell, otherwise its toolbox sidecar, started when --tools sidecar is given or the user accepts it
+func (m *Manager) toolsTarget(devContainerName string) (string, error) {
+	sidecar := toolsSidecarName(devContainerName)
+	if m.resourceExists("container", sidecar) {
+		return sidecar, nil
+	}
+
+	shell, err := m.probeShell(devContainerName)
+	if err != nil {
+		return "", err
+	}
+	if shell == shellMinimal && len(m.devOptions.devPackages()) > 0 {
+		m.logger.Warnf("'%s' has a shell but no package manager (BusyBox); packages need --tools sidecar with a toolbox image that has them", devContainerName)
+	}
+	if shell != shellNone {
+		return devContainerName, nil
+	}
+
+	noShell := &errNoShell{container: devContainerName}
+	switch m.devOptions.Tools {
+	case ToolsSidecar:
+		return m.startToolsSidecar(devContainerName)
+	case ToolsAuto:
+		warnf(os.Stderr, "%v", noShell)
+		if confirm(tr("prompt.tools.sidecar", m.toolboxImage())) {
+			return m.startToolsSidecar(devContainerName)
+		}
+	}
+	return "", noShell
+}
+
+// derivedImageDockerfile adds the toolbox's static busybox and its applets to an image, as root
+// since distroless images often default to a non-root user, then restores the image's user
+const derivedImageDockerfile = `FROM %s AS tools
+FROM %s
+USER root
+COPY --from=tools /bin/busybox /bin/busybox
+RUN ["/bin/busybox", "--install", "-s", "/bin"]
+USER %s
+`
+
+// buildToolsImage builds a derived dev image with a shell from the toolbox image and returns its tag
+// The tag is derived from both images, so a rebuild reuses the same tag
+func (m *Manager) buildToolsImage(devContainerName, image string) (string, error) {
+	action, tag, err := m.toolsImageAction(devContainerName, image)
+	if err != nil {
+		return "", err
+	}
+
+	m.logger.Printf("Building derived image '%s' from '%s' with tools from '%s'...", tag, image, m.toolboxImage())
+	if err := registryError(action.Description, action.Image, m.engineClient().Build(*action.Build)); err != nil {
+		return "", err
+	}
+	return tag, nil
+}
+
+// toolsImageAction returns the docker build of the derived dev image and the image's tag
+func (m *Manager) toolsImageAction(devContainerName, image string) (PlanAction, string, error) {
+	sum := sha256.Sum256([]byte(image + "\n" + m.toolboxImage()))
+	tag, err := m.templateName(m.devOptions.ImageTemplate, containerconfig.NameImage, devContainerName, "", "dce-tools:"+hex.EncodeToString(sum[:6]))
+	if err != nil {
+		return PlanAction{}, "", err
+	}
+
+	user := "root"
+	info, err := m.InspectImage(image)
+	if err != nil {
+		return PlanAction{}, "", err
+	}
+	if info.User != "" {
+		user = info.User
+	}
+
+	build := buildRequest{
+		Dockerfile: fmt.Sprintf(derivedImageDockerfile, m.toolboxImage(), image, user),
+		Tags:       []string{tag},
+		Labels:     map[string]string{containerconfig.CompanionOfLabel: devContainerName},
+	}
+	return PlanAction{
+		Kind:        ActionBuild,
+		Description: "build derived image",
+		Image:       m.toolboxImage(),
+		Args:        build.args(),
+		Build:       &build,
+	}, tag, nil
+}
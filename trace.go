@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Tools the trace subcommand runs
+const (
+	TraceStrace  = "strace"
+	TraceTcpdump = "tcpdump"
+)
+
+// defaultTraceImage has both strace and tcpdump, unlike the busybox toolbox
+const defaultTraceImage = "nicolaka/netshoot"
+
+// timeoutExitCode is the exit code of timeout when it stopped the command, which is how every trace ends
+const timeoutExitCode = 124
+
+// traceCommand returns the command the trace sidecar runs: strace attached to a process of the
+// target, or tcpdump on all its interfaces, writing to stdout for the given number of seconds
+// timeout sends SIGINT so strace detaches and tcpdump flushes its capture
+func traceCommand(tool string, seconds int, pid int) ([]string, error) {
+	timeout := []string{"timeout", "-s", "INT", strconv.Itoa(seconds)}
+	switch tool {
+	case TraceStrace:
+		return append(timeout, "strace", "-f", "-tt", "-o", "/dev/stdout", "-p", strconv.Itoa(pid)), nil
+	case TraceTcpdump:
+		return append(timeout, "tcpdump", "-i", "any", "-U", "-w", "-"), nil
+	default:
+		return nil, fmt.Errorf("invalid --tool value '%s' (expected %s or %s)", tool, TraceStrace, TraceTcpdump)
+	}
+}
+
+// traceOutputName returns the default host file of a trace, e.g. myapp-tcpdump-20240102-150405.pcap
+func traceOutputName(containerName, tool string, now time.Time) string {
+	ext := ".txt"
+	if tool == TraceTcpdump {
+		ext = ".pcap"
+	}
+	return fmt.Sprintf("%s-%s-%s%s", containerName, tool, now.Format("20060102-150405"), ext)
+}
+
+// Trace runs strace or tcpdump against a container for the given duration from a sidecar sharing
+// its namespaces, as the toolbox sidecar does, and writes the output to a host file
+// Nothing is installed in the container itself, so it also works for images without a shell
+func (m *Manager) Trace(containerName, tool string, duration time.Duration, pid int, image, output string) error {
+	seconds := int(math.Ceil(duration.Seconds()))
+	if seconds <= 0 {
+		return fmt.Errorf("invalid --duration %s (expected a positive duration such as 30s)", duration)
+	}
+	command, err := traceCommand(tool, seconds, pid)
+	if err != nil {
+		return err
+	}
+	if err := m.ensureImage(image); err != nil {
+		return err
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create '%s': %w", output, err)
+	}
+	defer file.Close()
+
+	var capAdd []string
+	if tool == TraceTcpdump {
+		capAdd = []string{"NET_ADMIN", "NET_RAW"}
+	}
+	create := sidecarCreate(containerName+"-trace", containerName, image, command, capAdd...)
+	create.HostConfig.AutoRemove = true
+	create.Args = append([]string{"--rm"}, create.Args...)
+	m.logger.Printf("Running %s against '%s' for %ds from '%s'...", tool, containerName, seconds, image)
+	code, err := m.engineClient().RunContainer(create, commandIO{Stdout: file, Stderr: os.Stderr})
+	if err == nil && code != 0 && code != timeoutExitCode {
+		err = fmt.Errorf("exit status %d", code)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(output)
+		return fmt.Errorf("failed to run %s against '%s': %w", tool, containerName, err)
+	}
+	return nil
+}
+
+// runTrace implements the trace subcommand: a one-shot strace or tcpdump of a running container
+func runTrace(args []string) error {
+	fs := newFlagSet("trace")
+	tool := fs.String("tool", TraceStrace, "tool to run: strace or tcpdump")
+	duration := fs.Duration("duration", 30*time.Second, "how long to trace")
+	pid := fs.Int("pid", 1, "process strace attaches to, as numbered in the container")
+	image := fs.String("image", defaultTraceImage, "image providing strace and tcpdump")
+	output := fs.String("output", "", "host file the output is written to (default: <container>-<tool>-<time>.txt or .pcap)")
+	dockerContext := fs.String("context", "", "docker context of the container")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: trace <container> [--tool strace|tcpdump] [--duration 30s] [--pid n] [--image image] [--output file] [--context name]")
+	}
+
+	manager := NewManager(positional[0], "")
+	manager.SetDockerContext(*dockerContext)
+	manager.logger.SetOutput(io.Discard)
+	if *output == "" {
+		*output = traceOutputName(positional[0], *tool, time.Now())
+	}
+	if err := manager.Trace(positional[0], *tool, *duration, *pid, *image, *output); err != nil {
+		return err
+	}
+	successf(os.Stderr, "✓ Wrote %s output of '%s' to %s", *tool, positional[0], *output)
+	return nil
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// contextEndpoint returns the daemon endpoint of the manager's docker context, e.g. ssh://user@host;
+// without a --context it is the one docker itself uses: DOCKER_HOST, or the current context's
+func (m *Manager) contextEndpoint() (*url.URL, error) {
+	endpoint, name := m.dockerContext, m.dockerContext
+	if endpoint == "" {
+		endpoint = os.Getenv("DOCKER_HOST")
+	}
+	if !isEndpoint(endpoint) {
+		if name == "" {
+			name = m.currentContext()
+		}
+		endpoint = client.DefaultDockerHost
+		if name != defaultContextName {
+			stored, err := readContextEndpoint(name)
+			if err != nil {
+				return nil, err
+			}
+			endpoint = stored.Host
+		}
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint '%s' of docker context '%s': %w", endpoint, name, err)
+	}
+	return u, nil
+}
+
+// containerIP returns the container's address on its first network
+func (m *Manager) containerIP(containerName string) (string, error) {
+	out, err := m.engineClient().Inspect("container", containerName)
+	if err != nil {
+		return "", fmt.Errorf("failed to read container address: %w", err)
+	}
+	var inspected []struct {
+		NetworkSettings struct {
+			Networks map[string]struct{ IPAddress string }
+		}
+	}
+	if err := json.Unmarshal([]byte(out), &inspected); err != nil {
+		return "", fmt.Errorf("failed to parse inspect JSON for '%s': %w", containerName, err)
+	}
+	var addresses []string
+	if len(inspected) > 0 {
+		networks := inspected[0].NetworkSettings.Networks
+		names := make([]string, 0, len(networks))
+		for name := range networks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if address := networks[name].IPAddress; address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		return "", fmt.Errorf("container '%s' has no network address", containerName)
+	}
+	return addresses[0], nil
+}
+
+// tunnelBindAddress returns the local address tunnels listen on, reachable from local containers
+// through host-gateway: the default bridge's gateway on Linux, loopback with Docker Desktop
+func (m *Manager) tunnelBindAddress() string {
+	if runtime.GOOS != "linux" {
+		return "127.0.0.1"
+	}
+	out, err := m.engineClient().Inspect("network", "bridge")
+	var inspected []struct {
+		IPAM struct {
+			Config []struct{ Gateway string }
+		}
+	}
+	if err == nil && json.Unmarshal([]byte(out), &inspected) == nil && len(inspected) > 0 {
+		for _, config := range inspected[0].IPAM.Config {
+			if config.Gateway != "" {
+				return config.Gateway
+			}
+		}
+	}
+	m.logger.Warnf("bridge gateway unknown, tunnels listen on all interfaces")
+	return "0.0.0.0"
+}
+
+// remoteHostEntry returns the extra host address local containers reach a remote target's
+// dev container at: host-gateway for an ssh context, where a tunnel is started, or the daemon host
+// itself for a tcp endpoint, where the dev container's published ports are used
+func (m *Manager) remoteHostEntry() (string, error) {
+	endpoint, err := m.contextEndpoint()
+	if err != nil {
+		return "", err
+	}
+	switch endpoint.Scheme {
+	case "ssh":
+		return containerconfig.HostGatewayAddress, nil
+	case "tcp":
+		addrs, err := net.LookupHost(endpoint.Hostname())
+		if err != nil || len(addrs) == 0 {
+			return "", fmt.Errorf("failed to resolve docker host '%s': %v", endpoint.Hostname(), err)
+		}
+		return addrs[0], nil
+	default:
+		return "", fmt.Errorf("docker context '%s' (%s) is not an ssh or tcp endpoint; targets on it can't be reached from the current context", m.dockerContext, endpoint)
+	}
+}
+
+// startTunnel forwards the container ports of a dev container on the manager's ssh context to the
+// local bind address with ssh -L; the tunnel is stopped on teardown
+func (m *Manager) startTunnel(containerName string, ports []int, bind string) error {
+	endpoint, err := m.contextEndpoint()
+	if err != nil {
+		return err
+	}
+	if endpoint.Scheme != "ssh" {
+		return nil
+	}
+	ip, err := m.containerIP(containerName)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-N", "-o", "ExitOnForwardFailure=yes"}
+	if endpoint.Port() != "" {
+		args = append(args, "-p", endpoint.Port())
+	}
+	for _, port := range ports {
+		p := strconv.Itoa(port)
+		args = append(args, "-L", bind+":"+p+":"+ip+":"+p)
+	}
+	host := endpoint.Hostname()
+	if endpoint.User != nil {
+		host = endpoint.User.Username() + "@" + host
+	}
+	args = append(args, host)
+
+	m.logger.Printf("Tunneling %s:%v to '%s' on %s...", bind, ports, containerName, endpoint.Hostname())
+	cmd := exec.Command("ssh", args...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ssh tunnel: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	// ExitOnForwardFailure makes ssh exit right away when a port can't be bound
+	select {
+	case err := <-exited:
+		return fmt.Errorf("ssh tunnel to '%s' exited: %v", endpoint.Hostname(), err)
+	case <-time.After(2 * time.Second):
+	}
+	pidFile, err := tunnelPIDFile(containerName)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(pidFile), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0o644)
+	}
+	if err != nil {
+		m.logger.Warnf("failed to record the tunnel, down won't be able to stop it: %v", err)
+	}
+	m.cleanup.push(fmt.Sprintf("stop tunnel to '%s'", containerName), func() error {
+		cmd.Process.Kill()
+		<-exited
+		os.Remove(pidFile)
+		return nil
+	})
+	return nil
+}
+
+// tunnelPIDFile is where the PID of the ssh tunnel to a dev container is recorded, so that down can
+// stop a tunnel whose up is no longer around to do it
+func tunnelPIDFile(containerName string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate tunnel directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "docker-config-extractor", "tunnels", containerName+".pid"), nil
+}
+
+// stopRecordedTunnel stops the ssh tunnel recorded for a dev container, if its process is still an ssh
+func stopRecordedTunnel(containerName string) error {
+	pidFile, err := tunnelPIDFile(containerName)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		return nil
+	}
+	defer os.Remove(pidFile)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil
+	}
+	// Where /proc exists, make sure the PID wasn't reused by another program
+	if comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid)); err == nil && strings.TrimSpace(string(comm)) != "ssh" {
+		return nil
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return nil
+	}
+	if err := process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to stop tunnel to '%s': %w", containerName, err)
+	}
+	return nil
+}
+
+// hasRecordedTunnel reports whether a tunnel to the dev container was recorded
+func hasRecordedTunnel(containerName string) bool {
+	pidFile, err := tunnelPIDFile(containerName)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(pidFile)
+	return err == nil
+}
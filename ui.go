@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// ANSI escape sequences used for colored output
+const (
+	ansiReset  = "\033[0m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+)
+
+// colorEnabled is switched off by --no-color or the NO_COLOR environment variable (https://no-color.org)
+var colorEnabled = os.Getenv("NO_COLOR") == ""
+
+// colorsFor reports whether output to w should be colored: only terminals get escape sequences
+func colorsFor(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return colorEnabled && ok && isTerminal(f)
+}
+
+// colorize wraps text in a color when w is a terminal
+func colorize(w io.Writer, color, text string) string {
+	if !colorsFor(w) {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+// warnf prints a yellow warning line
+func warnf(w io.Writer, format string, args ...interface{}) {
+	fmt.Fprintln(w, colorize(w, ansiYellow, tr("label.warning")+fmt.Sprintf(format, args...)))
+}
+
+// successf prints a green success line; leading newlines are kept outside the color
+func successf(w io.Writer, format string, args ...interface{}) {
+	text := fmt.Sprintf(format, args...)
+	lead := ""
+	for len(text) > 0 && text[0] == '\n' {
+		lead, text = lead+"\n", text[1:]
+	}
+	fmt.Fprintln(w, lead+colorize(w, ansiGreen, text))
+}
+
+// highlight colors a generated command or other output meant to be copied
+func highlight(w io.Writer, text string) string {
+	return colorize(w, ansiCyan, text)
+}
+
+// fatalf prints a red error line to stderr and exits
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintln(os.Stderr, colorize(os.Stderr, ansiRed, tr("label.error")+fmt.Sprintf(format, args...)))
+	os.Exit(1)
+}
+
+// cliLogger is a leveled logger: Printf and Println log informational lines, Warnf and Errorf
+// log colored warnings and errors
+type cliLogger struct {
+	*log.Logger
+}
+
+// newCLILogger creates a leveled logger writing to out
+func newCLILogger(out io.Writer, prefix string) *cliLogger {
+	return &cliLogger{Logger: log.New(out, prefix, log.LstdFlags)}
+}
+
+// Warnf logs a warning
+func (l *cliLogger) Warnf(format string, args ...interface{}) {
+	l.Output(2, colorize(l.Writer(), ansiYellow, tr("label.warning")+fmt.Sprintf(format, args...)))
+}
+
+// Errorf logs an error without exiting
+func (l *cliLogger) Errorf(format string, args ...interface{}) {
+	l.Output(2, colorize(l.Writer(), ansiRed, tr("label.error")+fmt.Sprintf(format, args...)))
+}
+
+// stripGlobalFlags removes the flags that apply to every command, wherever they appear, and applies them
+func stripGlobalFlags(args []string) []string {
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--no-color" || arg == "-no-color":
+			colorEnabled = false
+		case (arg == "--lang" || arg == "-lang") && i+1 < len(args):
+			i++
+			applyLang(args[i])
+		case strings.HasPrefix(arg, "--lang=") || strings.HasPrefix(arg, "-lang="):
+			_, value, _ := strings.Cut(arg, "=")
+			applyLang(value)
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest
+}
+
+// applyLang switches the message language, warning and keeping the current one if unsupported
+func applyLang(name string) {
+	if err := setLocale(name); err != nil {
+		warnf(os.Stderr, "%v", err)
+	}
+}
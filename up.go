@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lhc03/docker-config-extractor/pkg/containerconfig"
+)
+
+// Restart policies applied by the up supervisor
+const (
+	SuperviseRestartOnFailure = "on-failure"
+	SuperviseRestartAlways    = "always"
+	SuperviseRestartNever     = "never"
+)
+
+// SuperviseOptions configures how up supervises the dev container
+type SuperviseOptions struct {
+	// Restart is on-failure, always or never
+	Restart string
+	// MaxRestarts stops supervision after this many restarts; 0 means unlimited
+	MaxRestarts int
+	// RestartDelay is the pause before each restart
+	RestartDelay time.Duration
+	// SyncInterval is how often the --copy sources are checked for changes; 0 turns syncing off
+	SyncInterval time.Duration
+	// RestartOnSync restarts the container after its sources were synced, for apps that read them
+	// only at startup; such restarts don't count toward MaxRestarts
+	RestartOnSync bool
+	// Replace removes an existing dev container of the same name without asking
+	Replace bool
+}
+
+// shouldRestart reports whether a container that exited with the given code is restarted
+func (o SuperviseOptions) shouldRestart(exitCode, restarts int) bool {
+	if o.MaxRestarts > 0 && restarts >= o.MaxRestarts {
+		return false
+	}
+	switch o.Restart {
+	case SuperviseRestartAlways:
+		return true
+	case SuperviseRestartOnFailure:
+		return exitCode != 0
+	default:
+		return false
+	}
+}
+
+// logStream is a container's output being followed
+type logStream struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Wait blocks until the stream ends with the container's stop
+func (s *logStream) Wait() {
+	<-s.done
+}
+
+// Stop ends the stream and waits for it
+func (s *logStream) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+// streamLogs follows the container's output on stdout/stderr until the container stops or the
+// stream is stopped; since limits the output to logs newer than the given time
+func (m *Manager) streamLogs(containerName string, since time.Time) *logStream {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &logStream{cancel: cancel, done: make(chan struct{})}
+	engine := m.engineClient()
+	go func() {
+		defer close(stream.done)
+		err := engine.Logs(ctx, containerName, logsRequest{Follow: true, Since: since}, commandIO{Stdout: os.Stdout, Stderr: os.Stderr})
+		if err != nil && ctx.Err() == nil {
+			m.logger.Warnf("failed to stream logs: %v", err)
+		}
+	}()
+	return stream
+}
+
+// waitExit blocks until the container stops and returns its exit code
+func (m *Manager) waitExit(containerName string) (int, error) {
+	code, err := m.engineClient().Wait(containerName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to wait for container '%s': %w", containerName, err)
+	}
+	return code, nil
+}
+
+// replaceDevContainer removes an existing dev container before up creates it anew, which discards
+// whatever state it holds, so it takes --replace or the user's confirmation
+func (m *Manager) replaceDevContainer(devContainerName string, replace bool) error {
+	exists, err := m.CheckDevContainerExists(devContainerName)
+	if err != nil || !exists {
+		return err
+	}
+	if !replace && !confirm(tr("prompt.up.replace", devContainerName)) {
+		return fmt.Errorf("dev container '%s' already exists; pass --replace to remove it and create it anew", devContainerName)
+	}
+	m.logger.Printf("Replacing existing dev container '%s'", devContainerName)
+	return m.removeResource("container", devContainerName)
+}
+
+// Supervise keeps the dev container running in the foreground: its logs are streamed, its --copy
+// sources are copied in again when they change, it is restarted according to the options when it
+// exits or, with RestartOnSync, after a sync, and everything created for it is torn down on Ctrl+C
+// or when supervision ends
+func (m *Manager) Supervise(containerName string, opts SuperviseOptions) error {
+	defer m.Teardown()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	// ticks stays nil, and never fires, without sources to sync
+	var workspace *workspaceSync
+	var ticks <-chan time.Time
+	if opts.SyncInterval > 0 && len(m.devOptions.Copies) > 0 {
+		var err error
+		if workspace, err = m.newWorkspaceSync(containerName); err != nil {
+			return err
+		}
+		ticker := time.NewTicker(opts.SyncInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	var since time.Time
+	restarts := 0
+	for {
+		logs := m.streamLogs(containerName, since)
+
+		type exit struct {
+			code int
+			err  error
+		}
+		exited := make(chan exit, 1)
+		go func() {
+			code, err := m.waitExit(containerName)
+			exited <- exit{code, err}
+		}()
+
+		var result exit
+		// synced is set when the container was stopped to pick up synced sources
+		synced := false
+	running:
+		for {
+			select {
+			case <-signals:
+				m.logger.Println("Interrupted, shutting down...")
+				logs.Stop()
+				return nil
+			case <-ticks:
+				if workspace.run() && opts.RestartOnSync && !synced {
+					m.logger.Printf("Stopping '%s' to restart it with the synced files...", containerName)
+					if err := m.engineClient().Stop(containerName); err != nil {
+						m.logger.Warnf("failed to stop container '%s': %v", containerName, err)
+						continue
+					}
+					synced = true
+				}
+			case result = <-exited:
+				break running
+			}
+		}
+		logs.Wait()
+		if result.err != nil {
+			return result.err
+		}
+		if synced {
+			m.logger.Printf("Restarting '%s' with the synced files...", containerName)
+		} else {
+			m.logger.Printf("Container '%s' exited with code %d", containerName, result.code)
+			if !opts.shouldRestart(result.code, restarts) {
+				if result.code != 0 {
+					return fmt.Errorf("container '%s' exited with code %d", containerName, result.code)
+				}
+				return nil
+			}
+
+			restarts++
+			m.logger.Printf("Restarting '%s' (restart %d)...", containerName, restarts)
+			select {
+			case <-signals:
+				m.logger.Println("Interrupted, shutting down...")
+				return nil
+			case <-time.After(opts.RestartDelay):
+			}
+		}
+		since = time.Now()
+		if err := m.engineClient().Start(containerName); err != nil {
+			return fmt.Errorf("failed to restart container '%s': %w", containerName, err)
+		}
+	}
+}
+
+// runUp creates the dev container and supervises it in the foreground until interrupted
+func runUp(args []string) error {
+	fs := newFlagSet("up")
+	var devOpts DevOptions
+	addDevFlags(fs, &devOpts)
+	var opts SuperviseOptions
+	fs.StringVar(&opts.Restart, "restart", SuperviseRestartOnFailure, "restart the dev container when it exits: on-failure, always or never")
+	fs.IntVar(&opts.MaxRestarts, "max-restarts", 5, "stop after this many restarts (0 = unlimited)")
+	fs.DurationVar(&opts.RestartDelay, "restart-delay", time.Second, "pause before each restart")
+	fs.DurationVar(&opts.SyncInterval, "sync-interval", 2*time.Second, "how often --copy sources (and copy-mode sync rules) are checked and copied in again when changed; 0 turns it off. Bind-mounted sources are live and need no syncing")
+	fs.BoolVar(&opts.RestartOnSync, "restart-on-sync", false, "restart the dev container after synced sources were copied in, for apps that read them only at startup")
+	fs.BoolVar(&opts.Replace, "replace", false, "remove an existing dev container of the same name without asking")
+	projectPath := fs.String("project", "", "project file declaring the targets to bring up (default: "+containerconfig.ProjectFileName+" when its targets are named)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := devOpts.validate(); err != nil {
+		return err
+	}
+	if devOpts.Ephemeral {
+		return fmt.Errorf("--ephemeral cannot be combined with up, which already tears everything down on exit")
+	}
+	switch opts.Restart {
+	case SuperviseRestartOnFailure, SuperviseRestartAlways, SuperviseRestartNever:
+	default:
+		return fmt.Errorf("invalid --restart value '%s' (expected on-failure, always or never)", opts.Restart)
+	}
+
+	project, err := loadProject(*projectPath, positional)
+	if err != nil {
+		return err
+	}
+	if project != nil {
+		names, err := project.Select(positional)
+		if err != nil {
+			return err
+		}
+		return UpProject(project, names, devOpts, opts)
+	}
+	if len(positional) == 0 {
+		return fmt.Errorf("usage: up [dev flags] <container> [dev-name] [swap-dir] | up [target...] [--project dce.yaml] [--restart on-failure|always|never] [--max-restarts n] [--sync-interval d] [--restart-on-sync] [--replace]")
+	}
+
+	devSwapDir := ""
+	if len(positional) >= 3 {
+		devSwapDir = positional[2]
+	}
+
+	// The supervisor owns restarts, so docker's restart policy is dropped
+	devOpts.Supervised = true
+	manager := NewManager(positional[0], devSwapDir)
+	manager.SetDevOptions(devOpts)
+
+	devContainerName, err := manager.devContainerName(positional)
+	if err != nil {
+		return err
+	}
+
+	if err := manager.replaceDevContainer(devContainerName, opts.Replace); err != nil {
+		return err
+	}
+
+	if err := manager.CreateDevContainer(devContainerName, true, devOpts.Inject); err != nil {
+		manager.Teardown()
+		return fmt.Errorf("failed to create dev container: %w", err)
+	}
+
+	successf(os.Stdout, "\n✓ Supervising '%s' (restart: %s). Press Ctrl+C to stop and clean up.\n", devContainerName, opts.Restart)
+	return manager.Supervise(devContainerName, opts)
+}
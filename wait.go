@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// containerState is the part of docker inspect output readiness depends on
+type containerState struct {
+	ID    string `json:"Id"`
+	State struct {
+		Status    string           `json:"Status"`
+		Pid       int              `json:"Pid"`
+		ExitCode  int              `json:"ExitCode"`
+		OOMKilled bool             `json:"OOMKilled"`
+		Error     string           `json:"Error"`
+		Health    *containerHealth `json:"Health"`
+	} `json:"State"`
+}
+
+// healthyTimeout is the least time given to a container with a healthcheck to report healthy,
+// since healthchecks commonly have start periods longer than a plain start takes
+const healthyTimeout = 2 * time.Minute
+
+// readContainerState inspects a container's current state, bypassing the inspect cache
+func (m *Manager) readContainerState(containerName string) (*containerState, error) {
+	m.forget(containerName)
+	data, err := m.inspectContainerJSON(containerName)
+	if err != nil {
+		return nil, err
+	}
+	var states []containerState
+	if err := json.Unmarshal([]byte(data), &states); err != nil || len(states) == 0 {
+		return nil, fmt.Errorf("failed to read state of container '%s'", containerName)
+	}
+	return &states[0], nil
+}
+
+// waitForContainer waits until the container is running, or healthy when it has a healthcheck
+// It follows the daemon's event stream from since (the time the container was created) instead of
+// polling, so it returns as soon as the container starts and fails right away when it dies
+func (m *Manager) waitForContainer(containerName string, since time.Time, timeout time.Duration) error {
+	m.logger.Printf("Waiting for container '%s' to be ready...", containerName)
+
+	state, err := m.readContainerState(containerName)
+	if err != nil {
+		return err
+	}
+	healthcheck := state.State.Health != nil
+	if healthcheck && timeout < healthyTimeout {
+		timeout = healthyTimeout
+	}
+	switch {
+	case state.State.Status == "exited" || state.State.Status == "dead":
+		return m.crashError(containerName, state.State.ExitCode)
+	case state.State.Status == "running" && (!healthcheck || state.State.Health.Status == "healthy"):
+		m.logger.Printf("Container '%s' is running", containerName)
+		return nil
+	}
+
+	// Events are replayed from since, so a start or die that happened before subscribing isn't missed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, errs := m.engineClient().Events(ctx, state.ID, since, []string{"start", "die", "health_status"})
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case err := <-errs:
+			if err != nil && err != io.EOF {
+				return fmt.Errorf("docker events stream ended while waiting for container '%s': %w", containerName, err)
+			}
+			return fmt.Errorf("docker events stream ended while waiting for container '%s'", containerName)
+		case event := <-events:
+			switch {
+			case event.Action == "die":
+				code := -1
+				fmt.Sscan(event.Attributes["exitCode"], &code)
+				return m.crashError(containerName, code)
+			case event.Action == "start" && !healthcheck:
+				m.logger.Printf("Container '%s' is running", containerName)
+				return nil
+			case event.Action == "health_status: healthy":
+				m.logger.Printf("Container '%s' is healthy", containerName)
+				return nil
+			case event.Action == "health_status: unhealthy":
+				// Running but failing its healthcheck is common for a dev build; don't block debugging on it
+				m.logger.Warnf("container '%s' is running but reports unhealthy", containerName)
+				return nil
+			}
+		case <-timer.C:
+			if healthcheck {
+				return fmt.Errorf("timeout waiting for container '%s' to become healthy", containerName)
+			}
+			return fmt.Errorf("timeout waiting for container '%s' to start", containerName)
+		}
+	}
+}
+
+// crashLogLines is how many of the last log lines a crash report includes
+const crashLogLines = 50
+
+// crashError describes a container that exited while it was expected to start, with its exit code,
+// the reason docker recorded and its last log lines, so the cause is visible without digging
+func (m *Manager) crashError(containerName string, exitCode int) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "container '%s' exited right after starting (exit code %d", containerName, exitCode)
+	if state, err := m.readContainerState(containerName); err == nil {
+		if state.State.OOMKilled {
+			b.WriteString(", killed for running out of memory")
+		}
+		if state.State.Error != "" {
+			fmt.Fprintf(&b, ", %s", state.State.Error)
+		}
+	}
+	b.WriteString(")")
+
+	var logs bytes.Buffer
+	tail := logsRequest{Tail: crashLogLines}
+	if err := m.engineClient().Logs(context.Background(), containerName, tail, commandIO{Stdout: &logs, Stderr: &logs}); err != nil {
+		fmt.Fprintf(&b, "\n(logs unavailable: %v)", err)
+	} else if output := strings.TrimRight(logs.String(), "\n"); output != "" {
+		fmt.Fprintf(&b, "\nlast %d log lines:\n%s", crashLogLines, output)
+	} else {
+		b.WriteString("\n(no log output)")
+	}
+	return errors.New(b.String())
+}